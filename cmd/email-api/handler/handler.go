@@ -3,20 +3,30 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/phbpx/gobeer/internal/mail"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/pkg/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// maxEventSize limits the size of the notification body.
+const maxEventSize = 64 << 10
+
 var propagator = otel.GetTextMapPropagator()
 
-// New creates a new http.Handler.
-func New(tracer trace.Tracer) http.Handler {
+// New creates a new http.Handler, delivering the notifications by email.
+func New(log *logger.Logger, tracer trace.Tracer, mailer *mail.Mailer) http.Handler {
 	router := mux.NewRouter()
 	router.HandleFunc("/users/{userID}/notify", func(w http.ResponseWriter, r *http.Request) {
 		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
@@ -27,18 +37,109 @@ func New(tracer trace.Tracer) http.Handler {
 		userID := vars["userID"]
 		span.SetAttributes(attribute.String("user_id", userID))
 
-		doSomething(ctx, tracer)
+		e, legacy, err := decodeEvent(r, userID)
+		if err != nil {
+			span.RecordError(err)
+
+			var herr *httpError
+			if !errors.As(err, &herr) {
+				herr = &httpError{code: http.StatusBadRequest, err: err}
+			}
+			respondError(w, herr)
+			return
+		}
+
+		// The gobeer-api instances not upgraded yet still send bodyless
+		// notifications.
+		if legacy {
+			log.Warn(ctx, "notify", "status", "deprecated bodyless notification", "user_id", userID)
+		} else {
+			span.SetAttributes(
+				attribute.String("event_id", e.ID),
+				attribute.String("event_type", e.Type),
+			)
+		}
+
+		if err := deliver(ctx, tracer, mailer, e); err != nil {
+			span.RecordError(err)
+			log.Error(ctx, "notify", "status", "delivering email", "user_id", userID, "ERROR", err)
+
+			// An unknown user won't become known by retrying, any other
+			// failure is reported as retryable to the gobeer-api.
+			code := http.StatusBadGateway
+			if errors.Is(err, mail.ErrUnknownUser) {
+				code = http.StatusNotFound
+			}
+			respondError(w, &httpError{code: code, err: err})
+			return
+		}
 
-		time.Sleep(3 * time.Millisecond)
 		w.WriteHeader(http.StatusOK)
 	})
 
 	return router
 }
 
-func doSomething(ctx context.Context, tracer trace.Tracer) {
-	_, span := tracer.Start(ctx, "external-service")
+// deliver sends the email of the event, in a span of its own.
+func deliver(ctx context.Context, tracer trace.Tracer, mailer *mail.Mailer, e notifications.Event) error {
+	ctx, span := tracer.Start(ctx, "deliver-email")
 	defer span.End()
 
-	time.Sleep(10 * time.Millisecond)
+	return mailer.Deliver(ctx, e)
+}
+
+// =============================================================================
+
+// httpError is an error with the status code to answer with.
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// respondError answers with the error in a JSON document.
+func respondError(w http.ResponseWriter, err *httpError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// decodeEvent decodes and validates the event sent to the user. A request
+// without body is the legacy notification, carrying nothing but the user.
+func decodeEvent(r *http.Request, userID string) (notifications.Event, bool, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxEventSize))
+	if err != nil {
+		var merr *http.MaxBytesError
+		if errors.As(err, &merr) {
+			return notifications.Event{}, false, &httpError{code: http.StatusRequestEntityTooLarge, err: err}
+		}
+		return notifications.Event{}, false, err
+	}
+
+	if len(body) == 0 {
+		return notifications.Event{UserID: userID}, true, nil
+	}
+
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		err := fmt.Errorf("unsupported content type %q", r.Header.Get("Content-Type"))
+		return notifications.Event{}, false, &httpError{code: http.StatusUnsupportedMediaType, err: err}
+	}
+
+	var e notifications.Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return notifications.Event{}, false, fmt.Errorf("decoding event: %w", err)
+	}
+
+	if err := e.Validate(); err != nil {
+		return notifications.Event{}, false, err
+	}
+
+	if e.UserID != userID {
+		return notifications.Event{}, false, fmt.Errorf("%w: user_id doesn't match the path", notifications.ErrInvalidEvent)
+	}
+
+	return e, false, nil
 }
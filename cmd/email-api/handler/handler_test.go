@@ -0,0 +1,205 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/cmd/email-api/handler"
+	"github.com/phbpx/gobeer/internal/mail"
+	"github.com/phbpx/gobeer/internal/mail/smtptest"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/pkg/logger"
+	"go.opentelemetry.io/otel"
+)
+
+func TestNotify(t *testing.T) {
+	srv, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("Should be able to start the smtp server: %v", err)
+	}
+	defer srv.Close()
+
+	userID := uuid.NewString()
+	h := newHandler(t, srv.Addr, mail.StaticDirectory{
+		userID: {Name: "Jane", Email: "jane@example.com"},
+	})
+
+	event := notifications.Event{
+		Version:    notifications.EventVersion,
+		ID:         uuid.NewString(),
+		Type:       notifications.KindReviewCreated,
+		OccurredAt: time.Now(),
+		UserID:     userID,
+		Review:     notifications.EventReview{ID: uuid.NewString(), Score: 4},
+		Beer:       notifications.EventBeer{ID: uuid.NewString(), Name: "IPA", Brewery: "BrewDog"},
+	}
+	unknown := event
+	unknown.UserID = uuid.NewString()
+
+	tt := []struct {
+		when        string
+		userID      string
+		body        func() []byte
+		contentType string
+		code        int
+		sent        int
+	}{
+		{
+			when:        "the event is valid",
+			userID:      userID,
+			body:        func() []byte { return encode(t, event) },
+			contentType: "application/json",
+			code:        http.StatusOK,
+			sent:        1,
+		},
+		{
+			when:   "the notification has no body",
+			userID: userID,
+			body:   func() []byte { return nil },
+			code:   http.StatusOK,
+			sent:   1,
+		},
+		{
+			when:        "the user is not in the directory",
+			userID:      unknown.UserID,
+			body:        func() []byte { return encode(t, unknown) },
+			contentType: "application/json",
+			code:        http.StatusNotFound,
+		},
+		{
+			when:   "the event version is not supported",
+			userID: userID,
+			body: func() []byte {
+				e := event
+				e.Version = notifications.EventVersion + 1
+				return encode(t, e)
+			},
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+		},
+		{
+			when:   "the event misses the beer",
+			userID: userID,
+			body: func() []byte {
+				e := event
+				e.Beer = notifications.EventBeer{}
+				return encode(t, e)
+			},
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+		},
+		{
+			when:        "the event is sent to another user",
+			userID:      userID,
+			body:        func() []byte { return encode(t, unknown) },
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+		},
+		{
+			when:        "the body is not JSON",
+			userID:      userID,
+			body:        func() []byte { return []byte("{") },
+			contentType: "application/json",
+			code:        http.StatusBadRequest,
+		},
+		{
+			when:        "the body is not declared as JSON",
+			userID:      userID,
+			body:        func() []byte { return encode(t, event) },
+			contentType: "text/plain",
+			code:        http.StatusUnsupportedMediaType,
+		},
+	}
+
+	t.Log("Given the need to receive the notifications")
+	{
+		for _, tc := range tt {
+			t.Logf("\tWhen %s", tc.when)
+			{
+				before := len(srv.Messages())
+
+				r := httptest.NewRequest(http.MethodPost, "/users/"+tc.userID+"/notify", bytes.NewReader(tc.body()))
+				if tc.contentType != "" {
+					r.Header.Set("Content-Type", tc.contentType)
+				}
+				w := httptest.NewRecorder()
+
+				h.ServeHTTP(w, r)
+
+				if w.Code != tc.code {
+					t.Fatalf("\t\t[ERROR] Should receive a status code of %d for the response. Received[%d]: %s", tc.code, w.Code, w.Body)
+				}
+				t.Logf("\t\t[OK] Should receive a status code of %d for the response.", tc.code)
+
+				if sent := len(srv.Messages()) - before; sent != tc.sent {
+					t.Fatalf("\t\t[ERROR] Should send %d emails. Sent %d", tc.sent, sent)
+				}
+				t.Logf("\t\t[OK] Should send %d emails.", tc.sent)
+			}
+		}
+	}
+}
+
+func TestNotifySMTPDown(t *testing.T) {
+	srv, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("Should be able to start the smtp server: %v", err)
+	}
+	srv.Close()
+
+	userID := uuid.NewString()
+	h := newHandler(t, srv.Addr, mail.StaticDirectory{
+		userID: {Name: "Jane", Email: "jane@example.com"},
+	})
+
+	t.Log("Given the need to report the delivery failures as retryable")
+	{
+		t.Log("\tWhen the smtp server is down")
+		{
+			r := httptest.NewRequest(http.MethodPost, "/users/"+userID+"/notify", nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusBadGateway {
+				t.Fatalf("\t\t[ERROR] Should receive a status code of %d for the response. Received[%d]: %s", http.StatusBadGateway, w.Code, w.Body)
+			}
+			t.Logf("\t\t[OK] Should receive a status code of %d for the response.", http.StatusBadGateway)
+		}
+	}
+}
+
+func newHandler(t *testing.T, smtpAddr string, directory mail.Directory) http.Handler {
+	t.Helper()
+
+	templates, err := mail.ParseTemplates()
+	if err != nil {
+		t.Fatalf("Should be able to parse the templates: %v", err)
+	}
+
+	sender, err := mail.NewSMTPSender(mail.SMTPConfig{Addr: smtpAddr, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Should be able to create the sender: %v", err)
+	}
+
+	from := mail.Address{Name: "gobeer", Email: "no-reply@gobeer.local"}
+	mailer := mail.NewMailer(from, directory, templates, sender)
+
+	return handler.New(logger.New(io.Discard, logger.LevelInfo, "TEST"), otel.Tracer(""), mailer)
+}
+
+func encode(t *testing.T, v any) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Should be able to encode the event: %v", err)
+	}
+	return data
+}
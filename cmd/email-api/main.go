@@ -12,6 +12,7 @@ import (
 
 	"github.com/ardanlabs/conf/v3"
 	"github.com/phbpx/gobeer/cmd/email-api/handler"
+	"github.com/phbpx/gobeer/internal/mail"
 	"github.com/phbpx/gobeer/pkg/logger"
 	"github.com/phbpx/gobeer/pkg/tracing"
 )
@@ -41,6 +42,18 @@ func run(ctx context.Context, log *logger.Logger) error {
 			ShutdownTimeout time.Duration `conf:"default:20s"`
 			APIHost         string        `conf:"default:0.0.0.0:3001"`
 		}
+		SMTP struct {
+			Addr       string `conf:"default:localhost:1025"`
+			Username   string
+			Password   string        `conf:"mask"`
+			RequireTLS bool          `conf:"default:false"`
+			Timeout    time.Duration `conf:"default:10s"`
+			FromName   string        `conf:"default:gobeer"`
+			FromEmail  string        `conf:"default:no-reply@gobeer.local"`
+		}
+		Directory struct {
+			Path string
+		}
 		Tracing struct {
 			ReporterURI string  `conf:"default:http://localhost:14268/api/traces"`
 			Probability float64 `conf:"default:1.0"`
@@ -70,6 +83,38 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	tracer := tp.Tracer("")
 
+	// -------------------------------------------------------------------------
+	// Mail Support
+
+	log.Info(ctx, "startup", "status", "initializing mail support", "smtp", cfg.SMTP.Addr)
+
+	directory := mail.StaticDirectory{}
+	if cfg.Directory.Path != "" {
+		if directory, err = mail.LoadDirectory(cfg.Directory.Path); err != nil {
+			return fmt.Errorf("loading directory: %w", err)
+		}
+	}
+	log.Info(ctx, "startup", "status", "directory loaded", "users", len(directory))
+
+	templates, err := mail.ParseTemplates()
+	if err != nil {
+		return fmt.Errorf("parsing templates: %w", err)
+	}
+
+	sender, err := mail.NewSMTPSender(mail.SMTPConfig{
+		Addr:       cfg.SMTP.Addr,
+		Username:   cfg.SMTP.Username,
+		Password:   cfg.SMTP.Password,
+		RequireTLS: cfg.SMTP.RequireTLS,
+		Timeout:    cfg.SMTP.Timeout,
+	})
+	if err != nil {
+		return fmt.Errorf("creating smtp sender: %w", err)
+	}
+
+	from := mail.Address{Name: cfg.SMTP.FromName, Email: cfg.SMTP.FromEmail}
+	mailer := mail.NewMailer(from, directory, templates, sender)
+
 	// -------------------------------------------------------------------------
 	// Start API Service
 
@@ -78,7 +123,7 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// Create a new HTTP server.
 	srv := http.Server{
 		Addr:         cfg.Server.APIHost,
-		Handler:      handler.New(tracer),
+		Handler:      handler.New(log, tracer, mailer),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
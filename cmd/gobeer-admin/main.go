@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ardanlabs/conf/v3"
+	"github.com/phbpx/gobeer/internal/registering"
+	"github.com/phbpx/gobeer/internal/storage/postgres"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+const service = "gobeer-admin"
+
+const usage = `Usage: gobeer-admin <command>
+
+Commands:
+  check-aggregates           Report the beers whose review aggregates drifted
+  repair-aggregates          Recompute the review aggregates of every beer
+  grant-role <user> <role>   Change the role of a user (admin, moderator, brewer, member)`
+
+func main() {
+	ctx := context.Background()
+	log := logger.New(os.Stdout, logger.LevelInfo, service)
+
+	if err := run(ctx, log); err != nil {
+		log.Error(ctx, "admin", "ERROR", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, log *logger.Logger) error {
+	// -------------------------------------------------------------------------
+	// Configuration
+
+	cfg := struct {
+		conf.Version
+		Args conf.Args
+		DB   struct {
+			User       string        `conf:"default:postgres"`
+			Password   string        `conf:"default:postgres,mask"`
+			Host       string        `conf:"default:localhost"`
+			Name       string        `conf:"default:testdb"`
+			DisableTLS bool          `conf:"default:true"`
+			Timeout    time.Duration `conf:"default:5m"`
+		}
+	}{}
+
+	const prefix = "GOBEER"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			fmt.Println(usage)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Database Support
+
+	db, err := postgres.Open(postgres.Config{
+		User:         cfg.DB.User,
+		Password:     cfg.DB.Password,
+		Host:         cfg.DB.Host,
+		Name:         cfg.DB.Name,
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+		DisableTLS:   cfg.DB.DisableTLS,
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.DB.Timeout)
+	defer cancel()
+
+	store := postgres.NewStore(db)
+
+	// -------------------------------------------------------------------------
+	// Commands
+
+	if cfg.Args.Num(0) == "grant-role" {
+		return grantRole(ctx, log, store, cfg.Args.Num(1), cfg.Args.Num(2))
+	}
+
+	var drifts []postgres.AggregateDrift
+
+	switch cmd := cfg.Args.Num(0); cmd {
+	case "check-aggregates":
+		drifts, err = store.CheckAggregates(ctx)
+	case "repair-aggregates":
+		drifts, err = store.RepairAggregates(ctx)
+	default:
+		fmt.Println(usage)
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.Args.Num(0), err)
+	}
+
+	for _, d := range drifts {
+		log.Warn(ctx, "aggregate drift",
+			"beer_id", d.BeerID,
+			"review_count", d.ReviewCount,
+			"expected_review_count", d.ExpectedCount,
+			"score_sum", d.ScoreSum,
+			"expected_score_sum", d.ExpectedScoreSum,
+			"sub_scores_drifted", d.SubScores,
+		)
+	}
+
+	log.Info(ctx, cfg.Args.Num(0), "status", "completed", "drifts", len(drifts))
+
+	return nil
+}
+
+// grantRole changes the role of a user, such as the first admin, who can then
+// grant the roles through the API.
+func grantRole(ctx context.Context, log *logger.Logger, store *postgres.Store, userID, role string) error {
+	u, err := registering.NewService(store).ChangeRole(ctx, userID, registering.ChangeRole{Role: role})
+	if err != nil {
+		return fmt.Errorf("grant-role: %w", err)
+	}
+
+	log.Info(ctx, "grant-role", "status", "completed", "user_id", u.ID, "handle", u.Handle, "role", u.Role)
+
+	return nil
+}
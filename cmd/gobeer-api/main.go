@@ -11,8 +11,20 @@ import (
 	"time"
 
 	"github.com/ardanlabs/conf/v3"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/delivering"
+	"github.com/phbpx/gobeer/internal/email"
+	"github.com/phbpx/gobeer/internal/events"
 	"github.com/phbpx/gobeer/internal/http/server"
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/internal/notifying"
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/storage/file"
+	"github.com/phbpx/gobeer/internal/storage/memory"
 	"github.com/phbpx/gobeer/internal/storage/postgres"
+	"github.com/phbpx/gobeer/internal/streaming"
 	"github.com/phbpx/gobeer/pkg/logger"
 	"github.com/phbpx/gobeer/pkg/tracing"
 )
@@ -35,7 +47,8 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	cfg := struct {
 		conf.Version
-		Server struct {
+		Storage string `conf:"default:postgres"`
+		Server  struct {
 			ReadTimeout     time.Duration `conf:"default:5s"`
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
@@ -51,8 +64,67 @@ func run(ctx context.Context, log *logger.Logger) error {
 			MaxOpenConns int    `conf:"default:0"`
 			DisableTLS   bool   `conf:"default:true"`
 		}
+		File struct {
+			Dir           string `conf:"default:data"`
+			SnapshotEvery int    `conf:"default:1000"`
+		}
 		Notifier struct {
-			EmailURL string `conf:"default:https://localhost:3001"`
+			EmailURL         string        `conf:"default:https://localhost:3001"`
+			Timeout          time.Duration `conf:"default:5s"`
+			MaxAttempts      int           `conf:"default:3"`
+			MinBackoff       time.Duration `conf:"default:100ms"`
+			MaxBackoff       time.Duration `conf:"default:5s"`
+			MaxIdleConns     int           `conf:"default:10"`
+			BreakerThreshold int           `conf:"default:5"`
+			BreakerCooldown  time.Duration `conf:"default:30s"`
+		}
+		Outbox struct {
+			Interval    time.Duration `conf:"default:1s"`
+			BatchSize   int           `conf:"default:10"`
+			Lease       time.Duration `conf:"default:5m"`
+			Timeout     time.Duration `conf:"default:20s"`
+			MaxAttempts int           `conf:"default:10"`
+			MinBackoff  time.Duration `conf:"default:1s"`
+			MaxBackoff  time.Duration `conf:"default:10m"`
+		}
+		Webhooks struct {
+			Interval    time.Duration `conf:"default:1s"`
+			BatchSize   int           `conf:"default:10"`
+			Lease       time.Duration `conf:"default:1m"`
+			Timeout     time.Duration `conf:"default:10s"`
+			MaxAttempts int           `conf:"default:10"`
+			MinBackoff  time.Duration `conf:"default:10s"`
+			MaxBackoff  time.Duration `conf:"default:1h"`
+		}
+		Events struct {
+			QueueSize int `conf:"default:100"`
+		}
+		Stream struct {
+			Heartbeat time.Duration `conf:"default:15s"`
+			Buffer    int           `conf:"default:32"`
+			MaxReplay int           `conf:"default:100"`
+		}
+		Auth struct {
+			Secret   string `conf:"mask"`
+			JWKSFile string
+			Issuer   string
+			Audience string
+			Leeway   time.Duration `conf:"default:1m"`
+		}
+		RateLimit struct {
+			Default        string   `conf:"default:600/1m"`
+			Routes         []string `conf:"default:POST /beers/:id/reviews=10/1m;POST /users=10/1h"`
+			TrustedProxies []string
+		}
+		Idempotency struct {
+			TTL time.Duration `conf:"default:24h"`
+		}
+		Ranking struct {
+			PriorMean   float32 `conf:"default:3.5"`
+			PriorWeight float32 `conf:"default:10"`
+		}
+		Purge struct {
+			Interval time.Duration `conf:"default:10m"`
 		}
 		Tracing struct {
 			ReporterURI string  `conf:"default:http://localhost:14268/api/traces"`
@@ -72,35 +144,102 @@ func run(ctx context.Context, log *logger.Logger) error {
 	}
 
 	// -------------------------------------------------------------------------
-	// Database Support
-
-	// Create connectivity to the database.
-	log.Info(ctx, "startup", "status", "initializing database support", "host", cfg.DB.Host)
-
-	db, err := postgres.Open(postgres.Config{
-		User:         cfg.DB.User,
-		Password:     cfg.DB.Password,
-		Host:         cfg.DB.Host,
-		Name:         cfg.DB.Name,
-		MaxIdleConns: cfg.DB.MaxIdleConns,
-		MaxOpenConns: cfg.DB.MaxOpenConns,
-		DisableTLS:   cfg.DB.DisableTLS,
-	})
-	if err != nil {
-		return fmt.Errorf("connecting to db: %w", err)
+	// Storage Support
+
+	// The storage is used by the http server and by the notification and
+	// webhook dispatchers.
+	var storage interface {
+		server.Storage
+		notifying.Repository
+		delivering.Repository
 	}
-	defer func() {
-		db.Close()
-	}()
 
-	// -------------------------------------------------------------------------
-	// Update the schema, if needed.
+	// The new reviews are streamed to the clients of every instance through
+	// the database, when it's shared. Otherwise they're taken from the events
+	// of this instance.
+	var listenReviews func(ctx context.Context, fn func(reviews.Review)) error
+
+	// The rate limits and the idempotency keys are shared by the instances
+	// through the database, when it's shared, which purges them once expired.
+	// Otherwise they're kept by this instance.
+	var (
+		limitStore ratelimit.Store
+		idemStore  idempotency.Store
+		purge      func(ctx context.Context, now time.Time) error
+	)
+
+	switch cfg.Storage {
+	case "memory":
+		log.Info(ctx, "startup", "status", "initializing memory storage")
+
+		storage = memory.NewStore()
+
+	case "file":
+		log.Info(ctx, "startup", "status", "initializing file storage", "dir", cfg.File.Dir)
+
+		store, err := file.Open(file.Config{
+			Dir:           cfg.File.Dir,
+			SnapshotEvery: cfg.File.SnapshotEvery,
+		})
+		if err != nil {
+			return fmt.Errorf("opening file storage: %w", err)
+		}
+		defer func() {
+			log.Info(ctx, "shutdown", "status", "stopping file storage", "dir", cfg.File.Dir)
+			if err := store.Close(); err != nil {
+				log.Error(ctx, "shutdown", "status", "closing file storage", "ERROR", err)
+			}
+		}()
+
+		storage = store
+
+	case "postgres":
+		// Create connectivity to the database.
+		log.Info(ctx, "startup", "status", "initializing database support", "host", cfg.DB.Host)
+
+		dbCfg := postgres.Config{
+			User:         cfg.DB.User,
+			Password:     cfg.DB.Password,
+			Host:         cfg.DB.Host,
+			Name:         cfg.DB.Name,
+			MaxIdleConns: cfg.DB.MaxIdleConns,
+			MaxOpenConns: cfg.DB.MaxOpenConns,
+			DisableTLS:   cfg.DB.DisableTLS,
+		}
+
+		db, err := postgres.Open(dbCfg)
+		if err != nil {
+			return fmt.Errorf("connecting to db: %w", err)
+		}
+		defer func() {
+			log.Info(ctx, "shutdown", "status", "stopping database support", "host", cfg.DB.Host)
+			db.Close()
+		}()
+
+		// Update the schema, if needed.
+		log.Info(ctx, "startup", "status", "updating database schema", "database", cfg.DB.Name, "host", cfg.DB.Host)
+
+		if err := postgres.RunMigrations(context.Background(), db, log); err != nil {
+			return fmt.Errorf("migrating db: %w", err)
+		}
 
-	log.Info(ctx, "startup", "status", "updating database schema", "database", cfg.DB.Name, "host", cfg.DB.Host)
+		store := postgres.NewStore(db)
+		storage = store
 
-	if err := postgres.RunMigrations(context.Background(), db, log); err != nil {
-		log.Info(ctx, "shutdown", "status", "stopping database support", "host", cfg.DB.Host)
-		return fmt.Errorf("migrating db: %w", err)
+		listenReviews = func(ctx context.Context, fn func(reviews.Review)) error {
+			return postgres.ListenReviews(ctx, dbCfg, log, store, fn)
+		}
+
+		limitStore = store
+		idemStore = store
+		purge = func(ctx context.Context, now time.Time) error {
+			_, limitErr := store.PurgeRateLimits(ctx, now)
+			_, idemErr := store.PurgeIdempotencyKeys(ctx, now)
+			return errors.Join(limitErr, idemErr)
+		}
+
+	default:
+		return fmt.Errorf("unknown storage %q", cfg.Storage)
 	}
 
 	// -------------------------------------------------------------------------
@@ -116,6 +255,227 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	tracer := tp.Tracer("")
 
+	// -------------------------------------------------------------------------
+	// Start Notification Dispatcher
+
+	log.Info(ctx, "startup", "status", "initializing notification dispatcher")
+
+	notifier := email.NewEmailNotifier(email.Config{
+		URL:              cfg.Notifier.EmailURL,
+		Timeout:          cfg.Notifier.Timeout,
+		MaxAttempts:      cfg.Notifier.MaxAttempts,
+		MinBackoff:       cfg.Notifier.MinBackoff,
+		MaxBackoff:       cfg.Notifier.MaxBackoff,
+		MaxIdleConns:     cfg.Notifier.MaxIdleConns,
+		BreakerThreshold: cfg.Notifier.BreakerThreshold,
+		BreakerCooldown:  cfg.Notifier.BreakerCooldown,
+		OnStateChange: func(from, to email.State) {
+			log.Warn(ctx, "notifier", "status", "circuit breaker changed", "from", from, "to", to)
+		},
+	})
+
+	// The notifications of a batch are sent one at a time, so the lease must
+	// outlast every one of them timing out.
+	if batch := time.Duration(cfg.Outbox.BatchSize) * cfg.Outbox.Timeout; cfg.Outbox.Lease <= batch {
+		return fmt.Errorf("parsing outbox lease: %v must be longer than the batch size times the timeout %v", cfg.Outbox.Lease, batch)
+	}
+
+	dispatcher := notifying.NewDispatcher(log, storage, notifier, outbox.Config{
+		Interval:    cfg.Outbox.Interval,
+		BatchSize:   cfg.Outbox.BatchSize,
+		Lease:       cfg.Outbox.Lease,
+		Timeout:     cfg.Outbox.Timeout,
+		MaxAttempts: cfg.Outbox.MaxAttempts,
+		MinBackoff:  cfg.Outbox.MinBackoff,
+		MaxBackoff:  cfg.Outbox.MaxBackoff,
+	})
+
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatchDone := make(chan struct{})
+
+	go func() {
+		defer close(dispatchDone)
+		dispatcher.Run(dispatchCtx)
+	}()
+
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping notification dispatcher")
+		stopDispatch()
+		<-dispatchDone
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Webhook Dispatcher
+
+	log.Info(ctx, "startup", "status", "initializing webhook dispatcher")
+
+	// The deliveries of a batch are sent concurrently, so the lease only has
+	// to outlast the slowest of them.
+	if cfg.Webhooks.Lease <= cfg.Webhooks.Timeout {
+		return fmt.Errorf("parsing webhooks lease: %v must be longer than the timeout %v", cfg.Webhooks.Lease, cfg.Webhooks.Timeout)
+	}
+
+	webhookDispatcher := delivering.NewDispatcher(log, storage, outbox.Config{
+		Interval:    cfg.Webhooks.Interval,
+		BatchSize:   cfg.Webhooks.BatchSize,
+		Lease:       cfg.Webhooks.Lease,
+		Timeout:     cfg.Webhooks.Timeout,
+		MaxAttempts: cfg.Webhooks.MaxAttempts,
+		MinBackoff:  cfg.Webhooks.MinBackoff,
+		MaxBackoff:  cfg.Webhooks.MaxBackoff,
+	})
+
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhookDone := make(chan struct{})
+
+	go func() {
+		defer close(webhookDone)
+		webhookDispatcher.Run(webhookCtx)
+	}()
+
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping webhook dispatcher")
+		stopWebhooks()
+		<-webhookDone
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Event Bus
+
+	log.Info(ctx, "startup", "status", "initializing event bus")
+
+	bus := events.NewBus(log, events.Config{
+		QueueSize: cfg.Events.QueueSize,
+	})
+
+	bus.SubscribeAsync("log", func(ctx context.Context, e events.Event) error {
+		log.Info(ctx, "events", "status", "event published", "event", e.EventName(), "id", e.EventID())
+		return nil
+	})
+
+	defer func() {
+		log.Info(ctx, "shutdown", "status", "stopping event bus")
+		bus.Close()
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Review Streams
+
+	log.Info(ctx, "startup", "status", "initializing review streams")
+
+	hub := streaming.NewHub(cfg.Stream.Buffer)
+
+	if listenReviews == nil {
+		bus.Subscribe("streaming", hub.Handler())
+	} else {
+		listenCtx, stopListen := context.WithCancel(context.Background())
+		listenDone := make(chan struct{})
+
+		go func() {
+			defer close(listenDone)
+			if err := listenReviews(listenCtx, hub.Publish); err != nil {
+				log.Error(ctx, "streaming", "status", "listening to reviews", "ERROR", err)
+			}
+		}()
+
+		defer func() {
+			log.Info(ctx, "shutdown", "status", "stopping review listener")
+			stopListen()
+			<-listenDone
+		}()
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Authentication Support
+
+	log.Info(ctx, "startup", "status", "initializing authentication support", "jwks", cfg.Auth.JWKSFile)
+
+	a, err := auth.New(auth.Config{
+		Secret:   cfg.Auth.Secret,
+		JWKSFile: cfg.Auth.JWKSFile,
+		Issuer:   cfg.Auth.Issuer,
+		Audience: cfg.Auth.Audience,
+		Leeway:   cfg.Auth.Leeway,
+	})
+	if err != nil {
+		return fmt.Errorf("starting auth: %w", err)
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Rate Limiting Support
+
+	log.Info(ctx, "startup", "status", "initializing rate limiting support", "default", cfg.RateLimit.Default)
+
+	defaultLimit, err := ratelimit.ParseLimit(cfg.RateLimit.Default)
+	if err != nil {
+		return fmt.Errorf("parsing default rate limit: %w", err)
+	}
+
+	routeLimits, err := ratelimit.ParseRoutes(cfg.RateLimit.Routes)
+	if err != nil {
+		return fmt.Errorf("parsing route rate limits: %w", err)
+	}
+
+	if limitStore == nil {
+		limitStore = ratelimit.NewMemory()
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Idempotency Support
+
+	log.Info(ctx, "startup", "status", "initializing idempotency support", "ttl", cfg.Idempotency.TTL)
+
+	if idemStore == nil {
+		idemStore = idempotency.NewMemory()
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Purge of Expired Records
+
+	if purge != nil {
+		log.Info(ctx, "startup", "status", "initializing purge of expired records", "interval", cfg.Purge.Interval)
+
+		purgeCtx, stopPurge := context.WithCancel(context.Background())
+		purgeDone := make(chan struct{})
+
+		go func() {
+			defer close(purgeDone)
+
+			ticker := time.NewTicker(cfg.Purge.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-purgeCtx.Done():
+					return
+				case <-ticker.C:
+					if err := purge(purgeCtx, time.Now().UTC()); err != nil {
+						log.Error(ctx, "purge", "status", "purging expired records", "ERROR", err)
+					}
+				}
+			}
+		}()
+
+		defer func() {
+			log.Info(ctx, "shutdown", "status", "stopping purge of expired records")
+			stopPurge()
+			<-purgeDone
+		}()
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Rankings
+
+	prior := reviews.Prior{Mean: cfg.Ranking.PriorMean, Weight: cfg.Ranking.PriorWeight}
+	if scale := reviews.DefaultScale; prior.Mean < scale.Min || prior.Mean > scale.Max {
+		return fmt.Errorf("parsing ranking prior mean: %v is not between %v and %v", prior.Mean, scale.Min, scale.Max)
+	}
+	if prior.Weight < 0 {
+		return fmt.Errorf("parsing ranking prior weight: %v is negative", prior.Weight)
+	}
+
+	log.Info(ctx, "startup", "status", "initializing rankings", "prior_mean", prior.Mean, "prior_weight", prior.Weight)
+
 	// -------------------------------------------------------------------------
 	// Start API Service
 
@@ -123,10 +483,29 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	// Create handler.
 	h := server.New(server.Config{
-		Log:         log,
-		Tracer:      tracer,
-		DB:          db,
-		NotifierURL: cfg.Notifier.EmailURL,
+		Log:     log,
+		Tracer:  tracer,
+		Storage: storage,
+		Events:  bus,
+		Auth:    a,
+		Stream: server.StreamConfig{
+			Hub:       hub,
+			Heartbeat: cfg.Stream.Heartbeat,
+			MaxReplay: cfg.Stream.MaxReplay,
+		},
+		RateLimit: server.RateLimitConfig{
+			Store: limitStore,
+			Limits: ratelimit.Limits{
+				Default: defaultLimit,
+				Routes:  routeLimits,
+			},
+			TrustedProxies: cfg.RateLimit.TrustedProxies,
+		},
+		Idempotency: server.IdempotencyConfig{
+			Store: idemStore,
+			TTL:   cfg.Idempotency.TTL,
+		},
+		Prior: prior,
 	})
 
 	// Create a new HTTP server.
@@ -162,6 +541,9 @@ func run(ctx context.Context, log *logger.Logger) error {
 		log.Info(ctx, "shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Info(ctx, "shutdown", "status", "shutdown complete", "signal", sig)
 
+		// The review streams never end by themselves.
+		hub.Close()
+
 		// Give outstanding requests a deadline for completion.
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -1,57 +1,98 @@
-// Package adding defines the use case for adding a beer.
+// Package adding defines the use cases for adding a beer or a brewery.
 package adding
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
 // NewBeer represents a new beer to be added to the system.
 type NewBeer struct {
 	Name      string  `json:"name" binding:"required"`
-	Brewery   string  `json:"brewery" binding:"required"`
+	BreweryID string  `json:"brewery_id" binding:"required"`
 	Style     string  `json:"style" binding:"required"`
 	ABV       float32 `json:"abv" binding:"required"`
 	ShortDesc string  `json:"short_desc" binding:"required"`
 }
 
+// NewBrewery represents a new brewery to be added to the system.
+type NewBrewery struct {
+	Name        string `json:"name" binding:"required,max=255"`
+	Location    string `json:"location" binding:"max=255"`
+	Website     string `json:"website" binding:"omitempty,url,max=255"`
+	FoundedYear int    `json:"founded_year" binding:"omitempty,min=1000"`
+}
+
 // Repository defines the interface for the adding service to interact
 // with the storage.
 type Repository interface {
-	// CreateBeer adds a new beer to the storage.
-	CreateBeer(ctx context.Context, b beers.Beer) error
+	// CreateBeer adds a new beer to the storage, along with the deliveries of
+	// the event to the webhooks subscribed to it.
+	CreateBeer(ctx context.Context, b beers.Beer, e webhooks.Event) error
 	// BeerExists checks if a beer with the given name and brewery already exists.
-	BeerExists(ctx context.Context, name, brewery string) (bool, error)
+	BeerExists(ctx context.Context, name, breweryID string) (bool, error)
+	// CreateBrewery adds a new brewery to the storage. Its canonical name
+	// must not belong to another brewery.
+	CreateBrewery(ctx context.Context, b breweries.Brewery) error
+	// GetBrewery returns the brewery with the given ID.
+	GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error)
 }
 
 // Service provides adding operations.
 type Service struct {
-	r Repository
+	r       Repository
+	ev      events.Publisher
+	catalog styles.Catalog
 }
 
 // NewService creates an adding service with the necessary dependencies.
-func NewService(r Repository) *Service {
-	return &Service{r}
+func NewService(r Repository, ev events.Publisher, catalog styles.Catalog) *Service {
+	return &Service{r, ev, catalog}
 }
 
-// AddBeer adds a new beer to the system.
+// AddBeer adds a new beer to the system, publishing the BeerAdded event once
+// it's stored. The style must be in the catalog, and its aliases are stored
+// as the canonical style.
 func (s *Service) AddBeer(ctx context.Context, b NewBeer) (*beers.Beer, error) {
+	style, err := s.catalog.Lookup(b.Style)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", err, b.Style)
+	}
+
+	// Validate the brewery ID.
+	if _, err := uuid.Parse(b.BreweryID); err != nil {
+		return nil, breweries.ErrInvalidID
+	}
+
+	brewery, err := s.r.GetBrewery(ctx, b.BreweryID)
+	if err != nil {
+		return nil, fmt.Errorf("get brewery[id=%s]: %w", b.BreweryID, err)
+	}
+
 	beer := beers.Beer{
-		ID:        uuid.NewString(),
-		Name:      b.Name,
-		Brewery:   b.Brewery,
-		Style:     b.Style,
-		ABV:       b.ABV,
-		ShortDesc: b.ShortDesc,
-		Score:     0,
-		CreatedAt: time.Now(),
+		ID:            uuid.NewString(),
+		Name:          b.Name,
+		BreweryID:     brewery.ID,
+		Brewery:       brewery.Name,
+		Style:         style.Name,
+		ABV:           b.ABV,
+		ABVOutOfRange: !style.ABV.Contains(b.ABV),
+		ShortDesc:     b.ShortDesc,
+		Score:         0,
+		CreatedAt:     time.Now(),
+		Version:       1,
 	}
 
 	// Check if the beer already exists.
-	exists, err := s.r.BeerExists(ctx, beer.Name, beer.Brewery)
+	exists, err := s.r.BeerExists(ctx, beer.Name, beer.BreweryID)
 	if err != nil {
 		return nil, err
 	}
@@ -61,5 +102,40 @@ func (s *Service) AddBeer(ctx context.Context, b NewBeer) (*beers.Beer, error) {
 		return nil, beers.ErrAlreadyExists
 	}
 
-	return &beer, s.r.CreateBeer(ctx, beer)
+	e := events.BeerAdded{
+		ID:         uuid.NewString(),
+		OccurredAt: beer.CreatedAt,
+		Beer:       beer,
+	}
+
+	if err := s.r.CreateBeer(ctx, beer, webhooks.NewBeerAdded(e)); err != nil {
+		return nil, err
+	}
+
+	s.ev.Publish(ctx, e)
+
+	return &beer, nil
+}
+
+// AddBrewery adds a new brewery to the system.
+func (s *Service) AddBrewery(ctx context.Context, nb NewBrewery) (*breweries.Brewery, error) {
+	now := time.Now()
+	if nb.FoundedYear > now.Year() {
+		return nil, fmt.Errorf("%w: %d is in the future", breweries.ErrInvalidFoundedYear, nb.FoundedYear)
+	}
+
+	b := breweries.Brewery{
+		ID:          uuid.NewString(),
+		Name:        breweries.DisplayName(nb.Name),
+		Location:    nb.Location,
+		Website:     nb.Website,
+		FoundedYear: nb.FoundedYear,
+		CreatedAt:   now,
+	}
+
+	if err := s.r.CreateBrewery(ctx, b); err != nil {
+		return nil, fmt.Errorf("create brewery: %w", err)
+	}
+
+	return &b, nil
 }
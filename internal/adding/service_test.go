@@ -2,46 +2,91 @@ package adding_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/adding"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
 // mockRepository is a mock implementation of the Repository interface.
 type mockRepository struct {
-	data []beers.Beer
+	data      []beers.Beer
+	breweries []breweries.Brewery
+	events    []webhooks.Event
 }
 
 // CreateBeer creates a new beer.
-func (m *mockRepository) CreateBeer(ctx context.Context, b beers.Beer) error {
+func (m *mockRepository) CreateBeer(ctx context.Context, b beers.Beer, e webhooks.Event) error {
 	m.data = append(m.data, b)
+	m.events = append(m.events, e)
 	return nil
 }
 
 // BeerExists returns true if the beer exists.
-func (m *mockRepository) BeerExists(ctx context.Context, name, brewery string) (bool, error) {
+func (m *mockRepository) BeerExists(ctx context.Context, name, breweryID string) (bool, error) {
 	for _, b := range m.data {
-		if b.Name == name && b.Brewery == brewery {
+		if b.Name == name && b.BreweryID == breweryID {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// CreateBrewery creates a new brewery.
+func (m *mockRepository) CreateBrewery(ctx context.Context, b breweries.Brewery) error {
+	for _, o := range m.breweries {
+		if breweries.Canonical(o.Name) == breweries.Canonical(b.Name) {
+			return breweries.ErrAlreadyExists
+		}
+	}
+	m.breweries = append(m.breweries, b)
+	return nil
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (m *mockRepository) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	for _, b := range m.breweries {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, breweries.ErrNotFound
+}
+
+// =============================================================================
+
+// mockPublisher is a mock implementation of the Publisher interface.
+type mockPublisher struct {
+	published []events.Event
+}
+
+// Publish records the event.
+func (m *mockPublisher) Publish(ctx context.Context, e events.Event) {
+	m.published = append(m.published, e)
+}
+
 func TestAddingBeer(t *testing.T) {
 	ctx := context.Background()
 
-	// Create a mock repository.
-	repo := &mockRepository{}
+	// Create a mock repository with the brewery of the beer.
+	brewery := breweries.Brewery{ID: "1b5bd8f6-5b4c-4a8e-9b5e-6b0d1c2e3f40", Name: "BrewDog"}
+	repo := &mockRepository{breweries: []breweries.Brewery{brewery}}
+	pub := &mockPublisher{}
 
 	// Create a new service with the mock repository.
-	s := adding.NewService(repo)
+	s := adding.NewService(repo, pub, styles.DefaultCatalog)
 
 	// Create a new beer.
 	b := adding.NewBeer{
 		Name:      "IPA",
-		Brewery:   "BrewDog",
+		BreweryID: brewery.ID,
 		Style:     "IPA",
 		ABV:       5.5,
 		ShortDesc: "A very nice IPA",
@@ -51,11 +96,34 @@ func TestAddingBeer(t *testing.T) {
 	{
 		t.Log("\tWhen adding a new beer")
 		{
-			_, err := s.AddBeer(ctx, b)
+			beer, err := s.AddBeer(ctx, b)
 			if err != nil {
 				t.Fatalf("\t\t[ERROR] Should be able to add the beer without error: %v", err)
 			}
 			t.Log("\t\t[OK] Should be able to add the beer without error.")
+
+			if beer.BreweryID != brewery.ID || beer.Brewery != brewery.Name {
+				t.Fatalf("\t\t[ERROR] Should set the brewery of the beer. Got %q, %q", beer.BreweryID, beer.Brewery)
+			}
+			t.Log("\t\t[OK] Should set the brewery of the beer.")
+
+			if beer.Style != "American IPA" || beer.ABVOutOfRange {
+				t.Fatalf("\t\t[ERROR] Should store the canonical style of the alias. Got %q, %v", beer.Style, beer.ABVOutOfRange)
+			}
+			t.Log("\t\t[OK] Should store the canonical style of the alias.")
+
+			if len(repo.events) != 1 || repo.events[0].Type != webhooks.EventBeerAdded || repo.events[0].Beer.ID != beer.ID {
+				t.Fatalf("\t\t[ERROR] Should store the beer_added event. Got %+v", repo.events)
+			}
+			t.Log("\t\t[OK] Should store the beer_added event.")
+
+			if len(pub.published) != 1 {
+				t.Fatalf("\t\t[ERROR] Should publish the BeerAdded event. Got %+v", pub.published)
+			}
+			if e, ok := pub.published[0].(events.BeerAdded); !ok || e.Beer.ID != beer.ID || e.ID != repo.events[0].ID {
+				t.Fatalf("\t\t[ERROR] Should publish the BeerAdded event. Got %+v", pub.published[0])
+			}
+			t.Log("\t\t[OK] Should publish the BeerAdded event.")
 		}
 
 		t.Log("\tWhen adding a beer that already exists")
@@ -64,7 +132,107 @@ func TestAddingBeer(t *testing.T) {
 			if err != beers.ErrAlreadyExists {
 				t.Fatalf("\t\t[ERROR] Should not be able to add the beer: %v", err)
 			}
+			if len(pub.published) != 1 {
+				t.Fatalf("\t\t[ERROR] Should not publish an event. Got %+v", pub.published)
+			}
+			t.Log("\t\t[OK] Should not be able to add the beer.")
+		}
+
+		t.Log("\tWhen adding a beer of an unknown brewery")
+		{
+			nb := b
+			nb.BreweryID = uuid.NewString()
+
+			_, err := s.AddBeer(ctx, nb)
+			if !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to add the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to add the beer.")
+		}
+
+		t.Log("\tWhen adding a beer of an unknown style")
+		{
+			nb := b
+			nb.Name = "Pumpkin Ale"
+			nb.Style = "Pumpkin Spice"
+
+			_, err := s.AddBeer(ctx, nb)
+			if !errors.Is(err, styles.ErrUnknown) {
+				t.Fatalf("\t\t[ERROR] Should not be able to add the beer: %v", err)
+			}
 			t.Log("\t\t[OK] Should not be able to add the beer.")
 		}
+
+		t.Log("\tWhen adding a beer with an ABV outside the range of its style")
+		{
+			nb := b
+			nb.Name = "Light IPA"
+			nb.ABV = 4.2
+
+			beer, err := s.AddBeer(ctx, nb)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to add the beer without error: %v", err)
+			}
+			if !beer.ABVOutOfRange {
+				t.Fatalf("\t\t[ERROR] Should flag the ABV of the beer.")
+			}
+			t.Log("\t\t[OK] Should add the beer flagging its ABV.")
+		}
+	}
+}
+
+func TestAddingBrewery(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &mockRepository{}
+	s := adding.NewService(repo, &mockPublisher{}, styles.DefaultCatalog)
+
+	nb := adding.NewBrewery{
+		Name:        "  Cervejaria   Bodebrown ",
+		Location:    "Curitiba, PR",
+		Website:     "https://bodebrown.com.br",
+		FoundedYear: 2009,
+	}
+
+	t.Log("Given the need to add a new brewery to the system")
+	{
+		t.Log("\tWhen adding a new brewery")
+		{
+			b, err := s.AddBrewery(ctx, nb)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to add the brewery without error: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to add the brewery without error.")
+
+			if b.Name != "Cervejaria Bodebrown" {
+				t.Fatalf("\t\t[ERROR] Should trim the spaces of the name. Got %q", b.Name)
+			}
+			t.Log("\t\t[OK] Should trim the spaces of the name.")
+		}
+
+		t.Log("\tWhen adding another spelling of the brewery")
+		{
+			nb := nb
+			nb.Name = "cervejaria bodebrown"
+
+			_, err := s.AddBrewery(ctx, nb)
+			if !errors.Is(err, breweries.ErrAlreadyExists) {
+				t.Fatalf("\t\t[ERROR] Should not be able to add the brewery: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to add the brewery.")
+		}
+
+		t.Log("\tWhen adding a brewery founded in the future")
+		{
+			nb := nb
+			nb.Name = "Future Brewing"
+			nb.FoundedYear = time.Now().Year() + 1
+
+			_, err := s.AddBrewery(ctx, nb)
+			if !errors.Is(err, breweries.ErrInvalidFoundedYear) {
+				t.Fatalf("\t\t[ERROR] Should not be able to add the brewery: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to add the brewery.")
+		}
 	}
 }
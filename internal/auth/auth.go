@@ -0,0 +1,247 @@
+// Package auth provides the authentication of the requests through JSON Web
+// Tokens, and the policy of what the authenticated users are allowed to do.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnauthenticated is returned when a request has no valid token.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Claims defines the claims of a token used by the API.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+}
+
+// Audience is the audience of a token, given either as a string or as a list
+// of strings.
+type Audience []string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = Audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+
+	return nil
+}
+
+// contains reports whether the audience includes the given one.
+func (a Audience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Config defines how the tokens are validated.
+type Config struct {
+	// Secret is the key of the HS256 tokens. They're rejected when it's
+	// empty.
+	Secret string
+	// JWKSFile is the JSON Web Key Set file holding the public keys of the
+	// RS256 tokens. They're rejected when it's empty.
+	JWKSFile string
+	// Issuer, when given, must be the issuer of the tokens.
+	Issuer string
+	// Audience, when given, must be an audience of the tokens.
+	Audience string
+	// Leeway is the clock skew tolerated when checking the expiration and
+	// the start of the tokens.
+	Leeway time.Duration
+}
+
+// Auth validates the tokens.
+type Auth struct {
+	cfg    Config
+	secret []byte
+	keys   map[string]*rsa.PublicKey
+}
+
+// New creates an Auth from the configuration, loading the public keys from
+// the JWKS file. At least one of the keys must be configured.
+func New(cfg Config) (*Auth, error) {
+	a := Auth{
+		cfg:    cfg,
+		secret: []byte(cfg.Secret),
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("load jwks[file=%s]: %w", cfg.JWKSFile, err)
+		}
+		a.keys = keys
+	}
+
+	if len(a.secret) == 0 && len(a.keys) == 0 {
+		return nil, errors.New("no secret nor public key configured")
+	}
+
+	return &a, nil
+}
+
+// header defines the header of a token.
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Authenticate validates the token, returning its claims.
+func (a *Auth) Authenticate(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed header", ErrUnauthenticated)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed signature", ErrUnauthenticated)
+	}
+
+	if err := a.verify(h, parts[0]+"."+parts[1], sig); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	var c Claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed claims", ErrUnauthenticated)
+	}
+
+	if err := a.validate(c); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	return c, nil
+}
+
+// verify checks the signature of the token with the key of its algorithm.
+// Each algorithm only accepts its own keys, so a public key is never used as
+// an HMAC secret.
+func (a *Auth) verify(h header, signed string, sig []byte) error {
+	switch h.Alg {
+	case "HS256":
+		if len(a.secret) == 0 {
+			return errors.New("HS256 tokens are not accepted")
+		}
+
+		mac := hmac.New(sha256.New, a.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errors.New("invalid signature")
+		}
+
+	case "RS256":
+		key, err := a.key(h.Kid)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("invalid signature")
+		}
+
+	default:
+		return fmt.Errorf("unsupported algorithm %q", h.Alg)
+	}
+
+	return nil
+}
+
+// key returns the public key with the given ID. The ID can be omitted when
+// there's a single key.
+func (a *Auth) key(kid string) (*rsa.PublicKey, error) {
+	if kid == "" && len(a.keys) == 1 {
+		for _, k := range a.keys {
+			return k, nil
+		}
+	}
+
+	k, ok := a.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return k, nil
+}
+
+// validate checks the registered claims.
+func (a *Auth) validate(c Claims) error {
+	now := time.Now()
+	leeway := int64(a.cfg.Leeway / time.Second)
+
+	switch {
+	case c.Subject == "":
+		return errors.New("missing subject")
+	case c.ExpiresAt == 0:
+		return errors.New("missing expiration")
+	case now.Unix() > c.ExpiresAt+leeway:
+		return errors.New("token expired")
+	case c.NotBefore != 0 && now.Unix() < c.NotBefore-leeway:
+		return errors.New("token not valid yet")
+	case a.cfg.Issuer != "" && c.Issuer != a.cfg.Issuer:
+		return errors.New("invalid issuer")
+	case a.cfg.Audience != "" && !c.Audience.contains(a.cfg.Audience):
+		return errors.New("invalid audience")
+	}
+
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a token.
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ctxKey is the type of the keys of the values stored in the context.
+type ctxKey int
+
+const claimsKey ctxKey = 1
+
+// SetClaims returns a copy of the context holding the claims of the
+// authenticated request.
+func SetClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, c)
+}
+
+// GetClaims returns the claims of the authenticated request.
+func GetClaims(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey).(Claims)
+	return c, ok
+}
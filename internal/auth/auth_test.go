@@ -0,0 +1,194 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/auth"
+)
+
+const secret = "test-secret"
+
+func TestAuthenticate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := auth.New(auth.Config{
+		Secret:   secret,
+		JWKSFile: writeJWKS(t, "key-1", &key.PublicKey),
+		Issuer:   "gobeer",
+		Audience: "gobeer-api",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create the auth: %v", err)
+	}
+
+	subject := uuid.NewString()
+	valid := map[string]any{
+		"sub": subject,
+		"iss": "gobeer",
+		"aud": []string{"gobeer-api", "other"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	t.Log("Given the need to authenticate the requests with tokens")
+	{
+		t.Log("\tWhen validating a valid HS256 token")
+		{
+			c, err := a.Authenticate(signHS256(t, secret, valid))
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should accept the token: %v", err)
+			}
+			if c.Subject != subject {
+				t.Fatalf("\t\t[ERROR] Should return the subject. Got %q", c.Subject)
+			}
+			t.Log("\t\t[OK] Should return the subject of the token.")
+		}
+
+		t.Log("\tWhen validating a valid RS256 token")
+		{
+			c, err := a.Authenticate(signRS256(t, key, "key-1", valid))
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should accept the token: %v", err)
+			}
+			if c.Subject != subject {
+				t.Fatalf("\t\t[ERROR] Should return the subject. Got %q", c.Subject)
+			}
+			t.Log("\t\t[OK] Should return the subject of the token.")
+		}
+
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		invalid := []struct {
+			name  string
+			token string
+		}{
+			{"malformed", "not-a-token"},
+			{"signed with another secret", signHS256(t, "other-secret", valid)},
+			{"signed with another key", signRS256(t, other, "key-1", valid)},
+			{"signed with an unknown key", signRS256(t, key, "key-2", valid)},
+			{"unsigned", sign(t, map[string]any{"alg": "none"}, valid, func(string) []byte { return nil })},
+			{"expired", signHS256(t, secret, with(valid, "exp", time.Now().Add(-time.Hour).Unix()))},
+			{"not valid yet", signHS256(t, secret, with(valid, "nbf", time.Now().Add(time.Hour).Unix()))},
+			{"without expiration", signHS256(t, secret, with(valid, "exp", nil))},
+			{"without subject", signHS256(t, secret, with(valid, "sub", ""))},
+			{"from another issuer", signHS256(t, secret, with(valid, "iss", "other"))},
+			{"for another audience", signHS256(t, secret, with(valid, "aud", "other"))},
+		}
+
+		for _, tt := range invalid {
+			t.Logf("\tWhen validating a token %s", tt.name)
+			{
+				if _, err := a.Authenticate(tt.token); !errors.Is(err, auth.ErrUnauthenticated) {
+					t.Fatalf("\t\t[ERROR] Should return ErrUnauthenticated. Got %v", err)
+				}
+				t.Log("\t\t[OK] Should return ErrUnauthenticated.")
+			}
+		}
+
+		t.Log("\tWhen no key is configured")
+		{
+			if _, err := auth.New(auth.Config{}); err == nil {
+				t.Fatal("\t\t[ERROR] Should fail to create the auth.")
+			}
+			t.Log("\t\t[OK] Should fail to create the auth.")
+		}
+	}
+}
+
+// with returns a copy of the claims with the given claim changed, or removed
+// when the value is nil.
+func with(claims map[string]any, name string, v any) map[string]any {
+	c := make(map[string]any, len(claims))
+	for k, v := range claims {
+		c[k] = v
+	}
+
+	if v == nil {
+		delete(c, name)
+	} else {
+		c[name] = v
+	}
+
+	return c
+}
+
+// signHS256 returns a token with the claims signed with the secret.
+func signHS256(t *testing.T, secret string, claims map[string]any) string {
+	return sign(t, map[string]any{"alg": "HS256", "typ": "JWT"}, claims, func(s string) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(s))
+		return mac.Sum(nil)
+	})
+}
+
+// signRS256 returns a token with the claims signed with the key.
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	return sign(t, map[string]any{"alg": "RS256", "typ": "JWT", "kid": kid}, claims, func(s string) []byte {
+		sum := sha256.Sum256([]byte(s))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	})
+}
+
+// sign encodes the token, signing it with the given function.
+func sign(t *testing.T, header, claims map[string]any, fn func(string) []byte) string {
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return s + "." + base64.RawURLEncoding.EncodeToString(fn(s))
+}
+
+// writeJWKS writes a JWKS file with the public key.
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	set := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// ErrForbidden is returned when a user is not allowed to do something.
+var ErrForbidden = errors.New("forbidden")
+
+// Permission defines something a user may be allowed to do.
+type Permission string
+
+// Set of permissions required by the API.
+const (
+	PermAddBeer         Permission = "beers:add"
+	PermEditBeer        Permission = "beers:edit"
+	PermDeleteBeer      Permission = "beers:delete"
+	PermAddBrewery      Permission = "breweries:add"
+	PermModerateReviews Permission = "reviews:moderate"
+	PermManageWebhooks  Permission = "webhooks:manage"
+	PermManageUsers     Permission = "users:manage"
+)
+
+// Policy defines the permissions granted to each role. The actions every
+// user can do, such as reviewing beers, need no permission.
+type Policy map[users.Role][]Permission
+
+// DefaultPolicy is the policy of the API: the brewers manage the catalog,
+// the moderators remove the reviews of other users, and the admins can do
+// everything.
+var DefaultPolicy = Policy{
+	users.RoleAdmin: {
+		PermAddBeer,
+		PermEditBeer,
+		PermDeleteBeer,
+		PermAddBrewery,
+		PermModerateReviews,
+		PermManageWebhooks,
+		PermManageUsers,
+	},
+	users.RoleModerator: {
+		PermModerateReviews,
+	},
+	users.RoleBrewer: {
+		PermAddBeer,
+		PermEditBeer,
+		PermAddBrewery,
+	},
+}
+
+// Allows reports whether the role is granted the permission.
+func (p Policy) Allows(role users.Role, perm Permission) bool {
+	for _, granted := range p[role] {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+// Package authorizing provides the use case for deciding whether a user is
+// allowed to do something, as granted to its role by the policy.
+package authorizing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// Repository defines the interface for the authorizing service to interact
+// with the storage.
+type Repository interface {
+	// GetUser returns the user with the given ID.
+	GetUser(ctx context.Context, id string) (*users.User, error)
+}
+
+// Service provides authorization operations.
+type Service struct {
+	log    *logger.Logger
+	r      Repository
+	policy auth.Policy
+}
+
+// NewService creates an authorizing service with the necessary dependencies.
+func NewService(log *logger.Logger, r Repository, policy auth.Policy) *Service {
+	return &Service{
+		log:    log,
+		r:      r,
+		policy: policy,
+	}
+}
+
+// Authorize returns auth.ErrForbidden unless the role of the user is granted
+// the permission. Users unknown to the storage, and subjects that aren't
+// user IDs, have no role. Every decision is logged, for auditing.
+func (s *Service) Authorize(ctx context.Context, userID string, perm auth.Permission) error {
+	var role users.Role
+
+	if _, err := uuid.Parse(userID); err == nil {
+		u, err := s.r.GetUser(ctx, userID)
+		switch {
+		case err == nil:
+			role = u.Role
+		case !errors.Is(err, users.ErrNotFound):
+			return fmt.Errorf("get user[id=%s]: %w", userID, err)
+		}
+	}
+
+	allowed := s.policy.Allows(role, perm)
+
+	s.log.Info(ctx, "authorization",
+		"subject", userID,
+		"role", role,
+		"permission", perm,
+		"allowed", allowed,
+	)
+
+	if !allowed {
+		return fmt.Errorf("%w: %s requires the %s permission", auth.ErrForbidden, userID, perm)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package authorizing_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/authorizing"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	users []users.User
+}
+
+// GetUser returns the user with the given ID, failing for invalid IDs like
+// the database does.
+func (m *mockRepository) GetUser(ctx context.Context, id string) (*users.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, err
+	}
+	for _, u := range m.users {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+	return nil, users.ErrNotFound
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+
+	brewer := users.User{ID: uuid.NewString(), Role: users.RoleBrewer}
+	member := users.User{ID: uuid.NewString(), Role: users.RoleMember}
+
+	r := &mockRepository{users: []users.User{brewer, member}}
+	s := authorizing.NewService(logger.New(io.Discard, logger.LevelInfo, "TEST"), r, auth.DefaultPolicy)
+
+	t.Log("Given the need to authorize the users.")
+	{
+		t.Log("\tWhen the role of the user is granted the permission.")
+		{
+			if err := s.Authorize(ctx, brewer.ID, auth.PermAddBeer); err != nil {
+				t.Fatalf("\t\t[ERROR] Should allow the user: %v", err)
+			}
+			t.Log("\t\t[OK] Should allow the user.")
+		}
+
+		t.Log("\tWhen the role of the user is not granted the permission.")
+		{
+			if err := s.Authorize(ctx, brewer.ID, auth.PermDeleteBeer); !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("\t\t[ERROR] Should return ErrForbidden. Got %v", err)
+			}
+			if err := s.Authorize(ctx, member.ID, auth.PermAddBeer); !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("\t\t[ERROR] Should return ErrForbidden. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not allow the user.")
+		}
+
+		t.Log("\tWhen the user is unknown.")
+		{
+			if err := s.Authorize(ctx, uuid.NewString(), auth.PermAddBeer); !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("\t\t[ERROR] Should return ErrForbidden. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not allow the user.")
+		}
+
+		t.Log("\tWhen the subject is not a user ID.")
+		{
+			if err := s.Authorize(ctx, "integration-client", auth.PermAddBeer); !errors.Is(err, auth.ErrForbidden) {
+				t.Fatalf("\t\t[ERROR] Should return ErrForbidden. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not allow the subject.")
+		}
+	}
+}
@@ -4,6 +4,8 @@ package beers
 import (
 	"errors"
 	"time"
+
+	"github.com/phbpx/gobeer/internal/reviews"
 )
 
 var (
@@ -15,16 +17,37 @@ var (
 
 	// ErrAlreadyExists is used when a beer already exists.
 	ErrAlreadyExists = errors.New("beer already exists")
+
+	// ErrVersionRequired is used when a beer is changed without telling
+	// which version of it is being changed.
+	ErrVersionRequired = errors.New("beer version required")
+
+	// ErrVersionMismatch is used when a beer was changed since the version
+	// being changed.
+	ErrVersionMismatch = errors.New("beer version mismatch")
 )
 
-// Beer defines the properties of a beer.
+// Beer defines the properties of a beer. The name of its brewery is kept
+// along with the brewery ID, so the beers are listed and searched by it. The
+// beers whose ABV is outside the range of their style are flagged. The
+// sub-scores are the averages of each dimension rated by the reviews. The
+// weighted score is the Bayesian average of the reviews, set on the beers
+// listed to rank them. It's computed from the score sum, the exact sum of the
+// scores kept by the storage, as the ranking is.
 type Beer struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Brewery   string    `json:"brewery"`
-	Style     string    `json:"style"`
-	ABV       float32   `json:"abv"`
-	ShortDesc string    `json:"short_desc"`
-	Score     float32   `json:"score"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	BreweryID     string          `json:"brewery_id"`
+	Brewery       string          `json:"brewery"`
+	Style         string          `json:"style"`
+	ABV           float32         `json:"abv"`
+	ABVOutOfRange bool            `json:"abv_out_of_range"`
+	ShortDesc     string          `json:"short_desc"`
+	Score         float32         `json:"score"`
+	ScoreSum      float64         `json:"-"`
+	WeightedScore *float32        `json:"weighted_score,omitempty"`
+	ReviewCount   int             `json:"review_count"`
+	SubScores     *reviews.Scores `json:"sub_scores,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Version       int             `json:"version"`
 }
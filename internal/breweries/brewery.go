@@ -0,0 +1,65 @@
+// Package breweries defines the brewery domain model.
+package breweries
+
+import (
+	"crypto/md5"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidID is returned when an invalid ID is provided.
+	ErrInvalidID = errors.New("invalid brewery ID")
+
+	// ErrNotFound is used when a brewery is not found.
+	ErrNotFound = errors.New("brewery not found")
+
+	// ErrAlreadyExists is used when a brewery with the same canonical name
+	// already exists.
+	ErrAlreadyExists = errors.New("brewery already exists")
+
+	// ErrInvalidFoundedYear is used when the founding year is in the future.
+	ErrInvalidFoundedYear = errors.New("invalid founded year")
+)
+
+// Brewery defines the properties of a brewery. A founding year of zero means
+// it's unknown.
+type Brewery struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Location    string    `json:"location"`
+	Website     string    `json:"website"`
+	FoundedYear int       `json:"founded_year,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Canonical returns the canonical form of a brewery name, in lower case and
+// with the spaces collapsed, so two spellings of a name are the same brewery.
+func Canonical(name string) string {
+	return strings.ToLower(DisplayName(name))
+}
+
+// DisplayName returns the name without leading, trailing and repeated spaces.
+func DisplayName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Legacy returns the brewery of the beers added while the brewery was a free
+// text, created at the time of its first beer. Its ID is the MD5 of the
+// canonical name, the same one the database migration derives, so every
+// storage turns a name into the same brewery.
+func Legacy(name string, createdAt time.Time) Brewery {
+	return Brewery{
+		ID:        LegacyID(name),
+		Name:      DisplayName(name),
+		CreatedAt: createdAt,
+	}
+}
+
+// LegacyID returns the ID of the legacy brewery with the given name.
+func LegacyID(name string) string {
+	return uuid.UUID(md5.Sum([]byte(Canonical(name)))).String()
+}
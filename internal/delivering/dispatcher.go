@@ -0,0 +1,236 @@
+// Package delivering provides a use case for delivering the events to the
+// webhook subscriptions.
+package delivering
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/internal/webhooks"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// Set of headers sent along with every delivery.
+const (
+	HeaderEvent     = "X-Gobeer-Event"
+	HeaderDelivery  = "X-Gobeer-Delivery"
+	HeaderTimestamp = "X-Gobeer-Timestamp"
+	HeaderSignature = "X-Gobeer-Signature"
+)
+
+// maxErrorBody limits how much of the response body is kept as the error of
+// a failed delivery.
+const maxErrorBody = 512
+
+// Repository defines the interface for the dispatcher to interact with the
+// storage.
+type Repository interface {
+	// ClaimDeliveries returns the pending deliveries due at the given time,
+	// postponing them by the lease so other dispatchers don't take them
+	// while they're being delivered.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Delivery, error)
+	// GetSubscription returns the subscription with the given ID.
+	GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error)
+	// UpdateDelivery stores the result of a delivery attempt.
+	UpdateDelivery(ctx context.Context, d webhooks.Delivery) error
+}
+
+// DefaultConfig is the configuration used for the fields left empty. The
+// timeout limits every request, and the deliveries of a batch are sent
+// concurrently, so the lease only has to outlast the slowest of them.
+var DefaultConfig = outbox.Config{
+	Interval:    time.Second,
+	BatchSize:   10,
+	Lease:       time.Minute,
+	Timeout:     10 * time.Second,
+	MaxAttempts: 10,
+	MinBackoff:  10 * time.Second,
+	MaxBackoff:  time.Hour,
+}
+
+// Dispatcher delivers the pending deliveries, retrying the failed ones with an
+// exponential backoff until they run out of attempts.
+type Dispatcher struct {
+	log    *logger.Logger
+	repo   Repository
+	client *http.Client
+	cfg    outbox.Config
+}
+
+// NewDispatcher creates a dispatcher with the necessary dependencies.
+func NewDispatcher(log *logger.Logger, repo Repository, cfg outbox.Config) *Dispatcher {
+	cfg = cfg.WithDefaults(DefaultConfig)
+
+	return &Dispatcher{
+		log:  log,
+		repo: repo,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// The response of a redirect is reported as is, the subscription
+			// must be changed to the new URL.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}
+}
+
+// Run dispatches the pending deliveries every interval, until the context is
+// canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	outbox.Run(ctx, d.log, "webhooks", d.cfg, d.Dispatch)
+}
+
+// Dispatch delivers a batch of pending deliveries, returning how many were
+// attempted.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	list, err := d.repo.ClaimDeliveries(ctx, time.Now(), d.cfg.Lease, d.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	// The deliveries are sent concurrently, so the batch takes about as long
+	// as its slowest delivery, bounded by the timeout, instead of adding up
+	// and outlasting the lease.
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, dl := range list {
+		wg.Add(1)
+		go func(dl webhooks.Delivery) {
+			defer wg.Done()
+
+			if err := d.deliver(ctx, dl); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(dl)
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+
+	return len(list), nil
+}
+
+// deliver attempts a claimed delivery and stores its result.
+func (d *Dispatcher) deliver(ctx context.Context, dl webhooks.Delivery) error {
+	sub, err := d.repo.GetSubscription(ctx, dl.SubscriptionID)
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		// The subscription was deleted after the delivery was claimed.
+		return nil
+	case err != nil:
+		return fmt.Errorf("get subscription[id=%s]: %w", dl.SubscriptionID, err)
+	}
+
+	status, err := d.send(ctx, *sub, dl)
+
+	now := time.Now()
+	dl.Attempts++
+	dl.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		dl.Status = webhooks.StatusSucceeded
+		dl.LastError = ""
+		dl.DeliveredAt = &now
+
+	case dl.Attempts >= d.cfg.MaxAttempts:
+		dl.Status = webhooks.StatusFailed
+		dl.LastError = err.Error()
+		d.log.Error(ctx, "webhooks", "status", "giving up delivery", "id", dl.ID, "subscription_id", dl.SubscriptionID, "attempts", dl.Attempts, "ERROR", err)
+
+	default:
+		dl.LastError = err.Error()
+		dl.NextAttemptAt = now.Add(d.cfg.Backoff(dl.Attempts))
+		d.log.Warn(ctx, "webhooks", "status", "retrying delivery", "id", dl.ID, "subscription_id", dl.SubscriptionID, "attempts", dl.Attempts, "next_attempt_at", dl.NextAttemptAt, "ERROR", err)
+	}
+
+	if err := d.repo.UpdateDelivery(ctx, dl); err != nil {
+		if errors.Is(err, webhooks.ErrDeliveryNotFound) {
+			return nil
+		}
+		return fmt.Errorf("update delivery[id=%s]: %w", dl.ID, err)
+	}
+
+	return nil
+}
+
+// send posts the signed event to the subscription URL, returning the status
+// code of the response. Any response other than 2xx is a failure.
+func (d *Dispatcher) send(ctx context.Context, sub webhooks.Subscription, dl webhooks.Delivery) (int, error) {
+	body, err := json.Marshal(dl.Event)
+	if err != nil {
+		return 0, fmt.Errorf("encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gobeer-webhooks")
+	req.Header.Set(HeaderEvent, dl.Event.Type)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// =============================================================================
+
+// Sign returns the signature of a delivery, sent in the X-Gobeer-Signature
+// header: the HMAC-SHA256 of the timestamp and the body, joined by a dot,
+// keyed by the subscription secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a delivery, as done by the receivers.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
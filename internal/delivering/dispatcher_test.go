@@ -0,0 +1,244 @@
+package delivering_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/delivering"
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/internal/webhooks"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	mu            sync.Mutex
+	subscriptions map[string]webhooks.Subscription
+	data          []webhooks.Delivery
+}
+
+// ClaimDeliveries returns the pending deliveries due at the given time.
+func (m *mockRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []webhooks.Delivery
+	for i := range m.data {
+		d := &m.data[i]
+		if d.Status != webhooks.StatusPending || d.NextAttemptAt.After(now) || len(list) == limit {
+			continue
+		}
+		d.NextAttemptAt = now.Add(lease)
+		list = append(list, *d)
+	}
+	return list, nil
+}
+
+// GetSubscription returns the subscription with the given ID.
+func (m *mockRepository) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub, ok := m.subscriptions[id]
+	if !ok {
+		return nil, webhooks.ErrNotFound
+	}
+	return &sub, nil
+}
+
+// UpdateDelivery stores the delivery.
+func (m *mockRepository) UpdateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.data {
+		if m.data[i].ID == d.ID {
+			m.data[i] = d
+			return nil
+		}
+	}
+	return webhooks.ErrDeliveryNotFound
+}
+
+// =============================================================================
+
+// receiver records the verified deliveries, answering with the given status
+// after the given delay.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	delay    time.Duration
+	received []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	time.Sleep(rc.delay)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !delivering.Verify(rc.secret, r.Header.Get(delivering.HeaderTimestamp), body, r.Header.Get(delivering.HeaderSignature)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	rc.received = append(rc.received, r.Header.Get(delivering.HeaderDelivery))
+	w.WriteHeader(rc.status)
+}
+
+// =============================================================================
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	healthy := &receiver{secret: "healthy-secret-0123", status: http.StatusNoContent}
+	failing := &receiver{secret: "failing-secret-0123", status: http.StatusInternalServerError}
+
+	healthySrv := httptest.NewServer(healthy)
+	defer healthySrv.Close()
+	failingSrv := httptest.NewServer(failing)
+	defer failingSrv.Close()
+
+	healthySub := webhooks.Subscription{ID: uuid.NewString(), URL: healthySrv.URL, Secret: healthy.secret, Events: webhooks.EventTypes}
+	failingSub := webhooks.Subscription{ID: uuid.NewString(), URL: failingSrv.URL, Secret: failing.secret, Events: webhooks.EventTypes}
+
+	e := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventBeerAdded, OccurredAt: time.Now()}
+
+	repo := &mockRepository{
+		subscriptions: map[string]webhooks.Subscription{
+			healthySub.ID: healthySub,
+			failingSub.ID: failingSub,
+		},
+		data: []webhooks.Delivery{
+			webhooks.NewDelivery(healthySub.ID, e),
+			webhooks.NewDelivery(failingSub.ID, e),
+			webhooks.NewDelivery(uuid.NewString(), e),
+		},
+	}
+
+	d := delivering.NewDispatcher(logger.New(io.Discard, logger.LevelInfo, "TEST"), repo, outbox.Config{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+
+	t.Log("Given the need to deliver the events to the webhooks")
+	{
+		t.Log("\tWhen dispatching the pending deliveries")
+		{
+			n, err := d.Dispatch(ctx)
+			if err != nil || n != 3 {
+				t.Fatalf("\t\t[ERROR] Should claim every delivery. Got %d: %v", n, err)
+			}
+
+			ok, retry := repo.data[0], repo.data[1]
+			if ok.Status != webhooks.StatusSucceeded || ok.DeliveredAt == nil || ok.Attempts != 1 || ok.ResponseStatus != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should mark the delivery as succeeded. Got %+v", ok)
+			}
+			if len(healthy.received) != 1 || healthy.received[0] != ok.ID {
+				t.Fatalf("\t\t[ERROR] Should send a signed delivery. Got %v", healthy.received)
+			}
+			if retry.Status != webhooks.StatusPending || retry.Attempts != 1 || retry.ResponseStatus != http.StatusInternalServerError || retry.LastError == "" {
+				t.Fatalf("\t\t[ERROR] Should keep the failed delivery pending. Got %+v", retry)
+			}
+			if orphan := repo.data[2]; orphan.Attempts != 0 {
+				t.Fatalf("\t\t[ERROR] Should skip the deliveries of deleted subscriptions. Got %+v", orphan)
+			}
+			t.Log("\t\t[OK] Should deliver the pending deliveries.")
+		}
+
+		t.Log("\tWhen the delivery runs out of attempts")
+		{
+			time.Sleep(2 * time.Millisecond)
+
+			if _, err := d.Dispatch(ctx); err != nil {
+				t.Fatalf("\t\t[ERROR] Should retry the failed delivery: %v", err)
+			}
+
+			if failed := repo.data[1]; failed.Status != webhooks.StatusFailed || failed.Attempts != 2 {
+				t.Fatalf("\t\t[ERROR] Should give up the delivery. Got %+v", failed)
+			}
+			if len(failing.received) != 2 || len(healthy.received) != 1 {
+				t.Fatalf("\t\t[ERROR] Should attempt the deliveries once each time. Got %d and %d", len(healthy.received), len(failing.received))
+			}
+			t.Log("\t\t[OK] Should give up the delivery.")
+		}
+	}
+}
+
+func TestDispatchBatch(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		batch = 5
+		delay = 200 * time.Millisecond
+	)
+
+	slow := &receiver{secret: "slow-secret-0123456", status: http.StatusNoContent, delay: delay}
+
+	srv := httptest.NewServer(slow)
+	defer srv.Close()
+
+	sub := webhooks.Subscription{ID: uuid.NewString(), URL: srv.URL, Secret: slow.secret, Events: webhooks.EventTypes}
+
+	repo := &mockRepository{subscriptions: map[string]webhooks.Subscription{sub.ID: sub}}
+	for i := 0; i < batch; i++ {
+		e := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventBeerAdded, OccurredAt: time.Now()}
+		repo.data = append(repo.data, webhooks.NewDelivery(sub.ID, e))
+	}
+
+	d := delivering.NewDispatcher(logger.New(io.Discard, logger.LevelInfo, "TEST"), repo, outbox.Config{BatchSize: batch})
+
+	t.Log("Given the need to deliver a batch before its lease expires")
+	{
+		t.Log("\tWhen the receiver is slow to answer")
+		{
+			start := time.Now()
+
+			n, err := d.Dispatch(ctx)
+			if err != nil || n != batch {
+				t.Fatalf("\t\t[ERROR] Should claim the batch. Got %d: %v", n, err)
+			}
+
+			if took := time.Since(start); took >= 2*delay {
+				t.Fatalf("\t\t[ERROR] Should send the batch concurrently. Took %v", took)
+			}
+			if len(slow.received) != batch {
+				t.Fatalf("\t\t[ERROR] Should send every delivery. Got %d", len(slow.received))
+			}
+			for _, dl := range repo.data {
+				if dl.Status != webhooks.StatusSucceeded {
+					t.Fatalf("\t\t[ERROR] Should mark the deliveries as succeeded. Got %+v", dl)
+				}
+			}
+			t.Log("\t\t[OK] Should send the batch concurrently.")
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	sig := delivering.Sign("secret", "1700000000", body)
+
+	t.Log("Given the need to verify the signature of a delivery")
+	{
+		if !delivering.Verify("secret", "1700000000", body, sig) {
+			t.Fatalf("\t[ERROR] Should accept the signature of the delivery.")
+		}
+		if delivering.Verify("secret", "1700000001", body, sig) {
+			t.Fatalf("\t[ERROR] Should reject a signature of another timestamp.")
+		}
+		if delivering.Verify("other", "1700000000", body, sig) {
+			t.Fatalf("\t[ERROR] Should reject a signature of another secret.")
+		}
+		t.Log("\t[OK] Should verify the signature of the delivery.")
+	}
+}
@@ -0,0 +1,137 @@
+// Package editing provides the use cases for editing and deleting a beer.
+package editing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/styles"
+)
+
+// UpdateBeer defines the properties of a beer that can be changed. Nil
+// properties are kept unchanged.
+type UpdateBeer struct {
+	Name      *string  `json:"name" binding:"omitempty,min=1"`
+	BreweryID *string  `json:"brewery_id" binding:"omitempty,min=1"`
+	Style     *string  `json:"style" binding:"omitempty,min=1"`
+	ABV       *float32 `json:"abv" binding:"omitempty,gt=0"`
+	ShortDesc *string  `json:"short_desc" binding:"omitempty,min=1"`
+}
+
+// Repository defines the interface for the editing service to interact
+// with the storage.
+type Repository interface {
+	// GetBeer returns the beer with the given ID.
+	GetBeer(ctx context.Context, id string) (*beers.Beer, error)
+	// BeerExists checks if a beer with the given name and brewery already exists.
+	BeerExists(ctx context.Context, name, breweryID string) (bool, error)
+	// GetBrewery returns the brewery with the given ID.
+	GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error)
+	// UpdateBeer updates the beer if its stored version is the given version.
+	UpdateBeer(ctx context.Context, b beers.Beer, version int) error
+	// DeleteBeer deletes the beer if its stored version is the given version.
+	DeleteBeer(ctx context.Context, id string, version int) error
+}
+
+// Service provides beer editing operations.
+type Service struct {
+	r       Repository
+	catalog styles.Catalog
+}
+
+// NewService creates an editing service with the necessary dependencies.
+func NewService(r Repository, catalog styles.Catalog) *Service {
+	return &Service{r, catalog}
+}
+
+// UpdateBeer changes the given version of a beer. A new style must be in the
+// catalog, and the ABV is flagged again against the style.
+func (s *Service) UpdateBeer(ctx context.Context, id string, version int, ub UpdateBeer) (*beers.Beer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, beers.ErrInvalidID
+	}
+
+	b, err := s.r.GetBeer(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get beer[id=%s]: %w", id, err)
+	}
+
+	// Fail fast when the beer was already changed.
+	if b.Version != version {
+		return nil, beers.ErrVersionMismatch
+	}
+
+	renamed := false
+	if ub.Name != nil && *ub.Name != b.Name {
+		b.Name = *ub.Name
+		renamed = true
+	}
+	if ub.BreweryID != nil && *ub.BreweryID != b.BreweryID {
+		if _, err := uuid.Parse(*ub.BreweryID); err != nil {
+			return nil, breweries.ErrInvalidID
+		}
+
+		brewery, err := s.r.GetBrewery(ctx, *ub.BreweryID)
+		if err != nil {
+			return nil, fmt.Errorf("get brewery[id=%s]: %w", *ub.BreweryID, err)
+		}
+
+		b.BreweryID = brewery.ID
+		b.Brewery = brewery.Name
+		renamed = true
+	}
+	if ub.Style != nil {
+		style, err := s.catalog.Lookup(*ub.Style)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, *ub.Style)
+		}
+		b.Style = style.Name
+	}
+	if ub.ABV != nil {
+		b.ABV = *ub.ABV
+	}
+	// The beers added before the catalog may have an unknown style, which
+	// can't flag the ABV.
+	if style, err := s.catalog.Lookup(b.Style); err == nil {
+		b.ABVOutOfRange = !style.ABV.Contains(b.ABV)
+	}
+	if ub.ShortDesc != nil {
+		b.ShortDesc = *ub.ShortDesc
+	}
+
+	// The new name must not clash with another beer of the brewery.
+	if renamed {
+		exists, err := s.r.BeerExists(ctx, b.Name, b.BreweryID)
+		if err != nil {
+			return nil, err
+		}
+
+		if exists {
+			return nil, beers.ErrAlreadyExists
+		}
+	}
+
+	b.Version = version + 1
+
+	if err := s.r.UpdateBeer(ctx, *b, version); err != nil {
+		return nil, fmt.Errorf("update beer[id=%s]: %w", id, err)
+	}
+
+	return b, nil
+}
+
+// DeleteBeer deletes the given version of a beer.
+func (s *Service) DeleteBeer(ctx context.Context, id string, version int) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return beers.ErrInvalidID
+	}
+
+	if err := s.r.DeleteBeer(ctx, id, version); err != nil {
+		return fmt.Errorf("delete beer[id=%s]: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package editing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/editing"
+	"github.com/phbpx/gobeer/internal/styles"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	data      []beers.Beer
+	breweries []breweries.Brewery
+}
+
+// GetBeer returns the beer with the given ID.
+func (m *mockRepository) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
+	for _, b := range m.data {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, beers.ErrNotFound
+}
+
+// BeerExists returns true if the beer exists.
+func (m *mockRepository) BeerExists(ctx context.Context, name, breweryID string) (bool, error) {
+	for _, b := range m.data {
+		if b.Name == name && b.BreweryID == breweryID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (m *mockRepository) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	for _, b := range m.breweries {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, breweries.ErrNotFound
+}
+
+// UpdateBeer updates the beer if the version matches.
+func (m *mockRepository) UpdateBeer(ctx context.Context, b beers.Beer, version int) error {
+	for i := range m.data {
+		if m.data[i].ID == b.ID {
+			if m.data[i].Version != version {
+				return beers.ErrVersionMismatch
+			}
+			m.data[i] = b
+			return nil
+		}
+	}
+	return beers.ErrNotFound
+}
+
+// DeleteBeer deletes the beer if the version matches.
+func (m *mockRepository) DeleteBeer(ctx context.Context, id string, version int) error {
+	for i := range m.data {
+		if m.data[i].ID == id {
+			if m.data[i].Version != version {
+				return beers.ErrVersionMismatch
+			}
+			m.data = append(m.data[:i], m.data[i+1:]...)
+			return nil
+		}
+	}
+	return beers.ErrNotFound
+}
+
+func TestEditingBeer(t *testing.T) {
+	ctx := context.Background()
+
+	beerID := uuid.NewString()
+	brewDog := breweries.Brewery{ID: uuid.NewString(), Name: "BrewDog"}
+	bodebrown := breweries.Brewery{ID: uuid.NewString(), Name: "Bodebrown"}
+
+	// Create a mock repository.
+	repo := &mockRepository{
+		data: []beers.Beer{
+			{ID: beerID, Name: "IPA", BreweryID: brewDog.ID, Brewery: brewDog.Name, Version: 1},
+			{ID: uuid.NewString(), Name: "Stout", BreweryID: brewDog.ID, Brewery: brewDog.Name, Version: 1},
+		},
+		breweries: []breweries.Brewery{brewDog, bodebrown},
+	}
+
+	// Create a new service with the mock repository.
+	s := editing.NewService(repo, styles.DefaultCatalog)
+
+	t.Log("Given the need to edit a beer")
+	{
+		t.Log("\tWhen updating the current version of the beer")
+		{
+			name := "Session IPA"
+			b, err := s.UpdateBeer(ctx, beerID, 1, editing.UpdateBeer{Name: &name})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the beer without error: %v", err)
+			}
+			if b.Name != name || b.Version != 2 {
+				t.Fatalf("\t\t[ERROR] Should bump the beer version. Got %+v", b)
+			}
+			t.Log("\t\t[OK] Should be able to update the beer without error.")
+		}
+
+		t.Log("\tWhen updating an outdated version of the beer")
+		{
+			abv := float32(4.5)
+			_, err := s.UpdateBeer(ctx, beerID, 1, editing.UpdateBeer{ABV: &abv})
+			if !errors.Is(err, beers.ErrVersionMismatch) {
+				t.Fatalf("\t\t[ERROR] Should not be able to update the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to update the beer.")
+		}
+
+		t.Log("\tWhen renaming the beer to the name of another beer of the brewery")
+		{
+			name := "Stout"
+			_, err := s.UpdateBeer(ctx, beerID, 2, editing.UpdateBeer{Name: &name})
+			if !errors.Is(err, beers.ErrAlreadyExists) {
+				t.Fatalf("\t\t[ERROR] Should not be able to rename the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to rename the beer.")
+		}
+
+		t.Log("\tWhen moving the beer to an unknown brewery")
+		{
+			id := uuid.NewString()
+			_, err := s.UpdateBeer(ctx, beerID, 2, editing.UpdateBeer{BreweryID: &id})
+			if !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to move the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to move the beer.")
+		}
+
+		t.Log("\tWhen moving the beer to another brewery")
+		{
+			b, err := s.UpdateBeer(ctx, beerID, 2, editing.UpdateBeer{BreweryID: &bodebrown.ID})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to move the beer without error: %v", err)
+			}
+			if b.BreweryID != bodebrown.ID || b.Brewery != bodebrown.Name {
+				t.Fatalf("\t\t[ERROR] Should change the brewery of the beer. Got %+v", b)
+			}
+			t.Log("\t\t[OK] Should be able to move the beer without error.")
+		}
+
+		t.Log("\tWhen changing the beer to an unknown style")
+		{
+			style := "Pumpkin Spice"
+			_, err := s.UpdateBeer(ctx, beerID, 3, editing.UpdateBeer{Style: &style})
+			if !errors.Is(err, styles.ErrUnknown) {
+				t.Fatalf("\t\t[ERROR] Should not be able to change the style: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to change the style.")
+		}
+
+		t.Log("\tWhen changing the style and the ABV of the beer")
+		{
+			style, abv := "tripel", float32(12)
+			b, err := s.UpdateBeer(ctx, beerID, 3, editing.UpdateBeer{Style: &style, ABV: &abv})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to change the style without error: %v", err)
+			}
+			if b.Style != "Belgian Tripel" || !b.ABVOutOfRange {
+				t.Fatalf("\t\t[ERROR] Should store the canonical style and flag the ABV. Got %+v", b)
+			}
+			t.Log("\t\t[OK] Should store the canonical style and flag the ABV.")
+		}
+
+		t.Log("\tWhen deleting an outdated version of the beer")
+		{
+			err := s.DeleteBeer(ctx, beerID, 1)
+			if !errors.Is(err, beers.ErrVersionMismatch) {
+				t.Fatalf("\t\t[ERROR] Should not be able to delete the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to delete the beer.")
+		}
+
+		t.Log("\tWhen deleting the current version of the beer")
+		{
+			if err := s.DeleteBeer(ctx, beerID, 4); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to delete the beer without error: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to delete the beer without error.")
+		}
+
+		t.Log("\tWhen deleting a beer that does not exist")
+		{
+			err := s.DeleteBeer(ctx, beerID, 4)
+			if !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to delete the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to delete the beer.")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package email
+
+import (
+	"sync"
+	"time"
+)
+
+// State defines the state of the circuit breaker.
+type State string
+
+// Set of circuit breaker states.
+const (
+	// StateClosed lets every notification through.
+	StateClosed State = "closed"
+	// StateOpen fails every notification fast, as the api is unhealthy.
+	StateOpen State = "open"
+	// StateHalfOpen lets a single notification through to probe the api.
+	StateHalfOpen State = "half-open"
+)
+
+// breaker is a circuit breaker that opens after a number of consecutive
+// failures and probes the api again after a cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(from, to State)
+
+	mu       sync.Mutex
+	current  State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration, onChange func(from, to State)) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+		current:   StateClosed,
+	}
+}
+
+// state returns the current state of the breaker.
+func (b *breaker) state() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current == StateOpen && time.Since(b.openedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+	return b.current
+}
+
+// allow tells if a call can go through. Once the cooldown is over, a single
+// call goes through to probe the api.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.current {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.set(StateHalfOpen)
+		b.probing = true
+		return true
+
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+
+	return true
+}
+
+// success records a successful call, closing the breaker.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.set(StateClosed)
+}
+
+// failure records a failed call, opening the breaker once the threshold is
+// reached or when the probe fails.
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+
+	if b.current == StateHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.set(StateOpen)
+	}
+}
+
+// skip records a call that doesn't tell anything about the api, letting
+// another call probe it.
+func (b *breaker) skip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// set changes the state, calling the hook when it changed.
+func (b *breaker) set(to State) {
+	from := b.current
+	if from == to {
+		return
+	}
+
+	b.current = to
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
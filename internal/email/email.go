@@ -2,52 +2,207 @@
 package email
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/phbpx/gobeer/internal/notifications"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 )
 
-var defaultClient = http.Client{
-	Transport: &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConns:          1,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	},
-}
+// ErrCircuitOpen is returned without calling the email api while it's
+// considered unhealthy.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
 
 var propagator = otel.GetTextMapPropagator()
 
+// Config defines how the notifier calls the email api. The zero fields take
+// the value of DefaultConfig.
+type Config struct {
+	URL string
+
+	// Timeout limits every attempt.
+	Timeout time.Duration
+
+	// MaxAttempts limits the attempts of a notification. The server errors
+	// and the network errors are retried, waiting an exponential backoff
+	// with jitter or what the server asks for in Retry-After.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+
+	// MaxIdleConns limits the connections kept open to the email api.
+	MaxIdleConns int
+
+	// BreakerThreshold is the number of consecutive failed notifications
+	// that opens the circuit breaker, and BreakerCooldown is how long it
+	// stays open before a notification is let through to probe the api.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// OnStateChange, when set, is called when the circuit breaker changes
+	// its state.
+	OnStateChange func(from, to State)
+}
+
+// DefaultConfig is the configuration used for the fields left empty.
+var DefaultConfig = Config{
+	Timeout:          5 * time.Second,
+	MaxAttempts:      3,
+	MinBackoff:       100 * time.Millisecond,
+	MaxBackoff:       5 * time.Second,
+	MaxIdleConns:     10,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+}
+
 // EmailNotifier is an email notifier service.
 type EmailNotifier struct {
-	url    string
-	client *http.Client
+	cfg     Config
+	client  *http.Client
+	breaker *breaker
 }
 
 // NewEmailNotifier creates a new email notifier service.
-func NewEmailNotifier(url string) *EmailNotifier {
+func NewEmailNotifier(cfg Config) *EmailNotifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultConfig.MinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultConfig.MaxBackoff
+	}
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = DefaultConfig.MaxIdleConns
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = DefaultConfig.BreakerThreshold
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = DefaultConfig.BreakerCooldown
+	}
+
+	client := http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          cfg.MaxIdleConns,
+			MaxIdleConnsPerHost:   cfg.MaxIdleConns,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+
 	return &EmailNotifier{
-		url:    url,
-		client: &defaultClient,
+		cfg:     cfg,
+		client:  &client,
+		breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, cfg.OnStateChange),
 	}
 }
 
-// Notify sends an email notification.
-func (s *EmailNotifier) Notify(ctx context.Context, userID string) error {
-	url := fmt.Sprintf("%s/users/%s/notify", s.url, userID)
+// State returns the state of the circuit breaker.
+func (s *EmailNotifier) State() State {
+	return s.breaker.state()
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+// Notify sends the event to the email api, retrying the transient failures.
+func (s *EmailNotifier) Notify(ctx context.Context, e notifications.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	if !s.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	err = s.notify(ctx, e.UserID, body)
+
+	var serr *statusError
+	switch {
+	case err == nil:
+		s.breaker.success()
+
+	// Neither the client errors nor the canceled notifications tell anything
+	// about the health of the api.
+	case errors.As(err, &serr) && !serr.retryable(), ctx.Err() != nil:
+		s.breaker.skip()
+
+	default:
+		s.breaker.failure()
+	}
+
+	return err
+}
+
+// notify sends the notification, until it's accepted, it fails for good or
+// it runs out of attempts.
+func (s *EmailNotifier) notify(ctx context.Context, userID string, body []byte) error {
+	url := fmt.Sprintf("%s/users/%s/notify", s.cfg.URL, userID)
+
+	for attempt := 1; ; attempt++ {
+		err := s.attempt(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+
+		var (
+			serr *statusError
+			wait = s.backoff(attempt)
+		)
+		if errors.As(err, &serr) {
+			if !serr.retryable() {
+				return err
+			}
+			if serr.retryAfter > 0 {
+				wait = serr.retryAfter
+			}
+		}
+
+		if attempt >= s.cfg.MaxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		// Waiting longer than the backoff allows is left to the caller.
+		if wait > s.cfg.MaxBackoff {
+			return fmt.Errorf("retry after %s: %w", wait, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("waiting to retry: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt makes a single request to the email api.
+func (s *EmailNotifier) attempt(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("creating http request: %w", err)
 	}
@@ -57,6 +212,7 @@ func (s *EmailNotifier) Notify(ctx context.Context, userID string) error {
 	propagator.Inject(ctx, carrier)
 
 	req.Header = http.Header(carrier)
+	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.client.Do(req)
 	if err != nil {
@@ -64,8 +220,67 @@ func (s *EmailNotifier) Notify(ctx context.Context, userID string) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status code: %d", resp.StatusCode)
+	// Drain the body, so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{
+			code:       resp.StatusCode,
+			retryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 	return nil
 }
+
+// backoff returns how long to wait after the given attempt: the wait doubles
+// after every attempt and a random half of it is dropped, so the clients
+// don't retry in lockstep.
+func (s *EmailNotifier) backoff(attempt int) time.Duration {
+	wait := s.cfg.MinBackoff
+	for i := 1; i < attempt && wait < s.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > s.cfg.MaxBackoff {
+		wait = s.cfg.MaxBackoff
+	}
+
+	half := int64(wait / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// =============================================================================
+
+// statusError is returned when the email api answers with an error.
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.code)
+}
+
+// retryable tells if the request can succeed if sent again.
+func (e *statusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as
+// a date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
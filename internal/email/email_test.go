@@ -0,0 +1,223 @@
+package email_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/email"
+	"github.com/phbpx/gobeer/internal/notifications"
+)
+
+// emailAPI is a fake email api answering with the given status codes, one
+// per call, repeating the last one.
+type emailAPI struct {
+	*httptest.Server
+	calls int32
+	event atomic.Value
+}
+
+// Calls returns how many times the api was called.
+func (api *emailAPI) Calls() int32 {
+	return atomic.LoadInt32(&api.calls)
+}
+
+func newEmailAPI(t *testing.T, delay time.Duration, header http.Header, codes ...int) *emailAPI {
+	api := emailAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&api.calls, 1))
+
+		var e notifications.Event
+		if r.Header.Get("Content-Type") == "application/json" && json.NewDecoder(r.Body).Decode(&e) == nil {
+			api.event.Store(e)
+		}
+		if n > len(codes) {
+			n = len(codes)
+		}
+
+		time.Sleep(delay)
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(codes[n-1])
+	}))
+	t.Cleanup(api.Close)
+
+	return &api
+}
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	e := notifications.Event{
+		Version: notifications.EventVersion,
+		ID:      uuid.NewString(),
+		Type:    notifications.KindReviewCreated,
+		UserID:  uuid.NewString(),
+	}
+
+	cfg := email.Config{
+		Timeout:     50 * time.Millisecond,
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  2 * time.Second,
+	}
+
+	t.Log("Given the need to notify users through the email api")
+	{
+		t.Log("\tWhen the api fails for a moment")
+		{
+			api := newEmailAPI(t, 0, nil, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+
+			cfg.URL = api.URL
+			if err := email.NewEmailNotifier(cfg).Notify(ctx, e); err != nil {
+				t.Fatalf("\t\t[ERROR] Should retry until the api accepts the notification: %v", err)
+			}
+			if api.Calls() != 3 {
+				t.Fatalf("\t\t[ERROR] Should call the api 3 times. Got %d", api.Calls())
+			}
+			if got, _ := api.event.Load().(notifications.Event); got.ID != e.ID || got.UserID != e.UserID {
+				t.Fatalf("\t\t[ERROR] Should send the event. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should retry until the api accepts the notification.")
+		}
+
+		t.Log("\tWhen the api accepts the notification for later")
+		{
+			api := newEmailAPI(t, 0, nil, http.StatusAccepted)
+
+			cfg.URL = api.URL
+			if err := email.NewEmailNotifier(cfg).Notify(ctx, e); err != nil || api.Calls() != 1 {
+				t.Fatalf("\t\t[ERROR] Should take any 2xx as a success. Got %d calls: %v", api.Calls(), err)
+			}
+			t.Log("\t\t[OK] Should take any 2xx as a success.")
+		}
+
+		t.Log("\tWhen the api rejects the notification")
+		{
+			api := newEmailAPI(t, 0, nil, http.StatusBadRequest)
+
+			cfg.URL = api.URL
+			if err := email.NewEmailNotifier(cfg).Notify(ctx, e); err == nil || api.Calls() != 1 {
+				t.Fatalf("\t\t[ERROR] Should not retry the notification. Got %d calls: %v", api.Calls(), err)
+			}
+			t.Log("\t\t[OK] Should not retry the notification.")
+		}
+
+		t.Log("\tWhen the api takes too long to answer")
+		{
+			api := newEmailAPI(t, 200*time.Millisecond, nil, http.StatusOK)
+
+			cfg.URL = api.URL
+			cfg.MaxAttempts = 2
+			err := email.NewEmailNotifier(cfg).Notify(ctx, e)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("\t\t[ERROR] Should time out every attempt: %v", err)
+			}
+			if calls := api.Calls(); calls != 2 {
+				t.Fatalf("\t\t[ERROR] Should call the api 2 times. Got %d", calls)
+			}
+			cfg.MaxAttempts = 3
+			t.Log("\t\t[OK] Should time out every attempt.")
+		}
+
+		t.Log("\tWhen the api asks to retry later")
+		{
+			api := newEmailAPI(t, 0, http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests, http.StatusOK)
+
+			cfg.URL = api.URL
+			start := time.Now()
+			if err := email.NewEmailNotifier(cfg).Notify(ctx, e); err != nil {
+				t.Fatalf("\t\t[ERROR] Should retry the notification: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed < time.Second {
+				t.Fatalf("\t\t[ERROR] Should wait what the api asked. Waited %s", elapsed)
+			}
+			t.Log("\t\t[OK] Should wait what the api asked.")
+		}
+
+		t.Log("\tWhen the api asks to retry later than the backoff allows")
+		{
+			api := newEmailAPI(t, 0, http.Header{"Retry-After": {"120"}}, http.StatusServiceUnavailable, http.StatusOK)
+
+			cfg.URL = api.URL
+			if err := email.NewEmailNotifier(cfg).Notify(ctx, e); err == nil || api.Calls() != 1 {
+				t.Fatalf("\t\t[ERROR] Should leave the retry to the caller. Got %d calls: %v", api.Calls(), err)
+			}
+			t.Log("\t\t[OK] Should leave the retry to the caller.")
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	e := notifications.Event{UserID: uuid.NewString()}
+
+	var healthy atomic.Bool
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	var changes []email.State
+	n := email.NewEmailNotifier(email.Config{
+		URL:              api.URL,
+		MaxAttempts:      1,
+		BreakerThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+		OnStateChange: func(from, to email.State) {
+			changes = append(changes, to)
+		},
+	})
+
+	t.Log("Given the need to stop calling the email api while it's unhealthy")
+	{
+		t.Log("\tWhen the api keeps failing")
+		{
+			for i := 0; i < 2; i++ {
+				if err := n.Notify(ctx, e); err == nil || errors.Is(err, email.ErrCircuitOpen) {
+					t.Fatalf("\t\t[ERROR] Should call the api: %v", err)
+				}
+			}
+
+			if n.State() != email.StateOpen {
+				t.Fatalf("\t\t[ERROR] Should open the circuit breaker. Got %s", n.State())
+			}
+			if err := n.Notify(ctx, e); !errors.Is(err, email.ErrCircuitOpen) {
+				t.Fatalf("\t\t[ERROR] Should fail fast: %v", err)
+			}
+			t.Log("\t\t[OK] Should fail fast.")
+		}
+
+		t.Log("\tWhen the api recovers")
+		{
+			healthy.Store(true)
+			time.Sleep(60 * time.Millisecond)
+
+			if n.State() != email.StateHalfOpen {
+				t.Fatalf("\t\t[ERROR] Should probe the api after the cooldown. Got %s", n.State())
+			}
+			if err := n.Notify(ctx, e); err != nil {
+				t.Fatalf("\t\t[ERROR] Should notify the user: %v", err)
+			}
+			if n.State() != email.StateClosed {
+				t.Fatalf("\t\t[ERROR] Should close the circuit breaker. Got %s", n.State())
+			}
+
+			want := []email.State{email.StateOpen, email.StateHalfOpen, email.StateClosed}
+			if len(changes) != len(want) || changes[0] != want[0] || changes[1] != want[1] || changes[2] != want[2] {
+				t.Fatalf("\t\t[ERROR] Should report the state changes. Got %v", changes)
+			}
+			t.Log("\t\t[OK] Should close the circuit breaker.")
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/phbpx/gobeer/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Publisher defines the interface for the services to publish events.
+type Publisher interface {
+	// Publish hands the event to every subscriber.
+	Publish(ctx context.Context, e Event)
+}
+
+// Handler handles the published events.
+type Handler func(ctx context.Context, e Event) error
+
+// Handle adapts a handler of a single event type, ignoring the events of
+// other types.
+func Handle[E Event](h func(ctx context.Context, e E) error) Handler {
+	return func(ctx context.Context, e Event) error {
+		if te, ok := e.(E); ok {
+			return h(ctx, te)
+		}
+		return nil
+	}
+}
+
+// Config defines how the events are handed to the async subscribers.
+type Config struct {
+	// QueueSize is the number of events waiting for each async subscriber.
+	// Once full, the new events are dropped for that subscriber.
+	QueueSize int
+}
+
+// DefaultConfig is the configuration used for the fields left empty.
+var DefaultConfig = Config{
+	QueueSize: 100,
+}
+
+// Bus publishes the events to the subscribers, in the process. The sync
+// subscribers handle the event before Publish returns, one after the other,
+// while the async ones handle it in the background, each from its own queue.
+//
+// The subscribers are isolated from each other: a subscriber failing, panicking
+// or falling behind doesn't affect the others, nor the publisher. The failures
+// are logged.
+type Bus struct {
+	log *logger.Logger
+	cfg Config
+
+	mu     sync.RWMutex
+	sync   []subscriber
+	async  []*asyncSubscriber
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// subscriber is a named handler.
+type subscriber struct {
+	name string
+	h    Handler
+}
+
+// asyncSubscriber is a handler fed from a queue.
+type asyncSubscriber struct {
+	subscriber
+	queue chan queued
+}
+
+// queued is an event waiting for an async subscriber.
+type queued struct {
+	ctx context.Context
+	e   Event
+}
+
+// NewBus creates a bus with the necessary dependencies.
+func NewBus(log *logger.Logger, cfg Config) *Bus {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultConfig.QueueSize
+	}
+
+	return &Bus{
+		log: log,
+		cfg: cfg,
+	}
+}
+
+// Subscribe adds a sync subscriber, handling the events as they're published.
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sync = append(b.sync, subscriber{name: name, h: h})
+}
+
+// SubscribeAsync adds an async subscriber, handling the events in the
+// background until the bus is closed. It's ignored once the bus is closed.
+func (b *Bus) SubscribeAsync(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	s := &asyncSubscriber{
+		subscriber: subscriber{name: name, h: h},
+		queue:      make(chan queued, b.cfg.QueueSize),
+	}
+	b.async = append(b.async, s)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for q := range s.queue {
+			b.handle(q.ctx, s.subscriber, q.e)
+		}
+	}()
+}
+
+// Publish hands the event to every subscriber. The events published after
+// the bus is closed are dropped.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	closed, subs := b.closed, b.sync
+	b.mu.RUnlock()
+
+	if closed {
+		b.log.Warn(ctx, "events", "status", "bus closed, dropping event", "event", e.EventName(), "id", e.EventID())
+		return
+	}
+
+	// The sync subscribers are called without holding the lock, so they can
+	// publish events of their own.
+	for _, s := range subs {
+		b.handle(ctx, s, e)
+	}
+
+	// The async subscribers outlive the request that published the event, so
+	// they only keep its trace.
+	actx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, s := range b.async {
+		select {
+		case s.queue <- queued{ctx: actx, e: e}:
+		default:
+			b.log.Error(ctx, "events", "status", "subscriber queue full, dropping event", "subscriber", s.name, "event", e.EventName(), "id", e.EventID())
+		}
+	}
+}
+
+// Close stops accepting events and waits for the async subscribers to handle
+// the queued ones.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, s := range b.async {
+		close(s.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+// handle calls the subscriber, logging its failure.
+func (b *Bus) handle(ctx context.Context, s subscriber, e Event) {
+	if err := call(ctx, s.h, e); err != nil {
+		b.log.Error(ctx, "events", "status", "handling event", "subscriber", s.name, "event", e.EventName(), "id", e.EventID(), "ERROR", err)
+	}
+}
+
+// call calls the handler, turning a panic into an error.
+func call(ctx context.Context, h Handler, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h(ctx, e)
+}
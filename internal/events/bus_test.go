@@ -0,0 +1,136 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// recorder records the IDs of the handled events.
+type recorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *recorder) record(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, id)
+}
+
+func (r *recorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.ids)
+}
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+
+	bus := events.NewBus(logger.New(io.Discard, logger.LevelInfo, "TEST"), events.Config{QueueSize: 10})
+
+	var beersAdded, reviewsAdded, async recorder
+
+	bus.Subscribe("failing", func(ctx context.Context, e events.Event) error {
+		return errors.New("unavailable")
+	})
+	bus.Subscribe("panicking", func(ctx context.Context, e events.Event) error {
+		panic("boom")
+	})
+	bus.Subscribe("beers", events.Handle(func(ctx context.Context, e events.BeerAdded) error {
+		beersAdded.record(e.ID)
+		return nil
+	}))
+	bus.Subscribe("reviews", events.Handle(func(ctx context.Context, e events.ReviewAdded) error {
+		reviewsAdded.record(e.ID)
+		return nil
+	}))
+	bus.SubscribeAsync("async", func(ctx context.Context, e events.Event) error {
+		async.record(e.EventID())
+		return nil
+	})
+
+	beerAdded := events.BeerAdded{ID: uuid.NewString()}
+	reviewAdded := events.ReviewAdded{ID: uuid.NewString()}
+
+	t.Log("Given the need to publish the domain events")
+	{
+		t.Log("\tWhen publishing the events")
+		{
+			bus.Publish(ctx, beerAdded)
+			bus.Publish(ctx, reviewAdded)
+
+			if beersAdded.len() != 1 || beersAdded.ids[0] != beerAdded.ID {
+				t.Fatalf("\t\t[ERROR] Should hand the BeerAdded event to its subscriber. Got %v", beersAdded.ids)
+			}
+			if reviewsAdded.len() != 1 || reviewsAdded.ids[0] != reviewAdded.ID {
+				t.Fatalf("\t\t[ERROR] Should hand the ReviewAdded event to its subscriber. Got %v", reviewsAdded.ids)
+			}
+			t.Log("\t\t[OK] Should hand the events to the sync subscribers, despite the failing ones.")
+		}
+
+		t.Log("\tWhen closing the bus")
+		{
+			bus.Close()
+
+			if async.len() != 2 {
+				t.Fatalf("\t\t[ERROR] Should hand the queued events to the async subscribers. Got %v", async.ids)
+			}
+			t.Log("\t\t[OK] Should hand the queued events to the async subscribers.")
+
+			bus.Publish(ctx, beerAdded)
+			if beersAdded.len() != 1 {
+				t.Fatalf("\t\t[ERROR] Should drop the events published once closed. Got %v", beersAdded.ids)
+			}
+			t.Log("\t\t[OK] Should drop the events published once closed.")
+		}
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+
+	bus := events.NewBus(logger.New(io.Discard, logger.LevelInfo, "TEST"), events.Config{QueueSize: 1})
+
+	release := make(chan struct{})
+	var slow, fast recorder
+
+	bus.SubscribeAsync("slow", func(ctx context.Context, e events.Event) error {
+		<-release
+		slow.record(e.EventID())
+		return nil
+	})
+	bus.Subscribe("fast", func(ctx context.Context, e events.Event) error {
+		fast.record(e.EventID())
+		return nil
+	})
+
+	t.Log("Given the need to isolate a subscriber falling behind")
+	{
+		t.Log("\tWhen the queue of the subscriber is full")
+		{
+			for i := 0; i < 5; i++ {
+				bus.Publish(ctx, events.BeerAdded{ID: uuid.NewString()})
+			}
+
+			if fast.len() != 5 {
+				t.Fatalf("\t\t[ERROR] Should keep handing the events to the other subscribers. Got %d", fast.len())
+			}
+			t.Log("\t\t[OK] Should keep handing the events to the other subscribers.")
+
+			close(release)
+			bus.Close()
+
+			if n := slow.len(); n == 0 || n > 2 {
+				t.Fatalf("\t\t[ERROR] Should drop the events beyond the queue. Got %d", n)
+			}
+			t.Log("\t\t[OK] Should drop the events beyond the queue.")
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Package events defines the domain events and the bus they're published on.
+package events
+
+import (
+	"time"
+
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/reviews"
+)
+
+// Set of event names.
+const (
+	NameBeerAdded   = "beer_added"
+	NameReviewAdded = "review_added"
+)
+
+// Event defines something that happened in the domain.
+type Event interface {
+	// EventName returns the name of the event.
+	EventName() string
+	// EventID returns the unique ID of the event.
+	EventID() string
+}
+
+// BeerAdded is published when a beer is added.
+type BeerAdded struct {
+	ID         string     `json:"id"`
+	OccurredAt time.Time  `json:"occurred_at"`
+	Beer       beers.Beer `json:"beer"`
+}
+
+// EventName returns the name of the event.
+func (e BeerAdded) EventName() string { return NameBeerAdded }
+
+// EventID returns the unique ID of the event.
+func (e BeerAdded) EventID() string { return e.ID }
+
+// ReviewAdded is published when a beer is reviewed.
+type ReviewAdded struct {
+	ID         string         `json:"id"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Beer       beers.Beer     `json:"beer"`
+	Review     reviews.Review `json:"review"`
+}
+
+// EventName returns the name of the event.
+func (e ReviewAdded) EventName() string { return NameReviewAdded }
+
+// EventID returns the unique ID of the event.
+func (e ReviewAdded) EventID() string { return e.ID }
@@ -0,0 +1,61 @@
+package mid
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/authorizing"
+)
+
+// Authenticate is a middleware that requires a valid bearer token, putting
+// its claims into the request context.
+func Authenticate(a *auth.Auth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.Error(fmt.Errorf("%w: missing bearer token", auth.ErrUnauthenticated))
+			c.Abort()
+			return
+		}
+
+		claims, err := a.Authenticate(token)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		ctx := auth.SetClaims(c.Request.Context(), claims)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// Authorize is a middleware that requires the authenticated user to be
+// granted the permission. It must run after Authenticate.
+func Authorize(az *authorizing.Service, perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		claims, _ := auth.GetClaims(ctx)
+
+		if err := az.Authorize(ctx, claims.Subject, perm); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -6,7 +6,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/phbpx/gobeer/internal/auth"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
 // errorReponse is the JSON response for an error.
@@ -32,6 +41,20 @@ func errorHandler(c *gin.Context) {
 	err := c.Errors.Last().Err
 
 	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		c.Header("WWW-Authenticate", "Bearer")
+		c.JSON(http.StatusUnauthorized, errorResponse{Error: err.Error()})
+	case errors.Is(err, auth.ErrForbidden):
+		c.JSON(http.StatusForbidden, errorResponse{Error: err.Error()})
+	case errors.Is(err, ratelimit.ErrLimited):
+		c.JSON(http.StatusTooManyRequests, errorResponse{Error: err.Error()})
+	case errors.Is(err, idempotency.ErrMismatch):
+		c.JSON(http.StatusUnprocessableEntity, errorResponse{Error: err.Error()})
+	case errors.Is(err, idempotency.ErrInProgress):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusConflict, errorResponse{Error: err.Error()})
+	case errors.Is(err, idempotency.ErrInvalidKey):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
 	case isFieldError(err):
 		c.JSON(http.StatusBadRequest, fieldErrorResponse(err))
 	case errors.Is(err, beers.ErrAlreadyExists):
@@ -40,6 +63,40 @@ func errorHandler(c *gin.Context) {
 		c.JSON(http.StatusNotFound, errorResponse{Error: err.Error()})
 	case errors.Is(err, beers.ErrInvalidID):
 		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, breweries.ErrAlreadyExists):
+		c.JSON(http.StatusConflict, errorResponse{Error: err.Error()})
+	case errors.Is(err, breweries.ErrNotFound):
+		c.JSON(http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, breweries.ErrInvalidID), errors.Is(err, breweries.ErrInvalidFoundedYear):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, styles.ErrUnknown):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, reviews.ErrNotFound):
+		c.JSON(http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, reviews.ErrInvalidID), errors.Is(err, reviews.ErrInvalidScore):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, reviews.ErrNotAuthor):
+		c.JSON(http.StatusForbidden, errorResponse{Error: err.Error()})
+	case errors.Is(err, reviews.ErrAlreadyReviewed), errors.Is(err, reviews.ErrRevisionConflict):
+		c.JSON(http.StatusConflict, errorResponse{Error: err.Error()})
+	case errors.Is(err, beers.ErrVersionRequired):
+		c.JSON(http.StatusPreconditionRequired, errorResponse{Error: err.Error()})
+	case errors.Is(err, beers.ErrVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, errorResponse{Error: err.Error()})
+	case errors.Is(err, listing.ErrInvalidQuery), errors.Is(err, listing.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, webhooks.ErrNotFound), errors.Is(err, webhooks.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, webhooks.ErrInvalidID), errors.Is(err, webhooks.ErrInvalidURL):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, users.ErrNotFound):
+		c.JSON(http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, users.ErrInvalidID), errors.Is(err, users.ErrInvalidHandle), errors.Is(err, users.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, users.ErrHandleTaken), errors.Is(err, users.ErrEmailTaken):
+		c.JSON(http.StatusConflict, errorResponse{Error: err.Error()})
+	case errors.Is(err, users.ErrWrongPassword):
+		c.JSON(http.StatusForbidden, errorResponse{Error: err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, errorResponse{Error: err.Error()})
 	}
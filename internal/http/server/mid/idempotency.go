@@ -0,0 +1,149 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// Set of headers of the idempotent requests.
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKey limits the length of the idempotency keys.
+const maxIdempotencyKey = 255
+
+// replayedHeaders are the headers of the responses saved along with them.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency is a middleware that honors the Idempotency-Key header: the
+// response of the first request made with a key is saved for the ttl and
+// replayed to the retries made with the same key and body. The keys belong to
+// the authenticated user, so it must run after Authenticate. The requests
+// that fail are not saved, so they can be retried with the same key.
+func Idempotency(log *logger.Logger, store idempotency.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if store == nil || key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKey {
+			c.Error(fmt.Errorf("%w: longer than %d characters", idempotency.ErrInvalidKey, maxIdempotencyKey))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		claims, _ := auth.GetClaims(ctx)
+		key = claims.Subject + " " + key
+
+		resp, err := store.LockKey(ctx, key, fingerprint(c, body), time.Now().UTC())
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if resp != nil {
+			for k, v := range resp.Header {
+				c.Header(k, v)
+			}
+			c.Header(HeaderReplayed, "true")
+			c.Data(resp.Status, resp.Header["Content-Type"], resp.Body)
+			c.Abort()
+			return
+		}
+
+		w := &recorder{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		// The response is saved even when the client gave up waiting for it,
+		// which is when it's going to be retried.
+		storeCtx := context.Background()
+
+		// The key is unlocked when the request fails, even by a panic.
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := store.UnlockKey(storeCtx, key); err != nil {
+				log.Error(ctx, "idempotency", "status", "unlocking key", "ERROR", err)
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) > 0 || w.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		resp = &idempotency.Response{
+			Status: w.Status(),
+			Header: make(map[string]string),
+			Body:   w.body.Bytes(),
+		}
+		for _, k := range replayedHeaders {
+			if v := w.Header().Get(k); v != "" {
+				resp.Header[k] = v
+			}
+		}
+
+		if err := store.SaveResponse(storeCtx, key, *resp, time.Now().UTC().Add(ttl)); err != nil {
+			log.Error(ctx, "idempotency", "status", "saving response", "ERROR", err)
+			return
+		}
+		saved = true
+	}
+}
+
+// fingerprint returns the fingerprint of the request, telling apart the
+// requests made to other routes, with other query parameters or with other
+// bodies.
+func fingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the body written to the response.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write implements the io.Writer interface.
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// WriteString implements the io.StringWriter interface.
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/phbpx/gobeer/internal/auth"
 	"github.com/phbpx/gobeer/pkg/logger"
 )
 
@@ -19,23 +20,22 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 
 		c.Next()
 
-		latency := time.Since(start)
-		status := c.Writer.Status()
+		args := []any{
+			"path", fmt.Sprintf("%s %s%s", method, path, query),
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+		}
+
+		// The subject is known once the request is authenticated.
+		if claims, ok := auth.GetClaims(c.Request.Context()); ok {
+			args = append(args, "subject", claims.Subject)
+		}
 
 		if len(c.Errors) > 0 {
-			log.Error(ctx, "request",
-				"path", fmt.Sprintf("%s %s%s", method, path, query),
-				"status", status,
-				"latency", latency,
-				"ERROR", c.Errors,
-			)
+			log.Error(ctx, "request", append(args, "ERROR", c.Errors)...)
 			return
 		}
 
-		log.Info(ctx, "request",
-			"path", fmt.Sprintf("%s %s%s", method, path, query),
-			"status", status,
-			"latency", latency,
-		)
+		log.Info(ctx, "request", args...)
 	}
 }
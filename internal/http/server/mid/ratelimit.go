@@ -0,0 +1,77 @@
+package mid
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// HeaderAPIKey identifies the clients that are not authenticated, such as the
+// integrations sharing an IP address.
+const HeaderAPIKey = "X-API-Key"
+
+// RateLimit is a middleware that limits the requests of each client to the
+// limit of the route, keyed by the authenticated user, the API key or the IP
+// address, in this order. It must run after Authenticate on the routes that
+// require a user. The requests are allowed when the store fails.
+func RateLimit(log *logger.Logger, store ratelimit.Store, limits ratelimit.Limits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		route := c.Request.Method + " " + c.FullPath()
+
+		l, ok := limits.For(route)
+		if store == nil || !ok {
+			c.Next()
+			return
+		}
+
+		res, err := store.TakeToken(ctx, route+" "+client(c), l, time.Now().UTC())
+		if err != nil {
+			log.Error(ctx, "ratelimit", "status", "taking token", "route", route, "ERROR", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", l.Requests, ceilSeconds(l.Period)))
+		c.Header("RateLimit-Limit", strconv.Itoa(l.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.Error(fmt.Errorf("%w: %s allows %s", ratelimit.ErrLimited, route, l))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// client returns the key of the client making the request. The API keys are
+// hashed, so they're not kept by the store.
+func client(c *gin.Context) string {
+	if claims, ok := auth.GetClaims(c.Request.Context()); ok {
+		return "user:" + claims.Subject
+	}
+
+	if key := c.GetHeader(HeaderAPIKey); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:])
+	}
+
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds returns the duration in whole seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
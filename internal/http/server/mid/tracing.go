@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gin-gonic/gin"
+	"github.com/phbpx/gobeer/internal/auth"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -56,6 +57,10 @@ func Tracing(tracer trace.Tracer) gin.HandlerFunc {
 		status := c.Writer.Status()
 		span.SetAttributes(semconv.HTTPStatusCode(status))
 
+		if claims, ok := auth.GetClaims(c.Request.Context()); ok {
+			span.SetAttributes(semconv.EnduserID(claims.Subject))
+		}
+
 		if status >= 400 {
 			span.SetStatus(codes.Error, "")
 		}
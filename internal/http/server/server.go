@@ -2,51 +2,153 @@
 package server
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 	"github.com/phbpx/gobeer/internal/adding"
-	"github.com/phbpx/gobeer/internal/email"
+	"github.com/phbpx/gobeer/internal/auth"
+	"github.com/phbpx/gobeer/internal/authorizing"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/editing"
+	"github.com/phbpx/gobeer/internal/events"
 	"github.com/phbpx/gobeer/internal/http/server/mid"
+	"github.com/phbpx/gobeer/internal/idempotency"
 	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/registering"
 	"github.com/phbpx/gobeer/internal/reviewing"
-	"github.com/phbpx/gobeer/internal/storage/postgres"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/streaming"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/subscribing"
 	"github.com/phbpx/gobeer/pkg/logger"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// Storage defines the storage used by the services.
+type Storage interface {
+	adding.Repository
+	editing.Repository
+	listing.Repository
+	reviewing.Storer
+	subscribing.Repository
+	streaming.Repository
+	registering.Repository
+}
+
+// DefaultHeartbeat is how long a review stream can stay silent before a
+// heartbeat is sent.
+const DefaultHeartbeat = 15 * time.Second
+
+// DefaultIdempotencyTTL is how long the responses of the idempotency keys are
+// kept.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
 // Config holds the dependencies for the handler.
 type Config struct {
 	Log         *logger.Logger
 	Tracer      trace.Tracer
-	DB          *sql.DB
-	NotifierURL string
+	Storage     Storage
+	Events      events.Publisher
+	Auth        *auth.Auth
+	Stream      StreamConfig
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+
+	// Prior is the prior of the weighted scores of the beers. The default
+	// prior is used when it's not set.
+	Prior reviews.Prior
+}
+
+// StreamConfig holds the dependencies for the review streams.
+type StreamConfig struct {
+	Hub       *streaming.Hub
+	Heartbeat time.Duration
+	MaxReplay int
+}
+
+// RateLimitConfig holds the dependencies for the rate limits. The requests
+// are not limited without a store.
+type RateLimitConfig struct {
+	Store          ratelimit.Store
+	Limits         ratelimit.Limits
+	TrustedProxies []string
+}
+
+// IdempotencyConfig holds the dependencies for the idempotency keys. The keys
+// are ignored without a store.
+type IdempotencyConfig struct {
+	Store idempotency.Store
+	TTL   time.Duration
 }
 
 // Server is the HTTP Server for the REST API.
 type Server struct {
 	log       *logger.Logger
 	tracer    trace.Tracer
+	auth      *auth.Auth
+	authz     *authorizing.Service
 	adding    *adding.Service
+	editing   *editing.Service
 	reviewing *reviewing.Service
 	listing   *listing.Service
+	webhooks  *subscribing.Service
+	streaming *streaming.Service
+	users     *registering.Service
+	heartbeat time.Duration
+	rateLimit RateLimitConfig
+	idem      IdempotencyConfig
 }
 
 // New creates a new Server.
 func New(cfg Config) *Server {
-	storage := postgres.NewStore(cfg.DB)
-	notifier := email.NewEmailNotifier(cfg.NotifierURL)
-	addingSrv := adding.NewService(storage)
-	reviewingSrv := reviewing.NewService(storage, notifier)
-	listingSrv := listing.NewService(storage)
+	addingSrv := adding.NewService(cfg.Storage, cfg.Events, styles.DefaultCatalog)
+	editingSrv := editing.NewService(cfg.Storage, styles.DefaultCatalog)
+	reviewingSrv := reviewing.NewService(cfg.Storage, cfg.Events, reviews.DefaultScale)
+	prior := cfg.Prior
+	if prior == (reviews.Prior{}) {
+		prior = reviews.DefaultPrior
+	}
+
+	listingSrv := listing.NewService(cfg.Storage, styles.DefaultCatalog, prior)
+	subscribingSrv := subscribing.NewService(cfg.Storage)
+	streamingSrv := streaming.NewService(cfg.Storage, cfg.Stream.Hub, cfg.Stream.MaxReplay)
+	registeringSrv := registering.NewService(cfg.Storage)
+	authorizingSrv := authorizing.NewService(cfg.Log, cfg.Storage, auth.DefaultPolicy)
+
+	heartbeat := cfg.Stream.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = DefaultHeartbeat
+	}
+
+	idem := cfg.Idempotency
+	if idem.TTL <= 0 {
+		idem.TTL = DefaultIdempotencyTTL
+	}
 
 	return &Server{
 		log:       cfg.Log,
 		tracer:    cfg.Tracer,
+		auth:      cfg.Auth,
+		authz:     authorizingSrv,
 		adding:    addingSrv,
+		editing:   editingSrv,
 		reviewing: reviewingSrv,
 		listing:   listingSrv,
+		webhooks:  subscribingSrv,
+		streaming: streamingSrv,
+		users:     registeringSrv,
+		heartbeat: heartbeat,
+		rateLimit: cfg.RateLimit,
+		idem:      idem,
 	}
 }
 
@@ -56,6 +158,12 @@ func (h *Server) Router() *gin.Engine {
 
 	r := gin.New()
 
+	// The client IP is taken from the forwarded headers only when they're set
+	// by a trusted proxy, so the clients can't pick their rate limit.
+	if err := r.SetTrustedProxies(h.rateLimit.TrustedProxies); err != nil {
+		h.log.Error(context.Background(), "router", "status", "setting trusted proxies", "ERROR", err)
+	}
+
 	// Add middlewares.
 	r.Use(
 		gin.Recovery(),
@@ -64,11 +172,47 @@ func (h *Server) Router() *gin.Engine {
 		mid.ErrorHandler(),
 	)
 
+	// The write endpoints require an authenticated user, and the management
+	// endpoints a user granted their permission. The requests are limited per
+	// user once authenticated. The creations can be retried safely with an
+	// idempotency key.
+	authn := mid.Authenticate(h.auth)
+	limit := mid.RateLimit(h.log, h.rateLimit.Store, h.rateLimit.Limits)
+	idem := mid.Idempotency(h.log, h.idem.Store, h.idem.TTL)
+	authz := func(perm auth.Permission) gin.HandlerFunc {
+		return mid.Authorize(h.authz, perm)
+	}
+
 	// app routes.
-	r.POST("/beers", h.addBeer)
-	r.GET("/beers", h.listBeers)
-	r.POST("/beers/:id/reviews", h.addReview)
-	r.GET("/beers/:id/reviews", h.listReviews)
+	r.POST("/beers", authn, limit, authz(auth.PermAddBeer), idem, h.addBeer)
+	r.GET("/beers", limit, h.listBeers)
+	r.GET("/beers/search", limit, h.searchBeers)
+	r.GET("/beers/top", limit, h.topBeers)
+	r.GET("/beers/:id", limit, h.getBeer)
+	r.PATCH("/beers/:id", authn, limit, authz(auth.PermEditBeer), h.updateBeer)
+	r.DELETE("/beers/:id", authn, limit, authz(auth.PermDeleteBeer), h.deleteBeer)
+	r.POST("/beers/:id/reviews", authn, limit, idem, h.addReview)
+	r.GET("/beers/:id/reviews", limit, h.listReviews)
+	r.GET("/beers/:id/reviews/stream", limit, h.streamReviews)
+	r.PATCH("/beers/:id/reviews/:reviewID", authn, limit, h.updateReview)
+	r.DELETE("/beers/:id/reviews/:reviewID", authn, limit, h.deleteReview)
+	r.GET("/beers/:id/reviews/:reviewID/history", limit, h.listReviewHistory)
+	r.POST("/breweries", authn, limit, authz(auth.PermAddBrewery), h.addBrewery)
+	r.GET("/breweries", limit, h.listBreweries)
+	r.GET("/breweries/:id", limit, h.getBrewery)
+	r.GET("/breweries/:id/beers", limit, h.listBreweryBeers)
+	r.GET("/styles", limit, h.listStyles)
+	r.GET("/reviews/stream", limit, h.streamReviews)
+	r.POST("/webhooks", authn, limit, authz(auth.PermManageWebhooks), h.addWebhook)
+	r.GET("/webhooks", authn, limit, authz(auth.PermManageWebhooks), h.listWebhooks)
+	r.GET("/webhooks/:id", authn, limit, authz(auth.PermManageWebhooks), h.getWebhook)
+	r.DELETE("/webhooks/:id", authn, limit, authz(auth.PermManageWebhooks), h.deleteWebhook)
+	r.GET("/webhooks/:id/deliveries", authn, limit, authz(auth.PermManageWebhooks), h.listWebhookDeliveries)
+	r.POST("/webhooks/:id/deliveries/:deliveryID/redeliver", authn, limit, authz(auth.PermManageWebhooks), h.redeliverWebhook)
+	r.POST("/users", limit, h.registerUser)
+	r.GET("/users/:id", limit, h.getUser)
+	r.PATCH("/users/me", authn, limit, h.updateMe)
+	r.PUT("/users/:id/role", authn, limit, authz(auth.PermManageUsers), h.changeRole)
 
 	// debug routes.
 	r.GET("/debug/health", func(c *gin.Context) {
@@ -94,6 +238,7 @@ func (h *Server) addBeer(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(b.Version))
 	c.JSON(http.StatusCreated, b)
 }
 
@@ -101,7 +246,128 @@ func (h *Server) addBeer(c *gin.Context) {
 func (h *Server) listBeers(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	bs, err := h.listing.ListBeers(ctx)
+	var q listing.BeerQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(queryError(err))
+		return
+	}
+
+	page, err := h.listing.ListBeers(ctx, q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(page.Beers) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// getBeer is the HTTP handler for the GET /beers/:id endpoint.
+func (h *Server) getBeer(c *gin.Context) {
+	ctx := c.Request.Context()
+	beerID := c.Param("id")
+
+	var q listing.DetailQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(err)
+		return
+	}
+
+	b, err := h.listing.GetBeer(ctx, beerID, q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(b.Version))
+	c.JSON(http.StatusOK, b)
+}
+
+// updateBeer is the HTTP handler for the PATCH /beers/:id endpoint.
+func (h *Server) updateBeer(c *gin.Context) {
+	ctx := c.Request.Context()
+	beerID := c.Param("id")
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var ub editing.UpdateBeer
+	if err := c.ShouldBindJSON(&ub); err != nil {
+		c.Error(err)
+		return
+	}
+
+	b, err := h.editing.UpdateBeer(ctx, beerID, version, ub)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag(b.Version))
+	c.JSON(http.StatusOK, b)
+}
+
+// deleteBeer is the HTTP handler for the DELETE /beers/:id endpoint.
+func (h *Server) deleteBeer(c *gin.Context) {
+	ctx := c.Request.Context()
+	beerID := c.Param("id")
+
+	version, err := ifMatch(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.editing.DeleteBeer(ctx, beerID, version); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// searchBeers is the HTTP handler for the GET /beers/search endpoint.
+func (h *Server) searchBeers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var q listing.SearchQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(queryError(err))
+		return
+	}
+
+	res, err := h.listing.SearchBeers(ctx, q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(res.Beers) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// topBeers is the HTTP handler for the GET /beers/top endpoint.
+func (h *Server) topBeers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var q listing.TopQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(queryError(err))
+		return
+	}
+
+	bs, err := h.listing.TopBeers(ctx, q)
 	if err != nil {
 		c.Error(err)
 		return
@@ -115,10 +381,20 @@ func (h *Server) listBeers(c *gin.Context) {
 	c.JSON(http.StatusOK, bs)
 }
 
-// addReview is the HTTP handler for the POST /beers/:id/reviews endpoint.
+// addReview is the HTTP handler for the POST /beers/:id/reviews endpoint. A
+// user reviews a beer once: reviewing it again is a conflict, unless the
+// upsert query parameter asks to replace the existing review.
 func (h *Server) addReview(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	var q struct {
+		Upsert bool `form:"upsert"`
+	}
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(queryError(err))
+		return
+	}
+
 	var nr reviewing.NewReview
 	if err := c.ShouldBindJSON(&nr); err != nil {
 		c.Error(err)
@@ -127,13 +403,28 @@ func (h *Server) addReview(c *gin.Context) {
 
 	beerID := c.Param("id")
 
-	bs, err := h.reviewing.CreateReview(ctx, beerID, nr)
+	if !q.Upsert {
+		bs, err := h.reviewing.CreateReview(ctx, beerID, subject(c), nr)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, bs)
+		return
+	}
+
+	bs, created, err := h.reviewing.UpsertReview(ctx, beerID, subject(c), nr)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, bs)
+	if created {
+		c.JSON(http.StatusCreated, bs)
+		return
+	}
+	c.JSON(http.StatusOK, bs)
 }
 
 // listReviews is the HTTP handler for the GET /beers/:id/reviews endpoint.
@@ -154,3 +445,405 @@ func (h *Server) listReviews(c *gin.Context) {
 
 	c.JSON(http.StatusOK, r)
 }
+
+// streamReviews is the HTTP handler for the GET /beers/:id/reviews/stream and
+// GET /reviews/stream endpoints. The new reviews are sent as Server-Sent
+// Events, identified by the review ID so the client resumes from the last one
+// it received through the Last-Event-ID header.
+func (h *Server) streamReviews(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	st, err := h.streaming.Stream(ctx, c.Param("id"), c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer st.Close()
+
+	// The stream outlives the write timeout of the server.
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Ask the clients to reconnect quickly, as a stream falling behind is
+	// closed.
+	if _, err := fmt.Fprint(c.Writer, "retry: 1000\n\n"); err != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	for {
+		r, ok, err := st.Next(ctx, h.heartbeat)
+		switch {
+		case errors.Is(err, streaming.ErrLagged):
+			h.log.Warn(ctx, "streaming", "status", "closing lagging stream", "beer_id", c.Param("id"))
+			return
+		case err != nil:
+			return
+		}
+
+		if ok {
+			err = writeEvent(c.Writer, r.ID, "review", r)
+		} else {
+			_, err = fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent writes a Server-Sent Event with the JSON of the value as data.
+func writeEvent(w gin.ResponseWriter, id, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, data)
+	return err
+}
+
+// queryError wraps the errors of query parameters that could not be parsed,
+// so they are reported as a bad request. Validation errors are kept as they
+// are to report the invalid fields.
+func queryError(err error) error {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", listing.ErrInvalidQuery, err)
+}
+
+// updateReview is the HTTP handler for the PATCH /beers/:id/reviews/:reviewID endpoint.
+func (h *Server) updateReview(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var ur reviewing.UpdateReview
+	if err := c.ShouldBindJSON(&ur); err != nil {
+		c.Error(err)
+		return
+	}
+
+	beerID := c.Param("id")
+	reviewID := c.Param("reviewID")
+
+	r, err := h.reviewing.UpdateReview(ctx, beerID, reviewID, subject(c), ur)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, r)
+}
+
+// deleteReview is the HTTP handler for the DELETE /beers/:id/reviews/:reviewID endpoint.
+// The moderators can delete the reviews of other users.
+func (h *Server) deleteReview(c *gin.Context) {
+	ctx := c.Request.Context()
+	beerID := c.Param("id")
+	reviewID := c.Param("reviewID")
+
+	err := h.reviewing.DeleteReview(ctx, beerID, reviewID, subject(c))
+	if errors.Is(err, reviews.ErrNotAuthor) {
+		if err := h.authz.Authorize(ctx, subject(c), auth.PermModerateReviews); err != nil {
+			c.Error(err)
+			return
+		}
+		err = h.reviewing.RemoveReview(ctx, beerID, reviewID)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listReviewHistory is the HTTP handler for the GET /beers/:id/reviews/:reviewID/history endpoint.
+func (h *Server) listReviewHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	beerID := c.Param("id")
+	reviewID := c.Param("reviewID")
+
+	rs, err := h.listing.ListReviewHistory(ctx, beerID, reviewID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rs)
+}
+
+// addBrewery is the HTTP handler for the POST /breweries endpoint.
+func (h *Server) addBrewery(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var nb adding.NewBrewery
+	if err := c.ShouldBindJSON(&nb); err != nil {
+		c.Error(err)
+		return
+	}
+
+	b, err := h.adding.AddBrewery(ctx, nb)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// listBreweries is the HTTP handler for the GET /breweries endpoint.
+func (h *Server) listBreweries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, err := h.listing.ListBreweries(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// getBrewery is the HTTP handler for the GET /breweries/:id endpoint.
+func (h *Server) getBrewery(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	b, err := h.listing.GetBrewery(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+// listBreweryBeers is the HTTP handler for the GET /breweries/:id/beers
+// endpoint. It takes the same query parameters as GET /beers.
+func (h *Server) listBreweryBeers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var q listing.BeerQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.Error(queryError(err))
+		return
+	}
+
+	page, err := h.listing.ListBreweryBeers(ctx, c.Param("id"), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(page.Beers) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// listStyles is the HTTP handler for the GET /styles endpoint.
+func (h *Server) listStyles(c *gin.Context) {
+	c.JSON(http.StatusOK, h.listing.ListStyles(c.Request.Context()))
+}
+
+// registerUser is the HTTP handler for the POST /users endpoint.
+func (h *Server) registerUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var nu registering.NewUser
+	if err := c.ShouldBindJSON(&nu); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u, err := h.users.Register(ctx, nu)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, u)
+}
+
+// getUser is the HTTP handler for the GET /users/:id endpoint.
+func (h *Server) getUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	u, err := h.users.GetProfile(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// updateMe is the HTTP handler for the PATCH /users/me endpoint.
+func (h *Server) updateMe(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var up registering.UpdateProfile
+	if err := c.ShouldBindJSON(&up); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u, err := h.users.UpdateProfile(ctx, subject(c), up)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// changeRole is the HTTP handler for the PUT /users/:id/role endpoint.
+func (h *Server) changeRole(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var cr registering.ChangeRole
+	if err := c.ShouldBindJSON(&cr); err != nil {
+		c.Error(err)
+		return
+	}
+
+	u, err := h.users.ChangeRole(ctx, c.Param("id"), cr)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, u)
+}
+
+// addWebhook is the HTTP handler for the POST /webhooks endpoint.
+func (h *Server) addWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var ns subscribing.NewSubscription
+	if err := c.ShouldBindJSON(&ns); err != nil {
+		c.Error(err)
+		return
+	}
+
+	sub, err := h.webhooks.Subscribe(ctx, ns)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// listWebhooks is the HTTP handler for the GET /webhooks endpoint.
+func (h *Server) listWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, err := h.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// getWebhook is the HTTP handler for the GET /webhooks/:id endpoint.
+func (h *Server) getWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sub, err := h.webhooks.GetSubscription(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sub)
+}
+
+// deleteWebhook is the HTTP handler for the DELETE /webhooks/:id endpoint.
+func (h *Server) deleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if err := h.webhooks.Unsubscribe(ctx, c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listWebhookDeliveries is the HTTP handler for the GET /webhooks/:id/deliveries endpoint.
+func (h *Server) listWebhookDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, err := h.webhooks.ListDeliveries(ctx, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(list) == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// redeliverWebhook is the HTTP handler for the POST /webhooks/:id/deliveries/:deliveryID/redeliver endpoint.
+func (h *Server) redeliverWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	d, err := h.webhooks.Redeliver(ctx, c.Param("id"), c.Param("deliveryID"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, d)
+}
+
+// subject returns the authenticated user.
+func subject(c *gin.Context) string {
+	claims, _ := auth.GetClaims(c.Request.Context())
+	return claims.Subject
+}
+
+// etag returns the entity tag of the given beer version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatch returns the beer version required by the If-Match header.
+func ifMatch(c *gin.Context) (int, error) {
+	v := c.GetHeader("If-Match")
+	if v == "" {
+		return 0, beers.ErrVersionRequired
+	}
+
+	version, err := strconv.Atoi(strings.Trim(v, `"`))
+	if err != nil {
+		return 0, beers.ErrVersionMismatch
+	}
+
+	return version, nil
+}
@@ -1,93 +1,357 @@
 package server_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/adding"
+	"github.com/phbpx/gobeer/internal/auth"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/events"
 	"github.com/phbpx/gobeer/internal/http/server"
+	"github.com/phbpx/gobeer/internal/http/server/mid"
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/registering"
 	"github.com/phbpx/gobeer/internal/reviewing"
-	"github.com/phbpx/gobeer/internal/storage/postgres/dbtest"
-	"github.com/phbpx/gobeer/pkg/docker"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/storage/memory"
+	"github.com/phbpx/gobeer/internal/streaming"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/subscribing"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
+	"github.com/phbpx/gobeer/pkg/logger"
 	"go.opentelemetry.io/otel"
 )
 
-var c *docker.Container
+// secret is the key of the tokens accepted by the server.
+const secret = "test-secret"
 
-func TestMain(m *testing.M) {
-	var err error
-
-	c, err = dbtest.StartDB()
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	defer dbtest.StopDB(c)
+// Set of users granted a role, seeded into the storage.
+var (
+	adminID     = uuid.NewString()
+	brewerID    = uuid.NewString()
+	moderatorID = uuid.NewString()
+)
 
-	m.Run()
-}
+// breweryID is the brewery of the beers, seeded into the storage.
+var breweryID = uuid.NewString()
 
 func TestServer(t *testing.T) {
 	t.Parallel()
 
-	// setup db
-	test := dbtest.NewTest(t, c)
-	defer test.Teardown()
+	log := logger.New(io.Discard, logger.LevelInfo, "TEST")
 
-	// setup notifier
-	notifier := httptest.NewServer(http.HandlerFunc(
-		func(w http.ResponseWriter, req *http.Request) {
-			w.WriteHeader(http.StatusOK)
-		},
-	))
-	defer notifier.Close()
+	a, err := auth.New(auth.Config{Secret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hub := streaming.NewHub(0)
+	bus := events.NewBus(log, events.Config{})
+	bus.Subscribe("streaming", hub.Handler())
+
+	store := memory.NewStore()
+	seedUsers(t, store)
+	seedBrewery(t, store)
 
 	h := server.New(server.Config{
-		Log:         test.Log,
-		Tracer:      otel.Tracer(""),
-		DB:          test.DB,
-		NotifierURL: notifier.URL,
+		Log:     log,
+		Tracer:  otel.Tracer(""),
+		Storage: store,
+		Events:  bus,
+		Auth:    a,
+		Stream: server.StreamConfig{
+			Hub:       hub,
+			Heartbeat: 50 * time.Millisecond,
+		},
 	})
 
+	testPostWebhook201(t, h)
+	testPostWebhook400(t, h)
+	testPostUser201(t, h)
+	testPostUser409(t, h)
+	testGetUser200(t, h)
+	testGetUser404(t, h)
+	testPatchMe200(t, h)
+	testPatchMe403(t, h)
+	testPutUserRole200(t, h)
+	testPutUserRole403(t, h)
+	testPostBrewery201(t, h)
+	testPostBrewery409(t, h)
+	testPostBrewery403(t, h)
+	testGetBreweries200(t, h)
+	testGetBrewery404(t, h)
+	testGetStyles200(t, h)
 	testPostBeer201(t, h)
 	testPostBeer400(t, h)
+	testPostBeerUnknownStyle400(t, h)
+	testPostBeer404(t, h)
 	testPostBeer409(t, h)
+	testPostBeer401(t, h)
+	testPostBeer403(t, h)
+	testGetBreweryBeers200(t, h)
+	testGetBreweryBeers404(t, h)
 	testGetBeers200(t, h)
+	testGetBeers400(t, h)
+	testSearchBeers200(t, h)
+	testGetBeer200(t, h)
+	testGetBeer400(t, h)
+	testGetBeer404(t, h)
+	testPatchBeer200(t, h)
+	testPatchBeer412(t, h)
+	testPatchBeer428(t, h)
+	testSearchBeers400(t, h)
 	testPostBeerReview201(t, h)
 	testPostBeerReview400(t, h)
+	testPostBeerReviewScore400(t, h)
+	testPostBeerReviewSubScores201(t, h)
+	testPostBeerReview409(t, h)
+	testPostBeerReviewUpsert200(t, h)
+	testGetTopBeers200(t, h)
+	testGetTopBeers400(t, h)
 	testPostBeerReview404(t, h)
+	testPostBeerReview401(t, h)
 	testGetBeerReviews200(t, h)
 	testGetBeerReviews204(t, h)
 	testGetBeerReviews400(t, h)
+	testPatchBeerReview200(t, h)
+	testPatchBeerReview403(t, h)
+	testGetBeerReviewHistory200(t, h)
+	testDeleteBeerReview403(t, h)
+	testDeleteBeerReview204(t, h)
+	testModerateBeerReview204(t, h)
+	testStreamBeerReviews200(t, h)
+	testStreamReviews200(t, h)
+	testStreamBeerReviews404(t, h)
+	testDeleteBeer204(t, h)
+	testGetWebhooks200(t, h)
+	testGetWebhooks401(t, h)
+	testGetWebhooks403(t, h)
+	testGetWebhookDeliveries200(t, h)
+	testPostWebhookRedeliver202(t, h)
+	testPostWebhookRedeliver404(t, h)
+	testDeleteWebhook204(t, h)
+	testGetWebhook404(t, h)
 }
 
-func testPostBeer201(t *testing.T, h *server.Server) {
-	nb := adding.NewBeer{
-		Name:      "Test Beer",
-		Brewery:   "Test Brewery",
-		ShortDesc: "Test Short Description",
-		Style:     "Test Style",
-		ABV:       5.5,
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.New(auth.Config{Secret: secret})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	body, err := json.Marshal(nb)
+	h := server.New(server.Config{
+		Log:     logger.New(io.Discard, logger.LevelInfo, "TEST"),
+		Tracer:  otel.Tracer(""),
+		Storage: memory.NewStore(),
+		Events:  events.NewBus(logger.New(io.Discard, logger.LevelInfo, "TEST"), events.Config{}),
+		Auth:    a,
+		RateLimit: server.RateLimitConfig{
+			Store: ratelimit.NewMemory(),
+			Limits: ratelimit.Limits{
+				Routes: map[string]ratelimit.Limit{
+					"GET /beers": {Requests: 2, Period: time.Minute},
+				},
+			},
+		},
+	})
+
+	get := func(path, apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if apiKey != "" {
+			r.Header.Set(mid.HeaderAPIKey, apiKey)
+		}
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	t.Log("Given the need to limit the requests of the clients.")
+	{
+		t.Log("\tWhen a client makes requests up to the limit.")
+		{
+			for remaining := 1; remaining >= 0; remaining-- {
+				w := get("/beers", "")
+				if w.Code != http.StatusNoContent {
+					t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+				}
+				if got := w.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(remaining) {
+					t.Fatalf("\t\t[ERROR] Should have %d requests remaining. Got %q", remaining, got)
+				}
+			}
+			t.Log("\t\t[OK] Should allow the requests.")
+		}
+
+		t.Log("\tWhen a client makes requests over the limit.")
+		{
+			w := get("/beers", "")
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("\t\t[ERROR] Should receive a 429 status code. Got %d", w.Code)
+			}
+			if w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Policy") != "2;w=60" {
+				t.Fatalf("\t\t[ERROR] Should send the rate limit headers. Got %v", w.Header())
+			}
+			t.Log("\t\t[OK] Should receive a 429 status code.")
+		}
+
+		t.Log("\tWhen another client, or another route, is requested.")
+		{
+			if w := get("/beers", "integration-key"); w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should limit the API key on its own. Got %d", w.Code)
+			}
+			if w := get("/breweries", ""); w.Code != http.StatusNoContent || w.Header().Get("RateLimit-Limit") != "" {
+				t.Fatalf("\t\t[ERROR] Should not limit the route. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should keep the limits apart.")
+		}
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Parallel()
+
+	a, err := auth.New(auth.Config{Secret: secret})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	r := httptest.NewRequest("POST", "/beers", bytes.NewReader(body))
+	store := memory.NewStore()
+	seedUsers(t, store)
+	seedBrewery(t, store)
+
+	h := server.New(server.Config{
+		Log:     logger.New(io.Discard, logger.LevelInfo, "TEST"),
+		Tracer:  otel.Tracer(""),
+		Storage: store,
+		Events:  events.NewBus(logger.New(io.Discard, logger.LevelInfo, "TEST"), events.Config{}),
+		Auth:    a,
+		Idempotency: server.IdempotencyConfig{
+			Store: idempotency.NewMemory(),
+		},
+	})
+
+	post := func(path, userID, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set(mid.HeaderIdempotencyKey, key)
+		authorize(t, r, userID)
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	beer := fmt.Sprintf(`{"name":"Idempotent IPA","brewery_id":%q,"style":"IPA","abv":6.5,"short_desc":"Test"}`, breweryID)
+
+	t.Log("Given the need to retry the creations safely.")
+	{
+		t.Log("\tWhen a beer is created twice with the same key.")
+		{
+			first := post("/beers", brewerID, "beer-1", beer)
+			retry := post("/beers", brewerID, "beer-1", beer)
+
+			if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d and %d", first.Code, retry.Code)
+			}
+			if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+				t.Fatalf("\t\t[ERROR] Should replay the response. Got %s", retry.Body)
+			}
+			if retry.Header().Get(mid.HeaderReplayed) != "true" || first.Header().Get(mid.HeaderReplayed) != "" {
+				t.Fatalf("\t\t[ERROR] Should tell the replayed response apart. Got %v", retry.Header())
+			}
+			if beers := getBeers(t, h); len(beers) != 1 {
+				t.Fatalf("\t\t[ERROR] Should create a single beer. Got %d", len(beers))
+			}
+			t.Log("\t\t[OK] Should replay the response of the first request.")
+		}
+
+		t.Log("\tWhen the key is reused with another body.")
+		{
+			w := post("/beers", brewerID, "beer-1", fmt.Sprintf(`{"name":"Another IPA","brewery_id":%q,"style":"IPA","abv":6.5,"short_desc":"Test"}`, breweryID))
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("\t\t[ERROR] Should receive a 422 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 422 status code.")
+		}
+
+		t.Log("\tWhen another user uses the same key.")
+		{
+			w := post("/beers", adminID, "beer-1", beer)
+			if w.Code != http.StatusConflict || w.Header().Get(mid.HeaderReplayed) != "" {
+				t.Fatalf("\t\t[ERROR] Should not share the key. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should not share the key.")
+		}
+
+		t.Log("\tWhen a review is created twice with the same key.")
+		{
+			path := fmt.Sprintf("/beers/%s/reviews", getBeers(t, h)[0].ID)
+			body := `{"score":4,"comment":"Retried review"}`
+
+			var first, retry reviews.Review
+			if err := json.NewDecoder(post(path, brewerID, "review-1", body).Body).Decode(&first); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.NewDecoder(post(path, brewerID, "review-1", body).Body).Decode(&retry); err != nil {
+				t.Fatal(err)
+			}
+
+			if first.ID == "" || retry.ID != first.ID {
+				t.Fatalf("\t\t[ERROR] Should create a single review. Got %s and %s", first.ID, retry.ID)
+			}
+			t.Log("\t\t[OK] Should create a single review.")
+		}
+
+		t.Log("\tWhen the key is reused with other query parameters.")
+		{
+			path := fmt.Sprintf("/beers/%s/reviews?upsert=true", getBeers(t, h)[0].ID)
+
+			w := post(path, brewerID, "review-1", `{"score":4,"comment":"Retried review"}`)
+			if w.Code != http.StatusUnprocessableEntity || w.Header().Get(mid.HeaderReplayed) != "" {
+				t.Fatalf("\t\t[ERROR] Should receive a 422 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 422 status code.")
+		}
+	}
+}
+
+func testPostUser201(t *testing.T, h *server.Server) {
+	nu := registering.NewUser{
+		Handle:      "Hop_Head",
+		Email:       "hop.head@example.com",
+		Password:    "correct horse battery",
+		DisplayName: "Hop Head",
+	}
+
+	body, err := json.Marshal(nu)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/users", bytes.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer can be added.")
+	t.Log("Given the neeed to validate a new user can be registered.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
@@ -96,65 +360,101 @@ func testPostBeer201(t *testing.T, h *server.Server) {
 			}
 			t.Log("\t\t[OK] Should receive a 201 status code.")
 		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var u users.User
+			if err := json.NewDecoder(w.Body).Decode(&u); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to decode the response body: %v", err)
+			}
+			if u.Handle != "hop_head" || u.PasswordHash != "" {
+				t.Fatalf("\t\t[ERROR] Should return the account without the password. Got %+v", u)
+			}
+			t.Log("\t\t[OK] Should return the account without the password.")
+		}
 	}
 }
 
-func testPostBeer400(t *testing.T, h *server.Server) {
-	r := httptest.NewRequest("POST", "/beers", strings.NewReader("{}"))
+func testPostUser409(t *testing.T, h *server.Server) {
+	body := `{"handle":"hop_head","email":"other@example.com","password":"correct horse battery"}`
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader(body))
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer can't be added with invalid payload.")
+	t.Log("Given the neeed to validate a user can't be registered with a taken handle.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusBadRequest {
-				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t\t[ERROR] Should receive a 409 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 400 status code.")
+			t.Log("\t\t[OK] Should receive a 409 status code.")
 		}
 	}
 }
 
-func testPostBeer409(t *testing.T, h *server.Server) {
-	nb := adding.NewBeer{
-		Name:      "Test Beer",
-		Brewery:   "Test Brewery",
-		ShortDesc: "Test Short Description",
-		Style:     "Test Style",
-		ABV:       5.5,
-	}
+func testGetUser200(t *testing.T, h *server.Server) {
+	u := registerUser(t, h)
 
-	body, err := json.Marshal(nb)
-	if err != nil {
-		t.Fatal(err)
+	r := httptest.NewRequest("GET", "/users/"+u.ID, nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the profile of a user can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got users.User
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to decode the response body: %v", err)
+			}
+			if got.Handle != u.Handle || got.Email != "" {
+				t.Fatalf("\t\t[ERROR] Should return the public profile. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should return the public profile.")
+		}
 	}
+}
 
-	r := httptest.NewRequest("POST", "/beers", bytes.NewReader(body))
+func testGetUser404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/users/"+uuid.NewString(), nil)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer can't be added with a duplicate name.")
+	t.Log("Given the neeed to validate an unknown user can't be retrieved.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusConflict {
-				t.Fatalf("\t\t[ERROR] Should receive a 409 status code. Got %d", w.Code)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 409 status code.")
+			t.Log("\t\t[OK] Should receive a 404 status code.")
 		}
 	}
 }
 
-func testGetBeers200(t *testing.T, h *server.Server) {
-	r := httptest.NewRequest("GET", "/beers", nil)
+func testPatchMe200(t *testing.T, h *server.Server) {
+	u := registerUser(t, h)
+
+	r := httptest.NewRequest("PATCH", "/users/me", strings.NewReader(`{"bio":"Hazy IPAs only"}`))
+	authorize(t, r, u.ID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a list of beers can be retrieved.")
+	t.Log("Given the neeed to validate a user can update their profile.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
@@ -163,146 +463,176 @@ func testGetBeers200(t *testing.T, h *server.Server) {
 			}
 			t.Log("\t\t[OK] Should receive a 200 status code.")
 		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got users.User
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to decode the response body: %v", err)
+			}
+			if got.Bio != "Hazy IPAs only" || got.Handle != u.Handle {
+				t.Fatalf("\t\t[ERROR] Should update the profile. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should update the profile.")
+		}
 	}
 }
 
-func testPostBeerReview201(t *testing.T, h *server.Server) {
-	nr := reviewing.NewReview{
-		UserID:  uuid.NewString(),
-		Score:   5,
-		Comment: "Test Comment",
-	}
+func testPatchMe403(t *testing.T, h *server.Server) {
+	u := registerUser(t, h)
 
-	body, err := json.Marshal(nr)
-	if err != nil {
-		t.Fatal(err)
-	}
+	r := httptest.NewRequest("PATCH", "/users/me", strings.NewReader(`{"password":"another long password","current_password":"wrong password"}`))
+	authorize(t, r, u.ID)
+	w := httptest.NewRecorder()
 
-	beers := getBeers(t, h)
-	if len(beers) == 0 {
-		t.Fatal("No beers found")
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a user can't change their password without the current one.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 403 status code.")
+		}
 	}
+}
 
-	beerID := beers[0].ID
+func testPutUserRole200(t *testing.T, h *server.Server) {
+	u := registerUser(t, h)
 
-	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beerID), bytes.NewReader(body))
+	r := httptest.NewRequest("PUT", "/users/"+u.ID+"/role", strings.NewReader(`{"role":"brewer"}`))
+	authorize(t, r, adminID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer review can be added.")
+	t.Log("Given the neeed to validate an admin can change the role of a user.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusCreated {
-				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 201 status code.")
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got users.User
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to decode the response body: %v", err)
+			}
+			if got.Role != users.RoleBrewer {
+				t.Fatalf("\t\t[ERROR] Should change the role. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should change the role.")
 		}
 	}
 }
 
-func testPostBeerReview400(t *testing.T, h *server.Server) {
-	r := httptest.NewRequest("POST", "/beers/123/reviews", strings.NewReader("{}"))
+func testPutUserRole403(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("PUT", "/users/"+brewerID+"/role", strings.NewReader(`{"role":"admin"}`))
+	authorize(t, r, brewerID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer review can't be added with invalid payload.")
+	t.Log("Given the neeed to validate a user can't grant roles without the permission.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusBadRequest {
-				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 400 status code.")
+			t.Log("\t\t[OK] Should receive a 403 status code.")
 		}
 	}
 }
 
-func testPostBeerReview404(t *testing.T, h *server.Server) {
-	nr := reviewing.NewReview{
-		UserID:  uuid.NewString(),
-		Score:   3.0,
-		Comment: "Test Comment",
+func testPostBeer201(t *testing.T, h *server.Server) {
+	nb := adding.NewBeer{
+		Name:      "Test Beer",
+		BreweryID: breweryID,
+		ShortDesc: "Test Short Description",
+		Style:     "Pale Ale",
+		ABV:       5.5,
 	}
 
-	body, err := json.Marshal(nr)
+	body, err := json.Marshal(nb)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	beerID := uuid.NewString()
-
-	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beerID), bytes.NewReader(body))
+	r := httptest.NewRequest("POST", "/beers", bytes.NewReader(body))
+	authorize(t, r, brewerID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a new beer review can't be added with a non existing beer.")
+	t.Log("Given the neeed to validate a new beer can be added.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusNotFound {
-				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 404 status code.")
+			t.Log("\t\t[OK] Should receive a 201 status code.")
 		}
 	}
 }
 
-func testGetBeerReviews200(t *testing.T, h *server.Server) {
-	beers := getBeers(t, h)
-	if len(beers) == 0 {
-		t.Fatal("No beers found")
-	}
-
-	beerID := beers[0].ID
+func testPostBeer404(t *testing.T, h *server.Server) {
+	body := fmt.Sprintf(`{"name":"Orphan Beer","brewery_id":%q,"style":"IPA","abv":5,"short_desc":"Test"}`, uuid.NewString())
 
-	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews", beerID), nil)
+	r := httptest.NewRequest("POST", "/beers", strings.NewReader(body))
+	authorize(t, r, brewerID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a list of beer reviews can be retrieved.")
+	t.Log("Given the neeed to validate a new beer can't be added to a non existing brewery.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusOK {
-				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 200 status code.")
+			t.Log("\t\t[OK] Should receive a 404 status code.")
 		}
 	}
 }
 
-func testGetBeerReviews204(t *testing.T, h *server.Server) {
-	beerID := uuid.NewString()
+func testPostBeer403(t *testing.T, h *server.Server) {
+	body := fmt.Sprintf(`{"name":"Member Beer","brewery_id":%q,"style":"IPA","abv":5}`, breweryID)
 
-	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews", beerID), nil)
+	r := httptest.NewRequest("POST", "/beers", strings.NewReader(body))
+	authorize(t, r, registerUser(t, h).ID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a list of beer reviews can't be retrieved with a non existing beer.")
+	t.Log("Given the neeed to validate a new beer can't be added by a member.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
-			if w.Code != http.StatusNoContent {
-				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
 			}
-			t.Log("\t\t[OK] Should receive a 204 status code.")
+			t.Log("\t\t[OK] Should receive a 403 status code.")
 		}
 	}
 }
 
-func testGetBeerReviews400(t *testing.T, h *server.Server) {
-	r := httptest.NewRequest("GET", "/beers/invalid/reviews", nil)
+func testPostBeer400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("POST", "/beers", strings.NewReader("{}"))
+	authorize(t, r, brewerID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	t.Log("Given the neeed to validate a list of beer reviews can't be retrieved with an invalid beer ID.")
+	t.Log("Given the neeed to validate a new beer can't be added with invalid payload.")
 	{
 		t.Log("\tWhen checking the response code.")
 		{
@@ -314,16 +644,1634 @@ func testGetBeerReviews400(t *testing.T, h *server.Server) {
 	}
 }
 
-func getBeers(t *testing.T, h *server.Server) []beers.Beer {
-	r := httptest.NewRequest("GET", "/beers", nil)
+func testPostBeerUnknownStyle400(t *testing.T, h *server.Server) {
+	body := fmt.Sprintf(`{"name":"Pumpkin Beer","brewery_id":%q,"style":"Pumpkin Spice","abv":5,"short_desc":"Test"}`, breweryID)
+
+	r := httptest.NewRequest("POST", "/beers", strings.NewReader(body))
+	authorize(t, r, brewerID)
 	w := httptest.NewRecorder()
 
 	h.Router().ServeHTTP(w, r)
 
-	var beers []beers.Beer
-	if err := json.NewDecoder(w.Body).Decode(&beers); err != nil {
-		t.Fatal(err)
-	}
+	t.Log("Given the neeed to validate a new beer can't be added with an unknown style.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testPostBeer409(t *testing.T, h *server.Server) {
+	nb := adding.NewBeer{
+		Name:      "Test Beer",
+		BreweryID: breweryID,
+		ShortDesc: "Test Short Description",
+		Style:     "Pale Ale",
+		ABV:       5.5,
+	}
+
+	body, err := json.Marshal(nb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/beers", bytes.NewReader(body))
+	authorize(t, r, brewerID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer can't be added with a duplicate name.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t\t[ERROR] Should receive a 409 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 409 status code.")
+		}
+	}
+}
+
+func testPostBeer401(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("POST", "/beers", strings.NewReader(`{"name": "Test Beer"}`))
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer can't be added without a token.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("\t\t[ERROR] Should receive a 401 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 401 status code.")
+		}
+	}
+}
+
+func testGetBeers200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a list of beers can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+	}
+}
+
+func testGetBeers400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers?sort=color&limit=abc", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a list of beers can't be retrieved with invalid parameters.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testSearchBeers200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers/search?q=test+bee", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate beers can be searched.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+	}
+}
+
+func testSearchBeers400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers/search", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate beers can't be searched without terms.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testGetBeer200(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s", beers[0].ID), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer detail can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+	}
+}
+
+func testGetBeer400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers/invalid", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer detail can't be retrieved with an invalid beer ID.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testPostBrewery201(t *testing.T, h *server.Server) {
+	body := `{"name":"Cervejaria Bodebrown","location":"Curitiba, PR","website":"https://bodebrown.com.br","founded_year":2009}`
+
+	r := httptest.NewRequest("POST", "/breweries", strings.NewReader(body))
+	authorize(t, r, brewerID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new brewery can be added.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 201 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var b breweries.Brewery
+			if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+				t.Fatal(err)
+			}
+			if b.ID == "" || b.Location != "Curitiba, PR" || b.FoundedYear != 2009 {
+				t.Fatalf("\t\t[ERROR] Should receive the brewery. Got %+v", b)
+			}
+			t.Log("\t\t[OK] Should receive the brewery.")
+		}
+	}
+}
+
+func testPostBrewery409(t *testing.T, h *server.Server) {
+	body := `{"name":"cervejaria  bodebrown"}`
+
+	r := httptest.NewRequest("POST", "/breweries", strings.NewReader(body))
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a brewery can't be added twice with another spelling.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t\t[ERROR] Should receive a 409 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 409 status code.")
+		}
+	}
+}
+
+func testPostBrewery403(t *testing.T, h *server.Server) {
+	body := `{"name":"Member Brewery"}`
+
+	r := httptest.NewRequest("POST", "/breweries", strings.NewReader(body))
+	authorize(t, r, registerUser(t, h).ID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new brewery can't be added by a member.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 403 status code.")
+		}
+	}
+}
+
+func testGetBreweries200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/breweries", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the breweries can be listed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var list []breweries.Brewery
+			if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[0].Name != "Cervejaria Bodebrown" || list[1].ID != breweryID {
+				t.Fatalf("\t\t[ERROR] Should list the breweries by name. Got %+v", list)
+			}
+			t.Log("\t\t[OK] Should list the breweries by name.")
+		}
+	}
+}
+
+func testGetBrewery404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/breweries/%s", uuid.NewString()), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a brewery can't be retrieved with a non existing brewery.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testGetStyles200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/styles", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the style catalog can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got styles.Catalog
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("\t\t[ERROR] Should decode the catalog: %v", err)
+			}
+			if len(got) == 0 || len(got[0].Styles) == 0 || got[0].Styles[0].Glassware == "" {
+				t.Fatalf("\t\t[ERROR] Should receive the styles grouped by family. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should receive the styles grouped by family.")
+		}
+	}
+}
+
+func testGetBreweryBeers200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/breweries/%s/beers", breweryID), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the beers of a brewery can be listed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var page listing.BeerPage
+			if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			if len(page.Beers) == 0 {
+				t.Fatal("\t\t[ERROR] Should list the beers of the brewery.")
+			}
+			for _, b := range page.Beers {
+				if b.BreweryID != breweryID || b.Brewery != "Test Brewery" {
+					t.Fatalf("\t\t[ERROR] Should only list the beers of the brewery. Got %+v", b)
+				}
+			}
+			t.Log("\t\t[OK] Should list the beers of the brewery.")
+		}
+	}
+}
+
+func testGetBreweryBeers404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/breweries/%s/beers", uuid.NewString()), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the beers of a non existing brewery can't be listed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testGetBeer404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s", uuid.NewString()), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer detail can't be retrieved with a non existing beer.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testPatchBeer200(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	url := fmt.Sprintf("/beers/%s", beers[0].ID)
+
+	r := httptest.NewRequest("PATCH", url, strings.NewReader(`{"short_desc": "Updated Short Description"}`))
+	authorize(t, r, brewerID)
+	r.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(beers[0].Version)))
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer can be edited.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the entity tag.")
+		{
+			want := fmt.Sprintf("%q", fmt.Sprint(beers[0].Version+1))
+			if got := w.Header().Get("ETag"); got != want {
+				t.Fatalf("\t\t[ERROR] Should receive the new version %s. Got %s", want, got)
+			}
+			t.Log("\t\t[OK] Should receive the new version.")
+		}
+	}
+}
+
+func testPatchBeer412(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	url := fmt.Sprintf("/beers/%s", beers[0].ID)
+
+	r := httptest.NewRequest("PATCH", url, strings.NewReader(`{"abv": 6.5}`))
+	authorize(t, r, brewerID)
+	r.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(beers[0].Version-1)))
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer can't be edited from an outdated version.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusPreconditionFailed {
+				t.Fatalf("\t\t[ERROR] Should receive a 412 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 412 status code.")
+		}
+	}
+}
+
+func testPatchBeer428(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	url := fmt.Sprintf("/beers/%s", beers[0].ID)
+
+	r := httptest.NewRequest("PATCH", url, strings.NewReader(`{"abv": 6.5}`))
+	authorize(t, r, brewerID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer can't be edited without its version.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusPreconditionRequired {
+				t.Fatalf("\t\t[ERROR] Should receive a 428 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 428 status code.")
+		}
+	}
+}
+
+func testDeleteBeer204(t *testing.T, h *server.Server) {
+	nb := adding.NewBeer{
+		Name:      "Deleted Beer",
+		BreweryID: breweryID,
+		ShortDesc: "Test Short Description",
+		Style:     "Pale Ale",
+		ABV:       4.5,
+	}
+
+	body, err := json.Marshal(nb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/beers", bytes.NewReader(body))
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var b beers.Beer
+	if err := json.NewDecoder(w.Body).Decode(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	r = httptest.NewRequest("DELETE", fmt.Sprintf("/beers/%s", b.ID), nil)
+	authorize(t, r, adminID)
+	r.Header.Set("If-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer can be deleted.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 204 status code.")
+		}
+	}
+}
+
+func testPostBeerReview201(t *testing.T, h *server.Server) {
+	score := float32(5)
+	nr := reviewing.NewReview{
+		Score:   &score,
+		Comment: "Test Comment",
+	}
+
+	body, err := json.Marshal(nr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	beerID := beers[0].ID
+	u := registerUser(t, h)
+
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beerID), bytes.NewReader(body))
+	authorize(t, r, u.ID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can be added.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 201 status code.")
+		}
+	}
+}
+
+func testPostBeerReview400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("POST", "/beers/123/reviews", strings.NewReader("{}"))
+	authorize(t, r, uuid.NewString())
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can't be added with invalid payload.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testPostBeerReviewScore400(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beers[0].ID), strings.NewReader(`{"score":9000,"comment":"Test Comment"}`))
+	authorize(t, r, registerUser(t, h).ID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can't be added with a score out of the scale.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testPostBeerReviewSubScores201(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	body := `{"sub_scores":{"aroma":4,"appearance":4,"taste":4,"mouthfeel":4,"overall":4},"comment":"Test Comment"}`
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beers[0].ID), strings.NewReader(body))
+	authorize(t, r, registerUser(t, h).ID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can be added with sub-scores.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 201 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got reviews.Review
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Score != 4 || got.SubScores == nil || got.SubScores.Taste == nil || *got.SubScores.Taste != 4 {
+				t.Fatalf("\t\t[ERROR] Should weight the sub-scores into the score. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should weight the sub-scores into the score.")
+		}
+	}
+}
+
+func testPostBeerReview409(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	userID := registerUser(t, h).ID
+	post := func() *httptest.ResponseRecorder {
+		body := `{"score":3,"comment":"Test Comment"}`
+		r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beers[0].ID), strings.NewReader(body))
+		authorize(t, r, userID)
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post(); w.Code != http.StatusCreated {
+		t.Fatalf("Should be able to create the review. Got %d", w.Code)
+	}
+	w := post()
+
+	t.Log("Given the neeed to validate a user can't review a beer twice.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusConflict {
+				t.Fatalf("\t\t[ERROR] Should receive a 409 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 409 status code.")
+		}
+	}
+}
+
+func testPostBeerReviewUpsert200(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	userID := registerUser(t, h).ID
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews?upsert=true", beers[0].ID), strings.NewReader(body))
+		authorize(t, r, userID)
+		w := httptest.NewRecorder()
+		h.Router().ServeHTTP(w, r)
+		return w
+	}
+
+	first := post(`{"score":2,"comment":"Test Comment"}`)
+	second := post(`{"score":4.5,"comment":"Changed my mind"}`)
+
+	t.Log("Given the neeed to validate a user can replace the review of a beer.")
+	{
+		t.Log("\tWhen checking the response codes.")
+		{
+			if first.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code for the first review. Got %d", first.Code)
+			}
+			if second.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code for the replaced review. Got %d", second.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 201 and then a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var created, replaced reviews.Review
+			if err := json.Unmarshal(first.Body.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(second.Body.Bytes(), &replaced); err != nil {
+				t.Fatal(err)
+			}
+			if replaced.ID != created.ID || replaced.Revision != 2 || replaced.Score != 4.5 || replaced.Comment != "Changed my mind" {
+				t.Fatalf("\t\t[ERROR] Should replace the review as a new revision. Got %+v", replaced)
+			}
+			t.Log("\t\t[OK] Should replace the review as a new revision.")
+		}
+	}
+}
+
+func testGetTopBeers200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers/top?min_reviews=1&style=pale%20ale", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the top beers can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the response body.")
+		{
+			var got []beers.Beer
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) == 0 || got[0].ReviewCount == 0 || got[0].WeightedScore == nil {
+				t.Fatalf("\t\t[ERROR] Should rank the reviewed beers by their weighted score. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should rank the reviewed beers by their weighted score.")
+		}
+	}
+}
+
+func testGetTopBeers400(t *testing.T, h *server.Server) {
+	t.Log("Given the neeed to validate the top beers can't be retrieved with invalid parameters.")
+	{
+		for _, query := range []string{"limit=1000", "min_reviews=abc", "style=pumpkin%20spice%20latte", "brewery_id=abc"} {
+			r := httptest.NewRequest("GET", "/beers/top?"+query, nil)
+			w := httptest.NewRecorder()
+
+			h.Router().ServeHTTP(w, r)
+
+			t.Logf("\tWhen checking the response code with %s.", query)
+			{
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+				}
+				t.Log("\t\t[OK] Should receive a 400 status code.")
+			}
+		}
+	}
+}
+
+func testPostBeerReview404(t *testing.T, h *server.Server) {
+	score := float32(3)
+	nr := reviewing.NewReview{
+		Score:   &score,
+		Comment: "Test Comment",
+	}
+
+	body, err := json.Marshal(nr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beerID := uuid.NewString()
+
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beerID), bytes.NewReader(body))
+	authorize(t, r, uuid.NewString())
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can't be added with a non existing beer.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testPostBeerReview401(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	body := `{"score": 5, "comment": "Test Comment"}`
+
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beers[0].ID), strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token(t, uuid.NewString(), time.Now().Add(-time.Minute)))
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a new beer review can't be added with an expired token.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("\t\t[ERROR] Should receive a 401 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 401 status code.")
+		}
+	}
+}
+
+func testGetBeerReviews200(t *testing.T, h *server.Server) {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	beerID := beers[0].ID
+
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews", beerID), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a list of beer reviews can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+	}
+}
+
+func testGetBeerReviews204(t *testing.T, h *server.Server) {
+	beerID := uuid.NewString()
+
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews", beerID), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a list of beer reviews can't be retrieved with a non existing beer.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 204 status code.")
+		}
+	}
+}
+
+func testGetBeerReviews400(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/beers/invalid/reviews", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a list of beer reviews can't be retrieved with an invalid beer ID.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testPatchBeerReview200(t *testing.T, h *server.Server) {
+	review := getFirstReview(t, h)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s", review.BeerID, review.ID)
+	r := httptest.NewRequest("PATCH", url, strings.NewReader(`{"score": 4}`))
+	authorize(t, r, review.UserID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer review can be edited by its author.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+	}
+}
+
+func testPatchBeerReview403(t *testing.T, h *server.Server) {
+	review := getFirstReview(t, h)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s", review.BeerID, review.ID)
+	r := httptest.NewRequest("PATCH", url, strings.NewReader(`{"score": 1}`))
+	authorize(t, r, uuid.NewString())
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer review can't be edited by another user.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 403 status code.")
+		}
+	}
+}
+
+func testGetBeerReviewHistory200(t *testing.T, h *server.Server) {
+	review := getFirstReview(t, h)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s/history", review.BeerID, review.ID)
+	r := httptest.NewRequest("GET", url, nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var history []reviews.Revision
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log("Given the neeed to validate the history of a beer review can be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the revisions.")
+		{
+			if len(history) != review.Revision {
+				t.Fatalf("\t\t[ERROR] Should receive %d revisions. Got %d", review.Revision, len(history))
+			}
+			t.Log("\t\t[OK] Should receive every revision.")
+		}
+	}
+}
+
+func testDeleteBeerReview204(t *testing.T, h *server.Server) {
+	review := getFirstReview(t, h)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s", review.BeerID, review.ID)
+	r := httptest.NewRequest("DELETE", url, nil)
+	authorize(t, r, review.UserID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer review can be deleted by its author.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 204 status code.")
+		}
+	}
+}
+
+func testDeleteBeerReview403(t *testing.T, h *server.Server) {
+	review := getFirstReview(t, h)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s", review.BeerID, review.ID)
+	r := httptest.NewRequest("DELETE", url, nil)
+	authorize(t, r, brewerID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer review can't be deleted by another member.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 403 status code.")
+		}
+	}
+}
+
+func testModerateBeerReview204(t *testing.T, h *server.Server) {
+	beer := getBeers(t, h)[0]
+	review := postReview(t, h, beer.ID, registerUser(t, h).ID)
+
+	url := fmt.Sprintf("/beers/%s/reviews/%s", review.BeerID, review.ID)
+	r := httptest.NewRequest("DELETE", url, nil)
+	authorize(t, r, moderatorID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a beer review can be deleted by a moderator.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 204 status code.")
+		}
+	}
+}
+
+func testPostWebhook201(t *testing.T, h *server.Server) {
+	ns := subscribing.NewSubscription{
+		URL:    "https://example.com/hooks",
+		Events: []string{webhooks.EventBeerAdded, webhooks.EventReviewCreated},
+		Secret: "0123456789abcdef",
+	}
+
+	body, err := json.Marshal(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/webhooks", bytes.NewReader(body))
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var sub webhooks.Subscription
+	if err := json.NewDecoder(w.Body).Decode(&sub); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Log("Given the neeed to validate a webhook can be subscribed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusCreated {
+				t.Fatalf("\t\t[ERROR] Should receive a 201 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 201 status code.")
+		}
+
+		t.Log("\tWhen checking the subscription.")
+		{
+			if sub.ID == "" || sub.Secret != "" {
+				t.Fatalf("\t\t[ERROR] Should receive the subscription without its secret. Got %+v", sub)
+			}
+			t.Log("\t\t[OK] Should receive the subscription without its secret.")
+		}
+	}
+}
+
+func testPostWebhook400(t *testing.T, h *server.Server) {
+	body := `{"url":"ftp://example.com/hooks","events":["beer_added"],"secret":"0123456789abcdef"}`
+
+	r := httptest.NewRequest("POST", "/webhooks", strings.NewReader(body))
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a webhook can't be subscribed to an invalid URL.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("\t\t[ERROR] Should receive a 400 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 400 status code.")
+		}
+	}
+}
+
+func testGetWebhooks200(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/webhooks", nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the webhooks can be listed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusOK {
+				t.Fatalf("\t\t[ERROR] Should receive a 200 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 200 status code.")
+		}
+
+		t.Log("\tWhen checking the secrets.")
+		{
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Fatalf("\t\t[ERROR] Should not receive the secrets. Got %s", w.Body)
+			}
+			t.Log("\t\t[OK] Should not receive the secrets.")
+		}
+	}
+}
+
+func testGetWebhooks401(t *testing.T, h *server.Server) {
+	id := getFirstWebhook(t, h).ID
+
+	t.Log("Given the neeed to validate the webhooks can't be read without a token.")
+	{
+		for _, path := range []string{"/webhooks", "/webhooks/" + id, "/webhooks/" + id + "/deliveries"} {
+			t.Logf("\tWhen requesting %s.", path)
+			{
+				r := httptest.NewRequest("GET", path, nil)
+				w := httptest.NewRecorder()
+
+				h.Router().ServeHTTP(w, r)
+
+				if w.Code != http.StatusUnauthorized {
+					t.Fatalf("\t\t[ERROR] Should receive a 401 status code. Got %d", w.Code)
+				}
+				t.Log("\t\t[OK] Should receive a 401 status code.")
+			}
+		}
+	}
+}
+
+func testGetWebhooks403(t *testing.T, h *server.Server) {
+	id := getFirstWebhook(t, h).ID
+
+	t.Log("Given the neeed to validate the webhooks can't be read without the permission.")
+	{
+		for _, path := range []string{"/webhooks", "/webhooks/" + id, "/webhooks/" + id + "/deliveries"} {
+			t.Logf("\tWhen requesting %s.", path)
+			{
+				r := httptest.NewRequest("GET", path, nil)
+				authorize(t, r, brewerID)
+				w := httptest.NewRecorder()
+
+				h.Router().ServeHTTP(w, r)
+
+				if w.Code != http.StatusForbidden {
+					t.Fatalf("\t\t[ERROR] Should receive a 403 status code. Got %d", w.Code)
+				}
+				t.Log("\t\t[OK] Should receive a 403 status code.")
+			}
+		}
+	}
+}
+
+func testGetWebhookDeliveries200(t *testing.T, h *server.Server) {
+	deliveries := getDeliveries(t, h, getFirstWebhook(t, h).ID)
+
+	t.Log("Given the neeed to validate the deliveries of a webhook can be listed.")
+	{
+		t.Log("\tWhen checking the deliveries.")
+		{
+			var beerAdded, reviewCreated bool
+			for _, d := range deliveries {
+				beerAdded = beerAdded || d.Event.Type == webhooks.EventBeerAdded
+				reviewCreated = reviewCreated || d.Event.Type == webhooks.EventReviewCreated
+			}
+			if !beerAdded || !reviewCreated {
+				t.Fatalf("\t\t[ERROR] Should queue the deliveries of the events. Got %+v", deliveries)
+			}
+			t.Log("\t\t[OK] Should queue the deliveries of the events.")
+		}
+	}
+}
+
+func testPostWebhookRedeliver202(t *testing.T, h *server.Server) {
+	sub := getFirstWebhook(t, h)
+	deliveries := getDeliveries(t, h, sub.ID)
+
+	url := fmt.Sprintf("/webhooks/%s/deliveries/%s/redeliver", sub.ID, deliveries[0].ID)
+	r := httptest.NewRequest("POST", url, nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a delivery can be redelivered.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("\t\t[ERROR] Should receive a 202 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 202 status code.")
+		}
+
+		t.Log("\tWhen checking the delivery log.")
+		{
+			if got := getDeliveries(t, h, sub.ID); len(got) != len(deliveries)+1 {
+				t.Fatalf("\t\t[ERROR] Should queue a new delivery. Got %d deliveries", len(got))
+			}
+			t.Log("\t\t[OK] Should queue a new delivery.")
+		}
+	}
+}
+
+func testPostWebhookRedeliver404(t *testing.T, h *server.Server) {
+	sub := getFirstWebhook(t, h)
+
+	url := fmt.Sprintf("/webhooks/%s/deliveries/%s/redeliver", sub.ID, uuid.NewString())
+	r := httptest.NewRequest("POST", url, nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate an unknown delivery can't be redelivered.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testDeleteWebhook204(t *testing.T, h *server.Server) {
+	sub := getFirstWebhook(t, h)
+
+	r := httptest.NewRequest("DELETE", "/webhooks/"+sub.ID, nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate a webhook can be unsubscribed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t\t[ERROR] Should receive a 204 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 204 status code.")
+		}
+	}
+}
+
+func testGetWebhook404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", "/webhooks/"+uuid.NewString(), nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate an unknown webhook can't be retrieved.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func testStreamBeerReviews200(t *testing.T, h *server.Server) {
+	// The server is closed once the streams are.
+	srv := httptest.NewServer(h.Router())
+	t.Cleanup(srv.Close)
+
+	beer := getBeers(t, h)[0]
+	u := registerUser(t, h)
+
+	events := openStream(t, srv.URL+fmt.Sprintf("/beers/%s/reviews/stream", beer.ID), "")
+	review := postReview(t, h, beer.ID, u.ID)
+
+	t.Log("Given the neeed to validate the new reviews of a beer are streamed.")
+	{
+		t.Log("\tWhen checking the events.")
+		{
+			e := nextEvent(t, events)
+			if e.id != review.ID || e.event != "review" || !strings.Contains(e.data, review.Comment) {
+				t.Fatalf("\t\t[ERROR] Should receive the new review. Got %+v", e)
+			}
+			t.Log("\t\t[OK] Should receive the new review.")
+		}
+
+		t.Log("\tWhen no review is created.")
+		{
+			if e := nextEvent(t, events); e.comment != "heartbeat" {
+				t.Fatalf("\t\t[ERROR] Should receive a heartbeat. Got %+v", e)
+			}
+			t.Log("\t\t[OK] Should receive a heartbeat.")
+		}
+	}
+}
+
+func testStreamReviews200(t *testing.T, h *server.Server) {
+	// The server is closed once the streams are.
+	srv := httptest.NewServer(h.Router())
+	t.Cleanup(srv.Close)
+
+	beer := getBeers(t, h)[0]
+	u := registerUser(t, h)
+	first := postReview(t, h, beer.ID, u.ID)
+	second := postReview(t, h, beer.ID, u.ID)
+
+	events := openStream(t, srv.URL+"/reviews/stream", first.ID)
+	third := postReview(t, h, beer.ID, u.ID)
+
+	t.Log("Given the neeed to validate a review stream can be resumed.")
+	{
+		t.Log("\tWhen checking the events.")
+		{
+			if e := nextEvent(t, events); e.id != second.ID {
+				t.Fatalf("\t\t[ERROR] Should replay the missed review. Got %+v", e)
+			}
+			if e := nextEvent(t, events); e.id != third.ID {
+				t.Fatalf("\t\t[ERROR] Should receive the new review. Got %+v", e)
+			}
+			t.Log("\t\t[OK] Should replay the missed reviews and keep streaming.")
+		}
+	}
+}
+
+func testStreamBeerReviews404(t *testing.T, h *server.Server) {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews/stream", uuid.NewString()), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	t.Log("Given the neeed to validate the reviews of an unknown beer can't be streamed.")
+	{
+		t.Log("\tWhen checking the response code.")
+		{
+			if w.Code != http.StatusNotFound {
+				t.Fatalf("\t\t[ERROR] Should receive a 404 status code. Got %d", w.Code)
+			}
+			t.Log("\t\t[OK] Should receive a 404 status code.")
+		}
+	}
+}
+
+func getBeers(t *testing.T, h *server.Server) []beers.Beer {
+	r := httptest.NewRequest("GET", "/beers", nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var page listing.BeerPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	return page.Beers
+}
+
+func getFirstReview(t *testing.T, h *server.Server) reviews.Review {
+	beers := getBeers(t, h)
+	if len(beers) == 0 {
+		t.Fatal("No beers found")
+	}
+
+	r := httptest.NewRequest("GET", fmt.Sprintf("/beers/%s/reviews", beers[0].ID), nil)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var rs []reviews.Review
+	if err := json.NewDecoder(w.Body).Decode(&rs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rs) == 0 {
+		t.Fatal("No reviews found")
+	}
+
+	return rs[0]
+}
+
+func getFirstWebhook(t *testing.T, h *server.Server) webhooks.Subscription {
+	r := httptest.NewRequest("GET", "/webhooks", nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var list []webhooks.Subscription
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) == 0 {
+		t.Fatal("No webhooks found")
+	}
+
+	return list[0]
+}
+
+func getDeliveries(t *testing.T, h *server.Server, id string) []webhooks.Delivery {
+	r := httptest.NewRequest("GET", fmt.Sprintf("/webhooks/%s/deliveries", id), nil)
+	authorize(t, r, adminID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var list []webhooks.Delivery
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) == 0 {
+		t.Fatal("No deliveries found")
+	}
+
+	return list
+}
+
+func registerUser(t *testing.T, h *server.Server) users.User {
+	handle := "user_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	body := fmt.Sprintf(`{"handle":%q,"email":"%s@example.com","password":"correct horse battery"}`, handle, handle)
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Should be able to register the user. Got %d: %s", w.Code, w.Body)
+	}
+
+	var u users.User
+	if err := json.NewDecoder(w.Body).Decode(&u); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+// seedBrewery stores the brewery of the beers.
+func seedBrewery(t *testing.T, s *memory.Store) {
+	b := breweries.Brewery{
+		ID:        breweryID,
+		Name:      "Test Brewery",
+		CreatedAt: time.Now(),
+	}
+	if err := s.CreateBrewery(context.Background(), b); err != nil {
+		t.Fatalf("Should be able to create the brewery: %v", err)
+	}
+}
+
+// seedUsers stores the users granted a role.
+func seedUsers(t *testing.T, s *memory.Store) {
+	seed := map[string]users.Role{
+		adminID:     users.RoleAdmin,
+		brewerID:    users.RoleBrewer,
+		moderatorID: users.RoleModerator,
+	}
+
+	for id, role := range seed {
+		u := users.User{
+			ID:        id,
+			Handle:    string(role),
+			Email:     string(role) + "@example.com",
+			Role:      role,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := s.CreateUser(context.Background(), u); err != nil {
+			t.Fatalf("Should be able to create the %s: %v", role, err)
+		}
+	}
+}
+
+func postReview(t *testing.T, h *server.Server, beerID, userID string) reviews.Review {
+	body := fmt.Sprintf(`{"score":4,"comment":"Review %s"}`, uuid.NewString())
+
+	r := httptest.NewRequest("POST", fmt.Sprintf("/beers/%s/reviews", beerID), strings.NewReader(body))
+	authorize(t, r, userID)
+	w := httptest.NewRecorder()
+
+	h.Router().ServeHTTP(w, r)
+
+	var review reviews.Review
+	if err := json.NewDecoder(w.Body).Decode(&review); err != nil {
+		t.Fatal(err)
+	}
+
+	return review
+}
+
+// sseEvent is an event, or a comment, read from a review stream.
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+// openStream opens a review stream, returning its events. The stream is
+// closed when the test ends.
+func openStream(t *testing.T, url, lastEventID string) <-chan sseEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Should open the stream. Got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 10)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var e sseEvent
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if e != (sseEvent{}) {
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+				e = sseEvent{}
+			case strings.HasPrefix(line, ": "):
+				e.comment = strings.TrimPrefix(line, ": ")
+			case strings.HasPrefix(line, "id: "):
+				e.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				e.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+
+	return events
+}
+
+// nextEvent returns the next event of the stream, skipping the retry field.
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("Stream closed")
+			}
+			if e.id == "" && e.event == "" && e.data == "" && e.comment == "" {
+				continue
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("No event received")
+		}
+	}
+}
+
+// authorize authenticates the request as the given user.
+func authorize(t *testing.T, r *http.Request, userID string) {
+	r.Header.Set("Authorization", "Bearer "+token(t, userID, time.Now().Add(time.Hour)))
+}
+
+// token returns an HS256 token for the given user.
+func token(t *testing.T, userID string, expiresAt time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+	claims, err := json.Marshal(auth.Claims{Subject: userID, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
 
-	return beers
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
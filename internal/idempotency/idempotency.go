@@ -0,0 +1,72 @@
+// Package idempotency provides the records of the requests made with an
+// idempotency key, so their retries get the response of the first request
+// instead of repeating it.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrMismatch is used when a key is reused for another request.
+	ErrMismatch = errors.New("idempotency key reused for another request")
+
+	// ErrInProgress is used when the request of a key is still in progress.
+	ErrInProgress = errors.New("request with the same idempotency key in progress")
+
+	// ErrInvalidKey is used when a key can't be used.
+	ErrInvalidKey = errors.New("invalid idempotency key")
+)
+
+// LockTimeout is how long a key stays locked by a request that neither saved
+// its response nor unlocked the key, as when the process dies.
+const LockTimeout = time.Minute
+
+// Response defines the response saved for a key.
+type Response struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header"`
+	Body   []byte            `json:"body"`
+}
+
+// Record defines what is known about a key: the fingerprint of its request
+// and, once done, the response. The record is forgotten once expired.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	ExpiresAt   time.Time
+}
+
+// Store defines the storage of the records.
+type Store interface {
+	// LockKey locks the key for the request with the given fingerprint, until
+	// its response is saved or the key is unlocked. When the key already has
+	// a response, it's returned instead.
+	LockKey(ctx context.Context, key, fingerprint string, now time.Time) (*Response, error)
+	// SaveResponse saves the response of the locked key, kept until it expires.
+	SaveResponse(ctx context.Context, key string, resp Response, expiresAt time.Time) error
+	// UnlockKey forgets the locked key, so the request can be retried.
+	UnlockKey(ctx context.Context, key string) error
+}
+
+// Check checks the record of a key for a request with the given fingerprint.
+// It returns the response to replay, ErrMismatch or ErrInProgress. It returns
+// neither when the key is free, or expired, and can be locked.
+func Check(rec *Record, fingerprint string, now time.Time) (*Response, error) {
+	switch {
+	case rec == nil || !now.Before(rec.ExpiresAt):
+		return nil, nil
+	case rec.Fingerprint != fingerprint:
+		return nil, ErrMismatch
+	case rec.Response == nil:
+		return nil, ErrInProgress
+	}
+	return rec.Response, nil
+}
+
+// NewLock returns the record of a key locked by a request.
+func NewLock(fingerprint string, now time.Time) Record {
+	return Record{Fingerprint: fingerprint, ExpiresAt: now.Add(LockTimeout)}
+}
@@ -0,0 +1,14 @@
+package idempotency_test
+
+import (
+	"testing"
+
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/internal/idempotency/idempotencytest"
+)
+
+func TestMemory(t *testing.T) {
+	idempotencytest.Run(t, func(t *testing.T) idempotency.Store {
+		return idempotency.NewMemory()
+	})
+}
@@ -0,0 +1,144 @@
+// Package idempotencytest provides a conformance test suite for the
+// idempotency stores.
+package idempotencytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/idempotency"
+)
+
+// Run runs the conformance suite against the store returned by newStore,
+// which is called once per test.
+func Run(t *testing.T, newStore func(t *testing.T) idempotency.Store) {
+	t.Run("Lock", func(t *testing.T) { testLock(t, newStore(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newStore(t)) })
+}
+
+func testLock(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+
+	// The database keeps the times to the microsecond.
+	start := time.Now().UTC().Truncate(time.Second)
+	key := uuid.NewString()
+
+	resp := idempotency.Response{
+		Status: http.StatusCreated,
+		Header: map[string]string{"Content-Type": "application/json"},
+		Body:   []byte(`{"id":"1"}`),
+	}
+
+	t.Log("Given the need to keep the responses of the idempotency keys.")
+	{
+		t.Log("\tWhen a request locks a new key.")
+		{
+			got, err := s.LockKey(ctx, key, "first", start)
+			if err != nil || got != nil {
+				t.Fatalf("\t\t[ERROR] Should lock the key. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should lock the key.")
+		}
+
+		t.Log("\tWhen a request uses the key while it's locked.")
+		{
+			if _, err := s.LockKey(ctx, key, "first", start); !errors.Is(err, idempotency.ErrInProgress) {
+				t.Fatalf("\t\t[ERROR] Should return ErrInProgress. Got %v", err)
+			}
+			if _, err := s.LockKey(ctx, key, "second", start); !errors.Is(err, idempotency.ErrMismatch) {
+				t.Fatalf("\t\t[ERROR] Should return ErrMismatch. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not lock the key.")
+		}
+
+		t.Log("\tWhen the response of the key is saved.")
+		{
+			if err := s.SaveResponse(ctx, key, resp, start.Add(time.Hour)); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to save the response: %v", err)
+			}
+
+			got, err := s.LockKey(ctx, key, "first", start.Add(time.Minute))
+			if err != nil || got == nil || !reflect.DeepEqual(*got, resp) {
+				t.Fatalf("\t\t[ERROR] Should return the saved response. Got %+v: %v", got, err)
+			}
+
+			if _, err := s.LockKey(ctx, key, "second", start.Add(time.Minute)); !errors.Is(err, idempotency.ErrMismatch) {
+				t.Fatalf("\t\t[ERROR] Should return ErrMismatch. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should return the saved response.")
+		}
+
+		t.Log("\tWhen the key expires.")
+		{
+			got, err := s.LockKey(ctx, key, "second", start.Add(time.Hour))
+			if err != nil || got != nil {
+				t.Fatalf("\t\t[ERROR] Should lock the key again. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should lock the key again.")
+		}
+
+		t.Log("\tWhen the key is unlocked.")
+		{
+			if err := s.UnlockKey(ctx, key); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to unlock the key: %v", err)
+			}
+
+			got, err := s.LockKey(ctx, key, "third", start.Add(time.Hour))
+			if err != nil || got != nil {
+				t.Fatalf("\t\t[ERROR] Should lock the key again. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should lock the key again.")
+		}
+
+		t.Log("\tWhen the lock is left behind.")
+		{
+			got, err := s.LockKey(ctx, key, "fourth", start.Add(time.Hour+idempotency.LockTimeout))
+			if err != nil || got != nil {
+				t.Fatalf("\t\t[ERROR] Should take over the lock. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should take over the lock.")
+		}
+	}
+}
+
+func testConcurrency(t *testing.T, s idempotency.Store) {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	key := uuid.NewString()
+
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			_, err := s.LockKey(ctx, key, "request", now)
+			results <- err
+		}()
+	}
+
+	var locked int
+	for i := 0; i < 10; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			locked++
+		case !errors.Is(err, idempotency.ErrInProgress):
+			t.Fatalf("Should be able to lock the key: %v", err)
+		}
+	}
+
+	t.Log("Given the need to handle concurrent retries.")
+	{
+		t.Log("\tWhen many requests use the same key at once.")
+		{
+			if locked != 1 {
+				t.Fatalf("\t\t[ERROR] Should lock the key once. Got %d", locked)
+			}
+			t.Log("\t\t[OK] Should lock the key once.")
+		}
+	}
+}
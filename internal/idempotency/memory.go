@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store forgets the expired records.
+const sweepInterval = time.Minute
+
+// Memory stores the records in memory, for a single instance.
+type Memory struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+}
+
+// NewMemory creates an empty memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		records: make(map[string]Record),
+	}
+}
+
+// LockKey locks the key for the request with the given fingerprint, until its
+// response is saved or the key is unlocked. When the key already has a
+// response, it's returned instead.
+func (m *Memory) LockKey(ctx context.Context, key, fingerprint string, now time.Time) (*Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	var rec *Record
+	if r, ok := m.records[key]; ok {
+		rec = &r
+	}
+
+	resp, err := Check(rec, fingerprint, now)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	m.records[key] = NewLock(fingerprint, now)
+
+	return nil, nil
+}
+
+// SaveResponse saves the response of the locked key, kept until it expires.
+func (m *Memory) SaveResponse(ctx context.Context, key string, resp Response, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec := m.records[key]
+	rec.Response = &resp
+	rec.ExpiresAt = expiresAt
+	m.records[key] = rec
+
+	return nil
+}
+
+// UnlockKey forgets the locked key, so the request can be retried.
+func (m *Memory) UnlockKey(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rec, ok := m.records[key]; ok && rec.Response == nil {
+		delete(m.records, key)
+	}
+
+	return nil
+}
+
+// sweep forgets the expired records.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, rec := range m.records {
+		if !now.Before(rec.ExpiresAt) {
+			delete(m.records, key)
+		}
+	}
+}
@@ -1,36 +1,397 @@
-// Package listing provides a use case for listing beers and reviews.
+// Package listing provides a use case for listing beers, breweries, styles
+// and reviews.
 package listing
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
 	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/styles"
 )
 
+var (
+	// ErrInvalidQuery is returned when the listing parameters are not valid.
+	ErrInvalidQuery = errors.New("invalid query")
+
+	// ErrInvalidCursor is returned when the pagination cursor can't be decoded.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Set of keys the beers can be sorted by.
+const (
+	SortCreatedAt = "created_at"
+	SortScore     = "score"
+	SortABV       = "abv"
+	SortName      = "name"
+)
+
+// Page size limits applied when listing beers.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// maxSearchLen is the maximum length of the search terms.
+const maxSearchLen = 100
+
+// Limits of the latest reviews returned in the beer detail.
+const (
+	DefaultLatestReviews = 5
+	MaxLatestReviews     = 50
+)
+
+// Defaults applied when ranking beers.
+const (
+	DefaultTopLimit   = 10
+	DefaultMinReviews = 5
+)
+
+// Filter defines the criteria a beer must match to be listed.
+type Filter struct {
+	Style        string     `form:"style"`
+	Brewery      string     `form:"brewery"`
+	BreweryID    string     `form:"brewery_id"`
+	MinABV       *float32   `form:"min_abv" binding:"omitempty,min=0"`
+	MaxABV       *float32   `form:"max_abv" binding:"omitempty,min=0"`
+	MinScore     *float32   `form:"min_score" binding:"omitempty,min=0"`
+	CreatedAfter *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// BeerQuery defines the input parameters for listing beers.
+type BeerQuery struct {
+	Filter
+	Sort   string `form:"sort" binding:"omitempty,oneof=created_at score abv name"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+// BeerPage is a page of the beer listing.
+type BeerPage struct {
+	Beers      []beers.Beer `json:"beers"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// DetailQuery defines the input parameters for getting the detail of a beer.
+type DetailQuery struct {
+	LatestReviews int `form:"latest_reviews" binding:"omitempty,min=1,max=50"`
+}
+
+// BeerDetail defines a beer along with the summary of its reviews.
+type BeerDetail struct {
+	beers.Beer
+	Reviews       reviews.Stats    `json:"reviews"`
+	LatestReviews []reviews.Review `json:"latest_reviews"`
+}
+
+// SearchQuery defines the input parameters for searching beers.
+type SearchQuery struct {
+	Q     string `form:"q" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// SearchHit is a beer matching a search along with its relevance.
+type SearchHit struct {
+	beers.Beer
+	Rank float32 `json:"rank"`
+}
+
+// Facet is the number of beers matching a search that share a value.
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets groups the facet counts of a search.
+type Facets struct {
+	Styles    []Facet `json:"styles"`
+	Breweries []Facet `json:"breweries"`
+}
+
+// SearchResult holds the beers matching a search, ordered by relevance, and
+// the facet counts over every match.
+type SearchResult struct {
+	Beers  []SearchHit `json:"beers"`
+	Facets Facets      `json:"facets"`
+}
+
+// TopQuery defines the input parameters for ranking beers. The beers are
+// ranked globally, or among the beers of a style, of a brewery or both, and
+// only the beers with at least MinReviews reviews are ranked.
+type TopQuery struct {
+	Style      string `form:"style"`
+	BreweryID  string `form:"brewery_id"`
+	MinReviews int    `form:"min_reviews" binding:"omitempty,min=1"`
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// Ranking defines the beers the repository must rank by the Bayesian average
+// of their reviews with the prior, from the highest. The ties are ranked by
+// the number of reviews, then by ID.
+type Ranking struct {
+	Style      string
+	BreweryID  string
+	MinReviews int
+	Limit      int
+	Prior      reviews.Prior
+}
+
+// Cursor holds the position of the last beer returned in a page. The
+// repository seeks past it to fetch the next page.
+type Cursor struct {
+	Sort      string    `json:"sort"`
+	Desc      bool      `json:"desc"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	ABV       float32   `json:"abv,omitempty"`
+	Score     float32   `json:"score,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Seek defines the page of beers the repository must fetch, using keyset
+// pagination on (Sort, ID).
+type Seek struct {
+	Filter
+	Sort  string
+	Desc  bool
+	Limit int
+	After *Cursor
+}
+
 // Repository defines the interface for the listing service to interact
 // with the storage.
 type Repository interface {
-	// ListBeers returns a list of beers.
-	ListBeers(ctx context.Context) ([]beers.Beer, error)
+	// ListBeers returns the page of beers described by the seek.
+	ListBeers(ctx context.Context, s Seek) ([]beers.Beer, error)
+	// SearchBeers returns the beers matching the search terms.
+	SearchBeers(ctx context.Context, q SearchQuery) (SearchResult, error)
+	// TopBeers returns the beers described by the ranking, from the highest
+	// ranked.
+	TopBeers(ctx context.Context, r Ranking) ([]beers.Beer, error)
+	// ListBreweries returns every brewery, ordered by name.
+	ListBreweries(ctx context.Context) ([]breweries.Brewery, error)
+	// GetBrewery returns the brewery with the given ID.
+	GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error)
 	// ListReviews returns a list of reviews.
 	ListReviews(ctx context.Context, id string) ([]reviews.Review, error)
+	// GetReview returns the review with the given ID.
+	GetReview(ctx context.Context, id string) (*reviews.Review, error)
+	// ListReviewRevisions returns the history of a review.
+	ListReviewRevisions(ctx context.Context, id string) ([]reviews.Revision, error)
+	// GetBeerDetail returns the beer with the given ID along with the
+	// aggregated data of its reviews and up to latest of its most recent
+	// reviews, all read from the same snapshot.
+	GetBeerDetail(ctx context.Context, id string, latest int) (*BeerDetail, error)
 }
 
-// Service provides beer listing operations.
+// Service provides beer listing operations. The beers are listed with their
+// weighted score, the Bayesian average of their reviews with the prior.
 type Service struct {
-	r Repository
+	r       Repository
+	catalog styles.Catalog
+	prior   reviews.Prior
 }
 
 // NewService creates a listing service with the necessary dependencies.
-func NewService(r Repository) *Service {
-	return &Service{r}
+func NewService(r Repository, catalog styles.Catalog, prior reviews.Prior) *Service {
+	return &Service{r, catalog, prior}
 }
 
-// ListBeers lists all the beers.
-func (s *Service) ListBeers(ctx context.Context) ([]beers.Beer, error) {
-	return s.r.ListBeers(ctx)
+// ListBeers lists a page of beers matching the query.
+func (s *Service) ListBeers(ctx context.Context, q BeerQuery) (BeerPage, error) {
+	seek, err := newSeek(q)
+	if err != nil {
+		return BeerPage{}, err
+	}
+
+	// Fetch one extra beer to know if there is a next page.
+	limit := seek.Limit
+	seek.Limit++
+
+	bs, err := s.r.ListBeers(ctx, seek)
+	if err != nil {
+		return BeerPage{}, err
+	}
+
+	for i := range bs {
+		s.weigh(&bs[i])
+	}
+
+	page := BeerPage{Beers: bs}
+	if len(bs) > limit {
+		page.Beers = bs[:limit]
+		page.NextCursor = encodeCursor(newCursor(seek, page.Beers[limit-1]))
+	}
+
+	return page, nil
+}
+
+// GetBeer returns the beer with the given ID, the summary of its reviews and
+// its latest reviews.
+func (s *Service) GetBeer(ctx context.Context, id string, q DetailQuery) (BeerDetail, error) {
+	// Validate the beer ID.
+	if _, err := uuid.Parse(id); err != nil {
+		return BeerDetail{}, beers.ErrInvalidID
+	}
+
+	switch {
+	case q.LatestReviews == 0:
+		q.LatestReviews = DefaultLatestReviews
+	case q.LatestReviews < 0 || q.LatestReviews > MaxLatestReviews:
+		return BeerDetail{}, fmt.Errorf("%w: latest_reviews must be between 1 and %d", ErrInvalidQuery, MaxLatestReviews)
+	}
+
+	d, err := s.r.GetBeerDetail(ctx, id, q.LatestReviews)
+	if err != nil {
+		return BeerDetail{}, fmt.Errorf("get beer[id=%s]: %w", id, err)
+	}
+
+	s.weigh(&d.Beer)
+
+	// Always render the lists, even when there are no reviews.
+	if d.Reviews.Distribution == nil {
+		d.Reviews.Distribution = []reviews.Bucket{}
+	}
+	if d.LatestReviews == nil {
+		d.LatestReviews = []reviews.Review{}
+	}
+
+	return *d, nil
+}
+
+// SearchBeers searches beers by name, brewery, style and description. The
+// last term is matched as a prefix to support autocomplete.
+func (s *Service) SearchBeers(ctx context.Context, q SearchQuery) (SearchResult, error) {
+	q.Q = strings.TrimSpace(q.Q)
+
+	switch {
+	case q.Q == "":
+		return SearchResult{}, fmt.Errorf("%w: empty search terms", ErrInvalidQuery)
+	case len(q.Q) > maxSearchLen:
+		return SearchResult{}, fmt.Errorf("%w: search terms longer than %d characters", ErrInvalidQuery, maxSearchLen)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultLimit
+	case q.Limit < 0 || q.Limit > MaxLimit:
+		return SearchResult{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+
+	res, err := s.r.SearchBeers(ctx, q)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	for i := range res.Beers {
+		s.weigh(&res.Beers[i].Beer)
+	}
+
+	return res, nil
+}
+
+// TopBeers ranks the beers by their weighted score, so a beer with a few great
+// reviews doesn't outrank a beer with many good ones.
+func (s *Service) TopBeers(ctx context.Context, q TopQuery) ([]beers.Beer, error) {
+	r := Ranking{
+		MinReviews: q.MinReviews,
+		Limit:      q.Limit,
+		Prior:      s.prior,
+	}
+
+	if q.Style != "" {
+		style, err := s.catalog.Lookup(q.Style)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, q.Style)
+		}
+		r.Style = style.Name
+	}
+
+	if q.BreweryID != "" {
+		if _, err := s.GetBrewery(ctx, q.BreweryID); err != nil {
+			return nil, err
+		}
+		r.BreweryID = q.BreweryID
+	}
+
+	switch {
+	case r.MinReviews == 0:
+		r.MinReviews = DefaultMinReviews
+	case r.MinReviews < 0:
+		return nil, fmt.Errorf("%w: min_reviews must be at least 1", ErrInvalidQuery)
+	}
+
+	switch {
+	case r.Limit == 0:
+		r.Limit = DefaultTopLimit
+	case r.Limit < 0 || r.Limit > MaxLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+
+	bs, err := s.r.TopBeers(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bs {
+		s.weigh(&bs[i])
+	}
+
+	return bs, nil
+}
+
+// ListBreweryBeers lists a page of the beers of a brewery matching the query.
+func (s *Service) ListBreweryBeers(ctx context.Context, id string, q BeerQuery) (BeerPage, error) {
+	if _, err := s.GetBrewery(ctx, id); err != nil {
+		return BeerPage{}, err
+	}
+
+	q.BreweryID = id
+	return s.ListBeers(ctx, q)
+}
+
+// ListBreweries lists every brewery, ordered by name.
+func (s *Service) ListBreweries(ctx context.Context) ([]breweries.Brewery, error) {
+	bs, err := s.r.ListBreweries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Always render the list, even when there are no breweries.
+	if bs == nil {
+		bs = []breweries.Brewery{}
+	}
+
+	return bs, nil
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (s *Service) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	// Validate the brewery ID.
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, breweries.ErrInvalidID
+	}
+
+	b, err := s.r.GetBrewery(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get brewery[id=%s]: %w", id, err)
+	}
+
+	return b, nil
+}
+
+// ListStyles returns the style catalog, grouped by family.
+func (s *Service) ListStyles(ctx context.Context) styles.Catalog {
+	return s.catalog
 }
 
 // ListReviews lists all the reviews for a given beer.
@@ -42,3 +403,143 @@ func (s *Service) ListReviews(ctx context.Context, id string) ([]reviews.Review,
 
 	return s.r.ListReviews(ctx, id)
 }
+
+// ListReviewHistory lists the revisions of a beer review, from the oldest to
+// the current one.
+func (s *Service) ListReviewHistory(ctx context.Context, beerID, reviewID string) ([]reviews.Revision, error) {
+	if _, err := uuid.Parse(beerID); err != nil {
+		return nil, beers.ErrInvalidID
+	}
+
+	if _, err := uuid.Parse(reviewID); err != nil {
+		return nil, reviews.ErrInvalidID
+	}
+
+	r, err := s.r.GetReview(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("get review[id=%s]: %w", reviewID, err)
+	}
+
+	if r.BeerID != beerID {
+		return nil, fmt.Errorf("get review[id=%s]: %w", reviewID, reviews.ErrNotFound)
+	}
+
+	return s.r.ListReviewRevisions(ctx, reviewID)
+}
+
+// =============================================================================
+
+// weigh sets the weighted score of the beer from its score sum, as the
+// repository ranks the beers, unless the repository already set it.
+func (s *Service) weigh(b *beers.Beer) {
+	if b.WeightedScore != nil {
+		return
+	}
+
+	ws := s.prior.Average(b.ScoreSum, b.ReviewCount)
+	b.WeightedScore = &ws
+}
+
+// newSeek validates the query and converts it to a seek.
+func newSeek(q BeerQuery) (Seek, error) {
+	s := Seek{
+		Filter: q.Filter,
+		Sort:   q.Sort,
+		Limit:  q.Limit,
+	}
+
+	switch s.Sort {
+	case "":
+		s.Sort = SortCreatedAt
+	case SortCreatedAt, SortScore, SortABV, SortName:
+	default:
+		return Seek{}, fmt.Errorf("%w: unknown sort key %q", ErrInvalidQuery, q.Sort)
+	}
+
+	// Names are listed alphabetically by default, everything else from the
+	// highest to the lowest value.
+	switch q.Order {
+	case "":
+		s.Desc = s.Sort != SortName
+	case "asc":
+	case "desc":
+		s.Desc = true
+	default:
+		return Seek{}, fmt.Errorf("%w: unknown order %q", ErrInvalidQuery, q.Order)
+	}
+
+	switch {
+	case s.Limit == 0:
+		s.Limit = DefaultLimit
+	case s.Limit < 0 || s.Limit > MaxLimit:
+		return Seek{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxLimit)
+	}
+
+	if q.BreweryID != "" {
+		if _, err := uuid.Parse(q.BreweryID); err != nil {
+			return Seek{}, fmt.Errorf("%w: invalid brewery_id", ErrInvalidQuery)
+		}
+	}
+
+	if q.MinABV != nil && q.MaxABV != nil && *q.MinABV > *q.MaxABV {
+		return Seek{}, fmt.Errorf("%w: min_abv is greater than max_abv", ErrInvalidQuery)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return Seek{}, err
+		}
+
+		// A cursor is only meaningful for the ordering that produced it.
+		if c.Sort != s.Sort || c.Desc != s.Desc {
+			return Seek{}, fmt.Errorf("%w: cursor does not match the sort order", ErrInvalidCursor)
+		}
+		s.After = &c
+	}
+
+	return s, nil
+}
+
+// newCursor creates the cursor pointing to the given beer.
+func newCursor(s Seek, b beers.Beer) Cursor {
+	c := Cursor{Sort: s.Sort, Desc: s.Desc, ID: b.ID}
+
+	switch s.Sort {
+	case SortName:
+		c.Name = b.Name
+	case SortABV:
+		c.ABV = b.ABV
+	case SortScore:
+		c.Score = b.Score
+	case SortCreatedAt:
+		c.CreatedAt = b.CreatedAt
+	}
+
+	return c
+}
+
+// encodeCursor encodes the cursor into an opaque string.
+func encodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes a cursor created by encodeCursor.
+func decodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
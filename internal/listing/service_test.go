@@ -2,23 +2,117 @@ package listing_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
 	"github.com/phbpx/gobeer/internal/listing"
 	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/styles"
 )
 
 // mockRepository is a mock implementation of the Repository interface.
 type mockRepository struct {
-	beers   []beers.Beer
-	reviews []reviews.Review
+	beers     []beers.Beer
+	breweries []breweries.Brewery
+	reviews   []reviews.Review
+	ranking   listing.Ranking
+	latest    int
 }
 
-// ListBeers returns a list of beers.
-func (r *mockRepository) ListBeers(ctx context.Context) ([]beers.Beer, error) {
-	return r.beers, nil
+// ListBeers returns a page of beers, ordered by ID.
+func (r *mockRepository) ListBeers(ctx context.Context, s listing.Seek) ([]beers.Beer, error) {
+	var list []beers.Beer
+	for _, b := range r.beers {
+		if s.After != nil && b.ID <= s.After.ID {
+			continue
+		}
+		if s.BreweryID != "" && b.BreweryID != s.BreweryID {
+			continue
+		}
+		if len(list) == s.Limit {
+			break
+		}
+		list = append(list, b)
+	}
+	return list, nil
+}
+
+// SearchBeers returns the beers whose name contains the search terms.
+func (r *mockRepository) SearchBeers(ctx context.Context, q listing.SearchQuery) (listing.SearchResult, error) {
+	var res listing.SearchResult
+	for _, b := range r.beers {
+		if strings.Contains(b.Name, q.Q) {
+			res.Beers = append(res.Beers, listing.SearchHit{Beer: b, Rank: 1})
+		}
+	}
+	return res, nil
+}
+
+// TopBeers records the ranking and returns the beers with enough reviews, in
+// their order.
+func (r *mockRepository) TopBeers(ctx context.Context, rk listing.Ranking) ([]beers.Beer, error) {
+	r.ranking = rk
+
+	var list []beers.Beer
+	for _, b := range r.beers {
+		if b.ReviewCount >= rk.MinReviews {
+			list = append(list, b)
+		}
+	}
+	return list, nil
+}
+
+// GetBeerDetail records the number of latest reviews and returns the beer with
+// the given ID along with its reviews.
+func (r *mockRepository) GetBeerDetail(ctx context.Context, id string, latest int) (*listing.BeerDetail, error) {
+	r.latest = latest
+
+	for _, b := range r.beers {
+		if b.ID == id {
+			rs, _ := r.ListReviews(ctx, id)
+			return &listing.BeerDetail{Beer: b, Reviews: reviews.Stats{Count: len(rs)}, LatestReviews: rs}, nil
+		}
+	}
+	return nil, beers.ErrNotFound
+}
+
+// ListBreweries returns every brewery.
+func (r *mockRepository) ListBreweries(ctx context.Context) ([]breweries.Brewery, error) {
+	return r.breweries, nil
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (r *mockRepository) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	for _, b := range r.breweries {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, breweries.ErrNotFound
+}
+
+// GetReview returns the review with the given ID.
+func (r *mockRepository) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	for _, review := range r.reviews {
+		if review.ID == id {
+			return &review, nil
+		}
+	}
+	return nil, reviews.ErrNotFound
+}
+
+// ListReviewRevisions returns the history of a review.
+func (r *mockRepository) ListReviewRevisions(ctx context.Context, id string) ([]reviews.Revision, error) {
+	for _, review := range r.reviews {
+		if review.ID == id {
+			return []reviews.Revision{{ReviewID: id, Revision: 1, Score: review.Score}}, nil
+		}
+	}
+	return nil, nil
 }
 
 // ListReviews returns a list of reviews.
@@ -35,29 +129,196 @@ func TestListing(t *testing.T) {
 	// Create a mock repository.
 	r := &mockRepository{
 		beers: []beers.Beer{
-			{ID: "1", Name: "Beer 1", Brewery: "Brewery 1"},
-			{ID: "2", Name: "Beer 2", Brewery: "Brewery 2"},
+			{ID: "00000000-0000-0000-0000-000000000001", Name: "Beer 1", BreweryID: "20000000-0000-0000-0000-000000000001", Brewery: "Brewery 1"},
+			{ID: "00000000-0000-0000-0000-000000000002", Name: "Beer 2", BreweryID: "20000000-0000-0000-0000-000000000002", Brewery: "Brewery 2"},
+			{ID: "00000000-0000-0000-0000-000000000003", Name: "Beer 3", BreweryID: "20000000-0000-0000-0000-000000000002", Brewery: "Brewery 2"},
+		},
+		breweries: []breweries.Brewery{
+			{ID: "20000000-0000-0000-0000-000000000001", Name: "Brewery 1"},
+			{ID: "20000000-0000-0000-0000-000000000002", Name: "Brewery 2"},
 		},
 		reviews: []reviews.Review{
-			{ID: "1", BeerID: "1", UserID: "1", Score: 5, Comment: "Comment 1"},
-			{ID: "2", BeerID: "2", UserID: "2", Score: 4, Comment: "Comment 2"},
+			{ID: "10000000-0000-0000-0000-000000000001", BeerID: "00000000-0000-0000-0000-000000000001", UserID: "1", Score: 5, Comment: "Comment 1"},
+			{ID: "10000000-0000-0000-0000-000000000002", BeerID: "00000000-0000-0000-0000-000000000002", UserID: "2", Score: 4, Comment: "Comment 2"},
 		},
 	}
 
 	// Create a listing service with the mock repository.
-	service := listing.NewService(r)
+	service := listing.NewService(r, styles.DefaultCatalog, reviews.DefaultPrior)
 
 	t.Log("Given the need to list beers.")
 	{
 		t.Log("\tWhen handling the list beers request.")
 		{
 			// List the beers.
-			_, err := service.ListBeers(context.Background())
+			_, err := service.ListBeers(context.Background(), listing.BeerQuery{})
 			if err != nil {
 				t.Fatalf("\t\t[ERROR] Should be able to list the beers. Error: %s", err)
 			}
 			t.Log("\t\t[OK] Should be able to list the beers.")
 		}
+
+		t.Log("\tWhen paginating through the beers.")
+		{
+			var ids []string
+			q := listing.BeerQuery{Limit: 2}
+			for {
+				page, err := service.ListBeers(context.Background(), q)
+				if err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to list the page. Error: %s", err)
+				}
+				for _, b := range page.Beers {
+					ids = append(ids, b.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+			if len(ids) != len(r.beers) {
+				t.Fatalf("\t\t[ERROR] Should list every beer once. Got %v", ids)
+			}
+			t.Log("\t\t[OK] Should list every beer once.")
+		}
+
+		t.Log("\tWhen handling the list beers request with an invalid cursor.")
+		{
+			_, err := service.ListBeers(context.Background(), listing.BeerQuery{Cursor: "invalid"})
+			if !errors.Is(err, listing.ErrInvalidCursor) {
+				t.Fatalf("\t\t[ERROR] Should not be able to list the beers. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to list the beers.")
+		}
+
+		t.Log("\tWhen handling the list beers request with an unknown sort key.")
+		{
+			_, err := service.ListBeers(context.Background(), listing.BeerQuery{Sort: "color"})
+			if !errors.Is(err, listing.ErrInvalidQuery) {
+				t.Fatalf("\t\t[ERROR] Should not be able to list the beers. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to list the beers.")
+		}
+	}
+
+	t.Log("Given the need to get a beer detail.")
+	{
+		t.Log("\tWhen handling the get beer request.")
+		{
+			d, err := service.GetBeer(context.Background(), "00000000-0000-0000-0000-000000000001", listing.DetailQuery{})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to get the beer. Error: %s", err)
+			}
+			if d.Reviews.Count != 1 || len(d.LatestReviews) != 1 {
+				t.Fatalf("\t\t[ERROR] Should summarize the beer reviews. Got %+v", d)
+			}
+			if r.latest != listing.DefaultLatestReviews {
+				t.Fatalf("\t\t[ERROR] Should get the default number of latest reviews. Got %d", r.latest)
+			}
+			t.Log("\t\t[OK] Should be able to get the beer.")
+		}
+
+		t.Log("\tWhen handling the get beer request with a number of latest reviews.")
+		{
+			_, err := service.GetBeer(context.Background(), "00000000-0000-0000-0000-000000000001", listing.DetailQuery{LatestReviews: 20})
+			if err != nil || r.latest != 20 {
+				t.Fatalf("\t\t[ERROR] Should get the requested number of latest reviews. Got %d: %v", r.latest, err)
+			}
+
+			for _, n := range []int{-1, listing.MaxLatestReviews + 1} {
+				_, err := service.GetBeer(context.Background(), "00000000-0000-0000-0000-000000000001", listing.DetailQuery{LatestReviews: n})
+				if !errors.Is(err, listing.ErrInvalidQuery) {
+					t.Fatalf("\t\t[ERROR] Should not get %d latest reviews. Error: %v", n, err)
+				}
+			}
+			t.Log("\t\t[OK] Should bound the number of latest reviews.")
+		}
+
+		t.Log("\tWhen handling the get beer request for a beer that does not exist.")
+		{
+			_, err := service.GetBeer(context.Background(), uuid.NewString(), listing.DetailQuery{})
+			if !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to get the beer. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to get the beer.")
+		}
+
+		t.Log("\tWhen handling the get beer request for a invalid id.")
+		{
+			_, err := service.GetBeer(context.Background(), "invalid", listing.DetailQuery{})
+			if err != beers.ErrInvalidID {
+				t.Fatalf("\t\t[ERROR] Should not be able to get the beer. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to get the beer.")
+		}
+	}
+
+	t.Log("Given the need to search beers.")
+	{
+		t.Log("\tWhen handling the search beers request.")
+		{
+			res, err := service.SearchBeers(context.Background(), listing.SearchQuery{Q: " Beer 2 "})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to search the beers. Error: %s", err)
+			}
+			if len(res.Beers) != 1 {
+				t.Fatalf("\t\t[ERROR] Should find a single beer. Got %d", len(res.Beers))
+			}
+			t.Log("\t\t[OK] Should be able to search the beers.")
+		}
+
+		t.Log("\tWhen handling the search beers request without terms.")
+		{
+			_, err := service.SearchBeers(context.Background(), listing.SearchQuery{Q: "  "})
+			if !errors.Is(err, listing.ErrInvalidQuery) {
+				t.Fatalf("\t\t[ERROR] Should not be able to search the beers. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to search the beers.")
+		}
+	}
+
+	t.Log("Given the need to list breweries.")
+	{
+		t.Log("\tWhen handling the list brewery beers request.")
+		{
+			page, err := service.ListBreweryBeers(context.Background(), "20000000-0000-0000-0000-000000000002", listing.BeerQuery{})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to list the beers of the brewery. Error: %s", err)
+			}
+			if len(page.Beers) != 2 {
+				t.Fatalf("\t\t[ERROR] Should list only the beers of the brewery. Got %d", len(page.Beers))
+			}
+			t.Log("\t\t[OK] Should be able to list the beers of the brewery.")
+		}
+
+		t.Log("\tWhen handling the list brewery beers request for a brewery that does not exist.")
+		{
+			_, err := service.ListBreweryBeers(context.Background(), uuid.NewString(), listing.BeerQuery{})
+			if !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to list the beers. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to list the beers.")
+		}
+
+		t.Log("\tWhen handling the get brewery request for a invalid id.")
+		{
+			_, err := service.GetBrewery(context.Background(), "invalid")
+			if err != breweries.ErrInvalidID {
+				t.Fatalf("\t\t[ERROR] Should not be able to get the brewery. Error: %s", err)
+			}
+			t.Log("\t\t[OK] Should not be able to get the brewery.")
+		}
+	}
+
+	t.Log("Given the need to list styles.")
+	{
+		t.Log("\tWhen handling the list styles request.")
+		{
+			c := service.ListStyles(context.Background())
+			if len(c) != len(styles.DefaultCatalog) {
+				t.Fatalf("\t\t[ERROR] Should list the style families. Got %d", len(c))
+			}
+			t.Log("\t\t[OK] Should list the style families.")
+		}
 	}
 
 	t.Log("Given the need to list reviews.")
@@ -82,4 +343,108 @@ func TestListing(t *testing.T) {
 			t.Log("\t\t[OK] Should not be able to list the reviews.")
 		}
 	}
+
+	t.Log("Given the need to list the history of a review.")
+	{
+		t.Log("\tWhen handling the review history request.")
+		{
+			rs, err := service.ListReviewHistory(context.Background(), "00000000-0000-0000-0000-000000000001", "10000000-0000-0000-0000-000000000001")
+			if err != nil || len(rs) != 1 {
+				t.Fatalf("\t\t[ERROR] Should be able to list the review history. Error: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to list the review history.")
+		}
+
+		t.Log("\tWhen handling the review history request through another beer.")
+		{
+			_, err := service.ListReviewHistory(context.Background(), "00000000-0000-0000-0000-000000000002", "10000000-0000-0000-0000-000000000001")
+			if !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to list the review history. Error: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to list the review history.")
+		}
+	}
+}
+
+func TestTopBeers(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a mock repository.
+	r := &mockRepository{
+		beers: []beers.Beer{
+			{ID: "00000000-0000-0000-0000-000000000001", Name: "One Hit", Score: 5, ScoreSum: 5, ReviewCount: 1},
+			{ID: "00000000-0000-0000-0000-000000000002", Name: "Crowd Pleaser", Score: 4.8, ScoreSum: 1440, ReviewCount: 300},
+		},
+		breweries: []breweries.Brewery{
+			{ID: "20000000-0000-0000-0000-000000000001", Name: "Brewery 1"},
+		},
+	}
+
+	// Create a listing service with the mock repository.
+	service := listing.NewService(r, styles.DefaultCatalog, reviews.Prior{Mean: 3.5, Weight: 10})
+
+	t.Log("Given the need to rank beers.")
+	{
+		t.Log("\tWhen ranking every beer with at least one review.")
+		{
+			bs, err := service.TopBeers(ctx, listing.TopQuery{MinReviews: 1})
+			if err != nil || len(bs) != 2 {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers. Got %+v: %v", bs, err)
+			}
+
+			oneHit, crowdPleaser := bs[0].WeightedScore, bs[1].WeightedScore
+			if oneHit == nil || crowdPleaser == nil || *crowdPleaser <= *oneHit {
+				t.Fatalf("\t\t[ERROR] Should weight the score by the number of reviews. Got %v and %v", oneHit, crowdPleaser)
+			}
+
+			// (10*3.5 + 1*5) / (10 + 1)
+			if want := float32(40) / 11; *oneHit != want {
+				t.Fatalf("\t\t[ERROR] Should pull the score towards the prior. Got %v, want %v", *oneHit, want)
+			}
+			t.Log("\t\t[OK] Should weight the score by the number of reviews.")
+		}
+
+		t.Log("\tWhen ranking the beers with the defaults.")
+		{
+			if _, err := service.TopBeers(ctx, listing.TopQuery{}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers. Error: %v", err)
+			}
+			want := listing.Ranking{MinReviews: listing.DefaultMinReviews, Limit: listing.DefaultTopLimit, Prior: reviews.Prior{Mean: 3.5, Weight: 10}}
+			if r.ranking != want {
+				t.Fatalf("\t\t[ERROR] Should rank with the defaults. Got %+v", r.ranking)
+			}
+			t.Log("\t\t[OK] Should rank with the defaults.")
+		}
+
+		t.Log("\tWhen ranking the beers of a style and a brewery.")
+		{
+			q := listing.TopQuery{Style: "neipa", BreweryID: "20000000-0000-0000-0000-000000000001"}
+			if _, err := service.TopBeers(ctx, q); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers. Error: %v", err)
+			}
+			if r.ranking.Style != "Hazy IPA" || r.ranking.BreweryID != q.BreweryID {
+				t.Fatalf("\t\t[ERROR] Should rank the beers of the canonical style and the brewery. Got %+v", r.ranking)
+			}
+			t.Log("\t\t[OK] Should rank the beers of the canonical style and the brewery.")
+		}
+
+		t.Log("\tWhen ranking the beers of an unknown style or brewery.")
+		{
+			if _, err := service.TopBeers(ctx, listing.TopQuery{Style: "Pumpkin Spice Latte"}); !errors.Is(err, styles.ErrUnknown) {
+				t.Fatalf("\t\t[ERROR] Should not rank the beers of an unknown style. Error: %v", err)
+			}
+			if _, err := service.TopBeers(ctx, listing.TopQuery{BreweryID: uuid.NewString()}); !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not rank the beers of an unknown brewery. Error: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to rank the beers.")
+		}
+
+		t.Log("\tWhen ranking the beers with an invalid limit.")
+		{
+			if _, err := service.TopBeers(ctx, listing.TopQuery{Limit: listing.MaxLimit + 1}); !errors.Is(err, listing.ErrInvalidQuery) {
+				t.Fatalf("\t\t[ERROR] Should not be able to rank the beers. Error: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to rank the beers.")
+		}
+	}
 }
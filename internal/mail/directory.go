@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+)
+
+// ErrUnknownUser is used when the directory has no address for the user.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Address defines where the emails of a user are sent.
+type Address struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// String returns the address formatted for an email header.
+func (a Address) String() string {
+	return (&mail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// Directory defines the interface to resolve the address of a user.
+type Directory interface {
+	// Lookup returns the address of the user, or ErrUnknownUser.
+	Lookup(ctx context.Context, userID string) (Address, error)
+}
+
+// StaticDirectory is a directory with a fixed set of addresses, indexed by
+// user ID.
+type StaticDirectory map[string]Address
+
+// LoadDirectory loads a static directory from a JSON file mapping the user
+// IDs to their addresses.
+func LoadDirectory(path string) (StaticDirectory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var d StaticDirectory
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("decoding directory: %w", err)
+	}
+
+	for id, a := range d {
+		if _, err := mail.ParseAddress(a.Email); err != nil {
+			return nil, fmt.Errorf("user[id=%s] address: %w", id, err)
+		}
+	}
+
+	return d, nil
+}
+
+// Lookup returns the address of the user.
+func (d StaticDirectory) Lookup(ctx context.Context, userID string) (Address, error) {
+	a, ok := d[userID]
+	if !ok {
+		return Address{}, ErrUnknownUser
+	}
+	return a, nil
+}
@@ -0,0 +1,69 @@
+// Package mail provides the delivery of the notification emails: resolving
+// the address of the user, rendering the templates of the event and sending
+// the message.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/notifications"
+)
+
+// Sender defines the interface to send the messages.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Mailer sends the emails of the notification events.
+type Mailer struct {
+	from      Address
+	directory Directory
+	templates *Templates
+	sender    Sender
+}
+
+// NewMailer creates a mailer with the necessary dependencies.
+func NewMailer(from Address, directory Directory, templates *Templates, sender Sender) *Mailer {
+	return &Mailer{
+		from:      from,
+		directory: directory,
+		templates: templates,
+		sender:    sender,
+	}
+}
+
+// Deliver sends the email of the event to its user.
+func (m *Mailer) Deliver(ctx context.Context, e notifications.Event) error {
+	to, err := m.directory.Lookup(ctx, e.UserID)
+	if err != nil {
+		return fmt.Errorf("lookup user[id=%s]: %w", e.UserID, err)
+	}
+
+	content, err := m.templates.Render(to, e)
+	if err != nil {
+		return fmt.Errorf("render event[id=%s]: %w", e.ID, err)
+	}
+
+	// The legacy notifications have no event, so there's no ID to reuse.
+	id := e.ID
+	if id == "" {
+		id = uuid.NewString()
+	}
+
+	msg := Message{
+		ID:      id + "@gobeer",
+		From:    m.from,
+		To:      to,
+		Date:    time.Now(),
+		Content: content,
+	}
+
+	if err := m.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send email[id=%s]: %w", msg.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	gomail "github.com/phbpx/gobeer/internal/mail"
+	"github.com/phbpx/gobeer/internal/mail/smtptest"
+	"github.com/phbpx/gobeer/internal/notifications"
+)
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+
+	srv, err := smtptest.NewTLSServer()
+	if err != nil {
+		t.Fatalf("Should be able to start the smtp server: %v", err)
+	}
+	defer srv.Close()
+
+	templates, err := gomail.ParseTemplates()
+	if err != nil {
+		t.Fatalf("Should be able to parse the templates: %v", err)
+	}
+
+	sender, err := gomail.NewSMTPSender(gomail.SMTPConfig{
+		Addr:       srv.Addr,
+		Username:   "gobeer",
+		Password:   "secret",
+		RequireTLS: true,
+		TLSConfig:  srv.ClientTLSConfig(),
+		Timeout:    time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create the sender: %v", err)
+	}
+
+	userID := uuid.NewString()
+	directory := gomail.StaticDirectory{
+		userID: {Name: "Jane", Email: "jane@example.com"},
+	}
+	from := gomail.Address{Name: "gobeer", Email: "no-reply@gobeer.local"}
+	mailer := gomail.NewMailer(from, directory, templates, sender)
+
+	event := notifications.Event{
+		Version:    notifications.EventVersion,
+		ID:         uuid.NewString(),
+		Type:       notifications.KindReviewCreated,
+		OccurredAt: time.Now(),
+		UserID:     userID,
+		Review:     notifications.EventReview{ID: uuid.NewString(), Score: 4.5, Comment: "Hoppy & <bitter>"},
+		Beer:       notifications.EventBeer{ID: uuid.NewString(), Name: "Punk IPA", Brewery: "BrewDog"},
+	}
+
+	t.Log("Given the need to email the users about their events")
+	{
+		t.Log("\tWhen delivering an event to a known user")
+		{
+			if err := mailer.Deliver(ctx, event); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to deliver the event: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to deliver the event.")
+
+			msgs := srv.Messages()
+			if len(msgs) != 1 {
+				t.Fatalf("\t\t[ERROR] Should send one message. Sent %d", len(msgs))
+			}
+			got := msgs[0]
+			if !got.TLS || got.Username != "gobeer" || got.Password != "secret" {
+				t.Fatalf("\t\t[ERROR] Should authenticate over TLS. Got tls=%v user=%q", got.TLS, got.Username)
+			}
+			if got.From != "no-reply@gobeer.local" || len(got.To) != 1 || got.To[0] != "jane@example.com" {
+				t.Fatalf("\t\t[ERROR] Should send from the sender to the user. Got %s -> %v", got.From, got.To)
+			}
+			t.Log("\t\t[OK] Should send the message over an authenticated TLS session.")
+
+			subject, parts := parse(t, got.Data)
+			if subject != "Your review of Punk IPA was published" {
+				t.Fatalf("\t\t[ERROR] Should render the subject. Got %q", subject)
+			}
+			if !strings.Contains(parts["text/plain"], "Comment: Hoppy & <bitter>") {
+				t.Fatalf("\t\t[ERROR] Should render the plaintext part. Got %q", parts["text/plain"])
+			}
+			if !strings.Contains(parts["text/html"], "Hoppy &amp; &lt;bitter&gt;") {
+				t.Fatalf("\t\t[ERROR] Should render the escaped html part. Got %q", parts["text/html"])
+			}
+			t.Log("\t\t[OK] Should send the rendered plaintext and html parts.")
+		}
+
+		t.Log("\tWhen delivering a legacy notification")
+		{
+			if err := mailer.Deliver(ctx, notifications.Event{UserID: userID}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to deliver the notification: %v", err)
+			}
+
+			msgs := srv.Messages()
+			if subject, _ := parse(t, msgs[len(msgs)-1].Data); subject != "You have a new notification" {
+				t.Fatalf("\t\t[ERROR] Should render the generic template. Got %q", subject)
+			}
+			t.Log("\t\t[OK] Should render the generic template.")
+		}
+
+		t.Log("\tWhen delivering an event to an unknown user")
+		{
+			e := event
+			e.UserID = uuid.NewString()
+
+			if err := mailer.Deliver(ctx, e); !errors.Is(err, gomail.ErrUnknownUser) {
+				t.Fatalf("\t\t[ERROR] Should get ErrUnknownUser. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should get ErrUnknownUser.")
+		}
+	}
+}
+
+func TestRequireTLS(t *testing.T) {
+	srv, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("Should be able to start the smtp server: %v", err)
+	}
+	defer srv.Close()
+
+	sender, err := gomail.NewSMTPSender(gomail.SMTPConfig{Addr: srv.Addr, RequireTLS: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Should be able to create the sender: %v", err)
+	}
+
+	t.Log("Given the need to protect the messages in transit")
+	{
+		t.Log("\tWhen the server doesn't offer STARTTLS")
+		{
+			msg := gomail.Message{
+				ID:   uuid.NewString() + "@gobeer",
+				From: gomail.Address{Email: "no-reply@gobeer.local"},
+				To:   gomail.Address{Email: "jane@example.com"},
+				Date: time.Now(),
+			}
+			if err := sender.Send(context.Background(), msg); !errors.Is(err, gomail.ErrTLSRequired) {
+				t.Fatalf("\t\t[ERROR] Should get ErrTLSRequired. Got %v", err)
+			}
+			if n := len(srv.Messages()); n != 0 {
+				t.Fatalf("\t\t[ERROR] Should not send the message. Sent %d", n)
+			}
+			t.Log("\t\t[OK] Should refuse to send the message.")
+		}
+	}
+}
+
+// parse returns the decoded subject and the parts of the message, indexed by
+// their media type.
+func parse(t *testing.T, data []byte) (string, map[string]string) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Should be able to parse the message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Should be able to decode the subject: %v", err)
+	}
+
+	mt, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/alternative" {
+		t.Fatalf("Should be a multipart/alternative message. Got %q: %v", mt, err)
+	}
+
+	parts := make(map[string]string)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Should be able to read the parts: %v", err)
+		}
+
+		// The multipart reader decodes the quoted-printable parts.
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("Should be able to read the part: %v", err)
+		}
+		pt, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[pt] = string(body)
+	}
+
+	return subject, parts
+}
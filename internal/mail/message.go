@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"time"
+)
+
+// Message defines an email to send.
+type Message struct {
+	ID      string
+	From    Address
+	To      Address
+	Date    time.Time
+	Content Content
+}
+
+// Bytes encodes the message as a multipart/alternative MIME message, with the
+// plaintext and HTML versions of the content.
+func (m Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := []struct{ key, value string }{
+		{"From", m.From.String()},
+		{"To", m.To.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Content.Subject)},
+		{"Date", m.Date.Format(time.RFC1123Z)},
+		{"Message-ID", "<" + m.ID + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()})},
+	}
+
+	var out bytes.Buffer
+	for _, h := range header {
+		fmt.Fprintf(&out, "%s: %s\r\n", h.key, h.value)
+	}
+	out.WriteString("\r\n")
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Content.Text},
+		{"text/html; charset=utf-8", m.Content.HTML},
+	}
+	for _, p := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(w)
+		if _, err := qw.Write([]byte(p.body)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// ErrTLSRequired is used when the server doesn't offer STARTTLS and the
+// sender requires it.
+var ErrTLSRequired = errors.New("server doesn't support STARTTLS")
+
+// SMTPConfig defines how to connect to the SMTP server.
+type SMTPConfig struct {
+	// Addr is the host:port of the server.
+	Addr     string
+	Username string
+	Password string
+	// RequireTLS fails the delivery when the server doesn't offer STARTTLS,
+	// otherwise it's used only when offered.
+	RequireTLS bool
+	// TLSConfig is used for STARTTLS. When nil, the server is verified with
+	// the system roots.
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+}
+
+// DefaultSMTPConfig is the configuration used for the fields left empty.
+var DefaultSMTPConfig = SMTPConfig{
+	Addr:    "localhost:25",
+	Timeout: 10 * time.Second,
+}
+
+// SMTPSender sends the messages through an SMTP server, opening a connection
+// per message.
+type SMTPSender struct {
+	cfg  SMTPConfig
+	host string
+}
+
+// NewSMTPSender creates a sender for the SMTP server.
+func NewSMTPSender(cfg SMTPConfig) (*SMTPSender, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultSMTPConfig.Addr
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultSMTPConfig.Timeout
+	}
+
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing smtp address: %w", err)
+	}
+
+	return &SMTPSender{cfg: cfg, host: host}, nil
+}
+
+// Send delivers the message to the SMTP server.
+func (s *SMTPSender) Send(ctx context.Context, m Message) error {
+	data, err := m.Bytes()
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("dialing smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("smtp greeting: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		cfg := s.cfg.TLSConfig
+		if cfg == nil {
+			cfg = &tls.Config{ServerName: s.host}
+		}
+		if err := c.StartTLS(cfg); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	} else if s.cfg.RequireTLS {
+		return ErrTLSRequired
+	}
+
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(m.From.Email); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := c.Rcpt(m.To.Email); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,268 @@
+// Package smtptest provides a fake SMTP server for testing, capturing the
+// messages it receives.
+package smtptest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message defines a message received by the server.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+	// Username is the user authenticated with AUTH PLAIN, if any.
+	Username string
+	Password string
+	// TLS tells if the message was sent after STARTTLS.
+	TLS bool
+}
+
+// Server is a fake SMTP server listening on a local port.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	ln      net.Listener
+	tlsConf *tls.Config
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewServer starts a server without STARTTLS support.
+func NewServer() (*Server, error) {
+	return start(nil)
+}
+
+// NewTLSServer starts a server offering STARTTLS, with a self-signed
+// certificate for 127.0.0.1 and localhost. Use ClientTLSConfig to trust it.
+func NewTLSServer() (*Server, error) {
+	cert, err := selfSigned()
+	if err != nil {
+		return nil, err
+	}
+	return start(&tls.Config{Certificates: []tls.Certificate{cert}})
+}
+
+func start(tlsConf *tls.Config) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := Server{
+		Addr:    ln.Addr().String(),
+		ln:      ln,
+		tlsConf: tlsConf,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+
+	return &s, nil
+}
+
+// Close stops the server, waiting for the open sessions to finish.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+// ClientTLSConfig returns a TLS configuration trusting the server certificate.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	if s.tlsConf == nil {
+		return nil
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(s.tlsConf.Certificates[0].Leaf)
+	return &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// =============================================================================
+
+// serve runs an SMTP session, supporting just what a client needs to deliver
+// messages.
+func (s *Server) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 smtptest ready")
+
+	var msg Message
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			lines := []string{"smtptest", "8BITMIME", "AUTH PLAIN"}
+			if s.tlsConf != nil && !msg.TLS {
+				lines = append(lines, "STARTTLS")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, l)
+			}
+
+		case "HELO", "NOOP":
+			tp.PrintfLine("250 OK")
+
+		case "STARTTLS":
+			if s.tlsConf == nil || msg.TLS {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 go ahead")
+
+			tlsConn := tls.Server(conn, s.tlsConf)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg = Message{TLS: true}
+
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mech, "PLAIN") {
+				tp.PrintfLine("504 unsupported mechanism")
+				continue
+			}
+			creds, err := base64.StdEncoding.DecodeString(initial)
+			if err != nil {
+				tp.PrintfLine("501 invalid credentials")
+				continue
+			}
+			parts := strings.Split(string(creds), "\x00")
+			if len(parts) != 3 {
+				tp.PrintfLine("501 invalid credentials")
+				continue
+			}
+			msg.Username, msg.Password = parts[1], parts[2]
+			tp.PrintfLine("235 authenticated")
+
+		case "MAIL":
+			msg.From = address(arg)
+			msg.To = nil
+			tp.PrintfLine("250 OK")
+
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			tp.PrintfLine("250 OK")
+
+		case "DATA":
+			if msg.From == "" || len(msg.To) == 0 {
+				tp.PrintfLine("503 need MAIL and RCPT")
+				continue
+			}
+			tp.PrintfLine("354 go ahead")
+
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+
+			msg = Message{TLS: msg.TLS, Username: msg.Username, Password: msg.Password}
+			tp.PrintfLine("250 OK")
+
+		case "RSET":
+			msg = Message{TLS: msg.TLS, Username: msg.Username, Password: msg.Password}
+			tp.PrintfLine("250 OK")
+
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+
+		default:
+			tp.PrintfLine("500 unknown command")
+		}
+	}
+}
+
+// address extracts the address from the MAIL and RCPT arguments, such as
+// "FROM:<user@example.com> BODY=8BITMIME".
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// selfSigned creates a certificate for the local addresses.
+func selfSigned() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"smtptest"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
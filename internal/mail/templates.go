@@ -0,0 +1,112 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/phbpx/gobeer/internal/notifications"
+)
+
+// genericTemplate is the template used for the events without their own, as
+// the legacy notifications carrying nothing but the user.
+const genericTemplate = "notification"
+
+//go:embed templates
+var templateFS embed.FS
+
+// Content defines the rendered content of an email.
+type Content struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// templateData defines the data available to the templates.
+type templateData struct {
+	To    Address
+	Event notifications.Event
+}
+
+// Templates renders the emails of every event type. Each type has a plaintext
+// template, also defining the "subject" template, and an HTML template.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// ParseTemplates parses the embedded templates.
+func ParseTemplates() (*Templates, error) {
+	entries, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+
+	t := Templates{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	for _, entry := range entries {
+		file := "templates/" + entry.Name()
+
+		switch name := entry.Name(); {
+		case strings.HasSuffix(name, ".txt"):
+			tmpl, err := texttemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return nil, err
+			}
+			if tmpl.Lookup("subject") == nil {
+				return nil, fmt.Errorf("template %s: missing subject", name)
+			}
+			t.text[strings.TrimSuffix(name, ".txt")] = tmpl
+
+		case strings.HasSuffix(name, ".html"):
+			tmpl, err := htmltemplate.ParseFS(templateFS, file)
+			if err != nil {
+				return nil, err
+			}
+			t.html[strings.TrimSuffix(name, ".html")] = tmpl
+		}
+	}
+
+	for name := range t.text {
+		if _, ok := t.html[name]; !ok {
+			return nil, fmt.Errorf("template %s: missing html version", name)
+		}
+	}
+	if _, ok := t.text[genericTemplate]; !ok {
+		return nil, fmt.Errorf("template %s: missing", genericTemplate)
+	}
+
+	return &t, nil
+}
+
+// Render renders the email sent to the address for the event.
+func (t *Templates) Render(to Address, e notifications.Event) (Content, error) {
+	name := e.Type
+	if _, ok := t.text[name]; !ok {
+		name = genericTemplate
+	}
+	data := templateData{To: to, Event: e}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text[name].ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Content{}, fmt.Errorf("rendering %s subject: %w", name, err)
+	}
+	if err := t.text[name].Execute(&text, data); err != nil {
+		return Content{}, fmt.Errorf("rendering %s text: %w", name, err)
+	}
+	if err := t.html[name].Execute(&html, data); err != nil {
+		return Content{}, fmt.Errorf("rendering %s html: %w", name, err)
+	}
+
+	return Content{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,150 @@
+// Package notifications defines the notification domain model.
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/events"
+)
+
+var (
+	// ErrNotFound is used when a notification is not found.
+	ErrNotFound = errors.New("notification not found")
+
+	// ErrInvalidEvent is used when an event is missing required fields or
+	// has an unsupported version.
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+// Set of notification kinds, also used as the type of their events.
+const (
+	KindReviewCreated = "review_created"
+)
+
+// Set of notification statuses.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// EventVersion is the version of the event format. It changes when a change
+// to the format can break its consumers.
+const EventVersion = 1
+
+// Notification defines a notification to a user, stored in the outbox along
+// with the change that caused it and delivered later.
+type Notification struct {
+	ID            string     `json:"id"`
+	Kind          string     `json:"kind"`
+	UserID        string     `json:"user_id"`
+	ReviewID      string     `json:"review_id"`
+	Event         Event      `json:"event"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+// NewReviewCreated returns the pending notification of the author of the
+// review.
+func NewReviewCreated(e events.ReviewAdded) Notification {
+	return Notification{
+		ID:       e.ID,
+		Kind:     KindReviewCreated,
+		UserID:   e.Review.UserID,
+		ReviewID: e.Review.ID,
+		Event: Event{
+			Version:    EventVersion,
+			ID:         e.ID,
+			Type:       KindReviewCreated,
+			OccurredAt: e.OccurredAt,
+			UserID:     e.Review.UserID,
+			Review: EventReview{
+				ID:        e.Review.ID,
+				Score:     e.Review.Score,
+				Comment:   e.Review.Comment,
+				CreatedAt: e.Review.CreatedAt,
+				UpdatedAt: e.Review.UpdatedAt,
+			},
+			Beer: EventBeer{
+				ID:      e.Beer.ID,
+				Name:    e.Beer.Name,
+				Brewery: e.Beer.Brewery,
+			},
+		},
+		Status:        StatusPending,
+		NextAttemptAt: e.OccurredAt,
+		CreatedAt:     e.OccurredAt,
+	}
+}
+
+// Event defines what happened, as sent to the email api. It's built when the
+// notification is stored, so it describes the data at that moment.
+type Event struct {
+	Version    int         `json:"version"`
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	UserID     string      `json:"user_id"`
+	Review     EventReview `json:"review"`
+	Beer       EventBeer   `json:"beer"`
+}
+
+// EventReview defines the review an event is about.
+type EventReview struct {
+	ID        string    `json:"id"`
+	Score     float32   `json:"score"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EventBeer defines the beer an event is about.
+type EventBeer struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Brewery string `json:"brewery"`
+}
+
+// Validate checks if the event can be handled.
+func (e Event) Validate() error {
+	if e.Version != EventVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidEvent, e.Version)
+	}
+
+	switch {
+	case !isUUID(e.ID):
+		return fmt.Errorf("%w: id must be a uuid", ErrInvalidEvent)
+	case !isUUID(e.UserID):
+		return fmt.Errorf("%w: user_id must be a uuid", ErrInvalidEvent)
+	case e.OccurredAt.IsZero():
+		return fmt.Errorf("%w: occurred_at is required", ErrInvalidEvent)
+	}
+
+	switch e.Type {
+	case KindReviewCreated:
+		switch {
+		case !isUUID(e.Review.ID):
+			return fmt.Errorf("%w: review.id must be a uuid", ErrInvalidEvent)
+		case !isUUID(e.Beer.ID):
+			return fmt.Errorf("%w: beer.id must be a uuid", ErrInvalidEvent)
+		case e.Beer.Name == "" || e.Beer.Brewery == "":
+			return fmt.Errorf("%w: beer.name and beer.brewery are required", ErrInvalidEvent)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidEvent, e.Type)
+	}
+
+	return nil
+}
+
+func isUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil
+}
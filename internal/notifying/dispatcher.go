@@ -0,0 +1,118 @@
+// Package notifying provides a use case for delivering the notifications
+// stored in the outbox.
+package notifying
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// Repository defines the interface for the dispatcher to interact with the
+// outbox.
+type Repository interface {
+	// ClaimNotifications returns the pending notifications due at the given
+	// time, postponing them by the lease so other dispatchers don't take them
+	// while they're being delivered.
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notifications.Notification, error)
+	// UpdateNotification stores the result of a delivery attempt.
+	UpdateNotification(ctx context.Context, n notifications.Notification) error
+}
+
+// Notifier defines the interface for the dispatcher to notify users.
+type Notifier interface {
+	Notify(ctx context.Context, e notifications.Event) error
+}
+
+// DefaultConfig is the configuration used for the fields left empty. The
+// timeout limits every notification, retries of the notifier included, so a
+// batch takes at most BatchSize times the timeout and must fit in the lease.
+var DefaultConfig = outbox.Config{
+	Interval:    time.Second,
+	BatchSize:   10,
+	Lease:       5 * time.Minute,
+	Timeout:     20 * time.Second,
+	MaxAttempts: 10,
+	MinBackoff:  time.Second,
+	MaxBackoff:  10 * time.Minute,
+}
+
+// Dispatcher delivers the pending notifications, retrying the failed ones
+// with an exponential backoff until they run out of attempts.
+type Dispatcher struct {
+	log      *logger.Logger
+	repo     Repository
+	notifier Notifier
+	cfg      outbox.Config
+}
+
+// NewDispatcher creates a dispatcher with the necessary dependencies.
+func NewDispatcher(log *logger.Logger, repo Repository, notifier Notifier, cfg outbox.Config) *Dispatcher {
+	return &Dispatcher{
+		log:      log,
+		repo:     repo,
+		notifier: notifier,
+		cfg:      cfg.WithDefaults(DefaultConfig),
+	}
+}
+
+// Run dispatches the pending notifications every interval, until the context
+// is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	outbox.Run(ctx, d.log, "dispatcher", d.cfg, d.Dispatch)
+}
+
+// Dispatch delivers a batch of pending notifications, returning how many were
+// attempted.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	list, err := d.repo.ClaimNotifications(ctx, time.Now(), d.cfg.Lease, d.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim notifications: %w", err)
+	}
+
+	for _, n := range list {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		err := d.notify(ctx, n.Event)
+
+		now := time.Now()
+		n.Attempts++
+
+		switch {
+		case err == nil:
+			n.Status = notifications.StatusSent
+			n.LastError = ""
+			n.SentAt = &now
+
+		case n.Attempts >= d.cfg.MaxAttempts:
+			n.Status = notifications.StatusFailed
+			n.LastError = err.Error()
+			d.log.Error(ctx, "dispatcher", "status", "giving up notification", "id", n.ID, "attempts", n.Attempts, "ERROR", err)
+
+		default:
+			n.LastError = err.Error()
+			n.NextAttemptAt = now.Add(d.cfg.Backoff(n.Attempts))
+			d.log.Warn(ctx, "dispatcher", "status", "retrying notification", "id", n.ID, "attempts", n.Attempts, "next_attempt_at", n.NextAttemptAt, "ERROR", err)
+		}
+
+		if err := d.repo.UpdateNotification(ctx, n); err != nil {
+			return 0, fmt.Errorf("update notification[id=%s]: %w", n.ID, err)
+		}
+	}
+
+	return len(list), nil
+}
+
+// notify sends a notification, giving up once the timeout expires.
+func (d *Dispatcher) notify(ctx context.Context, e notifications.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	return d.notifier.Notify(ctx, e)
+}
@@ -0,0 +1,170 @@
+package notifying_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/notifying"
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	data []notifications.Notification
+}
+
+// ClaimNotifications returns the pending notifications due at the given time.
+func (m *mockRepository) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notifications.Notification, error) {
+	var list []notifications.Notification
+	for i := range m.data {
+		n := &m.data[i]
+		if n.Status != notifications.StatusPending || n.NextAttemptAt.After(now) || len(list) == limit {
+			continue
+		}
+		n.NextAttemptAt = now.Add(lease)
+		list = append(list, *n)
+	}
+	return list, nil
+}
+
+// UpdateNotification stores the notification.
+func (m *mockRepository) UpdateNotification(ctx context.Context, n notifications.Notification) error {
+	for i := range m.data {
+		if m.data[i].ID == n.ID {
+			m.data[i] = n
+			return nil
+		}
+	}
+	return notifications.ErrNotFound
+}
+
+// =============================================================================
+
+// mockNotifier is a mock implementation of the Notifier interface, failing
+// for the given users and waiting for the context of the hanging ones.
+type mockNotifier struct {
+	failing map[string]bool
+	hanging map[string]bool
+	sent    []string
+}
+
+// Notify records the notified user.
+func (m *mockNotifier) Notify(ctx context.Context, e notifications.Event) error {
+	if m.failing[e.UserID] {
+		return errors.New("unavailable")
+	}
+	if m.hanging[e.UserID] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	m.sent = append(m.sent, e.UserID)
+	return nil
+}
+
+// =============================================================================
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+
+	healthy := uuid.NewString()
+	failing := uuid.NewString()
+
+	repo := &mockRepository{
+		data: []notifications.Notification{
+			{ID: uuid.NewString(), UserID: healthy, Status: notifications.StatusPending, Event: notifications.Event{UserID: healthy}},
+			{ID: uuid.NewString(), UserID: failing, Status: notifications.StatusPending, Event: notifications.Event{UserID: failing}},
+		},
+	}
+	notifier := &mockNotifier{failing: map[string]bool{failing: true}}
+
+	d := notifying.NewDispatcher(logger.New(io.Discard, logger.LevelInfo, "TEST"), repo, notifier, outbox.Config{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	})
+
+	t.Log("Given the need to deliver the notifications in the outbox")
+	{
+		t.Log("\tWhen dispatching the pending notifications")
+		{
+			n, err := d.Dispatch(ctx)
+			if err != nil || n != 2 {
+				t.Fatalf("\t\t[ERROR] Should attempt every notification. Got %d: %v", n, err)
+			}
+
+			sent, retry := repo.data[0], repo.data[1]
+			if sent.Status != notifications.StatusSent || sent.SentAt == nil || sent.Attempts != 1 {
+				t.Fatalf("\t\t[ERROR] Should mark the delivered notification as sent. Got %+v", sent)
+			}
+			if retry.Status != notifications.StatusPending || retry.Attempts != 1 || retry.LastError == "" {
+				t.Fatalf("\t\t[ERROR] Should keep the failed notification pending. Got %+v", retry)
+			}
+			t.Log("\t\t[OK] Should deliver the pending notifications.")
+		}
+
+		t.Log("\tWhen the notification runs out of attempts")
+		{
+			time.Sleep(2 * time.Millisecond)
+
+			n, err := d.Dispatch(ctx)
+			if err != nil || n != 1 {
+				t.Fatalf("\t\t[ERROR] Should retry the failed notification. Got %d: %v", n, err)
+			}
+
+			if failed := repo.data[1]; failed.Status != notifications.StatusFailed || failed.Attempts != 2 {
+				t.Fatalf("\t\t[ERROR] Should give up the notification. Got %+v", failed)
+			}
+			if len(notifier.sent) != 1 {
+				t.Fatalf("\t\t[ERROR] Should notify the users once. Got %v", notifier.sent)
+			}
+			t.Log("\t\t[OK] Should give up the notification.")
+		}
+	}
+}
+
+func TestDispatchTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	const timeout = 50 * time.Millisecond
+
+	hanging := uuid.NewString()
+
+	repo := &mockRepository{}
+	for i := 0; i < 3; i++ {
+		repo.data = append(repo.data, notifications.Notification{ID: uuid.NewString(), UserID: hanging, Status: notifications.StatusPending, Event: notifications.Event{UserID: hanging}})
+	}
+	notifier := &mockNotifier{hanging: map[string]bool{hanging: true}}
+
+	d := notifying.NewDispatcher(logger.New(io.Discard, logger.LevelInfo, "TEST"), repo, notifier, outbox.Config{
+		Timeout: timeout,
+	})
+
+	t.Log("Given the need to dispatch a batch before its lease expires")
+	{
+		t.Log("\tWhen the notifier does not answer")
+		{
+			start := time.Now()
+
+			n, err := d.Dispatch(ctx)
+			if err != nil || n != 3 {
+				t.Fatalf("\t\t[ERROR] Should claim the batch. Got %d: %v", n, err)
+			}
+
+			if took := time.Since(start); took >= 3*timeout+time.Second {
+				t.Fatalf("\t\t[ERROR] Should give up every notification after the timeout. Took %v", took)
+			}
+			for _, n := range repo.data {
+				if n.Status != notifications.StatusPending || n.Attempts != 1 || n.LastError == "" {
+					t.Fatalf("\t\t[ERROR] Should retry the notifications later. Got %+v", n)
+				}
+			}
+			t.Log("\t\t[OK] Should give up every notification after the timeout.")
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package outbox provides the loop shared by the dispatchers of the work
+// stored to be done later, such as the notifications and the webhook
+// deliveries: the due work is claimed in batches under a lease, and the failed
+// attempts are retried with an exponential backoff.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// Config defines how the work is dispatched. The claimed work is postponed by
+// the lease, so other dispatchers don't take it while it's being done, and
+// every attempt is limited by the timeout.
+type Config struct {
+	Interval    time.Duration
+	BatchSize   int
+	Lease       time.Duration
+	Timeout     time.Duration
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// WithDefaults returns the config with the fields left empty taken from def.
+func (c Config) WithDefaults(def Config) Config {
+	if c.Interval <= 0 {
+		c.Interval = def.Interval
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = def.BatchSize
+	}
+	if c.Lease <= 0 {
+		c.Lease = def.Lease
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = def.Timeout
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = def.MaxAttempts
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = def.MinBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = def.MaxBackoff
+	}
+	return c
+}
+
+// Backoff returns how long to wait before the next attempt, doubling after
+// every failed attempt.
+func (c Config) Backoff(attempts int) time.Duration {
+	wait := c.MinBackoff
+	for i := 1; i < attempts && wait < c.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	if wait > c.MaxBackoff {
+		return c.MaxBackoff
+	}
+	return wait
+}
+
+// DispatchFunc claims a batch of due work and attempts it, returning how many
+// were attempted.
+type DispatchFunc func(ctx context.Context) (int, error)
+
+// Run calls dispatch every interval, until the context is canceled. It's
+// called again right away while there are full batches waiting. The failures
+// are logged under the given caller.
+func Run(ctx context.Context, log *logger.Logger, caller string, cfg Config, dispatch DispatchFunc) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := dispatch(ctx)
+			if err != nil {
+				log.Error(ctx, caller, "status", "dispatching", "ERROR", err)
+				break
+			}
+			if n < cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/outbox"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+func TestBackoff(t *testing.T) {
+	cfg := outbox.Config{MaxBackoff: time.Minute}.WithDefaults(outbox.Config{MinBackoff: time.Second, MaxBackoff: time.Hour})
+
+	t.Log("Given the need to wait longer after every failed attempt")
+	{
+		t.Log("\tWhen computing the wait of the attempts")
+		{
+			for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 7: time.Minute, 100: time.Minute} {
+				if got := cfg.Backoff(attempts); got != want {
+					t.Fatalf("\t\t[ERROR] Should wait %s after %d attempts. Got %s", want, attempts, got)
+				}
+			}
+			t.Log("\t\t[OK] Should double the wait up to the configured maximum.")
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg := outbox.Config{Interval: time.Hour, BatchSize: 10}
+
+	t.Log("Given the need to dispatch the work waiting in the outbox")
+	{
+		t.Log("\tWhen there are full batches waiting")
+		{
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Two full batches and a partial one, then the dispatcher waits
+			// for the next interval.
+			var calls int
+			batches := []int{10, 10, 3}
+			dispatch := func(ctx context.Context) (int, error) {
+				calls++
+				if calls == len(batches) {
+					cancel()
+				}
+				if calls > len(batches) {
+					return 0, errors.New("dispatched past the partial batch")
+				}
+				return batches[calls-1], nil
+			}
+
+			outbox.Run(ctx, logger.New(io.Discard, logger.LevelInfo, "TEST"), "test", cfg, dispatch)
+
+			if calls != len(batches) {
+				t.Fatalf("\t\t[ERROR] Should dispatch until a batch isn't full. Got %d calls", calls)
+			}
+			t.Log("\t\t[OK] Should dispatch until a batch isn't full.")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store forgets the full buckets.
+const sweepInterval = time.Minute
+
+// entry is a bucket kept by the memory store.
+type entry struct {
+	bucket Bucket
+	fullAt time.Time
+}
+
+// Memory stores the buckets in memory, limiting the requests made to a
+// single instance.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]entry
+	lastSweep time.Time
+}
+
+// NewMemory creates an empty memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]entry),
+	}
+}
+
+// TakeToken takes a token from the bucket of the key, as of the given time.
+func (m *Memory) TakeToken(ctx context.Context, key string, l Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	e, ok := m.buckets[key]
+	if !ok {
+		e.bucket = NewBucket(l, now)
+	}
+
+	b, res := Take(e.bucket, l, now)
+	m.buckets[key] = entry{bucket: b, fullAt: FullAt(b, l)}
+
+	return res, nil
+}
+
+// sweep forgets the buckets that are full again, so the clients that stopped
+// making requests are not kept forever.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, e := range m.buckets {
+		if !now.Before(e.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package ratelimit provides the token buckets limiting how many requests a
+// client can make.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrLimited is used when a client made too many requests.
+	ErrLimited = errors.New("rate limit exceeded")
+
+	// ErrInvalidLimit is used when a limit can't be parsed.
+	ErrInvalidLimit = errors.New("invalid rate limit")
+)
+
+// Limit defines how many requests can be made in a period. The requests can
+// be made in a burst, after which they're refilled evenly over the period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written as requests/period, such as 10/1m.
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q", ErrInvalidLimit, s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// String returns the limit as requests/period.
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// Bucket is the state of the limit of a client: the tokens left at the time
+// it was last updated. Every request takes a token.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+
+	// RetryAfter is how long until a token is available, when the request
+	// was not allowed.
+	RetryAfter time.Duration
+}
+
+// Store defines the storage of the buckets.
+type Store interface {
+	// TakeToken takes a token from the bucket of the key, as of the given
+	// time.
+	TakeToken(ctx context.Context, key string, l Limit, now time.Time) (Result, error)
+}
+
+// NewBucket returns a full bucket.
+func NewBucket(l Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(l.Requests), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time since it was last updated and takes a
+// token, if there is one. It returns the bucket to be stored.
+func Take(b Bucket, l Limit, now time.Time) (Bucket, Result) {
+	capacity := float64(l.Requests)
+	rate := capacity / l.Period.Seconds()
+
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*rate)
+	}
+	b.UpdatedAt = now
+
+	res := Result{Limit: l}
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+
+	res.Remaining = int(b.Tokens)
+	res.Reset = seconds((capacity - b.Tokens) / rate)
+
+	return b, res
+}
+
+// FullAt returns when the bucket is full again, after which it's the same as
+// a new bucket and doesn't need to be kept.
+func FullAt(b Bucket, l Limit) time.Time {
+	rate := float64(l.Requests) / l.Period.Seconds()
+	return b.UpdatedAt.Add(seconds((float64(l.Requests) - b.Tokens) / rate))
+}
+
+// seconds converts the seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Limits defines the limits of the routes, identified by their method and
+// path pattern, such as POST /beers/:id/reviews. The routes without a limit
+// of their own use the default one, if any.
+type Limits struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+// For returns the limit of the route. It reports false when the route is not
+// limited.
+func (ls Limits) For(route string) (Limit, bool) {
+	if l, ok := ls.Routes[route]; ok {
+		return l, true
+	}
+	return ls.Default, ls.Default.Requests > 0
+}
+
+// ParseRoutes parses the limits of the routes, each written as
+// route=requests/period, such as POST /beers/:id/reviews=10/1m.
+func ParseRoutes(specs []string) (map[string]Limit, error) {
+	routes := make(map[string]Limit, len(specs))
+
+	for _, spec := range specs {
+		route, limit, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLimit, spec)
+		}
+
+		l, err := ParseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+
+		routes[strings.Join(strings.Fields(route), " ")] = l
+	}
+
+	return routes, nil
+}
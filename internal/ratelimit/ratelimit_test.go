@@ -0,0 +1,40 @@
+package ratelimit_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/ratelimit/ratelimittest"
+)
+
+func TestMemory(t *testing.T) {
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		return ratelimit.NewMemory()
+	})
+}
+
+func TestParseLimit(t *testing.T) {
+	t.Log("Given the need to configure the limits.")
+	{
+		t.Log("\tWhen parsing a valid limit.")
+		{
+			l, err := ratelimit.ParseLimit("10/1m")
+			if err != nil || l != (ratelimit.Limit{Requests: 10, Period: time.Minute}) {
+				t.Fatalf("\t\t[ERROR] Should parse the limit. Got %+v: %v", l, err)
+			}
+			t.Log("\t\t[OK] Should parse the limit.")
+		}
+
+		t.Log("\tWhen parsing an invalid limit.")
+		{
+			for _, s := range []string{"", "10", "0/1m", "ten/1m", "10/-1m", "10/minute"} {
+				if _, err := ratelimit.ParseLimit(s); !errors.Is(err, ratelimit.ErrInvalidLimit) {
+					t.Fatalf("\t\t[ERROR] Should not parse %q. Got %v", s, err)
+				}
+			}
+			t.Log("\t\t[OK] Should not parse the limit.")
+		}
+	}
+}
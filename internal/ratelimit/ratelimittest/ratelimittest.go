@@ -0,0 +1,132 @@
+// Package ratelimittest provides a conformance test suite for the rate limit
+// stores.
+package ratelimittest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+)
+
+// Run runs the conformance suite against the store returned by newStore,
+// which is called once per test.
+func Run(t *testing.T, newStore func(t *testing.T) ratelimit.Store) {
+	t.Run("TakeToken", func(t *testing.T) { testTakeToken(t, newStore(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newStore(t)) })
+}
+
+func testTakeToken(t *testing.T, s ratelimit.Store) {
+	ctx := context.Background()
+
+	// The database keeps the times to the microsecond.
+	start := time.Now().UTC().Truncate(time.Second)
+	limit := ratelimit.Limit{Requests: 3, Period: 3 * time.Second}
+	key := uuid.NewString()
+
+	t.Log("Given the need to limit the requests of a client.")
+	{
+		t.Log("\tWhen the client makes a burst of requests.")
+		{
+			for i := 2; i >= 0; i-- {
+				res, err := s.TakeToken(ctx, key, limit, start)
+				if err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to take a token: %v", err)
+				}
+				if !res.Allowed || res.Remaining != i {
+					t.Fatalf("\t\t[ERROR] Should allow the request with %d remaining. Got %+v", i, res)
+				}
+			}
+			t.Log("\t\t[OK] Should allow the requests up to the limit.")
+
+			res, err := s.TakeToken(ctx, key, limit, start)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to take a token: %v", err)
+			}
+			if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+				t.Fatalf("\t\t[ERROR] Should not allow the request. Got %+v", res)
+			}
+			t.Log("\t\t[OK] Should not allow the request over the limit.")
+		}
+
+		t.Log("\tWhen the client waits for a token.")
+		{
+			res, err := s.TakeToken(ctx, key, limit, start.Add(time.Second))
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to take a token: %v", err)
+			}
+			if !res.Allowed || res.Remaining != 0 {
+				t.Fatalf("\t\t[ERROR] Should allow the request. Got %+v", res)
+			}
+			t.Log("\t\t[OK] Should refill the bucket over the period.")
+		}
+
+		t.Log("\tWhen another client makes a request.")
+		{
+			res, err := s.TakeToken(ctx, uuid.NewString(), limit, start)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to take a token: %v", err)
+			}
+			if !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("\t\t[ERROR] Should use another bucket. Got %+v", res)
+			}
+			t.Log("\t\t[OK] Should use another bucket.")
+		}
+
+		t.Log("\tWhen the client stops making requests.")
+		{
+			res, err := s.TakeToken(ctx, key, limit, start.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to take a token: %v", err)
+			}
+			if !res.Allowed || res.Remaining != 2 {
+				t.Fatalf("\t\t[ERROR] Should fill the bucket up to the limit. Got %+v", res)
+			}
+			t.Log("\t\t[OK] Should fill the bucket up to the limit.")
+		}
+	}
+}
+
+func testConcurrency(t *testing.T, s ratelimit.Store) {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	limit := ratelimit.Limit{Requests: 5, Period: time.Hour}
+	key := uuid.NewString()
+
+	results := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func() {
+			res, err := s.TakeToken(ctx, key, limit, now)
+			if err == nil && !res.Allowed {
+				err = ratelimit.ErrLimited
+			}
+			results <- err
+		}()
+	}
+
+	var allowed int
+	for i := 0; i < 20; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			allowed++
+		case !errors.Is(err, ratelimit.ErrLimited):
+			t.Fatalf("Should be able to take a token: %v", err)
+		}
+	}
+
+	t.Log("Given the need to limit concurrent requests.")
+	{
+		t.Log("\tWhen many requests are made at once.")
+		{
+			if allowed != limit.Requests {
+				t.Fatalf("\t\t[ERROR] Should allow %d requests. Got %d", limit.Requests, allowed)
+			}
+			t.Log("\t\t[OK] Should allow the requests up to the limit.")
+		}
+	}
+}
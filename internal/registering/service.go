@@ -0,0 +1,191 @@
+// Package registering provides the use cases for registering the users and
+// managing their profiles.
+package registering
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// NewUser defines the input parameters for registering a new user.
+type NewUser struct {
+	Handle      string `json:"handle" binding:"required,min=3,max=30"`
+	Email       string `json:"email" binding:"required,email,max=255"`
+	Password    string `json:"password" binding:"required,min=8,max=128"`
+	DisplayName string `json:"display_name" binding:"max=64"`
+	Bio         string `json:"bio" binding:"max=280"`
+}
+
+// UpdateProfile defines the input parameters for changing the profile of a
+// user. Nil fields are kept unchanged. The password is only changed along
+// with the current one.
+type UpdateProfile struct {
+	Handle          *string `json:"handle" binding:"omitempty,min=3,max=30"`
+	Email           *string `json:"email" binding:"omitempty,email,max=255"`
+	Password        *string `json:"password" binding:"omitempty,min=8,max=128"`
+	CurrentPassword *string `json:"current_password" binding:"omitempty,max=128"`
+	DisplayName     *string `json:"display_name" binding:"omitempty,max=64"`
+	Bio             *string `json:"bio" binding:"omitempty,max=280"`
+}
+
+// ChangeRole defines the input parameters for changing the role of a user.
+type ChangeRole struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// Repository defines the interface for the registering service to interact
+// with the storage.
+type Repository interface {
+	// CreateUser stores a new user. The handle and the email must not belong
+	// to another user.
+	CreateUser(ctx context.Context, u users.User) error
+	// GetUser returns the user with the given ID.
+	GetUser(ctx context.Context, id string) (*users.User, error)
+	// UpdateUser stores the changes of a user. The handle and the email must
+	// not belong to another user.
+	UpdateUser(ctx context.Context, u users.User) error
+}
+
+// Service provides user registration operations.
+type Service struct {
+	r Repository
+}
+
+// NewService creates a registering service with the necessary dependencies.
+func NewService(r Repository) *Service {
+	return &Service{r}
+}
+
+// Register registers a new user, returning its account.
+func (s *Service) Register(ctx context.Context, nu NewUser) (users.User, error) {
+	handle, err := users.NormalizeHandle(nu.Handle)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	hash, err := users.HashPassword(nu.Password)
+	if err != nil {
+		return users.User{}, fmt.Errorf("hash password: %w", err)
+	}
+
+	now := time.Now()
+	u := users.User{
+		ID:           uuid.NewString(),
+		Handle:       handle,
+		Email:        users.NormalizeEmail(nu.Email),
+		DisplayName:  nu.DisplayName,
+		Bio:          nu.Bio,
+		Role:         users.RoleMember,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.r.CreateUser(ctx, u); err != nil {
+		return users.User{}, fmt.Errorf("create user: %w", err)
+	}
+
+	return redact(u), nil
+}
+
+// GetProfile returns the public profile of a user, without its email.
+func (s *Service) GetProfile(ctx context.Context, id string) (users.User, error) {
+	u, err := s.user(ctx, id)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	u.Email = ""
+	return redact(*u), nil
+}
+
+// UpdateProfile changes the profile of a user, returning its account.
+func (s *Service) UpdateProfile(ctx context.Context, id string, up UpdateProfile) (users.User, error) {
+	u, err := s.user(ctx, id)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	if up.Handle != nil {
+		handle, err := users.NormalizeHandle(*up.Handle)
+		if err != nil {
+			return users.User{}, err
+		}
+		u.Handle = handle
+	}
+	if up.Email != nil {
+		u.Email = users.NormalizeEmail(*up.Email)
+	}
+	if up.Password != nil {
+		// The users without a password, such as the legacy users, set one
+		// without the current password.
+		if u.PasswordHash != "" && (up.CurrentPassword == nil || !users.CheckPassword(u.PasswordHash, *up.CurrentPassword)) {
+			return users.User{}, users.ErrWrongPassword
+		}
+
+		hash, err := users.HashPassword(*up.Password)
+		if err != nil {
+			return users.User{}, fmt.Errorf("hash password: %w", err)
+		}
+		u.PasswordHash = hash
+	}
+	if up.DisplayName != nil {
+		u.DisplayName = *up.DisplayName
+	}
+	if up.Bio != nil {
+		u.Bio = *up.Bio
+	}
+	u.UpdatedAt = time.Now()
+
+	if err := s.r.UpdateUser(ctx, *u); err != nil {
+		return users.User{}, fmt.Errorf("update user[id=%s]: %w", id, err)
+	}
+
+	return redact(*u), nil
+}
+
+// ChangeRole changes the role of a user, returning its account.
+func (s *Service) ChangeRole(ctx context.Context, id string, cr ChangeRole) (users.User, error) {
+	role, err := users.ParseRole(cr.Role)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	u, err := s.user(ctx, id)
+	if err != nil {
+		return users.User{}, err
+	}
+
+	u.Role = role
+	u.UpdatedAt = time.Now()
+
+	if err := s.r.UpdateUser(ctx, *u); err != nil {
+		return users.User{}, fmt.Errorf("update user[id=%s]: %w", id, err)
+	}
+
+	return redact(*u), nil
+}
+
+// user returns the user with the given ID.
+func (s *Service) user(ctx context.Context, id string) (*users.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, users.ErrInvalidID
+	}
+
+	u, err := s.r.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get user[id=%s]: %w", id, err)
+	}
+
+	return u, nil
+}
+
+// redact removes the password hash from the user.
+func redact(u users.User) users.User {
+	u.PasswordHash = ""
+	return u
+}
@@ -0,0 +1,250 @@
+package registering_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/registering"
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	users []users.User
+}
+
+// CreateUser stores the user, as long as its handle and email are free.
+func (m *mockRepository) CreateUser(ctx context.Context, u users.User) error {
+	if err := m.taken(u); err != nil {
+		return err
+	}
+	m.users = append(m.users, u)
+	return nil
+}
+
+// GetUser returns the user with the given ID.
+func (m *mockRepository) GetUser(ctx context.Context, id string) (*users.User, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			return &u, nil
+		}
+	}
+	return nil, users.ErrNotFound
+}
+
+// UpdateUser stores the user, as long as its handle and email are free.
+func (m *mockRepository) UpdateUser(ctx context.Context, u users.User) error {
+	if err := m.taken(u); err != nil {
+		return err
+	}
+	for i := range m.users {
+		if m.users[i].ID == u.ID {
+			m.users[i] = u
+			return nil
+		}
+	}
+	return users.ErrNotFound
+}
+
+// taken checks if the handle or the email belong to another user.
+func (m *mockRepository) taken(u users.User) error {
+	for _, o := range m.users {
+		switch {
+		case o.ID == u.ID:
+		case o.Handle == u.Handle:
+			return users.ErrHandleTaken
+		case o.Email == u.Email:
+			return users.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+
+	r := &mockRepository{}
+	s := registering.NewService(r)
+
+	t.Log("Given the need to register users.")
+	{
+		t.Log("\tWhen registering a new user.")
+		{
+			u, err := s.Register(ctx, registering.NewUser{
+				Handle:   "Hop_Head",
+				Email:    "Hop.Head@Example.com ",
+				Password: "correct horse battery",
+			})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to register the user: %v", err)
+			}
+			if u.Handle != "hop_head" || u.Email != "hop.head@example.com" {
+				t.Fatalf("\t\t[ERROR] Should normalize the handle and the email. Got %+v", u)
+			}
+			if u.PasswordHash != "" {
+				t.Fatalf("\t\t[ERROR] Should not return the password hash. Got %q", u.PasswordHash)
+			}
+			if u.Role != users.RoleMember {
+				t.Fatalf("\t\t[ERROR] Should register a member. Got %q", u.Role)
+			}
+			t.Log("\t\t[OK] Should be able to register the user.")
+
+			stored := r.users[0].PasswordHash
+			if stored == "" || stored == "correct horse battery" {
+				t.Fatalf("\t\t[ERROR] Should store the password hash. Got %q", stored)
+			}
+			if !users.CheckPassword(stored, "correct horse battery") || users.CheckPassword(stored, "wrong password") {
+				t.Fatal("\t\t[ERROR] Should check the password against the hash.")
+			}
+			t.Log("\t\t[OK] Should store a hash of the password.")
+		}
+
+		t.Log("\tWhen registering the same password twice.")
+		{
+			u, err := s.Register(ctx, registering.NewUser{
+				Handle:   "stout_lover",
+				Email:    "stout@example.com",
+				Password: "correct horse battery",
+			})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to register the user: %v", err)
+			}
+			if r.users[1].PasswordHash == r.users[0].PasswordHash {
+				t.Fatalf("\t\t[ERROR] Should salt the hashes. Got %q for %s", r.users[1].PasswordHash, u.ID)
+			}
+			t.Log("\t\t[OK] Should salt the hashes.")
+		}
+
+		t.Log("\tWhen registering a taken handle or email.")
+		{
+			_, err := s.Register(ctx, registering.NewUser{Handle: "HOP_HEAD", Email: "other@example.com", Password: "12345678"})
+			if !errors.Is(err, users.ErrHandleTaken) {
+				t.Fatalf("\t\t[ERROR] Should return ErrHandleTaken. Got %v", err)
+			}
+
+			_, err = s.Register(ctx, registering.NewUser{Handle: "other", Email: "HOP.HEAD@example.com", Password: "12345678"})
+			if !errors.Is(err, users.ErrEmailTaken) {
+				t.Fatalf("\t\t[ERROR] Should return ErrEmailTaken. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not register the user.")
+		}
+
+		t.Log("\tWhen registering an invalid handle.")
+		{
+			_, err := s.Register(ctx, registering.NewUser{Handle: "hop head!", Email: "hop@example.com", Password: "12345678"})
+			if !errors.Is(err, users.ErrInvalidHandle) {
+				t.Fatalf("\t\t[ERROR] Should return ErrInvalidHandle. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not register the user.")
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+
+	r := &mockRepository{}
+	s := registering.NewService(r)
+
+	u, err := s.Register(ctx, registering.NewUser{
+		Handle:   "hop_head",
+		Email:    "hop.head@example.com",
+		Password: "correct horse battery",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to register the user: %v", err)
+	}
+
+	if _, err := s.Register(ctx, registering.NewUser{
+		Handle:   "stout_lover",
+		Email:    "stout@example.com",
+		Password: "correct horse battery",
+	}); err != nil {
+		t.Fatalf("Should be able to register the user: %v", err)
+	}
+
+	t.Log("Given the need to manage the user profiles.")
+	{
+		t.Log("\tWhen getting the profile of a user.")
+		{
+			p, err := s.GetProfile(ctx, u.ID)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to get the profile: %v", err)
+			}
+			if p.Handle != u.Handle || p.Email != "" || p.PasswordHash != "" {
+				t.Fatalf("\t\t[ERROR] Should return the public profile. Got %+v", p)
+			}
+			t.Log("\t\t[OK] Should return the public profile.")
+
+			if _, err := s.GetProfile(ctx, uuid.NewString()); !errors.Is(err, users.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should return ErrNotFound. Got %v", err)
+			}
+			if _, err := s.GetProfile(ctx, "invalid"); !errors.Is(err, users.ErrInvalidID) {
+				t.Fatalf("\t\t[ERROR] Should return ErrInvalidID. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not return unknown users.")
+		}
+
+		t.Log("\tWhen updating the profile.")
+		{
+			bio, password, current := "Hazy IPAs only", "another long password", "correct horse battery"
+			got, err := s.UpdateProfile(ctx, u.ID, registering.UpdateProfile{Bio: &bio, Password: &password, CurrentPassword: &current})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the profile: %v", err)
+			}
+			if got.Bio != bio || got.Handle != u.Handle || got.Email != u.Email || got.PasswordHash != "" {
+				t.Fatalf("\t\t[ERROR] Should only change the given fields. Got %+v", got)
+			}
+			if !users.CheckPassword(r.users[0].PasswordHash, password) {
+				t.Fatal("\t\t[ERROR] Should change the password.")
+			}
+			t.Log("\t\t[OK] Should be able to update the profile.")
+
+			for _, given := range []*string{nil, &current} {
+				if _, err := s.UpdateProfile(ctx, u.ID, registering.UpdateProfile{Password: &bio, CurrentPassword: given}); !errors.Is(err, users.ErrWrongPassword) {
+					t.Fatalf("\t\t[ERROR] Should return ErrWrongPassword. Got %v", err)
+				}
+			}
+			if !users.CheckPassword(r.users[0].PasswordHash, password) {
+				t.Fatal("\t\t[ERROR] Should keep the password.")
+			}
+			t.Log("\t\t[OK] Should not change the password without the current one.")
+
+			legacy := users.Legacy(uuid.NewString(), time.Now())
+			r.users = append(r.users, legacy)
+			if _, err := s.UpdateProfile(ctx, legacy.ID, registering.UpdateProfile{Password: &password}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should set the password of a user without one: %v", err)
+			}
+			if !users.CheckPassword(r.users[len(r.users)-1].PasswordHash, password) {
+				t.Fatal("\t\t[ERROR] Should set the password of a user without one.")
+			}
+			t.Log("\t\t[OK] Should set the password of a user without one.")
+
+			handle := "Stout_Lover"
+			if _, err := s.UpdateProfile(ctx, u.ID, registering.UpdateProfile{Handle: &handle}); !errors.Is(err, users.ErrHandleTaken) {
+				t.Fatalf("\t\t[ERROR] Should return ErrHandleTaken. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not take the handle of another user.")
+		}
+
+		t.Log("\tWhen changing the role of the user.")
+		{
+			got, err := s.ChangeRole(ctx, u.ID, registering.ChangeRole{Role: "brewer"})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to change the role: %v", err)
+			}
+			if got.Role != users.RoleBrewer || r.users[0].Role != users.RoleBrewer {
+				t.Fatalf("\t\t[ERROR] Should store the role. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should be able to change the role.")
+
+			if _, err := s.ChangeRole(ctx, u.ID, registering.ChangeRole{Role: "owner"}); !errors.Is(err, users.ErrInvalidRole) {
+				t.Fatalf("\t\t[ERROR] Should return ErrInvalidRole. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not grant an unknown role.")
+		}
+	}
+}
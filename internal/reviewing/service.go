@@ -3,19 +3,35 @@ package reviewing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/notifications"
 	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
-// NewReview defines the input parameters for creating a new review.
+// NewReview defines the input parameters for creating a new review. The
+// author is the authenticated user. A review is scored either directly or
+// with sub-scores, which are weighted into the score.
 type NewReview struct {
-	UserID  string  `json:"user_id" binding:"required,uuid"`
-	Score   float32 `json:"score" binding:"required"`
-	Comment string  `json:"comment" binding:"required"`
+	Score     *float32        `json:"score"`
+	SubScores *reviews.Scores `json:"sub_scores"`
+	Comment   string          `json:"comment" binding:"required"`
+}
+
+// UpdateReview defines the input parameters for changing a review. Nil
+// fields are kept unchanged. Changing the score directly discards the
+// sub-scores.
+type UpdateReview struct {
+	Score     *float32        `json:"score"`
+	SubScores *reviews.Scores `json:"sub_scores"`
+	Comment   *string         `json:"comment" binding:"omitempty,min=1"`
 }
 
 // Storer defines the interface for the reviewing service to interact
@@ -23,54 +39,245 @@ type NewReview struct {
 type Storer interface {
 	// GetBeer returns the beer with the given ID.
 	GetBeer(ctx context.Context, id string) (*beers.Beer, error)
-	// CreateReview creates a new review.
-	CreateReview(ctx context.Context, review reviews.Review) error
-}
-
-// Notifier defines the interface for the reviewing service to notify users.
-type Notifier interface {
-	Notify(ctx context.Context, userID string) error
+	// CreateReview creates a new review along with the notification of its
+	// creation and the deliveries of the event to the webhooks subscribed to
+	// it, so all of them are stored or none is.
+	CreateReview(ctx context.Context, review reviews.Review, n notifications.Notification, e webhooks.Event) error
+	// GetReview returns the review with the given ID.
+	GetReview(ctx context.Context, id string) (*reviews.Review, error)
+	// GetUserReview returns the review of a beer by a user.
+	GetUserReview(ctx context.Context, beerID, userID string) (*reviews.Review, error)
+	// UpdateReview stores the new revision of a review, as long as the
+	// stored revision is still the one it follows.
+	UpdateReview(ctx context.Context, review reviews.Review) error
+	// DeleteReview deletes a review and its history.
+	DeleteReview(ctx context.Context, id string) error
 }
 
 // Service provides beer reviewing operations.
 type Service struct {
-	storer   Storer
-	notifier Notifier
+	storer Storer
+	ev     events.Publisher
+	scale  reviews.Scale
 }
 
 // NewService creates a reviewing service with the necessary dependencies.
-func NewService(storer Storer, notifier Notifier) *Service {
+func NewService(storer Storer, ev events.Publisher, scale reviews.Scale) *Service {
 	return &Service{
-		storer:   storer,
-		notifier: notifier,
+		storer: storer,
+		ev:     ev,
+		scale:  scale,
 	}
 }
 
-// CreateReview creates a new review.
-func (s *Service) CreateReview(ctx context.Context, beerID string, nr NewReview) (reviews.Review, error) {
+// CreateReview creates a new review written by the given user, publishing the
+// ReviewAdded event once it's stored. The author and the webhooks are notified
+// later, from the outbox. A user reviews a beer once, ErrAlreadyReviewed is
+// returned when the user already reviewed it.
+func (s *Service) CreateReview(ctx context.Context, beerID, userID string, nr NewReview) (reviews.Review, error) {
 	if _, err := uuid.Parse(beerID); err != nil {
 		return reviews.Review{}, beers.ErrInvalidID
 	}
 
-	if _, err := s.storer.GetBeer(ctx, beerID); err != nil {
+	if _, err := uuid.Parse(userID); err != nil {
+		return reviews.Review{}, users.ErrInvalidID
+	}
+
+	if nr.Score == nil && nr.SubScores == nil {
+		return reviews.Review{}, fmt.Errorf("%w: score or sub-scores required", reviews.ErrInvalidScore)
+	}
+
+	score, subScores, err := s.score(nr.Score, nr.SubScores)
+	if err != nil {
+		return reviews.Review{}, err
+	}
+
+	b, err := s.storer.GetBeer(ctx, beerID)
+	if err != nil {
 		return reviews.Review{}, fmt.Errorf("get beer[id=%s]: %w", beerID, err)
 	}
 
+	now := time.Now()
 	r := reviews.Review{
 		ID:        uuid.NewString(),
 		BeerID:    beerID,
-		UserID:    nr.UserID,
-		Score:     nr.Score,
+		UserID:    userID,
+		Score:     score,
+		SubScores: subScores,
 		Comment:   nr.Comment,
-		CreatedAt: time.Now(),
+		Revision:  1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	e := events.ReviewAdded{
+		ID:         uuid.NewString(),
+		OccurredAt: now,
+		Beer:       *b,
+		Review:     r,
 	}
 
-	if err := s.storer.CreateReview(ctx, r); err != nil {
+	n := notifications.NewReviewCreated(e)
+	if err := s.storer.CreateReview(ctx, r, n, webhooks.NewReviewCreated(e)); err != nil {
 		return reviews.Review{}, fmt.Errorf("create beer[id=%s] review: %w", beerID, err)
 	}
 
-	if err := s.notifier.Notify(ctx, r.UserID); err != nil {
-		return reviews.Review{}, fmt.Errorf("notify user[id=%s]: %w", r.UserID, err)
+	s.ev.Publish(ctx, e)
+
+	return r, nil
+}
+
+// UpsertReview creates a new review written by the given user or, when the
+// user already reviewed the beer, replaces the content of that review, keeping
+// the previous content in its history. It reports whether the review was
+// created.
+func (s *Service) UpsertReview(ctx context.Context, beerID, userID string, nr NewReview) (reviews.Review, bool, error) {
+	r, err := s.CreateReview(ctx, beerID, userID, nr)
+	if err == nil {
+		return r, true, nil
+	}
+	if !errors.Is(err, reviews.ErrAlreadyReviewed) {
+		return reviews.Review{}, false, err
+	}
+
+	stored, err := s.storer.GetUserReview(ctx, beerID, userID)
+	if err != nil {
+		return reviews.Review{}, false, fmt.Errorf("get beer[id=%s] review by user[id=%s]: %w", beerID, userID, err)
+	}
+
+	score, subScores, err := s.score(nr.Score, nr.SubScores)
+	if err != nil {
+		return reviews.Review{}, false, err
+	}
+	stored.Score = score
+	stored.SubScores = subScores
+	stored.Comment = nr.Comment
+	stored.Revision++
+	stored.UpdatedAt = time.Now()
+
+	if err := s.storer.UpdateReview(ctx, *stored); err != nil {
+		return reviews.Review{}, false, fmt.Errorf("update review[id=%s]: %w", stored.ID, err)
+	}
+
+	return *stored, false, nil
+}
+
+// UpdateReview changes a review, keeping the previous content in its history.
+// Only the author of the review can change it.
+func (s *Service) UpdateReview(ctx context.Context, beerID, reviewID, userID string, ur UpdateReview) (reviews.Review, error) {
+	r, err := s.authorReview(ctx, beerID, reviewID, userID)
+	if err != nil {
+		return reviews.Review{}, err
+	}
+
+	if ur.Score != nil || ur.SubScores != nil {
+		score, subScores, err := s.score(ur.Score, ur.SubScores)
+		if err != nil {
+			return reviews.Review{}, err
+		}
+		r.Score = score
+		r.SubScores = subScores
+	}
+	if ur.Comment != nil {
+		r.Comment = *ur.Comment
+	}
+	r.Revision++
+	r.UpdatedAt = time.Now()
+
+	if err := s.storer.UpdateReview(ctx, *r); err != nil {
+		return reviews.Review{}, fmt.Errorf("update review[id=%s]: %w", reviewID, err)
+	}
+
+	return *r, nil
+}
+
+// DeleteReview deletes a review. Only the author of the review can delete it.
+func (s *Service) DeleteReview(ctx context.Context, beerID, reviewID, userID string) error {
+	if _, err := s.authorReview(ctx, beerID, reviewID, userID); err != nil {
+		return err
+	}
+
+	if err := s.storer.DeleteReview(ctx, reviewID); err != nil {
+		return fmt.Errorf("delete review[id=%s]: %w", reviewID, err)
+	}
+
+	return nil
+}
+
+// RemoveReview deletes a review whoever wrote it, as done by the moderators.
+func (s *Service) RemoveReview(ctx context.Context, beerID, reviewID string) error {
+	if _, err := s.beerReview(ctx, beerID, reviewID); err != nil {
+		return err
+	}
+
+	if err := s.storer.DeleteReview(ctx, reviewID); err != nil {
+		return fmt.Errorf("delete review[id=%s]: %w", reviewID, err)
+	}
+
+	return nil
+}
+
+// score validates the score or the sub-scores given to a review, returning
+// the score along with the sub-scores it was weighted from.
+func (s *Service) score(score *float32, subScores *reviews.Scores) (float32, *reviews.Scores, error) {
+	if subScores == nil {
+		if err := s.scale.Validate(*score); err != nil {
+			return 0, nil, err
+		}
+		return *score, nil, nil
+	}
+
+	if score != nil {
+		return 0, nil, fmt.Errorf("%w: the score of a review with sub-scores is weighted from them", reviews.ErrInvalidScore)
+	}
+	if subScores.Empty() {
+		return 0, nil, fmt.Errorf("%w: no sub-score given", reviews.ErrInvalidScore)
+	}
+
+	for _, v := range subScores.Dimensions() {
+		if v == nil {
+			continue
+		}
+		if err := s.scale.Validate(*v); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return subScores.Weighted(), subScores, nil
+}
+
+// authorReview returns the review of the beer, as long as it was written by
+// the given user.
+func (s *Service) authorReview(ctx context.Context, beerID, reviewID, userID string) (*reviews.Review, error) {
+	r, err := s.beerReview(ctx, beerID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.UserID != userID {
+		return nil, reviews.ErrNotAuthor
+	}
+
+	return r, nil
+}
+
+// beerReview returns the review of the beer.
+func (s *Service) beerReview(ctx context.Context, beerID, reviewID string) (*reviews.Review, error) {
+	if _, err := uuid.Parse(beerID); err != nil {
+		return nil, beers.ErrInvalidID
+	}
+
+	if _, err := uuid.Parse(reviewID); err != nil {
+		return nil, reviews.ErrInvalidID
+	}
+
+	r, err := s.storer.GetReview(ctx, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("get review[id=%s]: %w", reviewID, err)
+	}
+
+	if r.BeerID != beerID {
+		return nil, fmt.Errorf("get review[id=%s]: %w", reviewID, reviews.ErrNotFound)
 	}
 
 	return r, nil
@@ -7,13 +7,20 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/notifications"
 	"github.com/phbpx/gobeer/internal/reviewing"
 	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
 // mockStore is a mock implementation of the Storer interface.
 type mockStore struct {
-	data []beers.Beer
+	data          []beers.Beer
+	reviews       []reviews.Review
+	notifications []notifications.Notification
+	events        []webhooks.Event
 }
 
 // GetBeer returns the beer with the given ID.
@@ -26,20 +33,72 @@ func (r *mockStore) GetBeer(ctx context.Context, id string) (*beers.Beer, error)
 	return nil, beers.ErrNotFound
 }
 
-// ============================================================================
+// CreateReview creates a new review along with its notification and event.
+func (r *mockStore) CreateReview(ctx context.Context, nr reviews.Review, n notifications.Notification, e webhooks.Event) error {
+	if _, err := r.GetUserReview(ctx, nr.BeerID, nr.UserID); err == nil {
+		return reviews.ErrAlreadyReviewed
+	}
+	r.reviews = append(r.reviews, nr)
+	r.notifications = append(r.notifications, n)
+	r.events = append(r.events, e)
+	return nil
+}
 
-// mockNotifier is a mock implementation of the Notifier interface.
-type mockNotifier struct{}
+// GetReview returns the review with the given ID.
+func (r *mockStore) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	for _, rv := range r.reviews {
+		if rv.ID == id {
+			return &rv, nil
+		}
+	}
+	return nil, reviews.ErrNotFound
+}
 
-func (r *mockNotifier) Notify(ctx context.Context, userID string) error {
-	return nil
+// GetUserReview returns the review of a beer by a user.
+func (r *mockStore) GetUserReview(ctx context.Context, beerID, userID string) (*reviews.Review, error) {
+	for _, rv := range r.reviews {
+		if rv.BeerID == beerID && rv.UserID == userID {
+			return &rv, nil
+		}
+	}
+	return nil, reviews.ErrNotFound
+}
+
+// UpdateReview updates a review.
+func (r *mockStore) UpdateReview(ctx context.Context, ur reviews.Review) error {
+	for i := range r.reviews {
+		if r.reviews[i].ID == ur.ID {
+			if r.reviews[i].Revision != ur.Revision-1 {
+				return reviews.ErrRevisionConflict
+			}
+			r.reviews[i] = ur
+			return nil
+		}
+	}
+	return reviews.ErrNotFound
 }
 
-// ============================================================================
+// DeleteReview deletes a review.
+func (r *mockStore) DeleteReview(ctx context.Context, id string) error {
+	for i := range r.reviews {
+		if r.reviews[i].ID == id {
+			r.reviews = append(r.reviews[:i], r.reviews[i+1:]...)
+			return nil
+		}
+	}
+	return reviews.ErrNotFound
+}
 
-// CreateReview creates a new review.
-func (r *mockStore) CreateReview(ctx context.Context, nr reviews.Review) error {
-	return nil
+// =============================================================================
+
+// mockPublisher is a mock implementation of the Publisher interface.
+type mockPublisher struct {
+	published []events.Event
+}
+
+// Publish records the event.
+func (m *mockPublisher) Publish(ctx context.Context, e events.Event) {
+	m.published = append(m.published, e)
 }
 
 func TestCreateReview(t *testing.T) {
@@ -50,40 +109,333 @@ func TestCreateReview(t *testing.T) {
 	// Create a mock repository.
 	r := &mockStore{
 		data: []beers.Beer{
-			{ID: beerID, Name: "Beer 1"},
-			{ID: uuid.NewString(), Name: "Beer 2"},
+			{ID: beerID, Name: "Beer 1", Brewery: "Brewery 1"},
+			{ID: uuid.NewString(), Name: "Beer 2", Brewery: "Brewery 1"},
 		},
 	}
+	pub := &mockPublisher{}
 
 	// Create a new service with the mock repository.
-	s := reviewing.NewService(r, &mockNotifier{})
+	s := reviewing.NewService(r, pub, reviews.DefaultScale)
 
 	t.Logf("Given the need to test creating a new review.")
 	{
 		t.Logf("\tWhen creating a new review for a beer that exists.")
 		{
+			userID := uuid.NewString()
 			nr := reviewing.NewReview{
-				UserID:  uuid.NewString(),
-				Score:   5,
+				Score:   score(5),
 				Comment: "A very nice beer",
 			}
-			if _, err := s.CreateReview(ctx, beerID, nr); err != nil {
+			review, err := s.CreateReview(ctx, beerID, userID, nr)
+			if err != nil {
 				t.Fatalf("\t\t[ERROR] Should be able to create the review. Error: %v", err)
 			}
 			t.Logf("\t\t[OK] Should be able to create the review.")
+
+			if len(r.notifications) != 1 || r.notifications[0].ReviewID != review.ID ||
+				r.notifications[0].UserID != userID || r.notifications[0].Status != notifications.StatusPending ||
+				r.notifications[0].Event.Validate() != nil || r.notifications[0].Event.Beer.Name != "Beer 1" {
+				t.Fatalf("\t\t[ERROR] Should store the review notification. Got %+v", r.notifications)
+			}
+			t.Logf("\t\t[OK] Should store the review notification.")
+
+			if len(r.events) != 1 || r.events[0].Type != webhooks.EventReviewCreated ||
+				r.events[0].Review.ID != review.ID || r.events[0].Beer.ID != beerID {
+				t.Fatalf("\t\t[ERROR] Should store the review_created event. Got %+v", r.events)
+			}
+			t.Logf("\t\t[OK] Should store the review_created event.")
+
+			if len(pub.published) != 1 {
+				t.Fatalf("\t\t[ERROR] Should publish the ReviewAdded event. Got %+v", pub.published)
+			}
+			if e, ok := pub.published[0].(events.ReviewAdded); !ok || e.Review.ID != review.ID || e.Beer.ID != beerID {
+				t.Fatalf("\t\t[ERROR] Should publish the ReviewAdded event. Got %+v", pub.published[0])
+			}
+			t.Logf("\t\t[OK] Should publish the ReviewAdded event.")
 		}
 
 		t.Logf("\tWhen creating a new review for a beer that does not exist.")
 		{
 			nr := reviewing.NewReview{
-				UserID:  uuid.NewString(),
-				Score:   5,
+				Score:   score(5),
 				Comment: "A very nice beer",
 			}
-			if _, err := s.CreateReview(ctx, uuid.NewString(), nr); !errors.Is(err, beers.ErrNotFound) {
+			if _, err := s.CreateReview(ctx, uuid.NewString(), uuid.NewString(), nr); !errors.Is(err, beers.ErrNotFound) {
 				t.Fatalf("\t\t[ERROR] Should not be able to create the review. Error: %v", err)
 			}
 			t.Logf("\t\t[OK] Should not be able to create the review.")
 		}
+
+		t.Logf("\tWhen creating a new review without a valid user.")
+		{
+			nr := reviewing.NewReview{
+				Score:   score(5),
+				Comment: "A very nice beer",
+			}
+			if _, err := s.CreateReview(ctx, beerID, "someone", nr); !errors.Is(err, users.ErrInvalidID) {
+				t.Fatalf("\t\t[ERROR] Should not be able to create the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not be able to create the review.")
+		}
+	}
+}
+
+func TestChangeReview(t *testing.T) {
+	ctx := context.Background()
+
+	beerID := uuid.NewString()
+	userID := uuid.NewString()
+
+	// Create a mock repository.
+	r := &mockStore{
+		data: []beers.Beer{
+			{ID: beerID, Name: "Beer 1"},
+		},
+	}
+
+	// Create a new service with the mock repository.
+	s := reviewing.NewService(r, &mockPublisher{}, reviews.DefaultScale)
+
+	review, err := s.CreateReview(ctx, beerID, userID, reviewing.NewReview{
+		Score:   score(3),
+		Comment: "A nice beer",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create the review: %v", err)
+	}
+
+	t.Logf("Given the need to test changing a review.")
+	{
+		t.Logf("\tWhen the author updates the review.")
+		{
+			ur := reviewing.UpdateReview{Score: score(4)}
+			updated, err := s.UpdateReview(ctx, beerID, review.ID, userID, ur)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the review. Error: %v", err)
+			}
+			if updated.Score != 4 || updated.Revision != 2 {
+				t.Fatalf("\t\t[ERROR] Should create a new revision. Got %+v", updated)
+			}
+			t.Logf("\t\t[OK] Should be able to update the review.")
+		}
+
+		t.Logf("\tWhen another user updates the review.")
+		{
+			ur := reviewing.UpdateReview{}
+			if _, err := s.UpdateReview(ctx, beerID, review.ID, uuid.NewString(), ur); !errors.Is(err, reviews.ErrNotAuthor) {
+				t.Fatalf("\t\t[ERROR] Should not be able to update the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not be able to update the review.")
+		}
+
+		t.Logf("\tWhen the review is updated through another beer.")
+		{
+			ur := reviewing.UpdateReview{}
+			if _, err := s.UpdateReview(ctx, uuid.NewString(), review.ID, userID, ur); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to update the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not be able to update the review.")
+		}
+
+		t.Logf("\tWhen another user deletes the review.")
+		{
+			if err := s.DeleteReview(ctx, beerID, review.ID, uuid.NewString()); !errors.Is(err, reviews.ErrNotAuthor) {
+				t.Fatalf("\t\t[ERROR] Should not be able to delete the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not be able to delete the review.")
+		}
+
+		t.Logf("\tWhen the author deletes the review.")
+		{
+			if err := s.DeleteReview(ctx, beerID, review.ID, userID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to delete the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should be able to delete the review.")
+		}
+
+		t.Logf("\tWhen a moderator removes the review of another user.")
+		{
+			other, err := s.CreateReview(ctx, beerID, uuid.NewString(), reviewing.NewReview{Score: score(1)})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review: %v", err)
+			}
+
+			if err := s.RemoveReview(ctx, uuid.NewString(), other.ID); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not remove the review through another beer. Error: %v", err)
+			}
+			if err := s.RemoveReview(ctx, beerID, other.ID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to remove the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should be able to remove the review.")
+		}
 	}
 }
+
+func TestReviewScores(t *testing.T) {
+	ctx := context.Background()
+
+	beerID := uuid.NewString()
+	userID := uuid.NewString()
+
+	// Create a mock repository.
+	r := &mockStore{
+		data: []beers.Beer{
+			{ID: beerID, Name: "Beer 1"},
+		},
+	}
+
+	// Create a new service with the mock repository.
+	s := reviewing.NewService(r, &mockPublisher{}, reviews.DefaultScale)
+
+	t.Logf("Given the need to score reviews on the rating scale.")
+	{
+		t.Logf("\tWhen creating reviews with scores out of the scale.")
+		{
+			for _, v := range []float32{-3, 9000, 4.1} {
+				nr := reviewing.NewReview{Score: score(v), Comment: "Off the scale"}
+				if _, err := s.CreateReview(ctx, beerID, userID, nr); !errors.Is(err, reviews.ErrInvalidScore) {
+					t.Fatalf("\t\t[ERROR] Should not accept the score %v. Error: %v", v, err)
+				}
+			}
+			t.Logf("\t\t[OK] Should not be able to create the reviews.")
+		}
+
+		t.Logf("\tWhen creating a review without a score.")
+		{
+			nr := reviewing.NewReview{Comment: "No score"}
+			if _, err := s.CreateReview(ctx, beerID, userID, nr); !errors.Is(err, reviews.ErrInvalidScore) {
+				t.Fatalf("\t\t[ERROR] Should not be able to create the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not be able to create the review.")
+		}
+
+		t.Logf("\tWhen creating a review with the lowest score.")
+		{
+			nr := reviewing.NewReview{Score: score(0), Comment: "Undrinkable"}
+			if _, err := s.CreateReview(ctx, beerID, userID, nr); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should be able to create the review.")
+		}
+
+		t.Logf("\tWhen creating a review with sub-scores.")
+		{
+			userID := uuid.NewString()
+			nr := reviewing.NewReview{
+				SubScores: &reviews.Scores{Aroma: score(5), Taste: score(4), Overall: score(3.5)},
+				Comment:   "Great aroma",
+			}
+			review, err := s.CreateReview(ctx, beerID, userID, nr)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review. Error: %v", err)
+			}
+
+			// (12*5 + 20*4 + 10*3.5) / (12 + 20 + 10)
+			if want := float32(175) / 42; review.Score != want || review.SubScores == nil {
+				t.Fatalf("\t\t[ERROR] Should weight the sub-scores into the score. Got %v, want %v", review.Score, want)
+			}
+			t.Logf("\t\t[OK] Should weight the sub-scores into the score.")
+
+			ur := reviewing.UpdateReview{Score: score(2)}
+			updated, err := s.UpdateReview(ctx, beerID, review.ID, userID, ur)
+			if err != nil || updated.Score != 2 || updated.SubScores != nil {
+				t.Fatalf("\t\t[ERROR] Should discard the sub-scores when the score changes. Got %+v: %v", updated, err)
+			}
+			t.Logf("\t\t[OK] Should discard the sub-scores when the score changes.")
+		}
+
+		t.Logf("\tWhen creating a review with invalid sub-scores.")
+		{
+			for _, nr := range []reviewing.NewReview{
+				{SubScores: &reviews.Scores{Mouthfeel: score(6)}, Comment: "Off the scale"},
+				{SubScores: &reviews.Scores{}, Comment: "Nothing rated"},
+				{Score: score(4), SubScores: &reviews.Scores{Aroma: score(4)}, Comment: "Both"},
+			} {
+				if _, err := s.CreateReview(ctx, beerID, userID, nr); !errors.Is(err, reviews.ErrInvalidScore) {
+					t.Fatalf("\t\t[ERROR] Should not accept the sub-scores %+v. Error: %v", nr, err)
+				}
+			}
+			t.Logf("\t\t[OK] Should not be able to create the reviews.")
+		}
+	}
+}
+
+func TestUpsertReview(t *testing.T) {
+	ctx := context.Background()
+
+	beerID := uuid.NewString()
+	userID := uuid.NewString()
+
+	// Create a mock repository.
+	r := &mockStore{
+		data: []beers.Beer{
+			{ID: beerID, Name: "Beer 1"},
+		},
+	}
+	pub := &mockPublisher{}
+
+	// Create a new service with the mock repository.
+	s := reviewing.NewService(r, pub, reviews.DefaultScale)
+
+	t.Logf("Given the need to review a beer once per user.")
+	{
+		t.Logf("\tWhen the user reviews the beer for the first time.")
+		{
+			nr := reviewing.NewReview{
+				SubScores: &reviews.Scores{Aroma: score(4)},
+				Comment:   "First sip",
+			}
+			review, created, err := s.UpsertReview(ctx, beerID, userID, nr)
+			if err != nil || !created || review.Revision != 1 {
+				t.Fatalf("\t\t[ERROR] Should create the review. Got %+v, %v: %v", review, created, err)
+			}
+			t.Logf("\t\t[OK] Should create the review.")
+		}
+
+		t.Logf("\tWhen the user reviews the beer again.")
+		{
+			nr := reviewing.NewReview{Score: score(2), Comment: "Second sip"}
+			if _, err := s.CreateReview(ctx, beerID, userID, nr); !errors.Is(err, reviews.ErrAlreadyReviewed) {
+				t.Fatalf("\t\t[ERROR] Should not create another review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not create another review.")
+		}
+
+		t.Logf("\tWhen the user upserts the review of the beer.")
+		{
+			first := r.reviews[0]
+
+			nr := reviewing.NewReview{Score: score(2), Comment: "Second sip"}
+			review, created, err := s.UpsertReview(ctx, beerID, userID, nr)
+			if err != nil || created {
+				t.Fatalf("\t\t[ERROR] Should replace the review. Got %v: %v", created, err)
+			}
+			if review.ID != first.ID || review.Revision != 2 || review.Score != 2 ||
+				review.SubScores != nil || review.Comment != "Second sip" || !review.CreatedAt.Equal(first.CreatedAt) {
+				t.Fatalf("\t\t[ERROR] Should replace the content as a new revision. Got %+v", review)
+			}
+			if len(r.reviews) != 1 || r.reviews[0].Score != 2 {
+				t.Fatalf("\t\t[ERROR] Should store the replaced review. Got %+v", r.reviews)
+			}
+			if len(pub.published) != 1 {
+				t.Fatalf("\t\t[ERROR] Should publish a single ReviewAdded event. Got %+v", pub.published)
+			}
+			t.Logf("\t\t[OK] Should replace the content as a new revision.")
+		}
+
+		t.Logf("\tWhen the user upserts the review with an invalid score.")
+		{
+			nr := reviewing.NewReview{Score: score(7), Comment: "Off the scale"}
+			if _, _, err := s.UpsertReview(ctx, beerID, userID, nr); !errors.Is(err, reviews.ErrInvalidScore) {
+				t.Fatalf("\t\t[ERROR] Should not replace the review. Error: %v", err)
+			}
+			t.Logf("\t\t[OK] Should not replace the review.")
+		}
+	}
+}
+
+// score returns a pointer to the score.
+func score(v float32) *float32 {
+	return &v
+}
@@ -0,0 +1,23 @@
+package reviews
+
+// Prior defines what is assumed about the score of a beer before its reviews
+// are known: a mean score, weighted as if it were given by that many reviews.
+// It's the prior of the Bayesian average, which pulls the score of the beers
+// with few reviews towards the mean.
+type Prior struct {
+	Mean   float32
+	Weight float32
+}
+
+// DefaultPrior assumes a beer is scored 3.5 until it has about 10 reviews.
+var DefaultPrior = Prior{Mean: 3.5, Weight: 10}
+
+// Average returns the Bayesian average of the reviews, given the sum of their
+// scores and how many they are.
+func (p Prior) Average(sum float64, count int) float32 {
+	total := float64(p.Weight) + float64(count)
+	if total == 0 {
+		return 0
+	}
+	return float32((float64(p.Weight)*float64(p.Mean) + sum) / total)
+}
@@ -1,14 +1,66 @@
 // Package reviews defines the review domain model.
 package reviews
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
-// Review defines the properties of a review.
+var (
+	// ErrInvalidID is returned when an invalid ID is provided.
+	ErrInvalidID = errors.New("invalid review ID")
+
+	// ErrNotFound is used when a review is not found.
+	ErrNotFound = errors.New("review not found")
+
+	// ErrNotAuthor is used when a user changes a review written by
+	// another user.
+	ErrNotAuthor = errors.New("review belongs to another user")
+
+	// ErrAlreadyReviewed is used when a user reviews a beer they already
+	// reviewed.
+	ErrAlreadyReviewed = errors.New("beer already reviewed by the user")
+
+	// ErrRevisionConflict is used when a review is changed based on a
+	// revision that is no longer its current one.
+	ErrRevisionConflict = errors.New("review changed by another request")
+)
+
+// Review defines the properties of a review. When the review has
+// sub-scores, its score is their weighted average.
 type Review struct {
 	ID        string    `json:"id"`
 	BeerID    string    `json:"beer_id"`
 	UserID    string    `json:"user_id"`
 	Score     float32   `json:"score"`
+	SubScores *Scores   `json:"sub_scores,omitempty"`
+	Comment   string    `json:"comment"`
+	Revision  int       `json:"revision"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Revision defines the content of a review at a given point of its history.
+type Revision struct {
+	ReviewID  string    `json:"review_id"`
+	Revision  int       `json:"revision"`
+	Score     float32   `json:"score"`
+	SubScores *Scores   `json:"sub_scores,omitempty"`
 	Comment   string    `json:"comment"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// Stats defines the aggregated data of the reviews of a beer.
+type Stats struct {
+	Count        int        `json:"count"`
+	Distribution []Bucket   `json:"distribution"`
+	FirstAt      *time.Time `json:"first_review_at,omitempty"`
+	LastAt       *time.Time `json:"last_review_at,omitempty"`
+}
+
+// Bucket defines the number of reviews with a score in the
+// range [Score, Score+1).
+type Bucket struct {
+	Score int `json:"score"`
+	Count int `json:"count"`
+}
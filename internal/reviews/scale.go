@@ -0,0 +1,117 @@
+package reviews
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidScore is used when a score or a sub-score is not on the rating
+// scale.
+var ErrInvalidScore = errors.New("invalid score")
+
+// Scale defines the bounds and the increments of the scores.
+type Scale struct {
+	Min  float32
+	Max  float32
+	Step float32
+}
+
+// DefaultScale is the rating scale of the API, from 0 to 5 in quarters.
+var DefaultScale = Scale{Min: 0, Max: 5, Step: 0.25}
+
+// Validate checks that the score is within the bounds and is a whole number of
+// increments away from the minimum.
+func (s Scale) Validate(score float32) error {
+	if score < s.Min || score > s.Max {
+		return fmt.Errorf("%w: %v is not between %v and %v", ErrInvalidScore, score, s.Min, s.Max)
+	}
+
+	steps := float64((score - s.Min) / s.Step)
+	if math.Abs(steps-math.Round(steps)) > 1e-4 {
+		return fmt.Errorf("%w: %v is not a multiple of %v", ErrInvalidScore, score, s.Step)
+	}
+
+	return nil
+}
+
+// Clamp returns the score limited to the bounds of the scale. It fixes the
+// scores given before the scale was enforced. A score within the bounds is
+// returned as is, as the weighted scores fall between the increments.
+func (s Scale) Clamp(score float32) float32 {
+	if score < s.Min {
+		return s.Min
+	}
+	if score > s.Max {
+		return s.Max
+	}
+	return score
+}
+
+// Set of dimensions rated by the sub-scores, in the order returned by
+// Scores.Dimensions.
+const (
+	Aroma = iota
+	Appearance
+	Taste
+	Mouthfeel
+	Overall
+	NumDimensions
+)
+
+// weights are the weights of the dimensions in the score, taken from the
+// points of each section of the BJCP scoresheet.
+var weights = [NumDimensions]float32{12, 3, 20, 5, 10}
+
+// Scores defines the optional sub-scores of a review, each one rating a
+// dimension of the beer. Nil sub-scores were not rated.
+type Scores struct {
+	Aroma      *float32 `json:"aroma,omitempty"`
+	Appearance *float32 `json:"appearance,omitempty"`
+	Taste      *float32 `json:"taste,omitempty"`
+	Mouthfeel  *float32 `json:"mouthfeel,omitempty"`
+	Overall    *float32 `json:"overall,omitempty"`
+}
+
+// NewScores returns the sub-scores of the dimensions, given in the order of
+// Scores.Dimensions.
+func NewScores(d [NumDimensions]*float32) Scores {
+	return Scores{
+		Aroma:      d[Aroma],
+		Appearance: d[Appearance],
+		Taste:      d[Taste],
+		Mouthfeel:  d[Mouthfeel],
+		Overall:    d[Overall],
+	}
+}
+
+// Dimensions returns the sub-scores indexed by dimension.
+func (s Scores) Dimensions() [NumDimensions]*float32 {
+	return [NumDimensions]*float32{s.Aroma, s.Appearance, s.Taste, s.Mouthfeel, s.Overall}
+}
+
+// Empty reports whether no dimension was rated.
+func (s Scores) Empty() bool {
+	for _, v := range s.Dimensions() {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Weighted returns the weighted average of the rated dimensions.
+func (s Scores) Weighted() float32 {
+	var sum, total float32
+	for i, v := range s.Dimensions() {
+		if v != nil {
+			sum += *v * weights[i]
+			total += weights[i]
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
@@ -0,0 +1,831 @@
+// Package file provides a storage implementation that persists the data in a
+// directory, meant for single node deployments without a database.
+//
+// Every change is appended to a log file and synced to disk before it's
+// acknowledged. From time to time the whole content is written to a snapshot
+// and the log is emptied. On open, the snapshot is loaded and the log is
+// replayed on top of it, discarding the record left incomplete by a crash.
+package file
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/storage/memory"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// Set of files kept in the data directory.
+const (
+	lockFile     = "LOCK"
+	logFile      = "log"
+	snapshotFile = "snapshot"
+)
+
+// snapshotFormat is the version of the data written to the snapshots. The data
+// of an older snapshot, along with the log replayed on top of it, is upgraded
+// once on open.
+const snapshotFormat = 1
+
+// DefaultSnapshotEvery is the number of changes between snapshots when none
+// is configured.
+const DefaultSnapshotEvery = 1000
+
+// Set of error variables for the file storage.
+var (
+	ErrLocked = errors.New("data directory is used by another process")
+	ErrClosed = errors.New("store is closed")
+)
+
+// Config is the required properties to use the file storage.
+type Config struct {
+	Dir           string
+	SnapshotEvery int
+}
+
+// record defines a change written to the log.
+type record struct {
+	Seq          uint64                      `json:"seq"`
+	Op           string                      `json:"op"`
+	Beer         *beers.Beer                 `json:"beer,omitempty"`
+	Brewery      *breweries.Brewery          `json:"brewery,omitempty"`
+	Review       *reviews.Review             `json:"review,omitempty"`
+	User         *memory.StoredUser          `json:"user,omitempty"`
+	Notification *notifications.Notification `json:"notification,omitempty"`
+	Event        *webhooks.Event             `json:"event,omitempty"`
+	Subscription *webhooks.Subscription      `json:"subscription,omitempty"`
+	Delivery     *webhooks.Delivery          `json:"delivery,omitempty"`
+	ID           string                      `json:"id,omitempty"`
+	Version      int                         `json:"version,omitempty"`
+}
+
+// snapshot defines the content of the store after the change Seq.
+type snapshot struct {
+	Seq    uint64      `json:"seq"`
+	Format int         `json:"format,omitempty"`
+	Data   memory.Dump `json:"data"`
+}
+
+// Store provides a file backed implementation of the storage interfaces. The
+// data is kept in memory and every change is written to the log.
+type Store struct {
+	mem *memory.Store
+
+	// mu serializes the changes, so they are written in the order they are
+	// applied.
+	mu            sync.Mutex
+	dir           string
+	lock          *os.File
+	log           *os.File
+	seq           uint64
+	format        int
+	pending       int
+	snapshotEvery int
+
+	// err is set once the log can't be trusted anymore, failing every
+	// following change until the store is opened again.
+	err error
+
+	// failed holds the error of the log for the reads, which don't take mu.
+	// The change that failed is already applied to the data in memory but
+	// may be missing from the log, so the data isn't served anymore.
+	failed atomic.Pointer[error]
+}
+
+// Open opens the store kept in the configured directory, creating it when
+// needed. The directory is locked until the store is closed.
+func Open(cfg Config) (*Store, error) {
+	if cfg.SnapshotEvery <= 0 {
+		cfg.SnapshotEvery = DefaultSnapshotEvery
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating data dir: %w", err)
+	}
+
+	lock, err := os.OpenFile(filepath.Join(cfg.Dir, lockFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := lockFD(lock); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("locking data dir: %w", err)
+	}
+
+	s := Store{
+		mem:           memory.NewStore(),
+		dir:           cfg.Dir,
+		lock:          lock,
+		snapshotEvery: cfg.SnapshotEvery,
+	}
+
+	if err := s.recover(); err != nil {
+		s.release()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Close writes a snapshot of the store and releases the data directory.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if errors.Is(s.err, ErrClosed) {
+		return nil
+	}
+
+	var err error
+	if s.err == nil && s.pending > 0 {
+		err = s.snapshot()
+	}
+
+	s.err = ErrClosed
+
+	return errors.Join(err, s.release())
+}
+
+// Snapshot writes the whole content of the store to the snapshot and empties
+// the log.
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.snapshot()
+}
+
+// =============================================================================
+
+// CreateBeer creates a new beer, along with the deliveries of its event.
+func (s *Store) CreateBeer(ctx context.Context, b beers.Beer, e webhooks.Event) error {
+	return s.commit(ctx, record{Op: opCreateBeer, Beer: &b, Event: &e})
+}
+
+// BeerExists checks if a beer with the given name and brewery exists.
+func (s *Store) BeerExists(ctx context.Context, name, breweryID string) (bool, error) {
+	if err := s.check(); err != nil {
+		return false, err
+	}
+
+	return s.mem.BeerExists(ctx, name, breweryID)
+}
+
+// GetBeer returns the beer with the given ID.
+func (s *Store) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetBeer(ctx, id)
+}
+
+// UpdateBeer updates a beer, as long as its stored version is still the
+// given version.
+func (s *Store) UpdateBeer(ctx context.Context, b beers.Beer, version int) error {
+	return s.commit(ctx, record{Op: opUpdateBeer, Beer: &b, Version: version})
+}
+
+// DeleteBeer deletes a beer and its reviews, as long as its stored version is
+// still the given version.
+func (s *Store) DeleteBeer(ctx context.Context, id string, version int) error {
+	return s.commit(ctx, record{Op: opDeleteBeer, ID: id, Version: version})
+}
+
+// ListBeers returns the page of beers described by the seek.
+func (s *Store) ListBeers(ctx context.Context, seek listing.Seek) ([]beers.Beer, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListBeers(ctx, seek)
+}
+
+// TopBeers returns the beers described by the ranking, from the highest
+// ranked.
+func (s *Store) TopBeers(ctx context.Context, r listing.Ranking) ([]beers.Beer, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.TopBeers(ctx, r)
+}
+
+// SearchBeers returns the beers matching the search terms, along with the
+// facets of the matches.
+func (s *Store) SearchBeers(ctx context.Context, q listing.SearchQuery) (listing.SearchResult, error) {
+	if err := s.check(); err != nil {
+		return listing.SearchResult{}, err
+	}
+
+	return s.mem.SearchBeers(ctx, q)
+}
+
+// CreateBrewery stores a new brewery. Its canonical name must not belong to
+// another brewery.
+func (s *Store) CreateBrewery(ctx context.Context, b breweries.Brewery) error {
+	return s.commit(ctx, record{Op: opCreateBrewery, Brewery: &b})
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (s *Store) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetBrewery(ctx, id)
+}
+
+// ListBreweries returns every brewery, ordered by name.
+func (s *Store) ListBreweries(ctx context.Context) ([]breweries.Brewery, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListBreweries(ctx)
+}
+
+// CreateReview creates a new review, along with its first revision, its
+// notification and the deliveries of its event, and adds it to the beer score.
+func (s *Store) CreateReview(ctx context.Context, r reviews.Review, n notifications.Notification, e webhooks.Event) error {
+	return s.commit(ctx, record{Op: opCreateReview, Review: &r, Notification: &n, Event: &e})
+}
+
+// GetReview returns the review with the given ID.
+func (s *Store) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetReview(ctx, id)
+}
+
+// GetUserReview returns the review of a beer by a user.
+func (s *Store) GetUserReview(ctx context.Context, beerID, userID string) (*reviews.Review, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetUserReview(ctx, beerID, userID)
+}
+
+// UpdateReview stores the current revision of a review, appends it to the
+// review history and replaces the previous score in the beer score.
+func (s *Store) UpdateReview(ctx context.Context, r reviews.Review) error {
+	return s.commit(ctx, record{Op: opUpdateReview, Review: &r})
+}
+
+// DeleteReview deletes a review and its history and removes it from the beer
+// score.
+func (s *Store) DeleteReview(ctx context.Context, id string) error {
+	return s.commit(ctx, record{Op: opDeleteReview, ID: id})
+}
+
+// ListReviews returns the reviews of a beer, from the most recent to the
+// oldest.
+func (s *Store) ListReviews(ctx context.Context, id string) ([]reviews.Review, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListReviews(ctx, id)
+}
+
+// ListReviewsAfter returns up to limit reviews created after the given one,
+// from the oldest to the most recent.
+func (s *Store) ListReviewsAfter(ctx context.Context, beerID string, after reviews.Review, limit int) ([]reviews.Review, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListReviewsAfter(ctx, beerID, after, limit)
+}
+
+// ListReviewRevisions returns the history of a review.
+func (s *Store) ListReviewRevisions(ctx context.Context, id string) ([]reviews.Revision, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListReviewRevisions(ctx, id)
+}
+
+// GetBeerDetail returns the beer with the given ID along with the aggregated
+// data of its reviews and up to latest of its most recent reviews.
+func (s *Store) GetBeerDetail(ctx context.Context, id string, latest int) (*listing.BeerDetail, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetBeerDetail(ctx, id, latest)
+}
+
+// CreateUser stores a new user. The handle and the email must not belong to
+// another user.
+func (s *Store) CreateUser(ctx context.Context, u users.User) error {
+	su := memory.NewStoredUser(u)
+	return s.commit(ctx, record{Op: opCreateUser, User: &su})
+}
+
+// GetUser returns the user with the given ID.
+func (s *Store) GetUser(ctx context.Context, id string) (*users.User, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetUser(ctx, id)
+}
+
+// UpdateUser stores the changes of a user. The handle and the email must not
+// belong to another user.
+func (s *Store) UpdateUser(ctx context.Context, u users.User) error {
+	su := memory.NewStoredUser(u)
+	return s.commit(ctx, record{Op: opUpdateUser, User: &su})
+}
+
+// ClaimNotifications returns the pending notifications due at the given time,
+// postponing them by the lease. Claims aren't written to the log, the
+// notifications claimed before a restart are due again after it.
+func (s *Store) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notifications.Notification, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ClaimNotifications(ctx, now, lease, limit)
+}
+
+// UpdateNotification stores the result of a delivery attempt.
+func (s *Store) UpdateNotification(ctx context.Context, n notifications.Notification) error {
+	return s.commit(ctx, record{Op: opUpdateNotification, Notification: &n})
+}
+
+// CreateSubscription stores a new subscription.
+func (s *Store) CreateSubscription(ctx context.Context, sub webhooks.Subscription) error {
+	return s.commit(ctx, record{Op: opCreateSubscription, Subscription: &sub})
+}
+
+// GetSubscription returns the subscription with the given ID.
+func (s *Store) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetSubscription(ctx, id)
+}
+
+// ListSubscriptions returns every subscription, from the oldest to the most
+// recent.
+func (s *Store) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListSubscriptions(ctx)
+}
+
+// DeleteSubscription deletes a subscription and its deliveries.
+func (s *Store) DeleteSubscription(ctx context.Context, id string) error {
+	return s.commit(ctx, record{Op: opDeleteSubscription, ID: id})
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhooks.Delivery, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+// GetDelivery returns the delivery with the given ID.
+func (s *Store) GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.GetDelivery(ctx, id)
+}
+
+// CreateDelivery queues a new delivery.
+func (s *Store) CreateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	return s.commit(ctx, record{Op: opCreateDelivery, Delivery: &d})
+}
+
+// ClaimDeliveries returns the pending deliveries due at the given time,
+// postponing them by the lease. Like the notifications, claims aren't written
+// to the log.
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Delivery, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+
+	return s.mem.ClaimDeliveries(ctx, now, lease, limit)
+}
+
+// UpdateDelivery stores the result of a delivery attempt.
+func (s *Store) UpdateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	return s.commit(ctx, record{Op: opUpdateDelivery, Delivery: &d})
+}
+
+// =============================================================================
+
+// commit applies the change and writes it to the log, returning once it's
+// on disk. Only the changes applied without error are written, so replaying
+// the log is expected to succeed. When the log fails, the change is already
+// applied in memory, so the reads fail too from then on.
+func (s *Store) commit(ctx context.Context, rec record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	if err := s.apply(ctx, rec); err != nil {
+		return err
+	}
+
+	rec.Seq = s.seq + 1
+	if err := writeRecord(s.log, rec); err != nil {
+		return s.fail(fmt.Errorf("writing log: %w", err))
+	}
+	if err := s.log.Sync(); err != nil {
+		return s.fail(fmt.Errorf("syncing log: %w", err))
+	}
+
+	s.seq = rec.Seq
+	s.pending++
+
+	// The change is already on disk, so a failed snapshot is retried on the
+	// next change instead of failing this one.
+	if s.pending >= s.snapshotEvery {
+		s.snapshot()
+	}
+
+	return nil
+}
+
+// fail marks the log as no longer trusted, failing the following changes and
+// reads until the store is opened again.
+func (s *Store) fail(err error) error {
+	s.err = err
+	s.failed.Store(&err)
+	return err
+}
+
+// check returns the error of the log once it failed, so the reads don't serve
+// a change that may not be on disk.
+func (s *Store) check() error {
+	if err := s.failed.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// apply applies the change to the data kept in memory.
+func (s *Store) apply(ctx context.Context, rec record) error {
+	switch {
+	case rec.Op == opCreateBeer && rec.Beer != nil:
+		return s.mem.CreateBeer(ctx, *rec.Beer, eventOf(rec))
+	case rec.Op == opUpdateBeer && rec.Beer != nil:
+		return s.mem.UpdateBeer(ctx, *rec.Beer, rec.Version)
+	case rec.Op == opDeleteBeer:
+		return s.mem.DeleteBeer(ctx, rec.ID, rec.Version)
+	case rec.Op == opCreateBrewery && rec.Brewery != nil:
+		return s.mem.CreateBrewery(ctx, *rec.Brewery)
+	case rec.Op == opCreateReview && rec.Review != nil && rec.Notification != nil:
+		return s.mem.CreateReview(ctx, *rec.Review, *rec.Notification, eventOf(rec))
+	case rec.Op == opUpdateReview && rec.Review != nil:
+		return s.mem.UpdateReview(ctx, *rec.Review)
+	case rec.Op == opDeleteReview:
+		return s.mem.DeleteReview(ctx, rec.ID)
+	case rec.Op == opCreateUser && rec.User != nil:
+		return s.mem.CreateUser(ctx, rec.User.Restore())
+	case rec.Op == opUpdateUser && rec.User != nil:
+		return s.mem.UpdateUser(ctx, rec.User.Restore())
+	case rec.Op == opUpdateNotification && rec.Notification != nil:
+		return s.mem.UpdateNotification(ctx, *rec.Notification)
+	case rec.Op == opCreateSubscription && rec.Subscription != nil:
+		return s.mem.CreateSubscription(ctx, *rec.Subscription)
+	case rec.Op == opDeleteSubscription:
+		return s.mem.DeleteSubscription(ctx, rec.ID)
+	case rec.Op == opCreateDelivery && rec.Delivery != nil:
+		return s.mem.CreateDelivery(ctx, *rec.Delivery)
+	case rec.Op == opUpdateDelivery && rec.Delivery != nil:
+		return s.mem.UpdateDelivery(ctx, *rec.Delivery)
+	}
+
+	return fmt.Errorf("unknown operation %q", rec.Op)
+}
+
+// eventOf returns the webhook event of the change. The changes written before
+// the webhooks existed have none, and an event without type reaches no
+// subscription.
+func eventOf(rec record) webhooks.Event {
+	if rec.Event == nil {
+		return webhooks.Event{}
+	}
+	return *rec.Event
+}
+
+// recover loads the snapshot and replays the log on top of it. The log is
+// truncated at the first record that can't be read, which is the one being
+// written when the process died.
+func (s *Store) recover() error {
+	if err := s.loadSnapshot(); err != nil {
+		return fmt.Errorf("loading snapshot: %w", err)
+	}
+	snapSeq := s.seq
+
+	f, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening log: %w", err)
+	}
+	s.log = f
+
+	var (
+		ctx        = context.Background()
+		r          = bufio.NewReader(f)
+		offset     int64
+		superseded = make(map[string]bool)
+	)
+
+	for {
+		var rec record
+		n, err := readRecord(r, &rec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errCorrupted) {
+			if err := f.Truncate(offset); err != nil {
+				return fmt.Errorf("truncating log: %w", err)
+			}
+			if err := f.Sync(); err != nil {
+				return fmt.Errorf("syncing log: %w", err)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading log: %w", err)
+		}
+		offset += n
+
+		// The log wasn't emptied after the last snapshot was written.
+		if rec.Seq <= snapSeq {
+			continue
+		}
+
+		if rec.Seq != s.seq+1 {
+			return fmt.Errorf("replaying log: expected change %d, got %d", s.seq+1, rec.Seq)
+		}
+		if err := s.adoptAuthor(ctx, rec); err != nil {
+			return fmt.Errorf("replaying change %d: %w", rec.Seq, err)
+		}
+		if err := s.adoptBrewery(ctx, rec); err != nil {
+			return fmt.Errorf("replaying change %d: %w", rec.Seq, err)
+		}
+		if err := s.supersedeReview(ctx, rec, superseded); err != nil {
+			return fmt.Errorf("replaying change %d: %w", rec.Seq, err)
+		}
+		if !changesSuperseded(rec, superseded) {
+			if err := s.apply(ctx, rec); err != nil {
+				return fmt.Errorf("replaying change %d: %w", rec.Seq, err)
+			}
+		}
+
+		s.seq = rec.Seq
+		s.pending++
+	}
+
+	if s.format < snapshotFormat && s.seq > 0 {
+		if err := s.upgrade(); err != nil {
+			return fmt.Errorf("upgrading data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// upgrade brings the data written in an older format up to date and writes it
+// to a snapshot right away, so it's upgraded only once. Format 1 clamps the
+// scores given before the rating scale was enforced to its bounds, as the
+// database migration does.
+func (s *Store) upgrade() error {
+	d := s.mem.Dump()
+	for i := range d.Reviews {
+		d.Reviews[i].Score = reviews.DefaultScale.Clamp(d.Reviews[i].Score)
+	}
+	for i := range d.Revisions {
+		d.Revisions[i].Score = reviews.DefaultScale.Clamp(d.Revisions[i].Score)
+	}
+
+	if err := s.mem.Load(d); err != nil {
+		return err
+	}
+
+	return s.snapshot()
+}
+
+// adoptAuthor creates the legacy user of the author of a review logged before
+// the users were registered, so the review is replayed as it was accepted.
+func (s *Store) adoptAuthor(ctx context.Context, rec record) error {
+	if rec.Op != opCreateReview || rec.Review == nil {
+		return nil
+	}
+
+	_, err := s.mem.GetUser(ctx, rec.Review.UserID)
+	if !errors.Is(err, users.ErrNotFound) {
+		return err
+	}
+
+	return s.mem.CreateUser(ctx, users.Legacy(rec.Review.UserID, rec.Review.CreatedAt))
+}
+
+// supersedeReview deletes the previous review of the beer by the author of a
+// review logged before a user could review a beer only once, so the most
+// recent review stands as it does on the database migration. The IDs of the
+// deleted reviews are added to superseded.
+func (s *Store) supersedeReview(ctx context.Context, rec record, superseded map[string]bool) error {
+	if rec.Op != opCreateReview || rec.Review == nil {
+		return nil
+	}
+
+	prev, err := s.mem.GetUserReview(ctx, rec.Review.BeerID, rec.Review.UserID)
+	if errors.Is(err, reviews.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	superseded[prev.ID] = true
+	return s.mem.DeleteReview(ctx, prev.ID)
+}
+
+// changesSuperseded reports whether the change updates or deletes a review
+// deleted by supersedeReview.
+func changesSuperseded(rec record, superseded map[string]bool) bool {
+	switch {
+	case rec.Op == opUpdateReview && rec.Review != nil:
+		return superseded[rec.Review.ID]
+	case rec.Op == opDeleteReview:
+		return superseded[rec.ID]
+	}
+	return false
+}
+
+// adoptBrewery links a beer logged while the brewery was a free text to its
+// legacy brewery, creating the brewery when it's the first beer logged for it,
+// so the beer is replayed as it was accepted. Like the database migration, a
+// beer whose name is taken by another spelling of its brewery is renamed after
+// its ID.
+func (s *Store) adoptBrewery(ctx context.Context, rec record) error {
+	if (rec.Op != opCreateBeer && rec.Op != opUpdateBeer) || rec.Beer == nil || rec.Beer.BreweryID != "" {
+		return nil
+	}
+
+	id := breweries.LegacyID(rec.Beer.Brewery)
+
+	b, err := s.mem.GetBrewery(ctx, id)
+	if errors.Is(err, breweries.ErrNotFound) {
+		legacy := breweries.Legacy(rec.Beer.Brewery, rec.Beer.CreatedAt)
+		b, err = &legacy, s.mem.CreateBrewery(ctx, legacy)
+	}
+	if err != nil {
+		return err
+	}
+
+	rec.Beer.BreweryID = b.ID
+	rec.Beer.Brewery = b.Name
+
+	// The beer being updated may already hold its name.
+	if rec.Op == opUpdateBeer {
+		stored, err := s.mem.GetBeer(ctx, rec.Beer.ID)
+		if err == nil && stored.BreweryID == b.ID && stored.Name == rec.Beer.Name {
+			return nil
+		}
+	}
+
+	exists, err := s.mem.BeerExists(ctx, rec.Beer.Name, b.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		rec.Beer.Name = fmt.Sprintf("%s (%.8s)", rec.Beer.Name, rec.Beer.ID)
+	}
+
+	return nil
+}
+
+// loadSnapshot loads the snapshot, if there's one.
+func (s *Store) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(s.dir, snapshotFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if _, err := readRecord(bufio.NewReader(f), &snap); err != nil {
+		return err
+	}
+
+	if err := s.mem.Load(snap.Data); err != nil {
+		return err
+	}
+	s.seq = snap.Seq
+	s.format = snap.Format
+
+	return nil
+}
+
+// snapshot replaces the snapshot with the current content of the store and
+// empties the log. The snapshot is written to a temporary file first, so a
+// crash never leaves a partial snapshot behind.
+func (s *Store) snapshot() error {
+	tmp := filepath.Join(s.dir, snapshotFile+".tmp")
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	err = writeRecord(w, snapshot{Seq: s.seq, Format: snapshotFormat, Data: s.mem.Dump()})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return fmt.Errorf("syncing data dir: %w", err)
+	}
+
+	// From now on the records in the log are ignored when recovering, as they
+	// are older than the snapshot.
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("truncating log: %w", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("syncing log: %w", err)
+	}
+
+	s.pending = 0
+	s.format = snapshotFormat
+
+	return nil
+}
+
+// release closes the files and unlocks the data directory.
+func (s *Store) release() error {
+	var err error
+	if s.log != nil {
+		err = s.log.Close()
+	}
+
+	return errors.Join(err, unlockFD(s.lock), s.lock.Close())
+}
+
+// syncDir syncs the directory, so the files renamed into it are kept.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
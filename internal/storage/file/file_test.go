@@ -0,0 +1,320 @@
+package file_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/storage/file"
+	"github.com/phbpx/gobeer/internal/storage/storagetest"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		// Snapshot often, so the suite also runs across snapshots.
+		return open(t, file.Config{Dir: t.TempDir(), SnapshotEvery: 3})
+	})
+}
+
+func TestRecovery(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	bw := breweries.Brewery{
+		ID:        uuid.NewString(),
+		Name:      "BrewDog",
+		Location:  "Ellon, Scotland",
+		CreatedAt: time.Now().UTC(),
+	}
+	b := beers.Beer{
+		ID:        uuid.NewString(),
+		Name:      "IPA",
+		BreweryID: bw.ID,
+		Brewery:   bw.Name,
+		Style:     "IPA",
+		ABV:       5.5,
+		CreatedAt: time.Now().UTC(),
+		Version:   1,
+	}
+	u := users.User{
+		ID:           uuid.NewString(),
+		Handle:       "hop_head",
+		Email:        "hop.head@example.com",
+		PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5",
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}
+	r := reviews.Review{
+		ID:        uuid.NewString(),
+		BeerID:    b.ID,
+		UserID:    u.ID,
+		Score:     4,
+		Revision:  1,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	sub := webhooks.Subscription{
+		ID:        uuid.NewString(),
+		URL:       "http://localhost/hook",
+		Events:    webhooks.EventTypes,
+		Secret:    "0123456789abcdef",
+		CreatedAt: time.Now().UTC(),
+	}
+	beerAdded := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventBeerAdded, OccurredAt: b.CreatedAt, Beer: &b}
+
+	t.Log("Given the need to keep the data across restarts")
+	{
+		t.Log("\tWhen the data directory is already in use")
+		{
+			s := open(t, file.Config{Dir: dir})
+
+			if _, err := file.Open(file.Config{Dir: dir}); !errors.Is(err, file.ErrLocked) {
+				t.Fatalf("\t\t[ERROR] Should not be able to open the store: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to open the store.")
+
+			if err := s.CreateSubscription(ctx, sub); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the subscription: %v", err)
+			}
+			if err := s.CreateBrewery(ctx, bw); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the brewery: %v", err)
+			}
+			if err := s.CreateBeer(ctx, b, beerAdded); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the beer: %v", err)
+			}
+			if err := s.CreateUser(ctx, u); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the user: %v", err)
+			}
+			n := notifications.Notification{ID: uuid.NewString(), UserID: r.UserID, ReviewID: r.ID, Status: notifications.StatusPending}
+			e := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventReviewCreated, OccurredAt: r.CreatedAt, Review: &r}
+			if err := s.CreateReview(ctx, r, n, e); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review: %v", err)
+			}
+
+			delivered := webhooks.NewDelivery(sub.ID, beerAdded)
+			delivered.Status = webhooks.StatusSucceeded
+			delivered.Attempts = 1
+			if err := s.UpdateDelivery(ctx, delivered); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the delivery: %v", err)
+			}
+
+			// Leave the changes in the log, as if the process had died.
+			crash(t, s, dir)
+		}
+
+		t.Log("\tWhen opening the store after a crash")
+		{
+			s := open(t, file.Config{Dir: dir})
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || got.Score != r.Score {
+				t.Fatalf("\t\t[ERROR] Should replay the log. Got %+v: %v", got, err)
+			}
+
+			if got, err := s.GetUser(ctx, u.ID); err != nil || got.Handle != u.Handle || got.PasswordHash != u.PasswordHash {
+				t.Fatalf("\t\t[ERROR] Should replay the users. Got %+v: %v", got, err)
+			}
+
+			if got, err := s.GetBrewery(ctx, bw.ID); err != nil || got.Location != bw.Location {
+				t.Fatalf("\t\t[ERROR] Should replay the breweries. Got %+v: %v", got, err)
+			}
+
+			// The deliveries queued on replay must be the ones updated later.
+			d, err := s.GetDelivery(ctx, webhooks.NewDelivery(sub.ID, beerAdded).ID)
+			if err != nil || d.Status != webhooks.StatusSucceeded {
+				t.Fatalf("\t\t[ERROR] Should replay the webhook deliveries. Got %+v: %v", d, err)
+			}
+			if list, err := s.ListDeliveries(ctx, sub.ID, 10); err != nil || len(list) != 2 {
+				t.Fatalf("\t\t[ERROR] Should replay the webhook deliveries. Got %+v: %v", list, err)
+			}
+			t.Log("\t\t[OK] Should replay the log.")
+
+			crash(t, s, dir)
+		}
+
+		t.Log("\tWhen the last change was partially written")
+		{
+			f, err := os.OpenFile(filepath.Join(dir, "log"), os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to open the log: %v", err)
+			}
+			if _, err := f.Write([]byte{0, 0, 1, 0, 1, 2}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to write to the log: %v", err)
+			}
+			f.Close()
+
+			s := open(t, file.Config{Dir: dir})
+
+			if _, err := s.GetReview(ctx, r.ID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should keep the complete changes: %v", err)
+			}
+
+			up := r
+			up.Score = 2
+			up.Revision = 2
+			if err := s.UpdateReview(ctx, up); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to change the store: %v", err)
+			}
+			t.Log("\t\t[OK] Should discard the partial change.")
+
+			if err := s.Close(); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to close the store: %v", err)
+			}
+		}
+
+		t.Log("\tWhen opening the store from a snapshot")
+		{
+			s := open(t, file.Config{Dir: dir})
+
+			history, err := s.ListReviewRevisions(ctx, r.ID)
+			if err != nil || len(history) != 2 {
+				t.Fatalf("\t\t[ERROR] Should load the review history. Got %+v: %v", history, err)
+			}
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || got.Score != 2 {
+				t.Fatalf("\t\t[ERROR] Should load the beer score. Got %+v: %v", got, err)
+			}
+
+			if got, err := s.GetUser(ctx, u.ID); err != nil || got.PasswordHash != u.PasswordHash {
+				t.Fatalf("\t\t[ERROR] Should load the password hashes. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should load the snapshot.")
+		}
+	}
+}
+
+func TestUpgradeScores(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	bw := breweries.Brewery{ID: uuid.NewString(), Name: "BrewDog", CreatedAt: time.Now().UTC()}
+	b := beers.Beer{ID: uuid.NewString(), Name: "IPA", BreweryID: bw.ID, Brewery: bw.Name, CreatedAt: time.Now().UTC(), Version: 1}
+
+	aroma, taste := float32(4), float32(3)
+	subScores := reviews.Scores{Aroma: &aroma, Taste: &taste}
+
+	// The first reviews were given before the rating scale was enforced, the
+	// last one is weighted from its sub-scores, between the increments.
+	scores := []struct {
+		review reviews.Review
+		want   float32
+	}{
+		{reviews.Review{Score: -3}, 0},
+		{reviews.Review{Score: 9000}, 5},
+		{reviews.Review{Score: 3.3}, 3.3},
+		{reviews.Review{Score: subScores.Weighted(), SubScores: &subScores}, 3.375},
+	}
+
+	t.Log("Given the need to fix the scores given before the rating scale was enforced")
+	{
+		s := open(t, file.Config{Dir: dir})
+
+		if err := s.CreateBrewery(ctx, bw); err != nil {
+			t.Fatalf("\t\t[ERROR] Should be able to create the brewery: %v", err)
+		}
+		if err := s.CreateBeer(ctx, b, webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventBeerAdded, Beer: &b}); err != nil {
+			t.Fatalf("\t\t[ERROR] Should be able to create the beer: %v", err)
+		}
+		for i := range scores {
+			r := &scores[i].review
+			r.ID, r.BeerID, r.UserID = uuid.NewString(), b.ID, uuid.NewString()
+			r.Revision, r.CreatedAt, r.UpdatedAt = 1, time.Now().UTC(), time.Now().UTC()
+
+			u := users.User{ID: r.UserID, Handle: "user_" + r.UserID[:8], Email: r.UserID + "@example.com", CreatedAt: r.CreatedAt, UpdatedAt: r.CreatedAt}
+			if err := s.CreateUser(ctx, u); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the user: %v", err)
+			}
+			n := notifications.Notification{ID: uuid.NewString(), UserID: r.UserID, ReviewID: r.ID, Status: notifications.StatusPending}
+			e := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventReviewCreated, OccurredAt: r.CreatedAt, Review: r}
+			if err := s.CreateReview(ctx, *r, n, e); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review: %v", err)
+			}
+		}
+
+		// Leave the reviews in the log, with no snapshot written yet.
+		crash(t, s, dir)
+
+		for _, when := range []string{"opening the store for the first time", "opening the store again"} {
+			t.Logf("\tWhen %s", when)
+			{
+				s := open(t, file.Config{Dir: dir})
+
+				var sum float32
+				for _, sc := range scores {
+					got, err := s.GetReview(ctx, sc.review.ID)
+					if err != nil || got.Score != sc.want {
+						t.Fatalf("\t\t[ERROR] Should score the review %v. Got %+v: %v", sc.want, got, err)
+					}
+					sum += sc.want
+				}
+				t.Log("\t\t[OK] Should clamp only the scores out of the bounds.")
+
+				got, err := s.GetBeer(ctx, b.ID)
+				if err != nil || got.Score != sum/float32(len(scores)) {
+					t.Fatalf("\t\t[ERROR] Should score the beer %v. Got %+v: %v", sum/float32(len(scores)), got, err)
+				}
+				t.Log("\t\t[OK] Should score the beer with the fixed scores.")
+
+				if err := s.Close(); err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to close the store: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// =============================================================================
+
+// open opens the store, closing it at the end of the test.
+func open(t *testing.T, cfg file.Config) *file.Store {
+	t.Helper()
+
+	s, err := file.Open(cfg)
+	if err != nil {
+		t.Fatalf("Should be able to open the store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// crash closes the store without taking a snapshot, by restoring the files
+// left behind before closing it.
+func crash(t *testing.T, s *file.Store, dir string) {
+	t.Helper()
+
+	files := map[string][]byte{}
+	for _, name := range []string{"log", "snapshot"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("Should be able to read the %s: %v", name, err)
+		}
+		files[name] = data
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Should be able to close the store: %v", err)
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if data == nil {
+			os.Remove(path)
+			continue
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Should be able to restore the %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// errLockUnsupported is returned on the platforms without file locks.
+var errLockUnsupported = errors.New("file locking is not supported on this platform")
+
+// lockFD fails, as the data directory can't be protected on this platform.
+func lockFD(f *os.File) error {
+	return errLockUnsupported
+}
+
+// unlockFD fails, as the data directory can't be protected on this platform.
+func unlockFD(f *os.File) error {
+	return errLockUnsupported
+}
@@ -0,0 +1,94 @@
+package file
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Set of operations written to the log.
+const (
+	opCreateBeer   = "create_beer"
+	opUpdateBeer   = "update_beer"
+	opDeleteBeer   = "delete_beer"
+	opCreateReview = "create_review"
+	opUpdateReview = "update_review"
+	opDeleteReview = "delete_review"
+
+	opCreateBrewery = "create_brewery"
+
+	opCreateUser = "create_user"
+	opUpdateUser = "update_user"
+
+	opUpdateNotification = "update_notification"
+
+	opCreateSubscription = "create_subscription"
+	opDeleteSubscription = "delete_subscription"
+	opCreateDelivery     = "create_delivery"
+	opUpdateDelivery     = "update_delivery"
+)
+
+// Every record is framed by a header holding the size and the checksum of its
+// payload.
+const (
+	headerSize    = 8
+	maxRecordSize = 256 << 20
+)
+
+// errCorrupted is returned when a record is incomplete or doesn't match its
+// checksum, which happens when the process dies in the middle of a write.
+var errCorrupted = errors.New("corrupted record")
+
+// writeRecord encodes v as JSON and writes it as a single framed record.
+func writeRecord(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+// readRecord reads the next framed record into v, returning its size. It
+// returns io.EOF when there are no more records and errCorrupted when the
+// record can't be trusted.
+func readRecord(r io.Reader, v any) (int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, errCorrupted
+		}
+		return 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return 0, errCorrupted
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, errCorrupted
+		}
+		return 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, errCorrupted
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return 0, errCorrupted
+	}
+
+	return int64(headerSize + len(payload)), nil
+}
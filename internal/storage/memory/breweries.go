@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+)
+
+// CreateBrewery stores a new brewery. Its canonical name must not belong to
+// another brewery.
+func (s *Store) CreateBrewery(ctx context.Context, b breweries.Brewery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.breweries[b.ID]; ok || s.breweryExists(b.Name) {
+		return breweries.ErrAlreadyExists
+	}
+
+	s.breweries[b.ID] = &b
+
+	return nil
+}
+
+// GetBrewery returns the brewery with the given ID.
+func (s *Store) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.breweries[id]
+	if !ok {
+		return nil, breweries.ErrNotFound
+	}
+
+	bc := *b
+	return &bc, nil
+}
+
+// ListBreweries returns every brewery, ordered by name.
+func (s *Store) ListBreweries(ctx context.Context) ([]breweries.Brewery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []breweries.Brewery
+	for _, b := range s.breweries {
+		list = append(list, *b)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+// breweryExists checks if a brewery has the canonical name of the given name.
+func (s *Store) breweryExists(name string) bool {
+	canonical := breweries.Canonical(name)
+	for _, b := range s.breweries {
+		if breweries.Canonical(b.Name) == canonical {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptBreweries links the beers added while the brewery was a free text to
+// their legacy breweries, creating each brewery from its oldest beer. As two
+// spellings of a brewery become the same brewery, the newer of two beers with
+// the same name is renamed after its ID, like the database migration does.
+func adoptBreweries(bws map[string]*breweries.Brewery, bs []*beers.Beer) {
+	sort.Slice(bs, func(i, j int) bool {
+		if !bs[i].CreatedAt.Equal(bs[j].CreatedAt) {
+			return bs[i].CreatedAt.Before(bs[j].CreatedAt)
+		}
+		return bs[i].ID < bs[j].ID
+	})
+
+	seen := make(map[[2]string]bool, len(bs))
+	for _, b := range bs {
+		id := breweries.LegacyID(b.Brewery)
+		if _, ok := bws[id]; !ok {
+			bw := breweries.Legacy(b.Brewery, b.CreatedAt)
+			bws[id] = &bw
+		}
+
+		b.BreweryID = id
+		b.Brewery = bws[id].Name
+
+		key := [2]string{b.Name, id}
+		if seen[key] {
+			b.Name = fmt.Sprintf("%s (%.8s)", b.Name, b.ID)
+		}
+		seen[key] = true
+	}
+}
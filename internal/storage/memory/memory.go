@@ -0,0 +1,1051 @@
+// Package memory provides a storage implementation that keeps the data in
+// memory. It's meant for development and tests, the data is lost when the
+// process exits.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// beer holds a beer along with the aggregates of its reviews, including the
+// aggregates of each dimension rated by the sub-scores.
+type beer struct {
+	beers.Beer
+	reviewCount int
+	scoreSum    float64
+	subCounts   [reviews.NumDimensions]int
+	subSums     [reviews.NumDimensions]float64
+}
+
+// review holds a review along with its history.
+type review struct {
+	reviews.Review
+	revisions []reviews.Revision
+}
+
+// Store provides an in memory implementation of the storage interfaces.
+type Store struct {
+	mu            sync.RWMutex
+	beers         map[string]*beer
+	breweries     map[string]*breweries.Brewery
+	reviews       map[string]*review
+	users         map[string]*users.User
+	notifications map[string]*notifications.Notification
+	subscriptions map[string]*webhooks.Subscription
+	deliveries    map[string]*webhooks.Delivery
+}
+
+// NewStore creates a new, empty, Store instance.
+func NewStore() *Store {
+	return &Store{
+		beers:         make(map[string]*beer),
+		breweries:     make(map[string]*breweries.Brewery),
+		reviews:       make(map[string]*review),
+		users:         make(map[string]*users.User),
+		notifications: make(map[string]*notifications.Notification),
+		subscriptions: make(map[string]*webhooks.Subscription),
+		deliveries:    make(map[string]*webhooks.Delivery),
+	}
+}
+
+// CreateBeer creates a new beer, along with the deliveries of its event.
+func (s *Store) CreateBeer(ctx context.Context, b beers.Beer, e webhooks.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.beers[b.ID]; ok || s.beerExists(b.Name, b.BreweryID, "") {
+		return beers.ErrAlreadyExists
+	}
+
+	if _, ok := s.breweries[b.BreweryID]; !ok {
+		return breweries.ErrNotFound
+	}
+
+	b.Score = 0
+	b.SubScores = nil
+	s.beers[b.ID] = &beer{Beer: b}
+	s.queueDeliveries(e)
+
+	return nil
+}
+
+// BeerExists checks if a beer with the given name and brewery exists.
+func (s *Store) BeerExists(ctx context.Context, name, breweryID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.beerExists(name, breweryID, ""), nil
+}
+
+// GetBeer returns the beer with the given ID.
+func (s *Store) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.beers[id]
+	if !ok {
+		return nil, beers.ErrNotFound
+	}
+
+	bc := b.view()
+	return &bc, nil
+}
+
+// UpdateBeer updates a beer, as long as its stored version is still the
+// given version.
+func (s *Store) UpdateBeer(ctx context.Context, b beers.Beer, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.beers[b.ID]
+	switch {
+	case !ok:
+		return beers.ErrNotFound
+	case stored.Version != version:
+		return beers.ErrVersionMismatch
+	case s.beerExists(b.Name, b.BreweryID, b.ID):
+		return beers.ErrAlreadyExists
+	}
+
+	if _, ok := s.breweries[b.BreweryID]; !ok {
+		return breweries.ErrNotFound
+	}
+
+	stored.Name = b.Name
+	stored.BreweryID = b.BreweryID
+	stored.Brewery = b.Brewery
+	stored.Style = b.Style
+	stored.ABV = b.ABV
+	stored.ABVOutOfRange = b.ABVOutOfRange
+	stored.ShortDesc = b.ShortDesc
+	stored.Version = b.Version
+
+	return nil
+}
+
+// DeleteBeer deletes a beer and its reviews, as long as its stored version is
+// still the given version.
+func (s *Store) DeleteBeer(ctx context.Context, id string, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.beers[id]
+	switch {
+	case !ok:
+		return beers.ErrNotFound
+	case stored.Version != version:
+		return beers.ErrVersionMismatch
+	}
+
+	delete(s.beers, id)
+	for rid, r := range s.reviews {
+		if r.BeerID == id {
+			delete(s.reviews, rid)
+		}
+	}
+
+	return nil
+}
+
+// ListBeers returns the page of beers described by the seek.
+func (s *Store) ListBeers(ctx context.Context, seek listing.Seek) ([]beers.Beer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []beers.Beer
+	for _, b := range s.beers {
+		bv := b.view()
+		if matchFilter(seek.Filter, bv) {
+			list = append(list, bv)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return less(seek.Sort, seek.Desc, cursorOf(list[i]), cursorOf(list[j]))
+	})
+
+	var page []beers.Beer
+	for _, b := range list {
+		if seek.After != nil && !less(seek.Sort, seek.Desc, *seek.After, cursorOf(b)) {
+			continue
+		}
+		if len(page) == seek.Limit {
+			break
+		}
+		page = append(page, b)
+	}
+
+	return page, nil
+}
+
+// TopBeers returns the beers described by the ranking, from the highest
+// ranked.
+func (s *Store) TopBeers(ctx context.Context, r listing.Ranking) ([]beers.Beer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type ranked struct {
+		*beer
+		weighted float32
+	}
+
+	var list []ranked
+	for _, b := range s.beers {
+		if b.reviewCount < r.MinReviews ||
+			(r.Style != "" && b.Style != r.Style) ||
+			(r.BreweryID != "" && b.BreweryID != r.BreweryID) {
+			continue
+		}
+		list = append(list, ranked{b, r.Prior.Average(b.scoreSum, b.reviewCount)})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		switch {
+		case list[i].weighted != list[j].weighted:
+			return list[i].weighted > list[j].weighted
+		case list[i].reviewCount != list[j].reviewCount:
+			return list[i].reviewCount > list[j].reviewCount
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	if len(list) > r.Limit {
+		list = list[:r.Limit]
+	}
+
+	bs := make([]beers.Beer, len(list))
+	for i, b := range list {
+		bs[i] = b.view()
+		bs[i].WeightedScore = &list[i].weighted
+	}
+
+	return bs, nil
+}
+
+// SearchBeers returns the beers matching the search terms, ranked by
+// relevance, along with the style and brewery facets.
+func (s *Store) SearchBeers(ctx context.Context, q listing.SearchQuery) (listing.SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := words(q.Q)
+
+	var (
+		hits      []listing.SearchHit
+		styles    = map[string]int{}
+		breweries = map[string]int{}
+	)
+
+	for _, b := range s.beers {
+		rank := searchRank(terms, b.Beer)
+		if rank == 0 {
+			continue
+		}
+
+		hits = append(hits, listing.SearchHit{Beer: b.view(), Rank: rank})
+		styles[b.Style]++
+		breweries[b.Brewery]++
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+
+	return listing.SearchResult{
+		Beers: hits,
+		Facets: listing.Facets{
+			Styles:    facets(styles),
+			Breweries: facets(breweries),
+		},
+	}, nil
+}
+
+// CreateReview creates a new review, along with its first revision, its
+// notification and the deliveries of its event, and adds it to the beer score.
+func (s *Store) CreateReview(ctx context.Context, r reviews.Review, n notifications.Notification, e webhooks.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.beers[r.BeerID]
+	if !ok {
+		return beers.ErrNotFound
+	}
+
+	if _, ok := s.users[r.UserID]; !ok {
+		return users.ErrNotFound
+	}
+
+	if s.userReview(r.BeerID, r.UserID) != nil {
+		return reviews.ErrAlreadyReviewed
+	}
+
+	b.reviewCount++
+	b.scoreSum += float64(r.Score)
+	b.addSubScores(r.SubScores, 1)
+
+	s.reviews[r.ID] = &review{
+		Review:    r,
+		revisions: []reviews.Revision{revisionOf(r)},
+	}
+	s.notifications[n.ID] = &n
+	s.queueDeliveries(e)
+
+	return nil
+}
+
+// GetReview returns the review with the given ID.
+func (s *Store) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.reviews[id]
+	if !ok {
+		return nil, reviews.ErrNotFound
+	}
+
+	rc := r.Review
+	return &rc, nil
+}
+
+// GetUserReview returns the review of a beer by a user.
+func (s *Store) GetUserReview(ctx context.Context, beerID, userID string) (*reviews.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := s.userReview(beerID, userID)
+	if r == nil {
+		return nil, reviews.ErrNotFound
+	}
+
+	rc := r.Review
+	return &rc, nil
+}
+
+// UpdateReview stores the current revision of a review, appends it to the
+// review history and replaces the previous score in the beer score.
+func (s *Store) UpdateReview(ctx context.Context, r reviews.Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.reviews[r.ID]
+	if !ok {
+		return reviews.ErrNotFound
+	}
+
+	if stored.Revision != r.Revision-1 {
+		return reviews.ErrRevisionConflict
+	}
+
+	if b, ok := s.beers[stored.BeerID]; ok {
+		b.scoreSum += float64(r.Score) - float64(stored.Score)
+		b.addSubScores(stored.SubScores, -1)
+		b.addSubScores(r.SubScores, 1)
+	}
+
+	stored.Score = r.Score
+	stored.SubScores = r.SubScores
+	stored.Comment = r.Comment
+	stored.Revision = r.Revision
+	stored.UpdatedAt = r.UpdatedAt
+	stored.revisions = append(stored.revisions, revisionOf(stored.Review))
+
+	return nil
+}
+
+// DeleteReview deletes a review and its history and removes it from the beer
+// score.
+func (s *Store) DeleteReview(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.reviews[id]
+	if !ok {
+		return reviews.ErrNotFound
+	}
+
+	if b, ok := s.beers[r.BeerID]; ok {
+		b.reviewCount--
+		b.scoreSum -= float64(r.Score)
+		b.addSubScores(r.SubScores, -1)
+	}
+
+	delete(s.reviews, id)
+
+	return nil
+}
+
+// ListReviews returns the reviews of a beer, from the most recent to the
+// oldest.
+func (s *Store) ListReviews(ctx context.Context, id string) ([]reviews.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listReviews(id), nil
+}
+
+// ListReviewsAfter returns up to limit reviews created after the given one,
+// from the oldest to the most recent. The reviews of every beer are returned
+// when the beer ID is empty.
+func (s *Store) ListReviewsAfter(ctx context.Context, beerID string, after reviews.Review, limit int) ([]reviews.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []reviews.Review
+	for _, r := range s.reviews {
+		if beerID != "" && r.BeerID != beerID {
+			continue
+		}
+		if r.CreatedAt.Before(after.CreatedAt) || (r.CreatedAt.Equal(after.CreatedAt) && r.ID <= after.ID) {
+			continue
+		}
+		list = append(list, r.Review)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+// ListReviewRevisions returns the history of a review.
+func (s *Store) ListReviewRevisions(ctx context.Context, id string) ([]reviews.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.reviews[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return append([]reviews.Revision(nil), r.revisions...), nil
+}
+
+// GetBeerDetail returns the beer with the given ID along with the aggregated
+// data of its reviews and up to latest of its most recent reviews.
+func (s *Store) GetBeerDetail(ctx context.Context, id string, latest int) (*listing.BeerDetail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.beers[id]
+	if !ok {
+		return nil, beers.ErrNotFound
+	}
+
+	rs := s.listReviews(id)
+
+	d := listing.BeerDetail{
+		Beer:          b.view(),
+		Reviews:       reviewStats(rs),
+		LatestReviews: rs,
+	}
+	if len(rs) > latest {
+		d.LatestReviews = rs[:latest]
+	}
+
+	return &d, nil
+}
+
+// ClaimNotifications returns the pending notifications due at the given time,
+// postponing them by the lease.
+func (s *Store) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notifications.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*notifications.Notification
+	for _, n := range s.notifications {
+		if n.Status == notifications.StatusPending && !n.NextAttemptAt.After(now) {
+			due = append(due, n)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	list := make([]notifications.Notification, len(due))
+	for i, n := range due {
+		n.NextAttemptAt = now.Add(lease)
+		list[i] = *n
+	}
+
+	return list, nil
+}
+
+// UpdateNotification stores the result of a delivery attempt.
+func (s *Store) UpdateNotification(ctx context.Context, n notifications.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notifications[n.ID]; !ok {
+		return notifications.ErrNotFound
+	}
+
+	s.notifications[n.ID] = &n
+
+	return nil
+}
+
+// Dump defines the whole content of a store.
+type Dump struct {
+	Beers         []beers.Beer                 `json:"beers"`
+	Breweries     []breweries.Brewery          `json:"breweries"`
+	Reviews       []reviews.Review             `json:"reviews"`
+	Revisions     []reviews.Revision           `json:"revisions"`
+	Users         []StoredUser                 `json:"users"`
+	Notifications []notifications.Notification `json:"notifications"`
+	Subscriptions []webhooks.Subscription      `json:"subscriptions"`
+	Deliveries    []webhooks.Delivery          `json:"deliveries"`
+}
+
+// StoredUser defines a user as it's stored, along with the password hash that
+// is never encoded with the user itself.
+type StoredUser struct {
+	users.User
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// NewStoredUser returns the stored form of a user.
+func NewStoredUser(u users.User) StoredUser {
+	return StoredUser{User: u, PasswordHash: u.PasswordHash}
+}
+
+// Restore returns the user stored, with its password hash.
+func (su StoredUser) Restore() users.User {
+	u := su.User
+	u.PasswordHash = su.PasswordHash
+	return u
+}
+
+// Dump returns the whole content of the store.
+func (s *Store) Dump() Dump {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var d Dump
+	for _, b := range s.beers {
+		d.Beers = append(d.Beers, b.view())
+	}
+	for _, b := range s.breweries {
+		d.Breweries = append(d.Breweries, *b)
+	}
+	for _, r := range s.reviews {
+		d.Reviews = append(d.Reviews, r.Review)
+		d.Revisions = append(d.Revisions, r.revisions...)
+	}
+	for _, u := range s.users {
+		d.Users = append(d.Users, NewStoredUser(*u))
+	}
+	for _, n := range s.notifications {
+		d.Notifications = append(d.Notifications, *n)
+	}
+	for _, sub := range s.subscriptions {
+		d.Subscriptions = append(d.Subscriptions, *sub)
+	}
+	for _, dl := range s.deliveries {
+		d.Deliveries = append(d.Deliveries, *dl)
+	}
+
+	return d
+}
+
+// Load replaces the content of the store with the content of the dump. The
+// review aggregates are computed from the reviews. The authors of the reviews
+// dumped before the users were registered are loaded as legacy users, and the
+// beers dumped before the breweries were added are linked to legacy breweries.
+// When a user reviewed a beer more than once, only the most recent review is
+// loaded, along with its history.
+func (s *Store) Load(d Dump) error {
+	bws := make(map[string]*breweries.Brewery, len(d.Breweries))
+	for _, b := range d.Breweries {
+		b := b
+		bws[b.ID] = &b
+	}
+
+	var legacy []*beers.Beer
+	bs := make(map[string]*beer, len(d.Beers))
+	for _, b := range d.Beers {
+		b.Score = 0
+		b.SubScores = nil
+		bs[b.ID] = &beer{Beer: b}
+
+		if b.BreweryID == "" {
+			legacy = append(legacy, &bs[b.ID].Beer)
+		}
+	}
+	adoptBreweries(bws, legacy)
+
+	for _, b := range bs {
+		if _, ok := bws[b.BreweryID]; !ok {
+			return fmt.Errorf("beer[id=%s]: %w", b.ID, breweries.ErrNotFound)
+		}
+	}
+
+	us := make(map[string]*users.User, len(d.Users))
+	for _, su := range d.Users {
+		u := su.Restore()
+		us[u.ID] = &u
+	}
+
+	latest := make(map[[2]string]reviews.Review, len(d.Reviews))
+	for _, r := range d.Reviews {
+		key := [2]string{r.BeerID, r.UserID}
+		if l, ok := latest[key]; !ok || newer(r, l) {
+			latest[key] = r
+		}
+	}
+
+	rs := make(map[string]*review, len(d.Reviews))
+	dropped := make(map[string]bool)
+	orphans := make(map[string]time.Time)
+	for _, r := range d.Reviews {
+		if latest[[2]string{r.BeerID, r.UserID}].ID != r.ID {
+			dropped[r.ID] = true
+			continue
+		}
+
+		b, ok := bs[r.BeerID]
+		if !ok {
+			return fmt.Errorf("review[id=%s]: %w", r.ID, beers.ErrNotFound)
+		}
+
+		if _, ok := us[r.UserID]; !ok {
+			if at, ok := orphans[r.UserID]; !ok || r.CreatedAt.Before(at) {
+				orphans[r.UserID] = r.CreatedAt
+			}
+		}
+
+		b.reviewCount++
+		b.scoreSum += float64(r.Score)
+		b.addSubScores(r.SubScores, 1)
+		rs[r.ID] = &review{Review: r}
+	}
+
+	for id, at := range orphans {
+		u := users.Legacy(id, at)
+		us[id] = &u
+	}
+
+	for _, rev := range d.Revisions {
+		if dropped[rev.ReviewID] {
+			continue
+		}
+
+		r, ok := rs[rev.ReviewID]
+		if !ok {
+			return fmt.Errorf("revision[review_id=%s]: %w", rev.ReviewID, reviews.ErrNotFound)
+		}
+		r.revisions = append(r.revisions, rev)
+	}
+
+	ns := make(map[string]*notifications.Notification, len(d.Notifications))
+	for _, n := range d.Notifications {
+		n := n
+		ns[n.ID] = &n
+	}
+
+	subs := make(map[string]*webhooks.Subscription, len(d.Subscriptions))
+	for _, sub := range d.Subscriptions {
+		sub := sub
+		subs[sub.ID] = &sub
+	}
+
+	dls := make(map[string]*webhooks.Delivery, len(d.Deliveries))
+	for _, dl := range d.Deliveries {
+		dl := dl
+		if _, ok := subs[dl.SubscriptionID]; !ok {
+			return fmt.Errorf("delivery[id=%s]: %w", dl.ID, webhooks.ErrNotFound)
+		}
+		dls[dl.ID] = &dl
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.beers = bs
+	s.breweries = bws
+	s.reviews = rs
+	s.users = us
+	s.notifications = ns
+	s.subscriptions = subs
+	s.deliveries = dls
+
+	return nil
+}
+
+// =============================================================================
+
+// view returns the beer with its current score and sub-scores.
+func (b *beer) view() beers.Beer {
+	bv := b.Beer
+	bv.ReviewCount = b.reviewCount
+	bv.ScoreSum = b.scoreSum
+	bv.Score = 0
+	if b.reviewCount > 0 {
+		bv.Score = float32(b.scoreSum / float64(b.reviewCount))
+	}
+
+	var (
+		avgs  [reviews.NumDimensions]*float32
+		rated bool
+	)
+	for i, count := range b.subCounts {
+		if count > 0 {
+			avg := float32(b.subSums[i] / float64(count))
+			avgs[i] = &avg
+			rated = true
+		}
+	}
+
+	bv.SubScores = nil
+	if rated {
+		sub := reviews.NewScores(avgs)
+		bv.SubScores = &sub
+	}
+
+	return bv
+}
+
+// addSubScores adds the sub-scores of a review to the aggregates of the beer,
+// or removes them when the sign is negative.
+func (b *beer) addSubScores(s *reviews.Scores, sign int) {
+	if s == nil {
+		return
+	}
+
+	for i, v := range s.Dimensions() {
+		if v != nil {
+			b.subCounts[i] += sign
+			b.subSums[i] += float64(sign) * float64(*v)
+		}
+	}
+}
+
+// beerExists checks if another beer, other than the given ID, has the given
+// name and brewery.
+func (s *Store) beerExists(name, breweryID, id string) bool {
+	for _, b := range s.beers {
+		if b.ID != id && b.Name == name && b.BreweryID == breweryID {
+			return true
+		}
+	}
+	return false
+}
+
+// userReview returns the review of a beer by a user, or nil when the user
+// didn't review the beer.
+func (s *Store) userReview(beerID, userID string) *review {
+	for _, r := range s.reviews {
+		if r.BeerID == beerID && r.UserID == userID {
+			return r
+		}
+	}
+	return nil
+}
+
+// listReviews returns the reviews of a beer, from the most recent to the
+// oldest.
+func (s *Store) listReviews(id string) []reviews.Review {
+	var list []reviews.Review
+	for _, r := range s.reviews {
+		if r.BeerID == id {
+			list = append(list, r.Review)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return newer(list[i], list[j])
+	})
+
+	return list
+}
+
+// newer reports whether the review a was created after the review b, breaking
+// the ties by the ID.
+func newer(a, b reviews.Review) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// reviewStats returns the aggregated data of the reviews.
+func reviewStats(rs []reviews.Review) reviews.Stats {
+	var (
+		stats   reviews.Stats
+		buckets = map[int]int{}
+	)
+
+	for _, r := range rs {
+		r := r
+
+		stats.Count++
+		buckets[int(math.Floor(float64(r.Score)))]++
+
+		if stats.FirstAt == nil || r.CreatedAt.Before(*stats.FirstAt) {
+			stats.FirstAt = &r.CreatedAt
+		}
+		if stats.LastAt == nil || r.CreatedAt.After(*stats.LastAt) {
+			stats.LastAt = &r.CreatedAt
+		}
+	}
+
+	for score, count := range buckets {
+		stats.Distribution = append(stats.Distribution, reviews.Bucket{Score: score, Count: count})
+	}
+
+	sort.Slice(stats.Distribution, func(i, j int) bool {
+		return stats.Distribution[i].Score < stats.Distribution[j].Score
+	})
+
+	return stats
+}
+
+// revisionOf returns the current revision of a review.
+func revisionOf(r reviews.Review) reviews.Revision {
+	return reviews.Revision{
+		ReviewID:  r.ID,
+		Revision:  r.Revision,
+		Score:     r.Score,
+		SubScores: r.SubScores,
+		Comment:   r.Comment,
+		CreatedAt: r.UpdatedAt,
+	}
+}
+
+// matchFilter checks if the beer matches the filter.
+func matchFilter(f listing.Filter, b beers.Beer) bool {
+	switch {
+	case f.Style != "" && b.Style != f.Style:
+		return false
+	case f.Brewery != "" && b.Brewery != f.Brewery:
+		return false
+	case f.BreweryID != "" && b.BreweryID != f.BreweryID:
+		return false
+	case f.MinABV != nil && b.ABV < *f.MinABV:
+		return false
+	case f.MaxABV != nil && b.ABV > *f.MaxABV:
+		return false
+	case f.MinScore != nil && b.Score < *f.MinScore:
+		return false
+	case f.CreatedAfter != nil && !b.CreatedAt.After(*f.CreatedAfter):
+		return false
+	}
+	return true
+}
+
+// cursorOf returns the cursor pointing to the beer.
+func cursorOf(b beers.Beer) listing.Cursor {
+	return listing.Cursor{
+		ID:        b.ID,
+		Name:      b.Name,
+		ABV:       b.ABV,
+		Score:     b.Score,
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+// less reports whether the position x comes before the position y in the
+// given order, using the ID to break ties.
+func less(sortKey string, desc bool, x, y listing.Cursor) bool {
+	cmp := 0
+	switch sortKey {
+	case listing.SortName:
+		cmp = strings.Compare(x.Name, y.Name)
+	case listing.SortABV:
+		cmp = compareFloat(x.ABV, y.ABV)
+	case listing.SortScore:
+		cmp = compareFloat(x.Score, y.Score)
+	case listing.SortCreatedAt:
+		cmp = x.CreatedAt.Compare(y.CreatedAt)
+	}
+
+	if cmp == 0 {
+		cmp = strings.Compare(x.ID, y.ID)
+	}
+
+	if desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// compareFloat compares two floats like strings.Compare.
+func compareFloat(a, b float32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// words splits the text into lower case words.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchRank returns the relevance of a beer for the search terms, or zero
+// when it does not match. Every term must be a prefix of a word of the beer,
+// otherwise the terms must be similar to the words of its name, brewery or
+// style to tolerate typos.
+func searchRank(terms []string, b beers.Beer) float32 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	// Weights of the fields, from the most to the least relevant.
+	fields := []struct {
+		words  []string
+		weight float32
+	}{
+		{words(b.Name), 1},
+		{words(b.Brewery), 0.4},
+		{words(b.Style), 0.4},
+		{words(b.ShortDesc), 0.2},
+	}
+
+	var rank float32
+	for _, t := range terms {
+		var best float32
+		for _, f := range fields {
+			for _, w := range f.words {
+				if strings.HasPrefix(w, t) && f.weight > best {
+					best = f.weight
+				}
+			}
+		}
+
+		if best == 0 {
+			rank = 0
+			break
+		}
+		rank += best
+	}
+
+	if rank > 0 {
+		return rank / float32(len(terms))
+	}
+
+	// Fall back to similar words.
+	for _, t := range terms {
+		similar := false
+		for _, f := range fields[:3] {
+			for _, w := range f.words {
+				if similarWords(t, w) {
+					similar = true
+				}
+			}
+		}
+
+		if !similar {
+			return 0
+		}
+	}
+
+	return 0.1
+}
+
+// similarWords checks if the term is at most one edit apart from the word
+// or from one of its prefixes. Short terms are never similar.
+func similarWords(term, word string) bool {
+	t, w := []rune(term), []rune(word)
+	if len(t) < 4 {
+		return false
+	}
+	if len(w) > len(t)+1 {
+		w = w[:len(t)+1]
+	}
+
+	// Levenshtein distance with a single row, at the end row[j] holds the
+	// distance between the term and the first j runes of the word.
+	row := make([]int, len(w)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(t); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(w); j++ {
+			cost := 1
+			if t[i-1] == w[j-1] {
+				cost = 0
+			}
+
+			cur := prev + cost
+			if row[j]+1 < cur {
+				cur = row[j] + 1
+			}
+			if row[j-1]+1 < cur {
+				cur = row[j-1] + 1
+			}
+
+			prev, row[j] = row[j], cur
+		}
+	}
+
+	for j := len(t) - 1; j <= len(w); j++ {
+		if row[j] <= 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// facets converts the counts to facets, ordered by count.
+func facets(counts map[string]int) []listing.Facet {
+	var list []listing.Facet
+	for v, c := range counts {
+		list = append(list, listing.Facet{Value: v, Count: c})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Value < list[j].Value
+	})
+
+	return list
+}
@@ -0,0 +1,129 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/storage/memory"
+	"github.com/phbpx/gobeer/internal/storage/storagetest"
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return memory.NewStore()
+	})
+}
+
+func TestLoadLegacyBreweries(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().UTC()
+
+	// Beers dumped while the brewery was a free text.
+	d := memory.Dump{
+		Beers: []beers.Beer{
+			{ID: "00000000-0000-0000-0000-000000000002", Name: "IPA", Brewery: "brewdog ", CreatedAt: start.Add(time.Minute), Version: 1},
+			{ID: "00000000-0000-0000-0000-000000000001", Name: "IPA", Brewery: "BrewDog", CreatedAt: start, Version: 1},
+			{ID: "00000000-0000-0000-0000-000000000003", Name: "Pale", Brewery: "Brooklyn", CreatedAt: start, Version: 1},
+		},
+	}
+
+	t.Log("Given the need to load beers dumped before the breweries were added")
+	{
+		s := memory.NewStore()
+		if err := s.Load(d); err != nil {
+			t.Fatalf("\t\t[ERROR] Should be able to load the dump: %v", err)
+		}
+
+		t.Log("\tWhen listing the breweries")
+		{
+			list, err := s.ListBreweries(ctx)
+			if err != nil || len(list) != 2 {
+				t.Fatalf("\t\t[ERROR] Should create a brewery per canonical name. Got %+v: %v", list, err)
+			}
+			if list[0].ID != breweries.LegacyID("BrewDog") || list[0].Name != "BrewDog" || !list[0].CreatedAt.Equal(start) {
+				t.Fatalf("\t\t[ERROR] Should name the brewery after its oldest beer. Got %+v", list[0])
+			}
+			t.Log("\t\t[OK] Should create a brewery per canonical name.")
+		}
+
+		t.Log("\tWhen getting the beers")
+		{
+			oldest, err := s.GetBeer(ctx, "00000000-0000-0000-0000-000000000001")
+			if err != nil || oldest.BreweryID != breweries.LegacyID("BrewDog") || oldest.Name != "IPA" {
+				t.Fatalf("\t\t[ERROR] Should link the beer to its brewery. Got %+v: %v", oldest, err)
+			}
+
+			newest, err := s.GetBeer(ctx, "00000000-0000-0000-0000-000000000002")
+			if err != nil || newest.BreweryID != oldest.BreweryID || newest.Brewery != "BrewDog" || newest.Name != "IPA (00000000)" {
+				t.Fatalf("\t\t[ERROR] Should rename the newest beer with the same name. Got %+v: %v", newest, err)
+			}
+			t.Log("\t\t[OK] Should link the beers to their breweries.")
+		}
+	}
+}
+
+func TestLoadDuplicateReviews(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().UTC()
+
+	const (
+		beerID = "00000000-0000-0000-0000-000000000001"
+		userID = "00000000-0000-0000-0000-000000000002"
+		oldID  = "00000000-0000-0000-0000-000000000003"
+		newID  = "00000000-0000-0000-0000-000000000004"
+	)
+
+	// Reviews dumped while a user could review a beer more than once.
+	d := memory.Dump{
+		Beers: []beers.Beer{
+			{ID: beerID, Name: "IPA", Brewery: "BrewDog", CreatedAt: start, Version: 1},
+		},
+		Users: []memory.StoredUser{
+			memory.NewStoredUser(users.User{ID: userID, Handle: "hop_head", CreatedAt: start, UpdatedAt: start}),
+		},
+		Reviews: []reviews.Review{
+			{ID: newID, BeerID: beerID, UserID: userID, Score: 2, Revision: 1, CreatedAt: start.Add(time.Minute)},
+			{ID: oldID, BeerID: beerID, UserID: userID, Score: 5, Revision: 2, CreatedAt: start},
+		},
+		Revisions: []reviews.Revision{
+			{ReviewID: oldID, Score: 4, Revision: 1, CreatedAt: start},
+			{ReviewID: oldID, Score: 5, Revision: 2, CreatedAt: start},
+			{ReviewID: newID, Score: 2, Revision: 1, CreatedAt: start.Add(time.Minute)},
+		},
+	}
+
+	t.Log("Given the need to load reviews dumped while a user could review a beer more than once")
+	{
+		s := memory.NewStore()
+		if err := s.Load(d); err != nil {
+			t.Fatalf("\t\t[ERROR] Should be able to load the dump: %v", err)
+		}
+
+		t.Log("\tWhen getting the review of the user")
+		{
+			got, err := s.GetUserReview(ctx, beerID, userID)
+			if err != nil || got.ID != newID {
+				t.Fatalf("\t\t[ERROR] Should keep the most recent review. Got %+v: %v", got, err)
+			}
+			if _, err := s.GetReview(ctx, oldID); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should drop the previous review: %v", err)
+			}
+			t.Log("\t\t[OK] Should keep the most recent review.")
+		}
+
+		t.Log("\tWhen getting the beer")
+		{
+			got, err := s.GetBeer(ctx, beerID)
+			if err != nil || got.Score != 2 {
+				t.Fatalf("\t\t[ERROR] Should score the most recent review only. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should score the most recent review only.")
+		}
+	}
+}
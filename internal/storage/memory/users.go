@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// CreateUser stores a new user. The handle and the email must not belong to
+// another user.
+func (s *Store) CreateUser(ctx context.Context, u users.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.userTaken(u); err != nil {
+		return err
+	}
+
+	s.users[u.ID] = &u
+
+	return nil
+}
+
+// GetUser returns the user with the given ID.
+func (s *Store) GetUser(ctx context.Context, id string) (*users.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, users.ErrNotFound
+	}
+
+	uc := *u
+	return &uc, nil
+}
+
+// UpdateUser stores the changes of a user. The handle and the email must not
+// belong to another user.
+func (s *Store) UpdateUser(ctx context.Context, u users.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[u.ID]; !ok {
+		return users.ErrNotFound
+	}
+
+	if err := s.userTaken(u); err != nil {
+		return err
+	}
+
+	s.users[u.ID] = &u
+
+	return nil
+}
+
+// userTaken checks if the handle or the email of the user belong to another
+// user.
+func (s *Store) userTaken(u users.User) error {
+	for _, o := range s.users {
+		switch {
+		case o.ID == u.ID:
+		case o.Handle == u.Handle:
+			return users.ErrHandleTaken
+		case o.Email == u.Email:
+			return users.ErrEmailTaken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// CreateSubscription stores a new subscription.
+func (s *Store) CreateSubscription(ctx context.Context, sub webhooks.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub.Events = append([]string(nil), sub.Events...)
+	s.subscriptions[sub.ID] = &sub
+
+	return nil
+}
+
+// GetSubscription returns the subscription with the given ID.
+func (s *Store) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, webhooks.ErrNotFound
+	}
+
+	sc := copySubscription(sub)
+	return &sc, nil
+}
+
+// ListSubscriptions returns every subscription, from the oldest to the most
+// recent.
+func (s *Store) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []webhooks.Subscription
+	for _, sub := range s.subscriptions {
+		list = append(list, copySubscription(sub))
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+// DeleteSubscription deletes a subscription and its deliveries.
+func (s *Store) DeleteSubscription(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return webhooks.ErrNotFound
+	}
+
+	delete(s.subscriptions, id)
+	for did, d := range s.deliveries {
+		if d.SubscriptionID == id {
+			delete(s.deliveries, did)
+		}
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhooks.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []webhooks.Delivery
+	for _, d := range s.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			list = append(list, *d)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.After(list[j].CreatedAt)
+		}
+		return list[i].ID > list[j].ID
+	})
+
+	if len(list) > limit {
+		list = list[:limit]
+	}
+
+	return list, nil
+}
+
+// GetDelivery returns the delivery with the given ID.
+func (s *Store) GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return nil, webhooks.ErrDeliveryNotFound
+	}
+
+	dc := *d
+	return &dc, nil
+}
+
+// CreateDelivery queues a new delivery.
+func (s *Store) CreateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[d.SubscriptionID]; !ok {
+		return webhooks.ErrNotFound
+	}
+
+	s.deliveries[d.ID] = &d
+
+	return nil
+}
+
+// ClaimDeliveries returns the pending deliveries due at the given time,
+// postponing them by the lease.
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*webhooks.Delivery
+	for _, d := range s.deliveries {
+		if d.Status == webhooks.StatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	list := make([]webhooks.Delivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		list[i] = *d
+	}
+
+	return list, nil
+}
+
+// UpdateDelivery stores the result of a delivery attempt.
+func (s *Store) UpdateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.deliveries[d.ID]; !ok {
+		return webhooks.ErrDeliveryNotFound
+	}
+
+	s.deliveries[d.ID] = &d
+
+	return nil
+}
+
+// =============================================================================
+
+// queueDeliveries queues the deliveries of the event to the subscriptions
+// accepting it. The caller must hold the lock.
+func (s *Store) queueDeliveries(e webhooks.Event) {
+	for _, sub := range s.subscriptions {
+		if sub.Accepts(e.Type) {
+			d := webhooks.NewDelivery(sub.ID, e)
+			s.deliveries[d.ID] = &d
+		}
+	}
+}
+
+// copySubscription returns a copy of the subscription not sharing its events.
+func copySubscription(sub *webhooks.Subscription) webhooks.Subscription {
+	sc := *sub
+	sc.Events = append([]string(nil), sub.Events...)
+	return sc
+}
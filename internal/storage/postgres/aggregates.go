@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+// AggregateDrift defines a beer whose stored review aggregates don't match
+// its reviews.
+type AggregateDrift struct {
+	BeerID           string
+	ReviewCount      int
+	ScoreSum         float64
+	ExpectedCount    int
+	ExpectedScoreSum float64
+	SubScores        bool // whether the sub-score aggregates drifted
+}
+
+// CheckAggregates returns the beers whose review aggregates drifted from
+// their reviews.
+func (s *Store) CheckAggregates(ctx context.Context) ([]AggregateDrift, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return aggregateDrifts(ctx, tx, false)
+}
+
+// RepairAggregates recomputes the review aggregates of every beer from its
+// reviews, returning the beers that drifted. Reviews can't be changed while
+// the aggregates are repaired.
+func (s *Store) RepairAggregates(ctx context.Context) ([]AggregateDrift, error) {
+	var drifts []AggregateDrift
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE reviews IN SHARE MODE`); err != nil {
+			return err
+		}
+
+		var err error
+		drifts, err = aggregateDrifts(ctx, tx, true)
+		return err
+	})
+
+	return drifts, err
+}
+
+// aggregateDrifts finds the beers whose review aggregates drifted, fixing
+// them when asked to.
+func aggregateDrifts(ctx context.Context, tx *sql.Tx, fix bool) ([]AggregateDrift, error) {
+	query := `
+        WITH expected AS (
+                SELECT
+                        b.id,
+                        b.review_count,
+                        b.score_sum,
+                        COALESCE(agg.review_count, 0) AS expected_count,
+                        COALESCE(agg.score_sum, 0) AS expected_score_sum,
+                        COALESCE(agg.aroma_count, 0) AS expected_aroma_count,
+                        COALESCE(agg.aroma_sum, 0) AS expected_aroma_sum,
+                        COALESCE(agg.appearance_count, 0) AS expected_appearance_count,
+                        COALESCE(agg.appearance_sum, 0) AS expected_appearance_sum,
+                        COALESCE(agg.taste_count, 0) AS expected_taste_count,
+                        COALESCE(agg.taste_sum, 0) AS expected_taste_sum,
+                        COALESCE(agg.mouthfeel_count, 0) AS expected_mouthfeel_count,
+                        COALESCE(agg.mouthfeel_sum, 0) AS expected_mouthfeel_sum,
+                        COALESCE(agg.overall_count, 0) AS expected_overall_count,
+                        COALESCE(agg.overall_sum, 0) AS expected_overall_sum,
+                        (
+                                b.aroma_count <> COALESCE(agg.aroma_count, 0) OR
+                                ABS(b.aroma_sum - COALESCE(agg.aroma_sum, 0)) > 1e-6 OR
+                                b.appearance_count <> COALESCE(agg.appearance_count, 0) OR
+                                ABS(b.appearance_sum - COALESCE(agg.appearance_sum, 0)) > 1e-6 OR
+                                b.taste_count <> COALESCE(agg.taste_count, 0) OR
+                                ABS(b.taste_sum - COALESCE(agg.taste_sum, 0)) > 1e-6 OR
+                                b.mouthfeel_count <> COALESCE(agg.mouthfeel_count, 0) OR
+                                ABS(b.mouthfeel_sum - COALESCE(agg.mouthfeel_sum, 0)) > 1e-6 OR
+                                b.overall_count <> COALESCE(agg.overall_count, 0) OR
+                                ABS(b.overall_sum - COALESCE(agg.overall_sum, 0)) > 1e-6
+                        ) AS sub_scores
+                FROM
+                        beers AS b
+                LEFT JOIN (
+                        SELECT
+                                beer_id,
+                                COUNT(*) AS review_count,
+                                SUM(score) AS score_sum,
+                                COUNT(aroma) AS aroma_count,
+                                COALESCE(SUM(aroma), 0) AS aroma_sum,
+                                COUNT(appearance) AS appearance_count,
+                                COALESCE(SUM(appearance), 0) AS appearance_sum,
+                                COUNT(taste) AS taste_count,
+                                COALESCE(SUM(taste), 0) AS taste_sum,
+                                COUNT(mouthfeel) AS mouthfeel_count,
+                                COALESCE(SUM(mouthfeel), 0) AS mouthfeel_sum,
+                                COUNT(overall) AS overall_count,
+                                COALESCE(SUM(overall), 0) AS overall_sum
+                        FROM reviews
+                        GROUP BY beer_id
+                ) AS agg ON agg.beer_id = b.id
+        ), drifts AS (
+                SELECT
+                        *
+                FROM
+                        expected AS e
+                WHERE
+                        e.review_count <> e.expected_count OR
+                        ABS(e.score_sum - e.expected_score_sum) > 1e-6 OR
+                        e.sub_scores
+        )`
+
+	if fix {
+		query += `
+        UPDATE
+                beers AS b
+        SET
+                review_count = d.expected_count,
+                score_sum = d.expected_score_sum,
+                aroma_count = d.expected_aroma_count,
+                aroma_sum = d.expected_aroma_sum,
+                appearance_count = d.expected_appearance_count,
+                appearance_sum = d.expected_appearance_sum,
+                taste_count = d.expected_taste_count,
+                taste_sum = d.expected_taste_sum,
+                mouthfeel_count = d.expected_mouthfeel_count,
+                mouthfeel_sum = d.expected_mouthfeel_sum,
+                overall_count = d.expected_overall_count,
+                overall_sum = d.expected_overall_sum
+        FROM
+                drifts AS d
+        WHERE
+                b.id = d.id
+        RETURNING
+                d.id, d.review_count, d.score_sum, d.expected_count, d.expected_score_sum, d.sub_scores`
+	} else {
+		query += `
+        SELECT
+                d.id, d.review_count, d.score_sum, d.expected_count, d.expected_score_sum, d.sub_scores
+        FROM
+                drifts AS d`
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []AggregateDrift
+	for rows.Next() {
+		var d AggregateDrift
+
+		err := rows.Scan(
+			&d.BeerID,
+			&d.ReviewCount,
+			&d.ScoreSum,
+			&d.ExpectedCount,
+			&d.ExpectedScoreSum,
+			&d.SubScores)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, d)
+	}
+
+	return list, rows.Err()
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/phbpx/gobeer/internal/breweries"
+)
+
+// CreateBrewery stores a new brewery on the database. Its canonical name must
+// not belong to another brewery.
+func (s *Store) CreateBrewery(ctx context.Context, b breweries.Brewery) error {
+	query := `
+        INSERT INTO breweries (
+                id,
+                name,
+                canonical_name,
+                location,
+                website,
+                founded_year,
+                created_at
+        ) VALUES (
+                $1, $2, $3, $4, $5, NULLIF($6, 0), $7
+        )`
+
+	_, err := s.db.ExecContext(ctx, query,
+		b.ID,
+		b.Name,
+		breweries.Canonical(b.Name),
+		b.Location,
+		b.Website,
+		b.FoundedYear,
+		b.CreatedAt)
+
+	if err != nil {
+		if isViolation(err, uniqueViolation) {
+			return breweries.ErrAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetBrewery returns a brewery from the database.
+func (s *Store) GetBrewery(ctx context.Context, id string) (*breweries.Brewery, error) {
+	query := `
+        SELECT
+                w.id,
+                w.name,
+                w.location,
+                w.website,
+                COALESCE(w.founded_year, 0),
+                w.created_at
+        FROM
+                breweries AS w
+        WHERE
+                w.id = $1`
+
+	var b breweries.Brewery
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&b.ID,
+		&b.Name,
+		&b.Location,
+		&b.Website,
+		&b.FoundedYear,
+		&b.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, breweries.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// ListBreweries returns every brewery from the database, ordered by name.
+func (s *Store) ListBreweries(ctx context.Context) ([]breweries.Brewery, error) {
+	query := `
+        SELECT
+                w.id,
+                w.name,
+                w.location,
+                w.website,
+                COALESCE(w.founded_year, 0),
+                w.created_at
+        FROM
+                breweries AS w
+        ORDER BY
+                w.name, w.id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []breweries.Brewery
+	for rows.Next() {
+		var b breweries.Brewery
+
+		err := rows.Scan(
+			&b.ID,
+			&b.Name,
+			&b.Location,
+			&b.Website,
+			&b.FoundedYear,
+			&b.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, b)
+	}
+
+	return list, rows.Err()
+}
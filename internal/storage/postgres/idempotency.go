@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/idempotency"
+)
+
+// LockKey locks the key for the request with the given fingerprint, until its
+// response is saved or the key is unlocked. When the key already has a
+// response, it's returned instead. The key is locked by a row, so the
+// instances sharing the database share the keys.
+func (s *Store) LockKey(ctx context.Context, key, fingerprint string, now time.Time) (*idempotency.Response, error) {
+	var resp *idempotency.Response
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		lock := idempotency.NewLock(fingerprint, now)
+
+		insert := `
+        INSERT INTO idempotency_keys (
+                key,
+                fingerprint,
+                expires_at
+        ) VALUES (
+                $1, $2, $3
+        )
+        ON CONFLICT (key) DO NOTHING`
+
+		res, err := tx.ExecContext(ctx, insert, key, lock.Fingerprint, lock.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 1 {
+			return err
+		}
+
+		query := `
+        SELECT
+                fingerprint,
+                response,
+                expires_at
+        FROM
+                idempotency_keys
+        WHERE
+                key = $1
+        FOR UPDATE`
+
+		var (
+			rec  idempotency.Record
+			data []byte
+		)
+		if err := tx.QueryRowContext(ctx, query, key).Scan(&rec.Fingerprint, &data, &rec.ExpiresAt); err != nil {
+			return err
+		}
+		if data != nil {
+			if err := json.Unmarshal(data, &rec.Response); err != nil {
+				return err
+			}
+		}
+
+		resp, err = idempotency.Check(&rec, fingerprint, now)
+		if resp != nil || err != nil {
+			return err
+		}
+
+		// The record expired, the key is locked anew.
+		update := `
+        UPDATE
+                idempotency_keys
+        SET
+                fingerprint = $2,
+                response = NULL,
+                expires_at = $3
+        WHERE
+                key = $1`
+
+		_, err = tx.ExecContext(ctx, update, key, lock.Fingerprint, lock.ExpiresAt)
+		return err
+	})
+
+	return resp, err
+}
+
+// SaveResponse saves the response of the locked key, kept until it expires.
+func (s *Store) SaveResponse(ctx context.Context, key string, resp idempotency.Response, expiresAt time.Time) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE
+                idempotency_keys
+        SET
+                response = $2,
+                expires_at = $3
+        WHERE
+                key = $1`
+
+	_, err = s.db.ExecContext(ctx, query, key, data, expiresAt)
+	return err
+}
+
+// UnlockKey forgets the locked key, so the request can be retried.
+func (s *Store) UnlockKey(ctx context.Context, key string) error {
+	query := `
+        DELETE FROM
+                idempotency_keys
+        WHERE
+                key = $1 AND response IS NULL`
+
+	_, err := s.db.ExecContext(ctx, query, key)
+	return err
+}
+
+// PurgeIdempotencyKeys deletes the expired keys. It returns how many were
+// deleted.
+func (s *Store) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+        DELETE FROM
+                idempotency_keys
+        WHERE
+                expires_at <= $1`
+
+	res, err := s.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "reviews_beer_id_idx";
+DROP INDEX IF EXISTS "beers_brewery_idx";
+DROP INDEX IF EXISTS "beers_style_idx";
+DROP INDEX IF EXISTS "beers_name_id_idx";
+DROP INDEX IF EXISTS "beers_abv_id_idx";
+DROP INDEX IF EXISTS "beers_created_at_id_idx";
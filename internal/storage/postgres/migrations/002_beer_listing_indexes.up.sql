@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS "beers_created_at_id_idx" ON "beers" ("created_at", "id");
+CREATE INDEX IF NOT EXISTS "beers_abv_id_idx" ON "beers" ("abv", "id");
+CREATE INDEX IF NOT EXISTS "beers_name_id_idx" ON "beers" ("name", "id");
+CREATE INDEX IF NOT EXISTS "beers_style_idx" ON "beers" ("style");
+CREATE INDEX IF NOT EXISTS "beers_brewery_idx" ON "beers" ("brewery");
+CREATE INDEX IF NOT EXISTS "reviews_beer_id_idx" ON "reviews" ("beer_id");
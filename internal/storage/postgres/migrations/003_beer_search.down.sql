@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "beers_search_text_idx";
+DROP INDEX IF EXISTS "beers_search_vector_idx";
+DROP TRIGGER IF EXISTS "beers_search_update" ON "beers";
+DROP FUNCTION IF EXISTS "beers_search_update"();
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "search_text";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "search_vector";
@@ -0,0 +1,28 @@
+CREATE EXTENSION IF NOT EXISTS "pg_trgm";
+
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "search_vector" TSVECTOR;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "search_text" TEXT;
+
+-- Keeps the search columns in sync with the searchable fields.
+CREATE OR REPLACE FUNCTION "beers_search_update"() RETURNS TRIGGER AS $$
+BEGIN
+    NEW."search_vector" :=
+        setweight(to_tsvector('simple', COALESCE(NEW."name", '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW."brewery", '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW."style", '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW."short_desc", '')), 'C');
+    NEW."search_text" := lower(concat_ws(' ', NEW."name", NEW."brewery", NEW."style"));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS "beers_search_update" ON "beers";
+CREATE TRIGGER "beers_search_update"
+    BEFORE INSERT OR UPDATE OF "name", "brewery", "style", "short_desc" ON "beers"
+    FOR EACH ROW EXECUTE FUNCTION "beers_search_update"();
+
+-- Backfill the existing beers.
+UPDATE "beers" SET "name" = "name";
+
+CREATE INDEX IF NOT EXISTS "beers_search_vector_idx" ON "beers" USING GIN ("search_vector");
+CREATE INDEX IF NOT EXISTS "beers_search_text_idx" ON "beers" USING GIN ("search_text" gin_trgm_ops);
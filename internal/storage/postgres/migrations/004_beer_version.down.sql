@@ -0,0 +1 @@
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "version";
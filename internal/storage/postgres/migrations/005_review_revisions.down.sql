@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "review_revisions";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "updated_at";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "revision";
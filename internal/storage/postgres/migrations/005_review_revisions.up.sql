@@ -0,0 +1,18 @@
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "revision" INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "updated_at" TIMESTAMP;
+UPDATE "reviews" SET "updated_at" = "created_at" WHERE "updated_at" IS NULL;
+ALTER TABLE "reviews" ALTER COLUMN "updated_at" SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS "review_revisions" (
+    "review_id" UUID NOT NULL REFERENCES "reviews" ("id") ON DELETE CASCADE,
+    "revision" INTEGER NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "comment" TEXT NOT NULL,
+    "score" FLOAT NOT NULL,
+    PRIMARY KEY ("review_id", "revision")
+);
+
+-- The existing reviews start their history with their current content.
+INSERT INTO "review_revisions" ("review_id", "revision", "created_at", "comment", "score")
+SELECT "id", "revision", "created_at", "comment", "score" FROM "reviews"
+ON CONFLICT DO NOTHING;
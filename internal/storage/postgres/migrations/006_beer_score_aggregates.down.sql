@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "beers_score_id_idx";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "score";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "score_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "review_count";
@@ -0,0 +1,19 @@
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "review_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "score_sum" FLOAT NOT NULL DEFAULT 0;
+
+UPDATE "beers" AS b
+SET 
+    "review_count" = agg."review_count",
+    "score_sum" = agg."score_sum"
+FROM (
+    SELECT "beer_id", COUNT(*) AS "review_count", SUM("score") AS "score_sum"
+    FROM "reviews"
+    GROUP BY "beer_id"
+) AS agg
+WHERE b."id" = agg."beer_id";
+
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "score" REAL GENERATED ALWAYS AS (
+    CASE WHEN "review_count" > 0 THEN "score_sum" / "review_count" ELSE 0 END
+) STORED;
+
+CREATE INDEX IF NOT EXISTS "beers_score_id_idx" ON "beers" ("score", "id");
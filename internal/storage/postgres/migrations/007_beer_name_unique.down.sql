@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "beers_name_brewery_key";
@@ -0,0 +1,14 @@
+-- The beers added before the names were unique may share a name with another
+-- beer of their brewery, so the newer of two such beers is renamed after its
+-- ID, keeping its reviews, as the other storages do.
+UPDATE "beers" AS b
+SET "name" = b."name" || ' (' || left(b."id"::TEXT, 8) || ')'
+FROM (
+    SELECT
+        "id",
+        ROW_NUMBER() OVER (PARTITION BY "name", "brewery" ORDER BY "created_at", "id") AS "n"
+    FROM "beers"
+) AS d
+WHERE d."id" = b."id" AND d."n" > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "beers_name_brewery_key" ON "beers" ("name", "brewery");
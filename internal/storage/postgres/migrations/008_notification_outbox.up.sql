@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" UUID PRIMARY KEY,
+    "kind" VARCHAR(64) NOT NULL,
+    "user_id" UUID NOT NULL,
+    "review_id" UUID NOT NULL,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_error" TEXT NOT NULL DEFAULT '',
+    "next_attempt_at" TIMESTAMP NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "sent_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "notifications_pending_idx" ON "notifications" ("next_attempt_at", "id") WHERE "status" = 'pending';
@@ -0,0 +1,40 @@
+ALTER TABLE "notifications" ADD COLUMN IF NOT EXISTS "event" JSONB;
+
+-- The notifications stored before the events get one built from their review.
+UPDATE "notifications" AS n
+SET "event" = jsonb_build_object(
+    'version', 1,
+    'id', n."id",
+    'type', n."kind",
+    'occurred_at', to_char(n."created_at", 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+    'user_id', n."user_id",
+    'review', jsonb_build_object(
+        'id', r."id",
+        'score', r."score",
+        'comment', r."comment",
+        'created_at', to_char(r."created_at", 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'updated_at', to_char(r."updated_at", 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+    ),
+    'beer', jsonb_build_object(
+        'id', b."id",
+        'name', b."name",
+        'brewery', b."brewery"
+    )
+)
+FROM "reviews" AS r
+JOIN "beers" AS b ON b."id" = r."beer_id"
+WHERE r."id" = n."review_id" AND n."event" IS NULL;
+
+-- The review is gone, so only what the notification knows is kept.
+UPDATE "notifications" AS n
+SET "event" = jsonb_build_object(
+    'version', 1,
+    'id', n."id",
+    'type', n."kind",
+    'occurred_at', to_char(n."created_at", 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+    'user_id', n."user_id",
+    'review', jsonb_build_object('id', n."review_id")
+)
+WHERE n."event" IS NULL;
+
+ALTER TABLE "notifications" ALTER COLUMN "event" SET NOT NULL;
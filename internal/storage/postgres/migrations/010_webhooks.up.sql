@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "webhook_subscriptions" (
+    "id" UUID PRIMARY KEY,
+    "url" TEXT NOT NULL,
+    "events" TEXT[] NOT NULL,
+    "secret" TEXT NOT NULL,
+    "created_at" TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" UUID PRIMARY KEY,
+    "subscription_id" UUID NOT NULL REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE,
+    "event" JSONB NOT NULL,
+    "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+    "attempts" INTEGER NOT NULL DEFAULT 0,
+    "last_error" TEXT NOT NULL DEFAULT '',
+    "response_status" INTEGER NOT NULL DEFAULT 0,
+    "next_attempt_at" TIMESTAMP NOT NULL,
+    "created_at" TIMESTAMP NOT NULL,
+    "delivered_at" TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_subscription_idx" ON "webhook_deliveries" ("subscription_id", "created_at" DESC, "id" DESC);
+CREATE INDEX IF NOT EXISTS "webhook_deliveries_pending_idx" ON "webhook_deliveries" ("next_attempt_at", "id") WHERE "status" = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "reviews_user_idx";
+ALTER TABLE "reviews" DROP CONSTRAINT IF EXISTS "reviews_user_id_fkey";
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" UUID PRIMARY KEY,
+    "handle" VARCHAR(64) NOT NULL,
+    "email" VARCHAR(255) NOT NULL,
+    "display_name" VARCHAR(64) NOT NULL DEFAULT '',
+    "bio" TEXT NOT NULL DEFAULT '',
+    "password_hash" TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP NOT NULL,
+    "updated_at" TIMESTAMP NOT NULL,
+    CONSTRAINT "users_handle_key" UNIQUE ("handle"),
+    CONSTRAINT "users_email_key" UNIQUE ("email")
+);
+
+-- The authors of the reviews written before the users were registered become
+-- legacy users, without password, created at the time of their first review.
+-- They're the same users the other storages derive (users.Legacy).
+INSERT INTO "users" ("id", "handle", "email", "display_name", "created_at", "updated_at")
+SELECT
+    r."user_id",
+    'legacy_' || REPLACE(r."user_id"::TEXT, '-', ''),
+    r."user_id"::TEXT || '@legacy.invalid',
+    'Legacy user',
+    MIN(r."created_at"),
+    MIN(r."created_at")
+FROM "reviews" AS r
+WHERE NOT EXISTS (SELECT 1 FROM "users" AS u WHERE u."id" = r."user_id")
+GROUP BY r."user_id";
+
+ALTER TABLE "reviews" ADD CONSTRAINT "reviews_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+CREATE INDEX IF NOT EXISTS "reviews_user_idx" ON "reviews" ("user_id");
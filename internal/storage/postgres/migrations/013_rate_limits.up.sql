@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "rate_limits" (
+    "key" TEXT PRIMARY KEY,
+    "tokens" DOUBLE PRECISION NOT NULL,
+    "updated_at" TIMESTAMP NOT NULL,
+    "full_at" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "rate_limits_full_at_idx" ON "rate_limits" ("full_at");
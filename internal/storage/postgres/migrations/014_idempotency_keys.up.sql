@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "idempotency_keys" (
+    "key" TEXT PRIMARY KEY,
+    "fingerprint" TEXT NOT NULL,
+    "response" JSONB,
+    "expires_at" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "idempotency_keys_expires_at_idx" ON "idempotency_keys" ("expires_at");
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "beers_brewery_id_idx";
+DROP INDEX IF EXISTS "beers_name_brewery_key";
+ALTER TABLE "beers" DROP CONSTRAINT IF EXISTS "beers_brewery_id_fkey";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "brewery_id";
+DROP TABLE IF EXISTS "breweries";
+CREATE UNIQUE INDEX IF NOT EXISTS "beers_name_brewery_key" ON "beers" ("name", "brewery");
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS "breweries" (
+    "id" UUID PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "canonical_name" VARCHAR(255) NOT NULL,
+    "location" VARCHAR(255) NOT NULL DEFAULT '',
+    "website" VARCHAR(255) NOT NULL DEFAULT '',
+    "founded_year" INTEGER,
+    "created_at" TIMESTAMP NOT NULL,
+    CONSTRAINT "breweries_canonical_name_key" UNIQUE ("canonical_name")
+);
+
+-- The brewery names of the beers added while the brewery was a free text
+-- become legacy breweries, one per canonical name (lower case, spaces
+-- collapsed), named and created after their oldest beer. Their IDs are the MD5
+-- of the canonical name, the same breweries the other storages derive
+-- (breweries.Legacy).
+INSERT INTO "breweries" ("id", "name", "canonical_name", "created_at")
+SELECT DISTINCT ON (c."canonical_name")
+    md5(c."canonical_name")::UUID,
+    c."name",
+    c."canonical_name",
+    c."created_at"
+FROM (
+    SELECT
+        b."id",
+        b."created_at",
+        btrim(regexp_replace(b."brewery", '\s+', ' ', 'g')) AS "name",
+        lower(btrim(regexp_replace(b."brewery", '\s+', ' ', 'g'))) AS "canonical_name"
+    FROM "beers" AS b
+) AS c
+ORDER BY c."canonical_name", c."created_at", c."id";
+
+-- The beers keep the name of their brewery for the listing and the search, so
+-- every spelling of a brewery is replaced by the name of its brewery.
+DROP INDEX IF EXISTS "beers_name_brewery_key";
+
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "brewery_id" UUID;
+
+UPDATE "beers" AS b
+SET
+    "brewery_id" = w."id",
+    "brewery" = w."name"
+FROM "breweries" AS w
+WHERE w."canonical_name" = lower(btrim(regexp_replace(b."brewery", '\s+', ' ', 'g')));
+
+-- Two spellings of a brewery are now the same brewery, so the newer of two of
+-- its beers with the same name is renamed after its ID.
+UPDATE "beers" AS b
+SET "name" = b."name" || ' (' || left(b."id"::TEXT, 8) || ')'
+FROM (
+    SELECT
+        "id",
+        ROW_NUMBER() OVER (PARTITION BY "name", "brewery_id" ORDER BY "created_at", "id") AS "n"
+    FROM "beers"
+) AS d
+WHERE d."id" = b."id" AND d."n" > 1;
+
+ALTER TABLE "beers" ALTER COLUMN "brewery_id" SET NOT NULL;
+ALTER TABLE "beers" ADD CONSTRAINT "beers_brewery_id_fkey" FOREIGN KEY ("brewery_id") REFERENCES "breweries" ("id");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "beers_name_brewery_key" ON "beers" ("name", "brewery_id");
+CREATE INDEX IF NOT EXISTS "beers_brewery_id_idx" ON "beers" ("brewery_id");
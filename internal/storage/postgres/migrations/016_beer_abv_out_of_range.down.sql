@@ -0,0 +1 @@
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "abv_out_of_range";
@@ -0,0 +1 @@
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "abv_out_of_range" BOOLEAN NOT NULL DEFAULT FALSE;
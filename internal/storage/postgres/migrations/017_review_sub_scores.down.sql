@@ -0,0 +1,27 @@
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "overall";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "mouthfeel";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "taste";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "appearance";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "aroma";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "overall_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "overall_count";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "mouthfeel_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "mouthfeel_count";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "taste_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "taste_count";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "appearance_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "appearance_count";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "aroma_sum";
+ALTER TABLE "beers" DROP COLUMN IF EXISTS "aroma_count";
+
+ALTER TABLE "review_revisions" DROP COLUMN IF EXISTS "overall";
+ALTER TABLE "review_revisions" DROP COLUMN IF EXISTS "mouthfeel";
+ALTER TABLE "review_revisions" DROP COLUMN IF EXISTS "taste";
+ALTER TABLE "review_revisions" DROP COLUMN IF EXISTS "appearance";
+ALTER TABLE "review_revisions" DROP COLUMN IF EXISTS "aroma";
+
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "overall";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "mouthfeel";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "taste";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "appearance";
+ALTER TABLE "reviews" DROP COLUMN IF EXISTS "aroma";
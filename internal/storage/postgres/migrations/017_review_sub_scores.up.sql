@@ -0,0 +1,91 @@
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "aroma" REAL NULL;
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "appearance" REAL NULL;
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "taste" REAL NULL;
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "mouthfeel" REAL NULL;
+ALTER TABLE "reviews" ADD COLUMN IF NOT EXISTS "overall" REAL NULL;
+
+ALTER TABLE "review_revisions" ADD COLUMN IF NOT EXISTS "aroma" REAL NULL;
+ALTER TABLE "review_revisions" ADD COLUMN IF NOT EXISTS "appearance" REAL NULL;
+ALTER TABLE "review_revisions" ADD COLUMN IF NOT EXISTS "taste" REAL NULL;
+ALTER TABLE "review_revisions" ADD COLUMN IF NOT EXISTS "mouthfeel" REAL NULL;
+ALTER TABLE "review_revisions" ADD COLUMN IF NOT EXISTS "overall" REAL NULL;
+
+-- The scores given before the rating scale was enforced are clamped to its
+-- bounds, from 0 to 5, so they no longer skew the scores and the rankings of
+-- the beers, whose aggregates are recomputed. The scores within the bounds are
+-- kept as they are.
+UPDATE "reviews"
+SET "score" = LEAST(GREATEST("score", 0), 5)
+WHERE "score" < 0 OR "score" > 5;
+
+UPDATE "review_revisions"
+SET "score" = LEAST(GREATEST("score", 0), 5)
+WHERE "score" < 0 OR "score" > 5;
+
+UPDATE "beers" AS b
+SET "score_sum" = agg."score_sum"
+FROM (
+    SELECT "beer_id", SUM("score") AS "score_sum"
+    FROM "reviews"
+    GROUP BY "beer_id"
+) AS agg
+WHERE b."id" = agg."beer_id" AND b."score_sum" <> agg."score_sum";
+
+-- The beers keep the aggregates of each dimension, like the score aggregates,
+-- so the averages are read without scanning the reviews.
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "aroma_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "aroma_sum" FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "appearance_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "appearance_sum" FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "taste_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "taste_sum" FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "mouthfeel_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "mouthfeel_sum" FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "overall_count" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "overall_sum" FLOAT NOT NULL DEFAULT 0;
+
+UPDATE "beers" AS b
+SET
+    "aroma_count" = agg."aroma_count",
+    "aroma_sum" = agg."aroma_sum",
+    "appearance_count" = agg."appearance_count",
+    "appearance_sum" = agg."appearance_sum",
+    "taste_count" = agg."taste_count",
+    "taste_sum" = agg."taste_sum",
+    "mouthfeel_count" = agg."mouthfeel_count",
+    "mouthfeel_sum" = agg."mouthfeel_sum",
+    "overall_count" = agg."overall_count",
+    "overall_sum" = agg."overall_sum"
+FROM (
+    SELECT
+        "beer_id",
+        COUNT("aroma") AS "aroma_count",
+        COALESCE(SUM("aroma"), 0) AS "aroma_sum",
+        COUNT("appearance") AS "appearance_count",
+        COALESCE(SUM("appearance"), 0) AS "appearance_sum",
+        COUNT("taste") AS "taste_count",
+        COALESCE(SUM("taste"), 0) AS "taste_sum",
+        COUNT("mouthfeel") AS "mouthfeel_count",
+        COALESCE(SUM("mouthfeel"), 0) AS "mouthfeel_sum",
+        COUNT("overall") AS "overall_count",
+        COALESCE(SUM("overall"), 0) AS "overall_sum"
+    FROM "reviews"
+    GROUP BY "beer_id"
+) AS agg
+WHERE b."id" = agg."beer_id";
+
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "aroma" REAL GENERATED ALWAYS AS (
+    CASE WHEN "aroma_count" > 0 THEN "aroma_sum" / "aroma_count" END
+) STORED;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "appearance" REAL GENERATED ALWAYS AS (
+    CASE WHEN "appearance_count" > 0 THEN "appearance_sum" / "appearance_count" END
+) STORED;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "taste" REAL GENERATED ALWAYS AS (
+    CASE WHEN "taste_count" > 0 THEN "taste_sum" / "taste_count" END
+) STORED;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "mouthfeel" REAL GENERATED ALWAYS AS (
+    CASE WHEN "mouthfeel_count" > 0 THEN "mouthfeel_sum" / "mouthfeel_count" END
+) STORED;
+ALTER TABLE "beers" ADD COLUMN IF NOT EXISTS "overall" REAL GENERATED ALWAYS AS (
+    CASE WHEN "overall_count" > 0 THEN "overall_sum" / "overall_count" END
+) STORED;
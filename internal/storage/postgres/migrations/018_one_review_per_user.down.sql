@@ -0,0 +1 @@
+ALTER TABLE "reviews" DROP CONSTRAINT IF EXISTS "reviews_beer_id_user_id_key";
@@ -0,0 +1,55 @@
+-- A user reviews a beer once. When a user reviewed a beer more than once, the
+-- most recent review stands and the previous ones are deleted, along with
+-- their history and their share of the beer score and of the beer sub-scores,
+-- as the other storages do.
+WITH "deleted" AS (
+    DELETE FROM "reviews" AS r
+    USING (
+        SELECT
+            "id",
+            ROW_NUMBER() OVER (
+                PARTITION BY "beer_id", "user_id"
+                ORDER BY "created_at" DESC, "id" DESC
+            ) AS "n"
+        FROM "reviews"
+    ) AS dup
+    WHERE r."id" = dup."id" AND dup."n" > 1
+    RETURNING
+        r."beer_id", r."score",
+        r."aroma", r."appearance", r."taste", r."mouthfeel", r."overall"
+)
+UPDATE "beers" AS b
+SET
+    "review_count" = b."review_count" - d."review_count",
+    "score_sum" = b."score_sum" - d."score_sum",
+    "aroma_count" = b."aroma_count" - d."aroma_count",
+    "aroma_sum" = b."aroma_sum" - d."aroma_sum",
+    "appearance_count" = b."appearance_count" - d."appearance_count",
+    "appearance_sum" = b."appearance_sum" - d."appearance_sum",
+    "taste_count" = b."taste_count" - d."taste_count",
+    "taste_sum" = b."taste_sum" - d."taste_sum",
+    "mouthfeel_count" = b."mouthfeel_count" - d."mouthfeel_count",
+    "mouthfeel_sum" = b."mouthfeel_sum" - d."mouthfeel_sum",
+    "overall_count" = b."overall_count" - d."overall_count",
+    "overall_sum" = b."overall_sum" - d."overall_sum"
+FROM (
+    SELECT
+        "beer_id",
+        COUNT(*) AS "review_count",
+        SUM("score") AS "score_sum",
+        COUNT("aroma") AS "aroma_count",
+        COALESCE(SUM("aroma"), 0) AS "aroma_sum",
+        COUNT("appearance") AS "appearance_count",
+        COALESCE(SUM("appearance"), 0) AS "appearance_sum",
+        COUNT("taste") AS "taste_count",
+        COALESCE(SUM("taste"), 0) AS "taste_sum",
+        COUNT("mouthfeel") AS "mouthfeel_count",
+        COALESCE(SUM("mouthfeel"), 0) AS "mouthfeel_sum",
+        COUNT("overall") AS "overall_count",
+        COALESCE(SUM("overall"), 0) AS "overall_sum"
+    FROM "deleted"
+    GROUP BY "beer_id"
+) AS d
+WHERE b."id" = d."beer_id";
+
+ALTER TABLE "reviews" ADD CONSTRAINT "reviews_beer_id_user_id_key" UNIQUE ("beer_id", "user_id");
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "beers_review_count_idx";
@@ -0,0 +1,2 @@
+-- The rankings only consider the beers with a minimum number of reviews.
+CREATE INDEX IF NOT EXISTS "beers_review_count_idx" ON "beers" ("review_count");
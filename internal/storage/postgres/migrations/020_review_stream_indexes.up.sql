@@ -0,0 +1,4 @@
+-- The review streams read the reviews after a given one, of a beer or of every
+-- beer, in the order they were created.
+CREATE INDEX IF NOT EXISTS "reviews_created_at_id_idx" ON "reviews" ("created_at", "id");
+CREATE INDEX IF NOT EXISTS "reviews_beer_id_created_at_id_idx" ON "reviews" ("beer_id", "created_at", "id");
@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/notifications"
+)
+
+// ClaimNotifications returns the pending notifications due at the given time,
+// postponing them by the lease. The notifications locked by other dispatchers
+// are skipped.
+func (s *Store) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]notifications.Notification, error) {
+	query := `
+        UPDATE
+                notifications AS n
+        SET
+                next_attempt_at = $2
+        WHERE
+                n.id IN (
+                        SELECT id
+                        FROM notifications
+                        WHERE status = $3 AND next_attempt_at <= $1
+                        ORDER BY next_attempt_at, id
+                        LIMIT $4
+                        FOR UPDATE SKIP LOCKED
+                )
+        RETURNING
+                n.id,
+                n.kind,
+                n.user_id,
+                n.review_id,
+                n.event,
+                n.status,
+                n.attempts,
+                n.last_error,
+                n.next_attempt_at,
+                n.created_at,
+                n.sent_at`
+
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), notifications.StatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []notifications.Notification
+	for rows.Next() {
+		var (
+			n      notifications.Notification
+			event  []byte
+			sentAt sql.NullTime
+		)
+
+		err := rows.Scan(
+			&n.ID,
+			&n.Kind,
+			&n.UserID,
+			&n.ReviewID,
+			&event,
+			&n.Status,
+			&n.Attempts,
+			&n.LastError,
+			&n.NextAttemptAt,
+			&n.CreatedAt,
+			&sentAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(event, &n.Event); err != nil {
+			return nil, fmt.Errorf("decoding notification[id=%s] event: %w", n.ID, err)
+		}
+
+		if sentAt.Valid {
+			n.SentAt = &sentAt.Time
+		}
+
+		list = append(list, n)
+	}
+
+	return list, rows.Err()
+}
+
+// UpdateNotification stores the result of a delivery attempt.
+func (s *Store) UpdateNotification(ctx context.Context, n notifications.Notification) error {
+	query := `
+        UPDATE
+                notifications
+        SET
+                status = $2,
+                attempts = $3,
+                last_error = $4,
+                next_attempt_at = $5,
+                sent_at = $6
+        WHERE
+                id = $1`
+
+	res, err := s.db.ExecContext(ctx, query,
+		n.ID,
+		n.Status,
+		n.Attempts,
+		n.LastError,
+		n.NextAttemptAt,
+		n.SentAt)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notifications.ErrNotFound
+	}
+
+	return nil
+}
+
+// createNotification stores a notification in the outbox.
+func createNotification(ctx context.Context, tx *sql.Tx, n notifications.Notification) error {
+	event, err := json.Marshal(n.Event)
+	if err != nil {
+		return fmt.Errorf("encoding notification[id=%s] event: %w", n.ID, err)
+	}
+
+	query := `
+        INSERT INTO notifications (
+                id,
+                kind,
+                user_id,
+                review_id,
+                event,
+                status,
+                attempts,
+                last_error,
+                next_attempt_at,
+                created_at
+        ) VALUES (
+                $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+        )`
+
+	_, err = tx.ExecContext(ctx, query,
+		n.ID,
+		n.Kind,
+		n.UserID,
+		n.ReviewID,
+		string(event),
+		n.Status,
+		n.Attempts,
+		n.LastError,
+		n.NextAttemptAt,
+		n.CreatedAt)
+
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -14,7 +15,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/phbpx/gobeer/pkg/logger"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
@@ -22,6 +23,12 @@ import (
 //go:embed migrations
 var migrations embed.FS
 
+// Set of PostgreSQL error codes handled by the store.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
 // Config is the required properties to use the database.
 type Config struct {
 	User         string
@@ -35,6 +42,21 @@ type Config struct {
 
 // Open knows how to open a database connection based on the configuration.
 func Open(cfg Config) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", dataSource(cfg), otelsql.WithAttributes(
+		semconv.DBSystemPostgreSQL,
+		semconv.DBName(cfg.Name),
+	))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+// dataSource returns the connection string of the database.
+func dataSource(cfg Config) string {
 	sslMode := "require"
 	if cfg.DisableTLS {
 		sslMode = "disable"
@@ -52,17 +74,7 @@ func Open(cfg Config) (*sql.DB, error) {
 		RawQuery: q.Encode(),
 	}
 
-	db, err := otelsql.Open("postgres", u.String(), otelsql.WithAttributes(
-		semconv.DBSystemPostgreSQL,
-		semconv.DBName(cfg.Name),
-	))
-	if err != nil {
-		return nil, err
-	}
-	db.SetMaxIdleConns(cfg.MaxIdleConns)
-	db.SetMaxOpenConns(cfg.MaxOpenConns)
-
-	return db, nil
+	return u.String()
 }
 
 // StatusCheck returns nil if it can successfully talk to the database. It
@@ -128,3 +140,16 @@ func RunMigrations(ctx context.Context, db *sql.DB, log *logger.Logger) error {
 	}
 	return nil
 }
+
+// isViolation checks if the error is a PostgreSQL error with the given code.
+func isViolation(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+// isConstraintViolation checks if the error is a PostgreSQL error with the
+// given code, raised by the given constraint.
+func isConstraintViolation(err error, code, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code && pqErr.Constraint == constraint
+}
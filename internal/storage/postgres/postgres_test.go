@@ -0,0 +1,67 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/phbpx/gobeer/internal/idempotency"
+	"github.com/phbpx/gobeer/internal/idempotency/idempotencytest"
+	"github.com/phbpx/gobeer/internal/ratelimit"
+	"github.com/phbpx/gobeer/internal/ratelimit/ratelimittest"
+	"github.com/phbpx/gobeer/internal/storage/postgres"
+	"github.com/phbpx/gobeer/internal/storage/postgres/dbtest"
+	"github.com/phbpx/gobeer/internal/storage/storagetest"
+	"github.com/phbpx/gobeer/pkg/docker"
+)
+
+var c *docker.Container
+
+func TestMain(m *testing.M) {
+	var err error
+
+	c, err = dbtest.StartDB()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer dbtest.StopDB(c)
+
+	m.Run()
+}
+
+func TestStore(t *testing.T) {
+	test := dbtest.NewTest(t, c)
+	defer test.Teardown()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		if _, err := test.DB.ExecContext(context.Background(), `TRUNCATE beers, breweries, users, notifications, webhook_subscriptions CASCADE`); err != nil {
+			t.Fatalf("Should be able to clean the database: %v", err)
+		}
+		return postgres.NewStore(test.DB)
+	})
+}
+
+func TestRateLimits(t *testing.T) {
+	test := dbtest.NewTest(t, c)
+	defer test.Teardown()
+
+	ratelimittest.Run(t, func(t *testing.T) ratelimit.Store {
+		if _, err := test.DB.ExecContext(context.Background(), `TRUNCATE rate_limits`); err != nil {
+			t.Fatalf("Should be able to clean the database: %v", err)
+		}
+		return postgres.NewStore(test.DB)
+	})
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	test := dbtest.NewTest(t, c)
+	defer test.Teardown()
+
+	idempotencytest.Run(t, func(t *testing.T) idempotency.Store {
+		if _, err := test.DB.ExecContext(context.Background(), `TRUNCATE idempotency_keys`); err != nil {
+			t.Fatalf("Should be able to clean the database: %v", err)
+		}
+		return postgres.NewStore(test.DB)
+	})
+}
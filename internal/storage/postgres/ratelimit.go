@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/phbpx/gobeer/internal/ratelimit"
+)
+
+// TakeToken takes a token from the bucket of the key, as of the given time.
+// The bucket is locked while it's updated, so the instances sharing the
+// database share the limit.
+func (s *Store) TakeToken(ctx context.Context, key string, l ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	var res ratelimit.Result
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		fresh := ratelimit.NewBucket(l, now)
+
+		insert := `
+        INSERT INTO rate_limits (
+                key,
+                tokens,
+                updated_at,
+                full_at
+        ) VALUES (
+                $1, $2, $3, $3
+        )
+        ON CONFLICT (key) DO NOTHING`
+
+		if _, err := tx.ExecContext(ctx, insert, key, fresh.Tokens, fresh.UpdatedAt); err != nil {
+			return err
+		}
+
+		query := `
+        SELECT
+                tokens,
+                updated_at
+        FROM
+                rate_limits
+        WHERE
+                key = $1
+        FOR UPDATE`
+
+		var b ratelimit.Bucket
+		if err := tx.QueryRowContext(ctx, query, key).Scan(&b.Tokens, &b.UpdatedAt); err != nil {
+			return err
+		}
+
+		b, res = ratelimit.Take(b, l, now)
+
+		update := `
+        UPDATE
+                rate_limits
+        SET
+                tokens = $2,
+                updated_at = $3,
+                full_at = $4
+        WHERE
+                key = $1`
+
+		_, err := tx.ExecContext(ctx, update, key, b.Tokens, b.UpdatedAt, ratelimit.FullAt(b, l))
+		return err
+	})
+
+	return res, err
+}
+
+// PurgeRateLimits deletes the buckets full again at the given time, which are
+// the same as new buckets. It returns how many were deleted.
+func (s *Store) PurgeRateLimits(ctx context.Context, now time.Time) (int64, error) {
+	query := `
+        DELETE FROM
+                rate_limits
+        WHERE
+                full_at <= $1`
+
+	res, err := s.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
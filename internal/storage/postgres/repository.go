@@ -3,11 +3,36 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/notifications"
 	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
 )
 
+// reviewsUserKey is the constraint requiring the authors of the reviews to be
+// registered users.
+const reviewsUserKey = "reviews_user_id_fkey"
+
+// reviewsBeerUserKey is the constraint allowing a single review of a beer by
+// each user.
+const reviewsBeerUserKey = "reviews_beer_id_user_id_key"
+
+// reviewRevisionsKey is the constraint allowing a single revision of a
+// review with each number.
+const reviewRevisionsKey = "review_revisions_pkey"
+
+// beersBreweryKey is the constraint requiring the breweries of the beers to
+// exist.
+const beersBreweryKey = "beers_brewery_id_fkey"
+
 // Store provides an implementation if the Storer interface.
 type Store struct {
 	db *sql.DB
@@ -20,39 +45,58 @@ func NewStore(db *sql.DB) *Store {
 	}
 }
 
-// CreateBeer creates a new beer on the database.
-func (s *Store) CreateBeer(ctx context.Context, b beers.Beer) error {
+// CreateBeer creates a new beer on the database, along with the deliveries of
+// its event.
+func (s *Store) CreateBeer(ctx context.Context, b beers.Beer, e webhooks.Event) error {
 	query := `
         INSERT INTO beers (
                 id, 
                 name, 
+                brewery_id, 
                 brewery, 
                 style, 
                 abv, 
+                abv_out_of_range, 
                 short_desc, 
-                created_at
+                created_at,
+                version
         ) VALUES (
-                $1, $2, $3, $4, $5, $6, $7
+                $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
         )`
 
-	_, err := s.db.ExecContext(ctx, query,
-		b.ID,
-		b.Name,
-		b.Brewery,
-		b.Style,
-		b.ABV,
-		b.ShortDesc,
-		b.CreatedAt)
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query,
+			b.ID,
+			b.Name,
+			b.BreweryID,
+			b.Brewery,
+			b.Style,
+			b.ABV,
+			b.ABVOutOfRange,
+			b.ShortDesc,
+			b.CreatedAt,
+			b.Version)
 
-	return err
+		if err != nil {
+			if isViolation(err, uniqueViolation) {
+				return beers.ErrAlreadyExists
+			}
+			if isConstraintViolation(err, foreignKeyViolation, beersBreweryKey) {
+				return breweries.ErrNotFound
+			}
+			return err
+		}
+
+		return queueDeliveries(ctx, tx, e)
+	})
 }
 
 // BeerExists checks if a beer exists on the database.
-func (s *Store) BeerExists(ctx context.Context, name, brewery string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM beers WHERE name = $1 AND brewery = $2)`
+func (s *Store) BeerExists(ctx context.Context, name, breweryID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM beers WHERE name = $1 AND brewery_id = $2)`
 
 	var exists bool
-	err := s.db.QueryRowContext(ctx, query, name, brewery).Scan(&exists)
+	err := s.db.QueryRowContext(ctx, query, name, breweryID).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
@@ -62,35 +106,92 @@ func (s *Store) BeerExists(ctx context.Context, name, brewery string) (bool, err
 
 // GetBeer returns a beer from the database.
 func (s *Store) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
+	return getBeer(ctx, s.db, id)
+}
+
+// GetBeerDetail returns a beer from the database along with the aggregated
+// data of its reviews and up to latest of its most recent reviews, read in a
+// transaction so they all come from the same snapshot.
+func (s *Store) GetBeerDetail(ctx context.Context, id string, latest int) (*listing.BeerDetail, error) {
+	var d listing.BeerDetail
+
+	err := s.withSnapshot(ctx, func(tx *sql.Tx) error {
+		b, err := getBeer(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		stats, err := reviewStats(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		rs, err := listReviews(ctx, tx, id, sql.NullInt64{Int64: int64(latest), Valid: true})
+		if err != nil {
+			return err
+		}
+
+		d = listing.BeerDetail{Beer: *b, Reviews: stats, LatestReviews: rs}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// getBeer returns a beer from the database or from a transaction.
+func getBeer(ctx context.Context, q querier, id string) (*beers.Beer, error) {
 	query := `
         SELECT 
                 b.id,
                 b.name,
+                b.brewery_id,
                 b.brewery,
                 b.style,
                 b.abv,
+                b.abv_out_of_range,
                 b.short_desc,
-                COALESCE(AVG(r.score), 0) AS score,
-                b.created_at
+                b.score,
+                b.review_count,
+                b.score_sum,
+                b.aroma,
+                b.appearance,
+                b.taste,
+                b.mouthfeel,
+                b.overall,
+                b.created_at,
+                b.version
         FROM 
                 beers AS b
-        LEFT JOIN 
-                reviews AS r ON r.beer_id = b.id
         WHERE 
-                b.id = $1
-        GROUP BY
-                b.id`
+                b.id = $1`
+
+	var (
+		b   beers.Beer
+		sub reviews.Scores
+	)
 
-	var b beers.Beer
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+	err := q.QueryRowContext(ctx, query, id).Scan(
 		&b.ID,
 		&b.Name,
+		&b.BreweryID,
 		&b.Brewery,
 		&b.Style,
 		&b.ABV,
+		&b.ABVOutOfRange,
 		&b.ShortDesc,
 		&b.Score,
-		&b.CreatedAt)
+		&b.ReviewCount,
+		&b.ScoreSum,
+		&sub.Aroma,
+		&sub.Appearance,
+		&sub.Taste,
+		&sub.Mouthfeel,
+		&sub.Overall,
+		&b.CreatedAt,
+		&b.Version)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -99,29 +200,204 @@ func (s *Store) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
 		return nil, err
 	}
 
+	b.SubScores = ratedScores(sub)
+
 	return &b, nil
 }
 
-// ListBeers returns a list of beers from the database.
-func (s *Store) ListBeers(ctx context.Context) ([]beers.Beer, error) {
+// ListBeers returns a page of beers from the database, using keyset
+// pagination on the sort key and the beer ID.
+func (s *Store) ListBeers(ctx context.Context, seek listing.Seek) ([]beers.Beer, error) {
+	sortColumn := map[string]string{
+		listing.SortCreatedAt: "b.created_at",
+		listing.SortScore:     "b.score",
+		listing.SortABV:       "b.abv",
+		listing.SortName:      "b.name",
+	}[seek.Sort]
+
+	var (
+		args  []any
+		where []string
+	)
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if seek.Style != "" {
+		where = append(where, "b.style = "+arg(seek.Style))
+	}
+	if seek.Brewery != "" {
+		where = append(where, "b.brewery = "+arg(seek.Brewery))
+	}
+	if seek.BreweryID != "" {
+		where = append(where, "b.brewery_id = "+arg(seek.BreweryID))
+	}
+	if seek.MinABV != nil {
+		where = append(where, "b.abv >= "+arg(*seek.MinABV))
+	}
+	if seek.MaxABV != nil {
+		where = append(where, "b.abv <= "+arg(*seek.MaxABV))
+	}
+	if seek.MinScore != nil {
+		where = append(where, "b.score >= "+arg(*seek.MinScore))
+	}
+	if seek.CreatedAfter != nil {
+		where = append(where, "b.created_at > "+arg(seek.CreatedAfter.UTC()))
+	}
+
+	dir, cmp := "ASC", ">"
+	if seek.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	// Seek past the last beer of the previous page.
+	if c := seek.After; c != nil {
+		var v any
+		switch seek.Sort {
+		case listing.SortCreatedAt:
+			v = c.CreatedAt.UTC()
+		case listing.SortScore:
+			v = c.Score
+		case listing.SortABV:
+			v = c.ABV
+		case listing.SortName:
+			v = c.Name
+		}
+		where = append(where, fmt.Sprintf("(%s, b.id) %s (%s, %s)", sortColumn, cmp, arg(v), arg(c.ID)))
+	}
+
 	query := `
         SELECT 
                 b.id,
                 b.name,
+                b.brewery_id,
                 b.brewery,
                 b.style,
                 b.abv,
+                b.abv_out_of_range,
                 b.short_desc,
-                COALESCE(AVG(r.score), 0) AS score,
-                b.created_at
+                b.score,
+                b.review_count,
+                b.score_sum,
+                b.aroma,
+                b.appearance,
+                b.taste,
+                b.mouthfeel,
+                b.overall,
+                b.created_at,
+                b.version
+        FROM 
+                beers AS b`
+
+	if len(where) > 0 {
+		query += `
+        WHERE
+                ` + strings.Join(where, " AND ")
+	}
+
+	query += fmt.Sprintf(`
+        ORDER BY
+                %s %s, b.id %s
+        LIMIT %s`, sortColumn, dir, dir, arg(seek.Limit))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []beers.Beer
+	for rows.Next() {
+		var (
+			b   beers.Beer
+			sub reviews.Scores
+		)
+
+		err := rows.Scan(
+			&b.ID,
+			&b.Name,
+			&b.BreweryID,
+			&b.Brewery,
+			&b.Style,
+			&b.ABV,
+			&b.ABVOutOfRange,
+			&b.ShortDesc,
+			&b.Score,
+			&b.ReviewCount,
+			&b.ScoreSum,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall,
+			&b.CreatedAt,
+			&b.Version)
+
+		if err != nil {
+			return nil, err
+		}
+
+		b.SubScores = ratedScores(sub)
+		list = append(list, b)
+	}
+
+	return list, rows.Err()
+}
+
+// TopBeers returns the beers described by the ranking from the database, from
+// the highest ranked. The Bayesian average is computed from the review
+// aggregates of the beers, so the reviews are not read.
+func (s *Store) TopBeers(ctx context.Context, r listing.Ranking) ([]beers.Beer, error) {
+	var args []any
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	weighted := fmt.Sprintf("(%[1]s::FLOAT8 * %[2]s::FLOAT8 + b.score_sum) / (%[1]s::FLOAT8 + b.review_count)",
+		arg(r.Prior.Weight), arg(r.Prior.Mean))
+
+	where := []string{"b.review_count >= " + arg(r.MinReviews)}
+	if r.Style != "" {
+		where = append(where, "b.style = "+arg(r.Style))
+	}
+	if r.BreweryID != "" {
+		where = append(where, "b.brewery_id = "+arg(r.BreweryID))
+	}
+
+	query := `
+        SELECT 
+                b.id,
+                b.name,
+                b.brewery_id,
+                b.brewery,
+                b.style,
+                b.abv,
+                b.abv_out_of_range,
+                b.short_desc,
+                b.score,
+                b.review_count,
+                b.score_sum,
+                b.aroma,
+                b.appearance,
+                b.taste,
+                b.mouthfeel,
+                b.overall,
+                b.created_at,
+                b.version,
+                ` + weighted + ` AS weighted_score
         FROM 
                 beers AS b
-        LEFT JOIN 
-                reviews AS r ON r.beer_id = b.id
-        GROUP BY
-                b.id`
+        WHERE
+                ` + strings.Join(where, " AND ") + `
+        ORDER BY
+                weighted_score DESC, b.review_count DESC, b.id
+        LIMIT ` + arg(r.Limit)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,94 +405,931 @@ func (s *Store) ListBeers(ctx context.Context) ([]beers.Beer, error) {
 
 	var list []beers.Beer
 	for rows.Next() {
-		var b beers.Beer
+		var (
+			b        beers.Beer
+			sub      reviews.Scores
+			weighted float32
+		)
 
 		err := rows.Scan(
 			&b.ID,
 			&b.Name,
+			&b.BreweryID,
 			&b.Brewery,
 			&b.Style,
 			&b.ABV,
+			&b.ABVOutOfRange,
 			&b.ShortDesc,
 			&b.Score,
-			&b.CreatedAt)
+			&b.ReviewCount,
+			&b.ScoreSum,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall,
+			&b.CreatedAt,
+			&b.Version,
+			&weighted)
 
 		if err != nil {
 			return nil, err
 		}
 
+		b.SubScores = ratedScores(sub)
+		b.WeightedScore = &weighted
 		list = append(list, b)
 	}
 
-	return list, nil
+	return list, rows.Err()
+}
+
+// UpdateBeer updates a beer on the database, as long as its stored version
+// is still the given version.
+func (s *Store) UpdateBeer(ctx context.Context, b beers.Beer, version int) error {
+	query := `
+        UPDATE 
+                beers
+        SET 
+                name = $3,
+                brewery_id = $4,
+                brewery = $5,
+                style = $6,
+                abv = $7,
+                abv_out_of_range = $8,
+                short_desc = $9,
+                version = $10
+        WHERE 
+                id = $1 AND version = $2`
+
+	res, err := s.db.ExecContext(ctx, query,
+		b.ID,
+		version,
+		b.Name,
+		b.BreweryID,
+		b.Brewery,
+		b.Style,
+		b.ABV,
+		b.ABVOutOfRange,
+		b.ShortDesc,
+		b.Version)
+
+	if err != nil {
+		if isViolation(err, uniqueViolation) {
+			return beers.ErrAlreadyExists
+		}
+		if isConstraintViolation(err, foreignKeyViolation, beersBreweryKey) {
+			return breweries.ErrNotFound
+		}
+		return err
+	}
+
+	return s.checkVersion(ctx, res, b.ID)
+}
+
+// DeleteBeer deletes a beer from the database, as long as its stored version
+// is still the given version.
+func (s *Store) DeleteBeer(ctx context.Context, id string, version int) error {
+	query := `DELETE FROM beers WHERE id = $1 AND version = $2`
+
+	res, err := s.db.ExecContext(ctx, query, id, version)
+	if err != nil {
+		return err
+	}
+
+	return s.checkVersion(ctx, res, id)
+}
+
+// checkVersion tells why a versioned write did not affect the beer: either it
+// does not exist or it was changed since the given version was read.
+func (s *Store) checkVersion(ctx context.Context, res sql.Result, id string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	query := `SELECT EXISTS(SELECT 1 FROM beers WHERE id = $1)`
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return beers.ErrNotFound
+	}
+
+	return beers.ErrVersionMismatch
 }
 
-func (s *Store) CreateReview(ctx context.Context, r reviews.Review) error {
+// SearchBeers returns the beers matching the search terms from the
+// database, ranked by relevance, along with the style and brewery facets.
+func (s *Store) SearchBeers(ctx context.Context, q listing.SearchQuery) (listing.SearchResult, error) {
+	// Beers match when the full text query matches or, to tolerate typos,
+	// when the terms are similar enough to the words of the beer.
+	const match = `(b.search_vector @@ to_tsquery('simple', $1) OR $2 <% b.search_text)`
+
+	query := `
+        SELECT 
+                b.id,
+                b.name,
+                b.brewery_id,
+                b.brewery,
+                b.style,
+                b.abv,
+                b.abv_out_of_range,
+                b.short_desc,
+                b.score,
+                b.review_count,
+                b.score_sum,
+                b.aroma,
+                b.appearance,
+                b.taste,
+                b.mouthfeel,
+                b.overall,
+                b.created_at,
+                b.version,
+                ts_rank(b.search_vector, to_tsquery('simple', $1)) + word_similarity($2, b.search_text) AS rank
+        FROM 
+                beers AS b
+        WHERE 
+                ` + match + `
+        ORDER BY
+                rank DESC, b.id
+        LIMIT $3`
+
+	tsquery, terms := searchTerms(q.Q)
+
+	rows, err := s.db.QueryContext(ctx, query, tsquery, terms, q.Limit)
+	if err != nil {
+		return listing.SearchResult{}, err
+	}
+	defer rows.Close()
+
+	var res listing.SearchResult
+	for rows.Next() {
+		var (
+			h   listing.SearchHit
+			sub reviews.Scores
+		)
+
+		err := rows.Scan(
+			&h.ID,
+			&h.Name,
+			&h.BreweryID,
+			&h.Brewery,
+			&h.Style,
+			&h.ABV,
+			&h.ABVOutOfRange,
+			&h.ShortDesc,
+			&h.Score,
+			&h.ReviewCount,
+			&h.ScoreSum,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall,
+			&h.CreatedAt,
+			&h.Version,
+			&h.Rank)
+
+		if err != nil {
+			return listing.SearchResult{}, err
+		}
+
+		h.SubScores = ratedScores(sub)
+		res.Beers = append(res.Beers, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return listing.SearchResult{}, err
+	}
+
+	// Count every match, not only the returned page, by style and brewery.
+	query = `
+        SELECT 
+                GROUPING(b.style) = 0 AS is_style,
+                COALESCE(b.style, b.brewery) AS value,
+                COUNT(*) AS count
+        FROM 
+                beers AS b
+        WHERE 
+                ` + match + `
+        GROUP BY 
+                GROUPING SETS ((b.style), (b.brewery))
+        ORDER BY
+                count DESC, value`
+
+	rows, err = s.db.QueryContext(ctx, query, tsquery, terms)
+	if err != nil {
+		return listing.SearchResult{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			isStyle bool
+			f       listing.Facet
+		)
+
+		if err := rows.Scan(&isStyle, &f.Value, &f.Count); err != nil {
+			return listing.SearchResult{}, err
+		}
+
+		if isStyle {
+			res.Facets.Styles = append(res.Facets.Styles, f)
+			continue
+		}
+		res.Facets.Breweries = append(res.Facets.Breweries, f)
+	}
+
+	return res, rows.Err()
+}
+
+// CreateReview creates a new review, along with its first revision, its
+// notification and the deliveries of its event, on the database and adds it to
+// the beer score. The review streams are notified once it's committed.
+func (s *Store) CreateReview(ctx context.Context, r reviews.Review, n notifications.Notification, e webhooks.Event) error {
 	query := `
         INSERT INTO reviews (
                 id,
                 beer_id,
                 user_id,
                 score,
+                aroma,
+                appearance,
+                taste,
+                mouthfeel,
+                overall,
                 comment,
-                created_at
+                revision,
+                created_at,
+                updated_at
         ) VALUES (
-                $1, $2, $3, $4, $5, $6
+                $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
         )`
 
-	_, err := s.db.ExecContext(ctx, query,
-		r.ID,
-		r.BeerID,
-		r.UserID,
-		r.Score,
-		r.Comment,
-		r.CreatedAt)
+	sub := subScoresOf(r.SubScores)
 
-	return err
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query,
+			r.ID,
+			r.BeerID,
+			r.UserID,
+			r.Score,
+			sub.Aroma,
+			sub.Appearance,
+			sub.Taste,
+			sub.Mouthfeel,
+			sub.Overall,
+			r.Comment,
+			r.Revision,
+			r.CreatedAt,
+			r.UpdatedAt)
+
+		if err != nil {
+			if isConstraintViolation(err, foreignKeyViolation, reviewsUserKey) {
+				return users.ErrNotFound
+			}
+			if isViolation(err, foreignKeyViolation) {
+				return beers.ErrNotFound
+			}
+			if isConstraintViolation(err, uniqueViolation, reviewsBeerUserKey) {
+				return reviews.ErrAlreadyReviewed
+			}
+			return err
+		}
+
+		var d scoreDelta
+		d.add(r.Score, r.SubScores, 1)
+
+		if err := updateScore(ctx, tx, r.BeerID, d); err != nil {
+			return err
+		}
+
+		if err := createRevision(ctx, tx, revisionOf(r)); err != nil {
+			return err
+		}
+
+		if err := createNotification(ctx, tx, n); err != nil {
+			return err
+		}
+
+		if err := notifyReview(ctx, tx, r); err != nil {
+			return err
+		}
 
+		return queueDeliveries(ctx, tx, e)
+	})
+}
+
+// GetReview returns a review from the database.
+func (s *Store) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	return s.getReview(ctx, "r.id = $1", id)
+}
+
+// GetUserReview returns the review of a beer by a user from the database.
+func (s *Store) GetUserReview(ctx context.Context, beerID, userID string) (*reviews.Review, error) {
+	return s.getReview(ctx, "r.beer_id = $1 AND r.user_id = $2", beerID, userID)
+}
+
+// getReview returns the review matching the condition from the database.
+func (s *Store) getReview(ctx context.Context, where string, args ...any) (*reviews.Review, error) {
+	query := `
+        SELECT 
+                r.id,
+                r.beer_id,
+                r.user_id,
+                r.score,
+                r.aroma,
+                r.appearance,
+                r.taste,
+                r.mouthfeel,
+                r.overall,
+                r.comment,
+                r.revision,
+                r.created_at,
+                r.updated_at
+        FROM 
+                reviews AS r
+        WHERE 
+                ` + where
+
+	var (
+		r   reviews.Review
+		sub reviews.Scores
+	)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&r.ID,
+		&r.BeerID,
+		&r.UserID,
+		&r.Score,
+		&sub.Aroma,
+		&sub.Appearance,
+		&sub.Taste,
+		&sub.Mouthfeel,
+		&sub.Overall,
+		&r.Comment,
+		&r.Revision,
+		&r.CreatedAt,
+		&r.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, reviews.ErrNotFound
+		}
+		return nil, err
+	}
+
+	r.SubScores = ratedScores(sub)
+	return &r, nil
+}
+
+// UpdateReview stores the current revision of a review on the database,
+// appends it to the review history and replaces the previous score in the
+// beer score, as long as the stored revision is still the previous one.
+func (s *Store) UpdateReview(ctx context.Context, r reviews.Review) error {
+	query := `
+        UPDATE 
+                reviews AS r
+        SET 
+                score = $2,
+                aroma = $3,
+                appearance = $4,
+                taste = $5,
+                mouthfeel = $6,
+                overall = $7,
+                comment = $8,
+                revision = $9,
+                updated_at = $10
+        FROM 
+                (
+                        SELECT id, score, aroma, appearance, taste, mouthfeel, overall
+                        FROM reviews 
+                        WHERE id = $1 AND revision = $9 - 1
+                        FOR UPDATE
+                ) AS prev
+        WHERE 
+                r.id = prev.id
+        RETURNING
+                prev.score,
+                prev.aroma,
+                prev.appearance,
+                prev.taste,
+                prev.mouthfeel,
+                prev.overall`
+
+	sub := subScoresOf(r.SubScores)
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var (
+			prevScore float32
+			prevSub   reviews.Scores
+		)
+
+		err := tx.QueryRowContext(ctx, query,
+			r.ID,
+			r.Score,
+			sub.Aroma,
+			sub.Appearance,
+			sub.Taste,
+			sub.Mouthfeel,
+			sub.Overall,
+			r.Comment,
+			r.Revision,
+			r.UpdatedAt).Scan(
+			&prevScore,
+			&prevSub.Aroma,
+			&prevSub.Appearance,
+			&prevSub.Taste,
+			&prevSub.Mouthfeel,
+			&prevSub.Overall)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return reviewNotUpdated(ctx, tx, r.ID)
+			}
+			return err
+		}
+
+		var d scoreDelta
+		d.add(prevScore, &prevSub, -1)
+		d.add(r.Score, r.SubScores, 1)
+
+		if err := updateScore(ctx, tx, r.BeerID, d); err != nil {
+			return err
+		}
+
+		err = createRevision(ctx, tx, revisionOf(r))
+		if isConstraintViolation(err, uniqueViolation, reviewRevisionsKey) {
+			return reviews.ErrRevisionConflict
+		}
+		return err
+	})
+}
+
+// reviewNotUpdated returns why a review was not updated: either it does not
+// exist or its revision changed.
+func reviewNotUpdated(ctx context.Context, tx *sql.Tx, id string) error {
+	query := `SELECT EXISTS(SELECT 1 FROM reviews WHERE id = $1)`
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return reviews.ErrNotFound
+	}
+
+	return reviews.ErrRevisionConflict
+}
+
+// DeleteReview deletes a review and its history from the database and
+// removes it from the beer score.
+func (s *Store) DeleteReview(ctx context.Context, id string) error {
+	query := `
+        DELETE FROM 
+                reviews 
+        WHERE 
+                id = $1 
+        RETURNING 
+                beer_id,
+                score,
+                aroma,
+                appearance,
+                taste,
+                mouthfeel,
+                overall`
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var (
+			beerID string
+			score  float32
+			sub    reviews.Scores
+		)
+
+		err := tx.QueryRowContext(ctx, query, id).Scan(
+			&beerID,
+			&score,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return reviews.ErrNotFound
+			}
+			return err
+		}
+
+		var d scoreDelta
+		d.add(score, &sub, -1)
+
+		return updateScore(ctx, tx, beerID, d)
+	})
+}
+
+// ListReviewRevisions returns the history of a review from the database.
+func (s *Store) ListReviewRevisions(ctx context.Context, id string) ([]reviews.Revision, error) {
+	query := `
+        SELECT 
+                rr.review_id,
+                rr.revision,
+                rr.score,
+                rr.aroma,
+                rr.appearance,
+                rr.taste,
+                rr.mouthfeel,
+                rr.overall,
+                rr.comment,
+                rr.created_at
+        FROM 
+                review_revisions AS rr
+        WHERE 
+                rr.review_id = $1
+        ORDER BY 
+                rr.revision`
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []reviews.Revision
+	for rows.Next() {
+		var (
+			r   reviews.Revision
+			sub reviews.Scores
+		)
+
+		err := rows.Scan(
+			&r.ReviewID,
+			&r.Revision,
+			&r.Score,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall,
+			&r.Comment,
+			&r.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.SubScores = ratedScores(sub)
+		list = append(list, r)
+	}
+
+	return list, rows.Err()
 }
 
 // ListReviews returns a list of reviews from the database.
 func (s *Store) ListReviews(ctx context.Context, id string) ([]reviews.Review, error) {
+	return listReviews(ctx, s.db, id, sql.NullInt64{})
+}
+
+// ListReviewsAfter returns up to limit reviews created after the given one
+// from the database, from the oldest to the most recent. The reviews of every
+// beer are returned when the beer ID is empty.
+func (s *Store) ListReviewsAfter(ctx context.Context, beerID string, after reviews.Review, limit int) ([]reviews.Review, error) {
+	query := `
+        SELECT
+                r.id,
+                r.beer_id,
+                r.user_id,
+                r.score,
+                r.aroma,
+                r.appearance,
+                r.taste,
+                r.mouthfeel,
+                r.overall,
+                r.comment,
+                r.revision,
+                r.created_at,
+                r.updated_at
+        FROM
+                reviews AS r
+        WHERE
+                ($1::UUID IS NULL OR r.beer_id = $1::UUID) AND
+                (r.created_at, r.id) > ($2, $3)
+        ORDER BY
+                r.created_at, r.id
+        LIMIT $4`
+
+	beer := sql.NullString{String: beerID, Valid: beerID != ""}
+
+	rows, err := s.db.QueryContext(ctx, query, beer, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReviews(rows)
+}
+
+// reviewStats returns the aggregated data of the reviews of a beer from the
+// database or from a transaction.
+func reviewStats(ctx context.Context, q querier, id string) (reviews.Stats, error) {
+	query := `
+        SELECT 
+                FLOOR(r.score)::int AS bucket,
+                COUNT(*),
+                MIN(r.created_at),
+                MAX(r.created_at)
+        FROM 
+                reviews AS r
+        WHERE 
+                r.beer_id = $1
+        GROUP BY 
+                bucket
+        ORDER BY 
+                bucket`
+
+	rows, err := q.QueryContext(ctx, query, id)
+	if err != nil {
+		return reviews.Stats{}, err
+	}
+	defer rows.Close()
+
+	var stats reviews.Stats
+	for rows.Next() {
+		var (
+			b             reviews.Bucket
+			first, latest time.Time
+		)
+
+		if err := rows.Scan(&b.Score, &b.Count, &first, &latest); err != nil {
+			return reviews.Stats{}, err
+		}
+
+		stats.Count += b.Count
+		stats.Distribution = append(stats.Distribution, b)
+
+		if stats.FirstAt == nil || first.Before(*stats.FirstAt) {
+			stats.FirstAt = &first
+		}
+		if stats.LastAt == nil || latest.After(*stats.LastAt) {
+			stats.LastAt = &latest
+		}
+	}
+
+	return stats, rows.Err()
+}
+
+// listReviews returns the reviews of a beer from the database or from a
+// transaction, from the most recent to the oldest. A null limit returns every
+// review.
+func listReviews(ctx context.Context, q querier, id string, limit sql.NullInt64) ([]reviews.Review, error) {
 	query := `
         SELECT 
                 r.id,
                 r.beer_id,
                 r.user_id,
                 r.score,
+                r.aroma,
+                r.appearance,
+                r.taste,
+                r.mouthfeel,
+                r.overall,
                 r.comment,
-                r.created_at
+                r.revision,
+                r.created_at,
+                r.updated_at
         FROM 
                 reviews AS r
         WHERE 
                 r.beer_id = $1
         ORDER BY 
-                r.created_at DESC`
+                r.created_at DESC, r.id DESC
+        LIMIT $2`
 
-	rows, err := s.db.QueryContext(ctx, query, id)
+	rows, err := q.QueryContext(ctx, query, id, limit)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanReviews(rows)
+}
+
+// scanReviews reads the reviews selected by the queries, closing the rows.
+func scanReviews(rows *sql.Rows) ([]reviews.Review, error) {
 	defer rows.Close()
 
 	var list []reviews.Review
 	for rows.Next() {
-		var r reviews.Review
+		var (
+			r   reviews.Review
+			sub reviews.Scores
+		)
 
 		err := rows.Scan(
 			&r.ID,
 			&r.BeerID,
 			&r.UserID,
 			&r.Score,
+			&sub.Aroma,
+			&sub.Appearance,
+			&sub.Taste,
+			&sub.Mouthfeel,
+			&sub.Overall,
 			&r.Comment,
-			&r.CreatedAt)
+			&r.Revision,
+			&r.CreatedAt,
+			&r.UpdatedAt)
 
 		if err != nil {
 			return nil, err
 		}
 
+		r.SubScores = ratedScores(sub)
 		list = append(list, r)
 	}
 
-	return list, nil
+	return list, rows.Err()
+}
+
+// withTx runs fn inside a transaction, which is committed if fn succeeds and
+// rolled back otherwise.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// withSnapshot runs fn in a read-only transaction, so every query of fn reads
+// the same snapshot of the database.
+func (s *Store) withSnapshot(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// querier is implemented by the database and by its transactions, so the
+// queries shared by the reads run on either.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scoreDelta is a change to the review aggregates of a beer.
+type scoreDelta struct {
+	count     int
+	sum       float64
+	subCounts [reviews.NumDimensions]int
+	subSums   [reviews.NumDimensions]float64
+}
+
+// add adds the scores of a review to the delta, or removes them when the sign
+// is negative.
+func (d *scoreDelta) add(score float32, sub *reviews.Scores, sign int) {
+	d.count += sign
+	d.sum += float64(sign) * float64(score)
+
+	if sub == nil {
+		return
+	}
+
+	for i, v := range sub.Dimensions() {
+		if v != nil {
+			d.subCounts[i] += sign
+			d.subSums[i] += float64(sign) * float64(*v)
+		}
+	}
+}
+
+// updateScore adds the delta to the review aggregates of a beer.
+func updateScore(ctx context.Context, tx *sql.Tx, beerID string, d scoreDelta) error {
+	query := `
+        UPDATE 
+                beers
+        SET 
+                review_count = review_count + $2,
+                score_sum = score_sum + $3,
+                aroma_count = aroma_count + $4,
+                aroma_sum = aroma_sum + $5,
+                appearance_count = appearance_count + $6,
+                appearance_sum = appearance_sum + $7,
+                taste_count = taste_count + $8,
+                taste_sum = taste_sum + $9,
+                mouthfeel_count = mouthfeel_count + $10,
+                mouthfeel_sum = mouthfeel_sum + $11,
+                overall_count = overall_count + $12,
+                overall_sum = overall_sum + $13
+        WHERE 
+                id = $1`
+
+	args := []any{beerID, d.count, d.sum}
+	for i := range d.subCounts {
+		args = append(args, d.subCounts[i], d.subSums[i])
+	}
+
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// createRevision appends a revision to the history of a review.
+func createRevision(ctx context.Context, tx *sql.Tx, r reviews.Revision) error {
+	query := `
+        INSERT INTO review_revisions (
+                review_id,
+                revision,
+                score,
+                aroma,
+                appearance,
+                taste,
+                mouthfeel,
+                overall,
+                comment,
+                created_at
+        ) VALUES (
+                $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+        )`
+
+	sub := subScoresOf(r.SubScores)
+
+	_, err := tx.ExecContext(ctx, query,
+		r.ReviewID,
+		r.Revision,
+		r.Score,
+		sub.Aroma,
+		sub.Appearance,
+		sub.Taste,
+		sub.Mouthfeel,
+		sub.Overall,
+		r.Comment,
+		r.CreatedAt)
+
+	return err
+}
+
+// revisionOf returns the current revision of a review.
+func revisionOf(r reviews.Review) reviews.Revision {
+	return reviews.Revision{
+		ReviewID:  r.ID,
+		Revision:  r.Revision,
+		Score:     r.Score,
+		SubScores: r.SubScores,
+		Comment:   r.Comment,
+		CreatedAt: r.UpdatedAt,
+	}
+}
+
+// subScoresOf returns the sub-scores of a review, leaving every dimension
+// unrated when it has none.
+func subScoresOf(s *reviews.Scores) reviews.Scores {
+	if s == nil {
+		return reviews.Scores{}
+	}
+	return *s
+}
+
+// ratedScores returns the sub-scores read from the database, or nil when no
+// dimension was rated.
+func ratedScores(s reviews.Scores) *reviews.Scores {
+	if s.Empty() {
+		return nil
+	}
+	return &s
+}
+
+// searchTerms normalizes the search terms, returning them as a full text
+// query where every term must match as a prefix and as plain text for the
+// similarity match.
+func searchTerms(q string) (tsquery string, terms string) {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	prefixes := make([]string, len(words))
+	for i, w := range words {
+		prefixes[i] = w + ":*"
+	}
+
+	return strings.Join(prefixes, " & "), strings.Join(words, " ")
 }
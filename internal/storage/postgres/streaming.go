@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/pkg/logger"
+)
+
+// reviewsChannel is the channel notified with the ID of every review created.
+const reviewsChannel = "reviews"
+
+// Set of timings of the connection listening to the notifications.
+const (
+	minReconnect = 10 * time.Second
+	maxReconnect = time.Minute
+	pingInterval = 90 * time.Second
+)
+
+// ListenReviews calls fn with every review created on the database, by any
+// instance, until the context is canceled. The reviews created while the
+// connection is being reestablished are missed, and recovered by the clients
+// resuming their streams.
+func ListenReviews(ctx context.Context, cfg Config, log *logger.Logger, s *Store, fn func(reviews.Review)) error {
+	l := pq.NewListener(dataSource(cfg), minReconnect, maxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error(ctx, "streaming", "status", "listening to reviews", "ERROR", err)
+		}
+	})
+	defer l.Close()
+
+	if err := l.Listen(reviewsChannel); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			// Check the connection, which is reestablished if it's lost.
+			go l.Ping()
+
+		case n := <-l.Notify:
+			// A nil notification follows a reconnection.
+			if n == nil {
+				log.Warn(ctx, "streaming", "status", "listener reconnected, reviews may have been missed")
+				continue
+			}
+
+			r, err := s.GetReview(ctx, n.Extra)
+			switch {
+			case errors.Is(err, reviews.ErrNotFound):
+				// The review was deleted in the meantime.
+				continue
+			case err != nil:
+				log.Error(ctx, "streaming", "status", "get review", "id", n.Extra, "ERROR", err)
+				continue
+			}
+
+			fn(*r)
+		}
+	}
+}
+
+// notifyReview notifies the listeners of the review once the transaction is
+// committed.
+func notifyReview(ctx context.Context, tx *sql.Tx, r reviews.Review) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, reviewsChannel, r.ID)
+	return err
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// Set of constraints keeping the handles and the emails unique.
+const (
+	usersHandleKey = "users_handle_key"
+	usersEmailKey  = "users_email_key"
+)
+
+// CreateUser stores a new user on the database. The handle and the email must
+// not belong to another user.
+func (s *Store) CreateUser(ctx context.Context, u users.User) error {
+	query := `
+        INSERT INTO users (
+                id,
+                handle,
+                email,
+                display_name,
+                bio,
+                role,
+                password_hash,
+                created_at,
+                updated_at
+        ) VALUES (
+                $1, $2, $3, $4, $5, $6, $7, $8, $9
+        )`
+
+	_, err := s.db.ExecContext(ctx, query,
+		u.ID,
+		u.Handle,
+		u.Email,
+		u.DisplayName,
+		u.Bio,
+		u.Role,
+		u.PasswordHash,
+		u.CreatedAt,
+		u.UpdatedAt)
+
+	return userError(err)
+}
+
+// GetUser returns a user from the database.
+func (s *Store) GetUser(ctx context.Context, id string) (*users.User, error) {
+	query := `
+        SELECT
+                u.id,
+                u.handle,
+                u.email,
+                u.display_name,
+                u.bio,
+                u.role,
+                u.password_hash,
+                u.created_at,
+                u.updated_at
+        FROM
+                users AS u
+        WHERE
+                u.id = $1`
+
+	var u users.User
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&u.ID,
+		&u.Handle,
+		&u.Email,
+		&u.DisplayName,
+		&u.Bio,
+		&u.Role,
+		&u.PasswordHash,
+		&u.CreatedAt,
+		&u.UpdatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, users.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+// UpdateUser stores the changes of a user on the database. The handle and the
+// email must not belong to another user.
+func (s *Store) UpdateUser(ctx context.Context, u users.User) error {
+	query := `
+        UPDATE
+                users
+        SET
+                handle = $2,
+                email = $3,
+                display_name = $4,
+                bio = $5,
+                role = $6,
+                password_hash = $7,
+                updated_at = $8
+        WHERE
+                id = $1`
+
+	res, err := s.db.ExecContext(ctx, query,
+		u.ID,
+		u.Handle,
+		u.Email,
+		u.DisplayName,
+		u.Bio,
+		u.Role,
+		u.PasswordHash,
+		u.UpdatedAt)
+
+	if err != nil {
+		return userError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return users.ErrNotFound
+	}
+
+	return nil
+}
+
+// userError converts the violations of the unique handles and emails to
+// their domain errors.
+func userError(err error) error {
+	switch {
+	case isConstraintViolation(err, uniqueViolation, usersHandleKey):
+		return users.ErrHandleTaken
+	case isConstraintViolation(err, uniqueViolation, usersEmailKey):
+		return users.ErrEmailTaken
+	}
+	return err
+}
@@ -0,0 +1,390 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// CreateSubscription stores a new subscription on the database.
+func (s *Store) CreateSubscription(ctx context.Context, sub webhooks.Subscription) error {
+	query := `
+        INSERT INTO webhook_subscriptions (
+                id,
+                url,
+                events,
+                secret,
+                created_at
+        ) VALUES (
+                $1, $2, $3, $4, $5
+        )`
+
+	_, err := s.db.ExecContext(ctx, query,
+		sub.ID,
+		sub.URL,
+		pq.Array(sub.Events),
+		sub.Secret,
+		sub.CreatedAt)
+
+	return err
+}
+
+// GetSubscription returns a subscription from the database.
+func (s *Store) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	query := `
+        SELECT
+                ws.id,
+                ws.url,
+                ws.events,
+                ws.secret,
+                ws.created_at
+        FROM
+                webhook_subscriptions AS ws
+        WHERE
+                ws.id = $1`
+
+	var sub webhooks.Subscription
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&sub.ID,
+		&sub.URL,
+		pq.Array(&sub.Events),
+		&sub.Secret,
+		&sub.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, webhooks.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// ListSubscriptions returns every subscription from the database, from the
+// oldest to the most recent.
+func (s *Store) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	query := `
+        SELECT
+                ws.id,
+                ws.url,
+                ws.events,
+                ws.secret,
+                ws.created_at
+        FROM
+                webhook_subscriptions AS ws
+        ORDER BY
+                ws.created_at, ws.id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []webhooks.Subscription
+	for rows.Next() {
+		var sub webhooks.Subscription
+
+		err := rows.Scan(
+			&sub.ID,
+			&sub.URL,
+			pq.Array(&sub.Events),
+			&sub.Secret,
+			&sub.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, sub)
+	}
+
+	return list, rows.Err()
+}
+
+// DeleteSubscription deletes a subscription and its deliveries from the
+// database.
+func (s *Store) DeleteSubscription(ctx context.Context, id string) error {
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	res, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return webhooks.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription from
+// the database.
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhooks.Delivery, error) {
+	query := `
+        SELECT
+                ` + deliveryColumns + `
+        FROM
+                webhook_deliveries AS wd
+        WHERE
+                wd.subscription_id = $1
+        ORDER BY
+                wd.created_at DESC, wd.id DESC
+        LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// GetDelivery returns a delivery from the database.
+func (s *Store) GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error) {
+	query := `
+        SELECT
+                ` + deliveryColumns + `
+        FROM
+                webhook_deliveries AS wd
+        WHERE
+                wd.id = $1`
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, webhooks.ErrDeliveryNotFound
+	}
+
+	return &list[0], nil
+}
+
+// CreateDelivery queues a new delivery on the database.
+func (s *Store) CreateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		err := createDelivery(ctx, tx, d)
+		if isViolation(err, foreignKeyViolation) {
+			return webhooks.ErrNotFound
+		}
+		return err
+	})
+}
+
+// ClaimDeliveries returns the pending deliveries due at the given time,
+// postponing them by the lease. The deliveries locked by other dispatchers are
+// skipped.
+func (s *Store) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhooks.Delivery, error) {
+	query := `
+        UPDATE
+                webhook_deliveries AS wd
+        SET
+                next_attempt_at = $2
+        WHERE
+                wd.id IN (
+                        SELECT id
+                        FROM webhook_deliveries
+                        WHERE status = $3 AND next_attempt_at <= $1
+                        ORDER BY next_attempt_at, id
+                        LIMIT $4
+                        FOR UPDATE SKIP LOCKED
+                )
+        RETURNING
+                ` + deliveryColumns
+
+	rows, err := s.db.QueryContext(ctx, query, now, now.Add(lease), webhooks.StatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeliveries(rows)
+}
+
+// UpdateDelivery stores the result of a delivery attempt.
+func (s *Store) UpdateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	query := `
+        UPDATE
+                webhook_deliveries
+        SET
+                status = $2,
+                attempts = $3,
+                last_error = $4,
+                response_status = $5,
+                next_attempt_at = $6,
+                delivered_at = $7
+        WHERE
+                id = $1`
+
+	res, err := s.db.ExecContext(ctx, query,
+		d.ID,
+		d.Status,
+		d.Attempts,
+		d.LastError,
+		d.ResponseStatus,
+		d.NextAttemptAt,
+		d.DeliveredAt)
+
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return webhooks.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// deliveryColumns are the columns read into a delivery by scanDeliveries.
+const deliveryColumns = `wd.id,
+                wd.subscription_id,
+                wd.event,
+                wd.status,
+                wd.attempts,
+                wd.last_error,
+                wd.response_status,
+                wd.next_attempt_at,
+                wd.created_at,
+                wd.delivered_at`
+
+// scanDeliveries reads the deliveries selected with deliveryColumns, closing
+// the rows.
+func scanDeliveries(rows *sql.Rows) ([]webhooks.Delivery, error) {
+	defer rows.Close()
+
+	var list []webhooks.Delivery
+	for rows.Next() {
+		var (
+			d           webhooks.Delivery
+			event       []byte
+			deliveredAt sql.NullTime
+		)
+
+		err := rows.Scan(
+			&d.ID,
+			&d.SubscriptionID,
+			&event,
+			&d.Status,
+			&d.Attempts,
+			&d.LastError,
+			&d.ResponseStatus,
+			&d.NextAttemptAt,
+			&d.CreatedAt,
+			&deliveredAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(event, &d.Event); err != nil {
+			return nil, fmt.Errorf("decoding delivery[id=%s] event: %w", d.ID, err)
+		}
+
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+
+		list = append(list, d)
+	}
+
+	return list, rows.Err()
+}
+
+// queueDeliveries queues the deliveries of the event to the subscriptions
+// accepting it.
+func queueDeliveries(ctx context.Context, tx *sql.Tx, e webhooks.Event) error {
+	query := `
+        SELECT
+                ws.id
+        FROM
+                webhook_subscriptions AS ws
+        WHERE
+                $1 = ANY(ws.events)
+        FOR SHARE`
+
+	rows, err := tx.QueryContext(ctx, query, e.Type)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := createDelivery(ctx, tx, webhooks.NewDelivery(id, e)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createDelivery stores a delivery.
+func createDelivery(ctx context.Context, tx *sql.Tx, d webhooks.Delivery) error {
+	event, err := json.Marshal(d.Event)
+	if err != nil {
+		return fmt.Errorf("encoding delivery[id=%s] event: %w", d.ID, err)
+	}
+
+	query := `
+        INSERT INTO webhook_deliveries (
+                id,
+                subscription_id,
+                event,
+                status,
+                attempts,
+                last_error,
+                response_status,
+                next_attempt_at,
+                created_at
+        ) VALUES (
+                $1, $2, $3, $4, $5, $6, $7, $8, $9
+        )`
+
+	_, err = tx.ExecContext(ctx, query,
+		d.ID,
+		d.SubscriptionID,
+		string(event),
+		d.Status,
+		d.Attempts,
+		d.LastError,
+		d.ResponseStatus,
+		d.NextAttemptAt,
+		d.CreatedAt)
+
+	return err
+}
@@ -0,0 +1,1445 @@
+// Package storagetest contains the conformance suite every storage
+// implementation must pass, so they all behave the same way.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/adding"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/breweries"
+	"github.com/phbpx/gobeer/internal/delivering"
+	"github.com/phbpx/gobeer/internal/editing"
+	"github.com/phbpx/gobeer/internal/listing"
+	"github.com/phbpx/gobeer/internal/notifications"
+	"github.com/phbpx/gobeer/internal/notifying"
+	"github.com/phbpx/gobeer/internal/registering"
+	"github.com/phbpx/gobeer/internal/reviewing"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/streaming"
+	"github.com/phbpx/gobeer/internal/styles"
+	"github.com/phbpx/gobeer/internal/subscribing"
+	"github.com/phbpx/gobeer/internal/users"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// Storage defines the interfaces a storage implementation must provide.
+type Storage interface {
+	adding.Repository
+	editing.Repository
+	listing.Repository
+	reviewing.Storer
+	notifying.Repository
+	subscribing.Repository
+	delivering.Repository
+	streaming.Repository
+	registering.Repository
+}
+
+// Run runs the conformance suite. Every test gets a new storage from
+// newStorage, which must be empty.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("Beers", func(t *testing.T) { testBeers(t, newStorage(t)) })
+	t.Run("Breweries", func(t *testing.T) { testBreweries(t, newStorage(t)) })
+	t.Run("ListBeers", func(t *testing.T) { testListBeers(t, newStorage(t)) })
+	t.Run("SearchBeers", func(t *testing.T) { testSearchBeers(t, newStorage(t)) })
+	t.Run("Reviews", func(t *testing.T) { testReviews(t, newStorage(t)) })
+	t.Run("SubScores", func(t *testing.T) { testSubScores(t, newStorage(t)) })
+	t.Run("TopBeers", func(t *testing.T) { testTopBeers(t, newStorage(t)) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStorage(t)) })
+	t.Run("Notifications", func(t *testing.T) { testNotifications(t, newStorage(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStorage(t)) })
+}
+
+// =============================================================================
+
+// now returns the current time with the precision every storage can keep.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// newBeer returns a beer ready to be created. The ID of its brewery is derived
+// from the name, so the beers of a brewery share it.
+func newBeer(name, brewery, style string, abv float32, createdAt time.Time) beers.Beer {
+	return beers.Beer{
+		ID:        uuid.NewString(),
+		Name:      name,
+		BreweryID: breweries.LegacyID(brewery),
+		Brewery:   brewery,
+		Style:     style,
+		ABV:       abv,
+		ShortDesc: fmt.Sprintf("A %s from %s", style, brewery),
+		CreatedAt: createdAt,
+		Version:   1,
+	}
+}
+
+// newBrewery returns a brewery ready to be created.
+func newBrewery(name string, createdAt time.Time) breweries.Brewery {
+	return breweries.Brewery{
+		ID:          breweries.LegacyID(name),
+		Name:        name,
+		Location:    "Somewhere",
+		Website:     "https://example.com",
+		FoundedYear: 2007,
+		CreatedAt:   createdAt,
+	}
+}
+
+// newReview returns a review ready to be created.
+func newReview(beerID string, score float32, createdAt time.Time) reviews.Review {
+	return reviews.Review{
+		ID:        uuid.NewString(),
+		BeerID:    beerID,
+		UserID:    uuid.NewString(),
+		Score:     score,
+		Comment:   "A comment",
+		Revision:  1,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+// newUser returns a user ready to be created.
+func newUser(handle string, createdAt time.Time) users.User {
+	return users.User{
+		ID:           uuid.NewString(),
+		Handle:       handle,
+		Email:        handle + "@example.com",
+		DisplayName:  handle,
+		Role:         users.RoleMember,
+		PasswordHash: "pbkdf2-sha256$1$c2FsdA$a2V5",
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+}
+
+// newNotification returns the notification of the review creation.
+func newNotification(r reviews.Review) notifications.Notification {
+	id := uuid.NewString()
+
+	return notifications.Notification{
+		ID:       id,
+		Kind:     notifications.KindReviewCreated,
+		UserID:   r.UserID,
+		ReviewID: r.ID,
+		Event: notifications.Event{
+			Version:    notifications.EventVersion,
+			ID:         id,
+			Type:       notifications.KindReviewCreated,
+			OccurredAt: r.CreatedAt,
+			UserID:     r.UserID,
+			Review: notifications.EventReview{
+				ID:        r.ID,
+				Score:     r.Score,
+				Comment:   r.Comment,
+				CreatedAt: r.CreatedAt,
+				UpdatedAt: r.UpdatedAt,
+			},
+			Beer: notifications.EventBeer{
+				ID:      r.BeerID,
+				Name:    "IPA",
+				Brewery: "BrewDog",
+			},
+		},
+		Status:        notifications.StatusPending,
+		NextAttemptAt: r.CreatedAt,
+		CreatedAt:     r.CreatedAt,
+	}
+}
+
+// beerAdded returns the webhook event of the beer creation.
+func beerAdded(b beers.Beer) webhooks.Event {
+	return webhooks.Event{
+		ID:         uuid.NewString(),
+		Type:       webhooks.EventBeerAdded,
+		OccurredAt: b.CreatedAt,
+		Beer:       &b,
+	}
+}
+
+// reviewCreated returns the webhook event of the review creation.
+func reviewCreated(r reviews.Review) webhooks.Event {
+	return webhooks.Event{
+		ID:         uuid.NewString(),
+		Type:       webhooks.EventReviewCreated,
+		OccurredAt: r.CreatedAt,
+		Review:     &r,
+	}
+}
+
+// mustCreateBrewery creates the brewery of the beer, unless it exists.
+func mustCreateBrewery(t *testing.T, s Storage, b beers.Beer) {
+	t.Helper()
+
+	_, err := s.GetBrewery(context.Background(), b.BreweryID)
+	if !errors.Is(err, breweries.ErrNotFound) {
+		return
+	}
+
+	bw := newBrewery(b.Brewery, b.CreatedAt)
+	bw.ID = b.BreweryID
+	if err := s.CreateBrewery(context.Background(), bw); err != nil {
+		t.Fatalf("Should be able to create brewery %q: %v", bw.Name, err)
+	}
+}
+
+func mustCreateBeer(t *testing.T, s Storage, b beers.Beer) beers.Beer {
+	t.Helper()
+	mustCreateBrewery(t, s, b)
+	if err := s.CreateBeer(context.Background(), b, beerAdded(b)); err != nil {
+		t.Fatalf("Should be able to create beer %q: %v", b.Name, err)
+	}
+	return b
+}
+
+// mustCreateAuthor creates the author of the review, unless it exists.
+func mustCreateAuthor(t *testing.T, s Storage, r reviews.Review) {
+	t.Helper()
+
+	_, err := s.GetUser(context.Background(), r.UserID)
+	if !errors.Is(err, users.ErrNotFound) {
+		return
+	}
+
+	u := newUser("user_"+r.UserID[:8], r.CreatedAt)
+	u.ID = r.UserID
+	if err := s.CreateUser(context.Background(), u); err != nil {
+		t.Fatalf("Should be able to create user %q: %v", u.Handle, err)
+	}
+}
+
+func mustCreateReview(t *testing.T, s Storage, r reviews.Review) reviews.Review {
+	t.Helper()
+	mustCreateAuthor(t, s, r)
+	if err := s.CreateReview(context.Background(), r, newNotification(r), reviewCreated(r)); err != nil {
+		t.Fatalf("Should be able to create review: %v", err)
+	}
+	return r
+}
+
+// =============================================================================
+
+func testBeers(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	b := newBeer("IPA", "BrewDog", "IPA", 5.5, now())
+	other := newBeer("Stout", "BrewDog", "Stout", 7, now())
+	other.ABVOutOfRange = true
+
+	t.Log("Given the need to store beers.")
+	{
+		t.Log("\tWhen creating beers.")
+		{
+			mustCreateBeer(t, s, b)
+			mustCreateBeer(t, s, other)
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to get the beer: %v", err)
+			}
+			if got.Name != b.Name || got.BreweryID != b.BreweryID || got.Brewery != b.Brewery || got.Style != b.Style ||
+				got.ABV != b.ABV || got.ShortDesc != b.ShortDesc || got.Score != 0 ||
+				got.Version != 1 || !got.CreatedAt.Equal(b.CreatedAt) {
+				t.Fatalf("\t\t[ERROR] Should get the created beer. Got %+v, want %+v", got, b)
+			}
+
+			got, err = s.GetBeer(ctx, other.ID)
+			if err != nil || !got.ABVOutOfRange {
+				t.Fatalf("\t\t[ERROR] Should keep the ABV flag. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should get the created beer.")
+		}
+
+		t.Log("\tWhen creating a beer with the name of another beer of the brewery.")
+		{
+			dup := newBeer(b.Name, b.Brewery, "IPA", 5, now())
+			if err := s.CreateBeer(ctx, dup, beerAdded(dup)); !errors.Is(err, beers.ErrAlreadyExists) {
+				t.Fatalf("\t\t[ERROR] Should not be able to create the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the beer.")
+		}
+
+		t.Log("\tWhen creating a beer of a brewery that does not exist.")
+		{
+			orphan := newBeer("Orphan", "BrewDog", "IPA", 5, now())
+			orphan.BreweryID = uuid.NewString()
+			if err := s.CreateBeer(ctx, orphan, beerAdded(orphan)); !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should return ErrNotFound. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the beer.")
+		}
+
+		t.Log("\tWhen checking if beers exist.")
+		{
+			exists, err := s.BeerExists(ctx, b.Name, b.BreweryID)
+			if err != nil || !exists {
+				t.Fatalf("\t\t[ERROR] Should find the beer: %v", err)
+			}
+			exists, err = s.BeerExists(ctx, b.Name, uuid.NewString())
+			if err != nil || exists {
+				t.Fatalf("\t\t[ERROR] Should not find the beer of another brewery: %v", err)
+			}
+			t.Log("\t\t[OK] Should only find the existing beers.")
+		}
+
+		t.Log("\tWhen getting a beer that does not exist.")
+		{
+			if _, err := s.GetBeer(ctx, uuid.NewString()); !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find the beer: %v", err)
+			}
+			t.Log("\t\t[OK] Should not find the beer.")
+		}
+
+		t.Log("\tWhen updating a beer.")
+		{
+			up := b
+			up.ShortDesc = "An updated description"
+			up.ABVOutOfRange = true
+			up.Version = 2
+
+			if err := s.UpdateBeer(ctx, up, 2); !errors.Is(err, beers.ErrVersionMismatch) {
+				t.Fatalf("\t\t[ERROR] Should not update an outdated version: %v", err)
+			}
+			if err := s.UpdateBeer(ctx, up, 1); err != nil {
+				t.Fatalf("\t\t[ERROR] Should update the current version: %v", err)
+			}
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || got.ShortDesc != up.ShortDesc || !got.ABVOutOfRange || got.Version != 2 {
+				t.Fatalf("\t\t[ERROR] Should get the updated beer. Got %+v: %v", got, err)
+			}
+
+			up.Name = other.Name
+			up.Version = 3
+			if err := s.UpdateBeer(ctx, up, 2); !errors.Is(err, beers.ErrAlreadyExists) {
+				t.Fatalf("\t\t[ERROR] Should not rename to an existing beer: %v", err)
+			}
+
+			moved := b
+			moved.BreweryID = uuid.NewString()
+			moved.Version = 3
+			if err := s.UpdateBeer(ctx, moved, 2); !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not move the beer to a brewery that does not exist: %v", err)
+			}
+
+			unknown := newBeer("Unknown", "BrewDog", "IPA", 5, now())
+			if err := s.UpdateBeer(ctx, unknown, 1); !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not update a beer that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should only update the current version.")
+		}
+
+		t.Log("\tWhen deleting a beer.")
+		{
+			r := mustCreateReview(t, s, newReview(b.ID, 4, now()))
+
+			if err := s.DeleteBeer(ctx, b.ID, 1); !errors.Is(err, beers.ErrVersionMismatch) {
+				t.Fatalf("\t\t[ERROR] Should not delete an outdated version: %v", err)
+			}
+			if err := s.DeleteBeer(ctx, b.ID, 2); err != nil {
+				t.Fatalf("\t\t[ERROR] Should delete the current version: %v", err)
+			}
+			if _, err := s.GetBeer(ctx, b.ID); !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find the deleted beer: %v", err)
+			}
+			if _, err := s.GetReview(ctx, r.ID); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should delete the beer reviews: %v", err)
+			}
+			if err := s.DeleteBeer(ctx, b.ID, 2); !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not delete a beer that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should only delete the current version.")
+		}
+	}
+}
+
+func testBreweries(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+
+	stone := newBrewery("Stone Brewing", start)
+	bodebrown := newBrewery("Bodebrown", start.Add(time.Minute))
+
+	t.Log("Given the need to store breweries.")
+	{
+		t.Log("\tWhen creating breweries.")
+		{
+			for _, b := range []breweries.Brewery{stone, bodebrown} {
+				if err := s.CreateBrewery(ctx, b); err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to create the brewery: %v", err)
+				}
+			}
+
+			got, err := s.GetBrewery(ctx, stone.ID)
+			if err != nil || *got != stone {
+				t.Fatalf("\t\t[ERROR] Should get the brewery as created. Got %+v: %v", got, err)
+			}
+
+			if _, err := s.GetBrewery(ctx, uuid.NewString()); !errors.Is(err, breweries.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find a brewery that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to create the breweries.")
+		}
+
+		t.Log("\tWhen creating a brewery without founding year.")
+		{
+			b := newBrewery("Lagunitas", start)
+			b.FoundedYear = 0
+			if err := s.CreateBrewery(ctx, b); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the brewery: %v", err)
+			}
+
+			got, err := s.GetBrewery(ctx, b.ID)
+			if err != nil || *got != b {
+				t.Fatalf("\t\t[ERROR] Should get the brewery as created. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should keep the founding year unknown.")
+		}
+
+		t.Log("\tWhen creating another spelling of a brewery.")
+		{
+			b := newBrewery("  stone   BREWING ", start)
+			b.ID = uuid.NewString()
+			if err := s.CreateBrewery(ctx, b); !errors.Is(err, breweries.ErrAlreadyExists) {
+				t.Fatalf("\t\t[ERROR] Should return ErrAlreadyExists. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the brewery.")
+		}
+
+		t.Log("\tWhen listing the breweries.")
+		{
+			list, err := s.ListBreweries(ctx)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to list the breweries: %v", err)
+			}
+
+			var names []string
+			for _, b := range list {
+				names = append(names, b.Name)
+			}
+			if fmt.Sprint(names) != "[Bodebrown Lagunitas Stone Brewing]" {
+				t.Fatalf("\t\t[ERROR] Should list the breweries by name. Got %v", names)
+			}
+			t.Log("\t\t[OK] Should list the breweries by name.")
+		}
+	}
+}
+
+func testListBeers(t *testing.T, s Storage) {
+	start := now()
+
+	list := []beers.Beer{
+		newBeer("Alpha", "BrewDog", "IPA", 5.5, start.Add(1*time.Minute)),
+		newBeer("Bravo", "BrewDog", "Stout", 7.2, start.Add(2*time.Minute)),
+		newBeer("Charlie", "Brooklyn", "IPA", 6.1, start.Add(3*time.Minute)),
+		newBeer("Delta", "Brooklyn", "Lager", 4.7, start.Add(4*time.Minute)),
+		newBeer("Echo", "Lagunitas", "IPA", 6.1, start.Add(5*time.Minute)),
+	}
+	for _, b := range list {
+		mustCreateBeer(t, s, b)
+	}
+
+	scores := map[string]float32{}
+	for i, score := range []float32{3, 4.5, 2, 0, 4.5} {
+		if score == 0 {
+			continue
+		}
+		mustCreateReview(t, s, newReview(list[i].ID, score, now()))
+		scores[list[i].ID] = score
+	}
+
+	svc := listing.NewService(s, styles.DefaultCatalog, reviews.DefaultPrior)
+
+	t.Log("Given the need to list beers.")
+	{
+		for _, sortKey := range []string{listing.SortCreatedAt, listing.SortScore, listing.SortABV, listing.SortName} {
+			for _, order := range []string{"asc", "desc"} {
+				t.Logf("\tWhen paginating the beers sorted by %s %s.", sortKey, order)
+				{
+					want := make([]beers.Beer, len(list))
+					copy(want, list)
+					sort.Slice(want, func(i, j int) bool {
+						x, y := want[i], want[j]
+						var cmp int
+						switch sortKey {
+						case listing.SortCreatedAt:
+							cmp = x.CreatedAt.Compare(y.CreatedAt)
+						case listing.SortScore:
+							cmp = compare(scores[x.ID], scores[y.ID])
+						case listing.SortABV:
+							cmp = compare(x.ABV, y.ABV)
+						case listing.SortName:
+							cmp = compare(x.Name, y.Name)
+						}
+						if cmp == 0 {
+							cmp = compare(x.ID, y.ID)
+						}
+						if order == "desc" {
+							return cmp > 0
+						}
+						return cmp < 0
+					})
+
+					got := listAll(t, svc, listing.BeerQuery{Sort: sortKey, Order: order, Limit: 2})
+					if err := sameOrder(got, want); err != nil {
+						t.Fatalf("\t\t[ERROR] Should list every beer in order: %v", err)
+					}
+					t.Log("\t\t[OK] Should list every beer in order.")
+				}
+			}
+		}
+
+		t.Log("\tWhen filtering the beers.")
+		{
+			f := func(v float32) *float32 { return &v }
+			after := start.Add(3 * time.Minute)
+
+			tt := []struct {
+				name   string
+				filter listing.Filter
+				want   []string
+			}{
+				{"style", listing.Filter{Style: "IPA"}, []string{"Alpha", "Charlie", "Echo"}},
+				{"brewery", listing.Filter{Brewery: "Brooklyn"}, []string{"Charlie", "Delta"}},
+				{"brewery id", listing.Filter{BreweryID: list[2].BreweryID}, []string{"Charlie", "Delta"}},
+				{"abv range", listing.Filter{MinABV: f(5.5), MaxABV: f(6.1)}, []string{"Alpha", "Charlie", "Echo"}},
+				{"min score", listing.Filter{MinScore: f(3)}, []string{"Alpha", "Bravo", "Echo"}},
+				{"created after", listing.Filter{CreatedAfter: &after}, []string{"Delta", "Echo"}},
+				{"combined", listing.Filter{Style: "IPA", MinScore: f(2.5)}, []string{"Alpha", "Echo"}},
+			}
+
+			for _, tc := range tt {
+				got := listAll(t, svc, listing.BeerQuery{Filter: tc.filter, Sort: listing.SortName, Limit: 2})
+				if err := sameNames(got, tc.want); err != nil {
+					t.Fatalf("\t\t[ERROR] Should filter the beers by %s: %v", tc.name, err)
+				}
+			}
+			t.Log("\t\t[OK] Should filter the beers.")
+		}
+	}
+}
+
+func testSearchBeers(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	for _, b := range []beers.Beer{
+		newBeer("Stout", "BrewDog", "Imperial", 9, now()),
+		newBeer("Night", "BrewDog", "Stout", 6, now()),
+		newBeer("Pale", "Brooklyn", "Ale", 5, now()),
+	} {
+		mustCreateBeer(t, s, b)
+	}
+
+	t.Log("Given the need to search beers.")
+	{
+		t.Log("\tWhen searching by a prefix.")
+		{
+			res, err := s.SearchBeers(ctx, listing.SearchQuery{Q: "brew", Limit: 10})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to search: %v", err)
+			}
+			if err := sameNames(hitBeers(res), []string{"Night", "Stout"}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should find the beers starting with the prefix: %v", err)
+			}
+			want := []listing.Facet{{Value: "BrewDog", Count: 2}}
+			if fmt.Sprint(res.Facets.Breweries) != fmt.Sprint(want) {
+				t.Fatalf("\t\t[ERROR] Should count the breweries. Got %v, want %v", res.Facets.Breweries, want)
+			}
+			if len(res.Facets.Styles) != 2 {
+				t.Fatalf("\t\t[ERROR] Should count the styles. Got %v", res.Facets.Styles)
+			}
+			t.Log("\t\t[OK] Should find the beers starting with the prefix.")
+		}
+
+		t.Log("\tWhen searching a term found in different fields.")
+		{
+			res, err := s.SearchBeers(ctx, listing.SearchQuery{Q: "stout", Limit: 10})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to search: %v", err)
+			}
+			if len(res.Beers) != 2 || res.Beers[0].Name != "Stout" {
+				t.Fatalf("\t\t[ERROR] Should rank the name matches first. Got %v", res.Beers)
+			}
+			t.Log("\t\t[OK] Should rank the name matches first.")
+		}
+
+		t.Log("\tWhen searching with a typo.")
+		{
+			res, err := s.SearchBeers(ctx, listing.SearchQuery{Q: "brewdgo", Limit: 10})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to search: %v", err)
+			}
+			if err := sameNames(hitBeers(res), []string{"Night", "Stout"}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should tolerate the typo: %v", err)
+			}
+			t.Log("\t\t[OK] Should tolerate the typo.")
+		}
+
+		t.Log("\tWhen searching terms without matches.")
+		{
+			res, err := s.SearchBeers(ctx, listing.SearchQuery{Q: "xyz", Limit: 10})
+			if err != nil || len(res.Beers) != 0 {
+				t.Fatalf("\t\t[ERROR] Should not find beers. Got %v: %v", res.Beers, err)
+			}
+			t.Log("\t\t[OK] Should not find beers.")
+		}
+	}
+}
+
+func testReviews(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+
+	b := mustCreateBeer(t, s, newBeer("IPA", "BrewDog", "IPA", 5.5, start))
+
+	t.Log("Given the need to store reviews.")
+	{
+		t.Log("\tWhen reviewing a beer that does not exist.")
+		{
+			r := newReview(uuid.NewString(), 3, start)
+			mustCreateAuthor(t, s, r)
+			err := s.CreateReview(ctx, r, newNotification(r), reviewCreated(r))
+			if !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to create the review: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the review.")
+		}
+
+		t.Log("\tWhen reviewing as a user that does not exist.")
+		{
+			r := newReview(b.ID, 3, start)
+			err := s.CreateReview(ctx, r, newNotification(r), reviewCreated(r))
+			if !errors.Is(err, users.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not be able to create the review: %v", err)
+			}
+
+			beer, err := s.GetBeer(ctx, b.ID)
+			if err != nil || beer.Score != 0 {
+				t.Fatalf("\t\t[ERROR] Should not score the review. Got %+v: %v", beer, err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the review.")
+		}
+
+		first := mustCreateReview(t, s, newReview(b.ID, 3, start.Add(1*time.Minute)))
+		second := mustCreateReview(t, s, newReview(b.ID, 4.5, start.Add(2*time.Minute)))
+		third := mustCreateReview(t, s, newReview(b.ID, 4, start.Add(3*time.Minute)))
+
+		t.Log("\tWhen listing the reviews.")
+		{
+			rs, err := s.ListReviews(ctx, b.ID)
+			if err != nil || len(rs) != 3 || rs[0].ID != third.ID || rs[2].ID != first.ID {
+				t.Fatalf("\t\t[ERROR] Should list the reviews from the most recent. Got %+v: %v", rs, err)
+			}
+
+			rs, err = s.ListReviews(ctx, uuid.NewString())
+			if err != nil || len(rs) != 0 {
+				t.Fatalf("\t\t[ERROR] Should not list reviews of unknown beers. Got %+v: %v", rs, err)
+			}
+			t.Log("\t\t[OK] Should list the reviews from the most recent.")
+		}
+
+		t.Log("\tWhen listing the reviews created after another.")
+		{
+			other := mustCreateBeer(t, s, newBeer("Stout", "BrewDog", "Stout", 6, start))
+			otherReview := mustCreateReview(t, s, newReview(other.ID, 2, start.Add(2*time.Minute)))
+
+			rs, err := s.ListReviewsAfter(ctx, b.ID, first, 10)
+			if err != nil || len(rs) != 2 || rs[0].ID != second.ID || rs[1].ID != third.ID {
+				t.Fatalf("\t\t[ERROR] Should list the later reviews of the beer from the oldest. Got %+v: %v", rs, err)
+			}
+
+			rs, err = s.ListReviewsAfter(ctx, "", first, 10)
+			if err != nil || len(rs) != 3 || rs[2].ID != third.ID {
+				t.Fatalf("\t\t[ERROR] Should list the later reviews of every beer. Got %+v: %v", rs, err)
+			}
+			if rs[0].ID != otherReview.ID && rs[1].ID != otherReview.ID {
+				t.Fatalf("\t\t[ERROR] Should list the reviews of the other beer. Got %+v", rs)
+			}
+
+			rs, err = s.ListReviewsAfter(ctx, b.ID, first, 1)
+			if err != nil || len(rs) != 1 || rs[0].ID != second.ID {
+				t.Fatalf("\t\t[ERROR] Should limit the reviews. Got %+v: %v", rs, err)
+			}
+
+			rs, err = s.ListReviewsAfter(ctx, b.ID, third, 10)
+			if err != nil || len(rs) != 0 {
+				t.Fatalf("\t\t[ERROR] Should not list reviews after the latest. Got %+v: %v", rs, err)
+			}
+			t.Log("\t\t[OK] Should list the reviews created after another.")
+		}
+
+		t.Log("\tWhen getting the detail of the beer.")
+		{
+			d, err := s.GetBeerDetail(ctx, b.ID, 2)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to get the detail of the beer: %v", err)
+			}
+			if d.Beer.ID != b.ID || d.Beer.Score != (3+4.5+4)/3.0 {
+				t.Fatalf("\t\t[ERROR] Should get the scored beer. Got %+v", d.Beer)
+			}
+			rs := d.LatestReviews
+			if len(rs) != 2 || rs[0].ID != third.ID || rs[1].ID != second.ID {
+				t.Fatalf("\t\t[ERROR] Should get the latest reviews. Got %+v", rs)
+			}
+			stats := d.Reviews
+			want := []reviews.Bucket{{Score: 3, Count: 1}, {Score: 4, Count: 2}}
+			if stats.Count != 3 || fmt.Sprint(stats.Distribution) != fmt.Sprint(want) ||
+				stats.FirstAt == nil || !stats.FirstAt.Equal(first.CreatedAt) ||
+				stats.LastAt == nil || !stats.LastAt.Equal(third.CreatedAt) {
+				t.Fatalf("\t\t[ERROR] Should summarize the reviews. Got %+v", stats)
+			}
+
+			_, err = s.GetBeerDetail(ctx, uuid.NewString(), 2)
+			if !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not get the detail of unknown beers: %v", err)
+			}
+			t.Log("\t\t[OK] Should get the beer, the summary and the latest of its reviews.")
+		}
+
+		t.Log("\tWhen the author reviews the beer again.")
+		{
+			again := newReview(b.ID, 1, start.Add(4*time.Minute))
+			again.UserID = first.UserID
+
+			err := s.CreateReview(ctx, again, newNotification(again), reviewCreated(again))
+			if !errors.Is(err, reviews.ErrAlreadyReviewed) {
+				t.Fatalf("\t\t[ERROR] Should not be able to review the beer twice: %v", err)
+			}
+
+			beer, err := s.GetBeer(ctx, b.ID)
+			if err != nil || beer.Score != (3+4.5+4)/3.0 {
+				t.Fatalf("\t\t[ERROR] Should not score the review. Got %+v: %v", beer, err)
+			}
+
+			got, err := s.GetUserReview(ctx, b.ID, first.UserID)
+			if err != nil || got.ID != first.ID {
+				t.Fatalf("\t\t[ERROR] Should get the review of the author. Got %+v: %v", got, err)
+			}
+
+			if _, err := s.GetUserReview(ctx, b.ID, uuid.NewString()); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find a review the user did not write: %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to review the beer twice.")
+		}
+
+		t.Log("\tWhen updating a review.")
+		{
+			up := first
+			up.Score = 1.5
+			up.Comment = "Changed my mind"
+			up.Revision = 2
+			up.UpdatedAt = start.Add(4 * time.Minute)
+
+			if err := s.UpdateReview(ctx, up); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the review: %v", err)
+			}
+
+			got, err := s.GetReview(ctx, first.ID)
+			if err != nil || got.Score != up.Score || got.Comment != up.Comment || got.Revision != 2 {
+				t.Fatalf("\t\t[ERROR] Should get the updated review. Got %+v: %v", got, err)
+			}
+
+			history, err := s.ListReviewRevisions(ctx, first.ID)
+			if err != nil || len(history) != 2 || history[0].Score != 3 || history[1].Score != 1.5 {
+				t.Fatalf("\t\t[ERROR] Should keep the review history. Got %+v: %v", history, err)
+			}
+
+			beer, err := s.GetBeer(ctx, b.ID)
+			if err != nil || beer.Score != (1.5+4.5+4)/3.0 {
+				t.Fatalf("\t\t[ERROR] Should score the current revision. Got %+v: %v", beer, err)
+			}
+
+			unknown := newReview(b.ID, 3, start)
+			if err := s.UpdateReview(ctx, unknown); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not update a review that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to update the review.")
+		}
+
+		t.Log("\tWhen updating a review concurrently from the same revision.")
+		{
+			const edits = 5
+
+			errs := make(chan error, edits)
+			for i := 0; i < edits; i++ {
+				up := first
+				up.Score = 1.5
+				up.Comment = fmt.Sprintf("Edit %d", i)
+				up.Revision = 3
+				up.UpdatedAt = start.Add(5 * time.Minute)
+
+				go func() { errs <- s.UpdateReview(ctx, up) }()
+			}
+
+			var updated int
+			for i := 0; i < edits; i++ {
+				switch err := <-errs; {
+				case err == nil:
+					updated++
+				case !errors.Is(err, reviews.ErrRevisionConflict):
+					t.Fatalf("\t\t[ERROR] Should reject the stale edits with a conflict: %v", err)
+				}
+			}
+			if updated != 1 {
+				t.Fatalf("\t\t[ERROR] Should apply a single edit. Got %d", updated)
+			}
+
+			history, err := s.ListReviewRevisions(ctx, first.ID)
+			if err != nil || len(history) != 3 {
+				t.Fatalf("\t\t[ERROR] Should keep a single revision per edit. Got %+v: %v", history, err)
+			}
+
+			got, err := s.GetReview(ctx, first.ID)
+			if err != nil || got.Revision != 3 || got.Comment != history[2].Comment {
+				t.Fatalf("\t\t[ERROR] Should keep the applied edit. Got %+v: %v", got, err)
+			}
+
+			beer, err := s.GetBeer(ctx, b.ID)
+			if err != nil || beer.Score != (1.5+4.5+4)/3.0 || beer.ReviewCount != 3 {
+				t.Fatalf("\t\t[ERROR] Should score the review once. Got %+v: %v", beer, err)
+			}
+
+			stale := first
+			stale.Revision = 2
+			if err := s.UpdateReview(ctx, stale); !errors.Is(err, reviews.ErrRevisionConflict) {
+				t.Fatalf("\t\t[ERROR] Should not update a review from a previous revision: %v", err)
+			}
+			t.Log("\t\t[OK] Should apply a single edit of the revision.")
+		}
+
+		t.Log("\tWhen deleting a review.")
+		{
+			if err := s.DeleteReview(ctx, second.ID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to delete the review: %v", err)
+			}
+			if _, err := s.GetReview(ctx, second.ID); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find the deleted review: %v", err)
+			}
+			if err := s.DeleteReview(ctx, second.ID); !errors.Is(err, reviews.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not delete a review that does not exist: %v", err)
+			}
+
+			beer, err := s.GetBeer(ctx, b.ID)
+			if err != nil || beer.Score != (1.5+4)/2.0 {
+				t.Fatalf("\t\t[ERROR] Should remove the review from the score. Got %+v: %v", beer, err)
+			}
+			t.Log("\t\t[OK] Should be able to delete the review.")
+		}
+	}
+}
+
+func testSubScores(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+	f := func(v float32) *float32 { return &v }
+
+	b := mustCreateBeer(t, s, newBeer("IPA", "BrewDog", "IPA", 5.5, start))
+	unrated := mustCreateBeer(t, s, newBeer("Stout", "BrewDog", "Stout", 6, start))
+
+	first := newReview(b.ID, 3.5, start.Add(1*time.Minute))
+	first.SubScores = &reviews.Scores{Aroma: f(4), Taste: f(3)}
+	first = mustCreateReview(t, s, first)
+
+	second := newReview(b.ID, 5, start.Add(2*time.Minute))
+	second.SubScores = &reviews.Scores{Aroma: f(5)}
+	second = mustCreateReview(t, s, second)
+
+	mustCreateReview(t, s, newReview(b.ID, 2, start.Add(3*time.Minute)))
+	mustCreateReview(t, s, newReview(unrated.ID, 2, start.Add(3*time.Minute)))
+
+	t.Log("Given the need to store the sub-scores of the reviews.")
+	{
+		t.Log("\tWhen getting a review with sub-scores.")
+		{
+			got, err := s.GetReview(ctx, first.ID)
+			if err != nil || !sameScores(got.SubScores, *first.SubScores) {
+				t.Fatalf("\t\t[ERROR] Should get the sub-scores. Got %+v: %v", got, err)
+			}
+
+			rs, err := s.ListReviews(ctx, b.ID)
+			if err != nil || len(rs) != 3 || rs[2].SubScores == nil || rs[0].SubScores != nil {
+				t.Fatalf("\t\t[ERROR] Should list the sub-scores. Got %+v: %v", rs, err)
+			}
+			t.Log("\t\t[OK] Should get the sub-scores.")
+		}
+
+		t.Log("\tWhen getting the beers.")
+		{
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || !sameScores(got.SubScores, reviews.Scores{Aroma: f(4.5), Taste: f(3)}) {
+				t.Fatalf("\t\t[ERROR] Should average each dimension. Got %+v: %v", got, err)
+			}
+
+			got, err = s.GetBeer(ctx, unrated.ID)
+			if err != nil || got.SubScores != nil {
+				t.Fatalf("\t\t[ERROR] Should not average the dimensions of a beer without sub-scores. Got %+v: %v", got, err)
+			}
+
+			list := listAll(t, listing.NewService(s, styles.DefaultCatalog, reviews.DefaultPrior), listing.BeerQuery{Sort: listing.SortName})
+			if len(list) != 2 || !sameScores(list[0].SubScores, reviews.Scores{Aroma: f(4.5), Taste: f(3)}) || list[1].SubScores != nil {
+				t.Fatalf("\t\t[ERROR] Should list the averages of each dimension. Got %+v", list)
+			}
+			t.Log("\t\t[OK] Should average each dimension rated by the reviews.")
+		}
+
+		t.Log("\tWhen updating the sub-scores of a review.")
+		{
+			up := second
+			up.Score = 4
+			up.SubScores = &reviews.Scores{Taste: f(5), Overall: f(3)}
+			up.Revision = 2
+			up.UpdatedAt = start.Add(4 * time.Minute)
+
+			if err := s.UpdateReview(ctx, up); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the review: %v", err)
+			}
+
+			history, err := s.ListReviewRevisions(ctx, second.ID)
+			if err != nil || len(history) != 2 || !sameScores(history[0].SubScores, *second.SubScores) ||
+				!sameScores(history[1].SubScores, *up.SubScores) {
+				t.Fatalf("\t\t[ERROR] Should keep the sub-scores in the review history. Got %+v: %v", history, err)
+			}
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || !sameScores(got.SubScores, reviews.Scores{Aroma: f(4), Taste: f(4), Overall: f(3)}) {
+				t.Fatalf("\t\t[ERROR] Should average the current revisions. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should be able to update the sub-scores.")
+		}
+
+		t.Log("\tWhen deleting a review with sub-scores.")
+		{
+			if err := s.DeleteReview(ctx, first.ID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to delete the review: %v", err)
+			}
+
+			got, err := s.GetBeer(ctx, b.ID)
+			if err != nil || !sameScores(got.SubScores, reviews.Scores{Taste: f(5), Overall: f(3)}) {
+				t.Fatalf("\t\t[ERROR] Should remove the review from the averages. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should remove the review from the averages.")
+		}
+	}
+}
+
+func testTopBeers(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+	prior := reviews.Prior{Mean: 3.5, Weight: 10}
+
+	oneHit := mustCreateBeer(t, s, newBeer("One Hit", "BrewDog", "American IPA", 6, start))
+	crowdPleaser := mustCreateBeer(t, s, newBeer("Crowd Pleaser", "BrewDog", "American IPA", 6, start))
+	stout := mustCreateBeer(t, s, newBeer("Stout", "Brooklyn", "Irish Stout", 4.5, start))
+	mustCreateBeer(t, s, newBeer("Newcomer", "Brooklyn", "American IPA", 6, start))
+
+	// Weighted scores: One Hit (35+5)/11, Crowd Pleaser (35+54)/22 and Stout
+	// (35+24)/16.
+	mustCreateReview(t, s, newReview(oneHit.ID, 5, start.Add(time.Minute)))
+	for i := 0; i < 12; i++ {
+		mustCreateReview(t, s, newReview(crowdPleaser.ID, 4.5, start.Add(time.Minute)))
+	}
+	for i := 0; i < 6; i++ {
+		mustCreateReview(t, s, newReview(stout.ID, 4, start.Add(time.Minute)))
+	}
+
+	t.Log("Given the need to rank beers by the Bayesian average of their reviews.")
+	{
+		t.Log("\tWhen ranking every beer with a review.")
+		{
+			top, err := s.TopBeers(ctx, listing.Ranking{MinReviews: 1, Limit: 10, Prior: prior})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{crowdPleaser, stout, oneHit}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should rank the beers with many good reviews first: %v", err)
+			}
+			if top[0].ReviewCount != 12 || top[0].Score != 4.5 || top[0].ScoreSum != 54 {
+				t.Fatalf("\t\t[ERROR] Should get the review aggregates. Got %+v", top[0])
+			}
+			if ws := top[0].WeightedScore; ws == nil || *ws != prior.Average(54, 12) {
+				t.Fatalf("\t\t[ERROR] Should get the weighted score the beers are ranked by. Got %v", ws)
+			}
+			t.Log("\t\t[OK] Should rank the beers with many good reviews first.")
+
+			got, err := s.GetBeer(ctx, crowdPleaser.ID)
+			if err != nil || got.ScoreSum != 54 {
+				t.Fatalf("\t\t[ERROR] Should get the score sum of the beer. Got %+v: %v", got, err)
+			}
+			t.Log("\t\t[OK] Should get the score sum the weighted scores are computed from.")
+		}
+
+		t.Log("\tWhen ranking the beers with a minimum number of reviews.")
+		{
+			top, err := s.TopBeers(ctx, listing.Ranking{MinReviews: 6, Limit: 10, Prior: prior})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{crowdPleaser, stout}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should rank only the beers with enough reviews: %v", err)
+			}
+
+			top, err = s.TopBeers(ctx, listing.Ranking{MinReviews: 1, Limit: 1, Prior: prior})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{crowdPleaser}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should limit the ranking: %v", err)
+			}
+			t.Log("\t\t[OK] Should rank only the beers with enough reviews.")
+		}
+
+		t.Log("\tWhen ranking the beers of a style or of a brewery.")
+		{
+			top, err := s.TopBeers(ctx, listing.Ranking{Style: "American IPA", MinReviews: 1, Limit: 10, Prior: prior})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{crowdPleaser, oneHit}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should rank the beers of the style: %v", err)
+			}
+
+			top, err = s.TopBeers(ctx, listing.Ranking{BreweryID: stout.BreweryID, MinReviews: 1, Limit: 10, Prior: prior})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{stout}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should rank the beers of the brewery: %v", err)
+			}
+			t.Log("\t\t[OK] Should rank the beers of a style or of a brewery.")
+		}
+
+		t.Log("\tWhen ranking the beers with a prior that trusts the reviews.")
+		{
+			top, err := s.TopBeers(ctx, listing.Ranking{MinReviews: 1, Limit: 10, Prior: reviews.Prior{Mean: 3.5, Weight: 0}})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to rank the beers: %v", err)
+			}
+			if err := sameOrder(top, []beers.Beer{oneHit, crowdPleaser, stout}); err != nil {
+				t.Fatalf("\t\t[ERROR] Should rank the beers by their plain average: %v", err)
+			}
+			t.Log("\t\t[OK] Should rank the beers by their plain average.")
+		}
+	}
+}
+
+func testUsers(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+
+	hop := newUser("hop_head", start)
+	stout := newUser("stout_lover", start)
+
+	t.Log("Given the need to store users.")
+	{
+		t.Log("\tWhen creating users.")
+		{
+			if err := s.CreateUser(ctx, hop); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the user: %v", err)
+			}
+			if err := s.CreateUser(ctx, stout); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the user: %v", err)
+			}
+
+			got, err := s.GetUser(ctx, hop.ID)
+			if err != nil || *got != hop {
+				t.Fatalf("\t\t[ERROR] Should get the user as created. Got %+v: %v", got, err)
+			}
+
+			if _, err := s.GetUser(ctx, uuid.NewString()); !errors.Is(err, users.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not find a user that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to create the users.")
+		}
+
+		t.Log("\tWhen creating a user with a taken handle or email.")
+		{
+			u := newUser("hop_head", start)
+			u.Email = "other@example.com"
+			if err := s.CreateUser(ctx, u); !errors.Is(err, users.ErrHandleTaken) {
+				t.Fatalf("\t\t[ERROR] Should return ErrHandleTaken. Got %v", err)
+			}
+
+			u = newUser("other", start)
+			u.Email = hop.Email
+			if err := s.CreateUser(ctx, u); !errors.Is(err, users.ErrEmailTaken) {
+				t.Fatalf("\t\t[ERROR] Should return ErrEmailTaken. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should not be able to create the user.")
+		}
+
+		t.Log("\tWhen updating a user.")
+		{
+			up := hop
+			up.Handle = "hazy_head"
+			up.Bio = "Hazy IPAs only"
+			up.Role = users.RoleModerator
+			up.UpdatedAt = start.Add(time.Minute)
+
+			if err := s.UpdateUser(ctx, up); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the user: %v", err)
+			}
+
+			got, err := s.GetUser(ctx, hop.ID)
+			if err != nil || *got != up {
+				t.Fatalf("\t\t[ERROR] Should get the updated user. Got %+v: %v", got, err)
+			}
+
+			// The previous handle is free again.
+			u := newUser("hop_head", start)
+			u.Email = "new.hop@example.com"
+			if err := s.CreateUser(ctx, u); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to take the previous handle: %v", err)
+			}
+
+			up.Email = stout.Email
+			if err := s.UpdateUser(ctx, up); !errors.Is(err, users.ErrEmailTaken) {
+				t.Fatalf("\t\t[ERROR] Should not take the email of another user. Got %v", err)
+			}
+
+			unknown := newUser("unknown", start)
+			if err := s.UpdateUser(ctx, unknown); !errors.Is(err, users.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not update a user that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should be able to update the user.")
+		}
+	}
+}
+
+func testNotifications(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+
+	b := mustCreateBeer(t, s, newBeer("IPA", "BrewDog", "IPA", 5.5, start))
+
+	var created []notifications.Notification
+	for i := 0; i < 3; i++ {
+		r := newReview(b.ID, 4, start.Add(time.Duration(i)*time.Minute))
+		n := newNotification(r)
+		mustCreateAuthor(t, s, r)
+		if err := s.CreateReview(ctx, r, n, reviewCreated(r)); err != nil {
+			t.Fatalf("Should be able to create review: %v", err)
+		}
+		created = append(created, n)
+	}
+
+	t.Log("Given the need to deliver the notifications in the outbox.")
+	{
+		t.Log("\tWhen claiming the notifications due.")
+		{
+			list, err := s.ClaimNotifications(ctx, start.Add(time.Minute), time.Hour, 10)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to claim the notifications: %v", err)
+			}
+			if len(list) != 2 {
+				t.Fatalf("\t\t[ERROR] Should claim the notifications due. Got %+v", list)
+			}
+			for _, n := range list {
+				if n.ID == created[2].ID || !n.NextAttemptAt.Equal(start.Add(time.Minute+time.Hour)) {
+					t.Fatalf("\t\t[ERROR] Should postpone the claimed notifications. Got %+v", n)
+				}
+			}
+
+			list, err = s.ClaimNotifications(ctx, start.Add(2*time.Minute), time.Hour, 10)
+			if err != nil || len(list) != 1 || list[0].ID != created[2].ID {
+				t.Fatalf("\t\t[ERROR] Should not claim the notifications claimed before. Got %+v: %v", list, err)
+			}
+
+			got, want := list[0].Event, created[2].Event
+			if got.ID != want.ID || got.Type != want.Type || got.Beer != want.Beer || got.Review.Score != want.Review.Score ||
+				!got.OccurredAt.Equal(want.OccurredAt) || !got.Review.CreatedAt.Equal(want.Review.CreatedAt) {
+				t.Fatalf("\t\t[ERROR] Should keep the notification event. Got %+v, want %+v", got, want)
+			}
+			t.Log("\t\t[OK] Should claim the notifications due.")
+		}
+
+		t.Log("\tWhen storing the result of the delivery.")
+		{
+			sent := created[0]
+			sentAt := start.Add(3 * time.Minute)
+			sent.Status = notifications.StatusSent
+			sent.Attempts = 1
+			sent.SentAt = &sentAt
+
+			retry := created[1]
+			retry.Attempts = 1
+			retry.LastError = "unavailable"
+			retry.NextAttemptAt = start.Add(4 * time.Minute)
+
+			for _, n := range []notifications.Notification{sent, retry} {
+				if err := s.UpdateNotification(ctx, n); err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to update the notification: %v", err)
+				}
+			}
+
+			list, err := s.ClaimNotifications(ctx, start.Add(5*time.Minute), time.Hour, 10)
+			if err != nil || len(list) != 1 || list[0].ID != retry.ID || list[0].Attempts != 1 || list[0].LastError != retry.LastError {
+				t.Fatalf("\t\t[ERROR] Should only claim the pending notifications. Got %+v: %v", list, err)
+			}
+
+			unknown := newNotification(newReview(b.ID, 4, start))
+			if err := s.UpdateNotification(ctx, unknown); !errors.Is(err, notifications.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not update a notification that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should store the result of the delivery.")
+		}
+	}
+}
+
+func testWebhooks(t *testing.T, s Storage) {
+	ctx := context.Background()
+	start := now()
+
+	beerHook := webhooks.Subscription{
+		ID:        uuid.NewString(),
+		URL:       "https://example.com/beers",
+		Events:    []string{webhooks.EventBeerAdded},
+		Secret:    "0123456789abcdef",
+		CreatedAt: start,
+	}
+	allHook := webhooks.Subscription{
+		ID:        uuid.NewString(),
+		URL:       "https://example.com/all",
+		Events:    webhooks.EventTypes,
+		Secret:    "fedcba9876543210",
+		CreatedAt: start.Add(time.Second),
+	}
+
+	t.Log("Given the need to deliver the events to the webhooks.")
+	{
+		t.Log("\tWhen subscribing webhooks.")
+		{
+			for _, sub := range []webhooks.Subscription{beerHook, allHook} {
+				if err := s.CreateSubscription(ctx, sub); err != nil {
+					t.Fatalf("\t\t[ERROR] Should be able to create the subscription: %v", err)
+				}
+			}
+
+			got, err := s.GetSubscription(ctx, allHook.ID)
+			if err != nil || got.URL != allHook.URL || got.Secret != allHook.Secret || fmt.Sprint(got.Events) != fmt.Sprint(allHook.Events) {
+				t.Fatalf("\t\t[ERROR] Should get the subscription. Got %+v: %v", got, err)
+			}
+
+			list, err := s.ListSubscriptions(ctx)
+			if err != nil || len(list) != 2 || list[0].ID != beerHook.ID || list[1].ID != allHook.ID {
+				t.Fatalf("\t\t[ERROR] Should list the subscriptions from the oldest. Got %+v: %v", list, err)
+			}
+
+			if _, err := s.GetSubscription(ctx, uuid.NewString()); !errors.Is(err, webhooks.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not get a subscription that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should store the subscriptions.")
+		}
+
+		b := newBeer("IPA", "BrewDog", "IPA", 5.5, start)
+		added := beerAdded(b)
+		r := newReview(b.ID, 4, start.Add(time.Minute))
+		created := reviewCreated(r)
+
+		t.Log("\tWhen beers and reviews are created.")
+		{
+			mustCreateBrewery(t, s, b)
+			if err := s.CreateBeer(ctx, b, added); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the beer: %v", err)
+			}
+			mustCreateAuthor(t, s, r)
+			if err := s.CreateReview(ctx, r, newNotification(r), created); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the review: %v", err)
+			}
+
+			list, err := s.ListDeliveries(ctx, beerHook.ID, 10)
+			if err != nil || len(list) != 1 || list[0].ID != webhooks.NewDelivery(beerHook.ID, added).ID {
+				t.Fatalf("\t\t[ERROR] Should only queue the subscribed events. Got %+v: %v", list, err)
+			}
+
+			list, err = s.ListDeliveries(ctx, allHook.ID, 10)
+			if err != nil || len(list) != 2 || list[0].Event.ID != created.ID || list[1].Event.ID != added.ID {
+				t.Fatalf("\t\t[ERROR] Should list the deliveries from the most recent. Got %+v: %v", list, err)
+			}
+
+			got := list[0]
+			if got.Status != webhooks.StatusPending || got.Event.Type != created.Type || got.Event.Review == nil ||
+				got.Event.Review.ID != r.ID || !got.Event.OccurredAt.Equal(created.OccurredAt) {
+				t.Fatalf("\t\t[ERROR] Should keep the delivery event. Got %+v", got)
+			}
+			t.Log("\t\t[OK] Should queue a delivery for every subscribed webhook.")
+		}
+
+		t.Log("\tWhen claiming the deliveries due.")
+		{
+			list, err := s.ClaimDeliveries(ctx, start, time.Hour, 10)
+			if err != nil || len(list) != 2 {
+				t.Fatalf("\t\t[ERROR] Should claim the deliveries due. Got %+v: %v", list, err)
+			}
+			for _, d := range list {
+				if d.Event.ID != added.ID || !d.NextAttemptAt.Equal(start.Add(time.Hour)) {
+					t.Fatalf("\t\t[ERROR] Should postpone the claimed deliveries. Got %+v", d)
+				}
+			}
+
+			list, err = s.ClaimDeliveries(ctx, start.Add(time.Minute), time.Hour, 10)
+			if err != nil || len(list) != 1 || list[0].Event.ID != created.ID {
+				t.Fatalf("\t\t[ERROR] Should not claim the deliveries claimed before. Got %+v: %v", list, err)
+			}
+			t.Log("\t\t[OK] Should claim the deliveries due.")
+		}
+
+		t.Log("\tWhen storing the result of the delivery.")
+		{
+			d := webhooks.NewDelivery(allHook.ID, created)
+			deliveredAt := start.Add(2 * time.Minute)
+			d.Status = webhooks.StatusSucceeded
+			d.Attempts = 1
+			d.ResponseStatus = 204
+			d.DeliveredAt = &deliveredAt
+
+			if err := s.UpdateDelivery(ctx, d); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to update the delivery: %v", err)
+			}
+
+			got, err := s.GetDelivery(ctx, d.ID)
+			if err != nil || got.Status != d.Status || got.ResponseStatus != d.ResponseStatus || got.DeliveredAt == nil {
+				t.Fatalf("\t\t[ERROR] Should store the result. Got %+v: %v", got, err)
+			}
+
+			unknown := webhooks.NewDelivery(allHook.ID, beerAdded(b))
+			if err := s.UpdateDelivery(ctx, unknown); !errors.Is(err, webhooks.ErrDeliveryNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not update a delivery that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should store the result of the delivery.")
+		}
+
+		t.Log("\tWhen redelivering an event.")
+		{
+			d := webhooks.Delivery{
+				ID:             uuid.NewString(),
+				SubscriptionID: allHook.ID,
+				Event:          created,
+				Status:         webhooks.StatusPending,
+				NextAttemptAt:  start.Add(3 * time.Minute),
+				CreatedAt:      start.Add(3 * time.Minute),
+			}
+			if err := s.CreateDelivery(ctx, d); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to create the delivery: %v", err)
+			}
+
+			list, err := s.ClaimDeliveries(ctx, start.Add(3*time.Minute), time.Hour, 10)
+			if err != nil || len(list) != 1 || list[0].ID != d.ID {
+				t.Fatalf("\t\t[ERROR] Should claim the new delivery. Got %+v: %v", list, err)
+			}
+
+			d.ID = uuid.NewString()
+			d.SubscriptionID = uuid.NewString()
+			if err := s.CreateDelivery(ctx, d); !errors.Is(err, webhooks.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not queue a delivery to a subscription that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should queue the delivery again.")
+		}
+
+		t.Log("\tWhen unsubscribing a webhook.")
+		{
+			if err := s.DeleteSubscription(ctx, allHook.ID); err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to delete the subscription: %v", err)
+			}
+
+			if list, err := s.ListDeliveries(ctx, allHook.ID, 10); err != nil || len(list) != 0 {
+				t.Fatalf("\t\t[ERROR] Should delete the deliveries. Got %+v: %v", list, err)
+			}
+
+			if err := s.DeleteSubscription(ctx, allHook.ID); !errors.Is(err, webhooks.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should not delete a subscription that does not exist: %v", err)
+			}
+			t.Log("\t\t[OK] Should delete the subscription and its deliveries.")
+		}
+	}
+}
+
+// =============================================================================
+
+// listAll lists every page of beers matching the query.
+func listAll(t *testing.T, svc *listing.Service, q listing.BeerQuery) []beers.Beer {
+	t.Helper()
+
+	var list []beers.Beer
+	for {
+		page, err := svc.ListBeers(context.Background(), q)
+		if err != nil {
+			t.Fatalf("Should be able to list the beers: %v", err)
+		}
+
+		list = append(list, page.Beers...)
+		if page.NextCursor == "" {
+			return list
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// sameScores checks if the sub-scores rate the same dimensions with the same
+// values.
+func sameScores(got *reviews.Scores, want reviews.Scores) bool {
+	if got == nil {
+		return want.Empty()
+	}
+
+	g, w := got.Dimensions(), want.Dimensions()
+	for i := range g {
+		if (g[i] == nil) != (w[i] == nil) || (g[i] != nil && *g[i] != *w[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameOrder checks if both lists have the same beers in the same order.
+func sameOrder(got, want []beers.Beer) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("got %d beers, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].ID != want[i].ID {
+			return fmt.Errorf("got %q at %d, want %q", got[i].Name, i, want[i].Name)
+		}
+	}
+	return nil
+}
+
+// sameNames checks if the beers have the given names, in any order.
+func sameNames(got []beers.Beer, want []string) error {
+	names := make([]string, len(got))
+	for i, b := range got {
+		names[i] = b.Name
+	}
+	sort.Strings(names)
+
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		return fmt.Errorf("got %v, want %v", names, want)
+	}
+	return nil
+}
+
+// hitBeers returns the beers of the search hits.
+func hitBeers(res listing.SearchResult) []beers.Beer {
+	list := make([]beers.Beer, len(res.Beers))
+	for i, h := range res.Beers {
+		list[i] = h.Beer
+	}
+	return list
+}
+
+// compare compares two ordered values like strings.Compare.
+func compare[T float32 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package streaming
+
+import (
+	"context"
+	"sync"
+
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/reviews"
+)
+
+// Hub fans the new reviews out to the streams. Every stream has a bounded
+// buffer: a stream falling behind is dropped, instead of holding the reviews
+// in memory, and its client resumes from the last review it received.
+type Hub struct {
+	buffer int
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+// subscription receives the reviews of a beer, or of every beer when the beer
+// ID is empty. Its channel is closed when it's dropped.
+type subscription struct {
+	beerID string
+	c      chan reviews.Review
+}
+
+// NewHub creates a hub buffering up to the given number of reviews for each
+// stream.
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	return &Hub{
+		buffer: buffer,
+		subs:   make(map[*subscription]struct{}),
+	}
+}
+
+// Publish hands the review to the streams following it, without blocking.
+// The streams with a full buffer are dropped.
+func (h *Hub) Publish(r reviews.Review) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs {
+		if s.beerID != "" && s.beerID != r.BeerID {
+			continue
+		}
+
+		select {
+		case s.c <- r:
+		default:
+			delete(h.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// Handler returns the event handler publishing the reviews added in this
+// process. It's used when the storage can't notify the other instances.
+func (h *Hub) Handler() events.Handler {
+	return events.Handle(func(ctx context.Context, e events.ReviewAdded) error {
+		h.Publish(e.Review)
+		return nil
+	})
+}
+
+// Close drops every stream, so their clients reconnect to another instance
+// while this one shuts down. The streams opened afterwards are dropped right
+// away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+// subscribe adds a subscription to the reviews of the beer.
+func (h *Hub) subscribe(beerID string) *subscription {
+	s := &subscription{
+		beerID: beerID,
+		c:      make(chan reviews.Review, h.buffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(s.c)
+		return s
+	}
+
+	h.subs[s] = struct{}{}
+
+	return s
+}
+
+// unsubscribe removes the subscription, unless it was already dropped.
+func (h *Hub) unsubscribe(s *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
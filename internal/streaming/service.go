@@ -0,0 +1,170 @@
+// Package streaming provides a use case for streaming the new reviews as they
+// are created.
+package streaming
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/reviews"
+)
+
+// ErrLagged is returned when a stream is dropped for falling behind. The
+// client must resume from the last review it received.
+var ErrLagged = errors.New("stream lagged behind")
+
+// Repository defines the interface for the streaming service to interact
+// with the storage.
+type Repository interface {
+	// GetBeer returns the beer with the given ID.
+	GetBeer(ctx context.Context, id string) (*beers.Beer, error)
+	// GetReview returns the review with the given ID.
+	GetReview(ctx context.Context, id string) (*reviews.Review, error)
+	// ListReviewsAfter returns up to limit reviews created after the given
+	// one, from the oldest to the most recent. The reviews of every beer are
+	// returned when the beer ID is empty.
+	ListReviewsAfter(ctx context.Context, beerID string, after reviews.Review, limit int) ([]reviews.Review, error)
+}
+
+// Set of limits used when none is given.
+const (
+	// DefaultBuffer is the number of reviews waiting to be sent to a stream.
+	DefaultBuffer = 32
+	// DefaultMaxReplay is the number of reviews replayed when a stream is
+	// resumed.
+	DefaultMaxReplay = 100
+)
+
+// Service provides review streaming operations.
+type Service struct {
+	r         Repository
+	hub       *Hub
+	maxReplay int
+}
+
+// NewService creates a streaming service with the necessary dependencies,
+// replaying up to maxReplay reviews when a stream is resumed.
+func NewService(r Repository, hub *Hub, maxReplay int) *Service {
+	if maxReplay <= 0 {
+		maxReplay = DefaultMaxReplay
+	}
+
+	return &Service{
+		r:         r,
+		hub:       hub,
+		maxReplay: maxReplay,
+	}
+}
+
+// Stream is a stream of new reviews. It must be closed once done.
+type Stream struct {
+	hub      *Hub
+	sub      *subscription
+	replay   []reviews.Review
+	replayed map[string]bool
+}
+
+// Stream opens a stream of the reviews of a beer, or of every beer when the
+// beer ID is empty. When the ID of the last review received is given, the
+// reviews created since then are replayed first.
+func (s *Service) Stream(ctx context.Context, beerID, lastEventID string) (*Stream, error) {
+	if beerID != "" {
+		if _, err := uuid.Parse(beerID); err != nil {
+			return nil, beers.ErrInvalidID
+		}
+
+		if _, err := s.r.GetBeer(ctx, beerID); err != nil {
+			return nil, fmt.Errorf("get beer[id=%s]: %w", beerID, err)
+		}
+	}
+
+	// Subscribe before reading the missed reviews, so none is lost in
+	// between. The reviews read both ways are sent once.
+	st := Stream{
+		hub:      s.hub,
+		sub:      s.hub.subscribe(beerID),
+		replayed: make(map[string]bool),
+	}
+
+	if lastEventID == "" {
+		return &st, nil
+	}
+
+	replay, err := s.replay(ctx, beerID, lastEventID)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+
+	st.replay = replay
+	for _, r := range replay {
+		st.replayed[r.ID] = true
+	}
+
+	return &st, nil
+}
+
+// replay returns the reviews created after the last one received. Nothing is
+// replayed when the last review is unknown.
+func (s *Service) replay(ctx context.Context, beerID, lastEventID string) ([]reviews.Review, error) {
+	if _, err := uuid.Parse(lastEventID); err != nil {
+		return nil, nil
+	}
+
+	last, err := s.r.GetReview(ctx, lastEventID)
+	switch {
+	case errors.Is(err, reviews.ErrNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("get review[id=%s]: %w", lastEventID, err)
+	}
+
+	list, err := s.r.ListReviewsAfter(ctx, beerID, *last, s.maxReplay)
+	if err != nil {
+		return nil, fmt.Errorf("list reviews after[id=%s]: %w", lastEventID, err)
+	}
+
+	return list, nil
+}
+
+// Next returns the next review of the stream, the replayed ones first. It
+// returns false when no review arrives within the wait, so the caller can
+// send a heartbeat.
+func (st *Stream) Next(ctx context.Context, wait time.Duration) (reviews.Review, bool, error) {
+	if len(st.replay) > 0 {
+		r := st.replay[0]
+		st.replay = st.replay[1:]
+		return r, true, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return reviews.Review{}, false, ctx.Err()
+
+		case <-timer.C:
+			return reviews.Review{}, false, nil
+
+		case r, ok := <-st.sub.c:
+			if !ok {
+				return reviews.Review{}, false, ErrLagged
+			}
+			if st.replayed[r.ID] {
+				continue
+			}
+			return r, true, nil
+		}
+	}
+}
+
+// Close stops the stream.
+func (st *Stream) Close() {
+	st.hub.unsubscribe(st.sub)
+}
@@ -0,0 +1,124 @@
+package streaming_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/reviews"
+	"github.com/phbpx/gobeer/internal/streaming"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	beers   []beers.Beer
+	reviews []reviews.Review
+}
+
+// GetBeer returns the beer with the given ID.
+func (m *mockRepository) GetBeer(ctx context.Context, id string) (*beers.Beer, error) {
+	for _, b := range m.beers {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, beers.ErrNotFound
+}
+
+// GetReview returns the review with the given ID.
+func (m *mockRepository) GetReview(ctx context.Context, id string) (*reviews.Review, error) {
+	for _, r := range m.reviews {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, reviews.ErrNotFound
+}
+
+// ListReviewsAfter returns the reviews stored after the given one.
+func (m *mockRepository) ListReviewsAfter(ctx context.Context, beerID string, after reviews.Review, limit int) ([]reviews.Review, error) {
+	var list []reviews.Review
+	for _, r := range m.reviews {
+		if r.CreatedAt.After(after.CreatedAt) && (beerID == "" || r.BeerID == beerID) && len(list) < limit {
+			list = append(list, r)
+		}
+	}
+	return list, nil
+}
+
+func TestStream(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+
+	beerID := uuid.NewString()
+	first := reviews.Review{ID: uuid.NewString(), BeerID: beerID, CreatedAt: start}
+	second := reviews.Review{ID: uuid.NewString(), BeerID: beerID, CreatedAt: start.Add(time.Second)}
+	other := reviews.Review{ID: uuid.NewString(), BeerID: uuid.NewString(), CreatedAt: start.Add(2 * time.Second)}
+
+	repo := &mockRepository{
+		beers:   []beers.Beer{{ID: beerID}},
+		reviews: []reviews.Review{first, second},
+	}
+
+	hub := streaming.NewHub(1)
+	s := streaming.NewService(repo, hub, 0)
+
+	t.Log("Given the need to stream the reviews of a beer")
+	{
+		t.Log("\tWhen resuming the stream")
+		{
+			st, err := s.Stream(ctx, beerID, first.ID)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to open the stream: %v", err)
+			}
+			defer st.Close()
+
+			// The replayed review is published while the stream is opened.
+			hub.Publish(second)
+
+			r, ok, err := st.Next(ctx, time.Second)
+			if err != nil || !ok || r.ID != second.ID {
+				t.Fatalf("\t\t[ERROR] Should replay the missed review. Got %+v: %v", r, err)
+			}
+
+			hub.Publish(other)
+
+			_, ok, err = st.Next(ctx, 10*time.Millisecond)
+			if err != nil || ok {
+				t.Fatalf("\t\t[ERROR] Should not send a review twice nor the reviews of other beers: %v", err)
+			}
+			t.Log("\t\t[OK] Should replay the missed reviews once.")
+		}
+
+		t.Log("\tWhen the stream falls behind")
+		{
+			st, err := s.Stream(ctx, "", "")
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to open the stream: %v", err)
+			}
+			defer st.Close()
+
+			hub.Publish(first)
+			hub.Publish(second)
+
+			if r, ok, err := st.Next(ctx, time.Second); err != nil || !ok || r.ID != first.ID {
+				t.Fatalf("\t\t[ERROR] Should send the buffered review. Got %+v: %v", r, err)
+			}
+			if _, _, err := st.Next(ctx, time.Second); !errors.Is(err, streaming.ErrLagged) {
+				t.Fatalf("\t\t[ERROR] Should drop the stream. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should drop the stream.")
+		}
+
+		t.Log("\tWhen streaming the reviews of an unknown beer")
+		{
+			if _, err := s.Stream(ctx, uuid.NewString(), ""); !errors.Is(err, beers.ErrNotFound) {
+				t.Fatalf("\t\t[ERROR] Should return ErrNotFound. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should return ErrNotFound.")
+		}
+	}
+}
@@ -0,0 +1,481 @@
+package styles
+
+// DefaultCatalog is the catalog of the API, based on the BJCP guidelines.
+var DefaultCatalog = Catalog{
+	{
+		Name: "Pale Lager",
+		Styles: []Style{
+			{
+				Name:        "American Lager",
+				Aliases:     []string{"Lager", "Standard American Lager"},
+				ABV:         Range{4.2, 5.3},
+				IBU:         Range{8, 18},
+				SRM:         Range{2, 3.5},
+				Glassware:   "Pilsner",
+				ServingTemp: Range{2, 4},
+			},
+			{
+				Name:        "Munich Helles",
+				Aliases:     []string{"Helles"},
+				ABV:         Range{4.7, 5.4},
+				IBU:         Range{16, 22},
+				SRM:         Range{3, 5},
+				Glassware:   "Mug",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "German Pils",
+				Aliases:     []string{"Pils", "Pilsner", "Pilsen"},
+				ABV:         Range{4.4, 5.2},
+				IBU:         Range{22, 40},
+				SRM:         Range{2, 4},
+				Glassware:   "Pilsner",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "Czech Premium Pale Lager",
+				Aliases:     []string{"Bohemian Pilsner", "Czech Pilsner"},
+				ABV:         Range{4.2, 5.8},
+				IBU:         Range{30, 45},
+				SRM:         Range{3.5, 6},
+				Glassware:   "Pilsner",
+				ServingTemp: Range{7, 10},
+			},
+		},
+	},
+	{
+		Name: "Amber and Dark Lager",
+		Styles: []Style{
+			{
+				Name:        "Vienna Lager",
+				ABV:         Range{4.7, 5.5},
+				IBU:         Range{18, 30},
+				SRM:         Range{9, 15},
+				Glassware:   "Pilsner",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Märzen",
+				Aliases:     []string{"Marzen", "Oktoberfest"},
+				ABV:         Range{5.6, 6.3},
+				IBU:         Range{18, 24},
+				SRM:         Range{8, 17},
+				Glassware:   "Mug",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Munich Dunkel",
+				Aliases:     []string{"Dunkel"},
+				ABV:         Range{4.5, 5.6},
+				IBU:         Range{18, 28},
+				SRM:         Range{13, 28},
+				Glassware:   "Mug",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Schwarzbier",
+				Aliases:     []string{"Black Lager"},
+				ABV:         Range{4.4, 5.4},
+				IBU:         Range{20, 35},
+				SRM:         Range{19, 40},
+				Glassware:   "Pilsner",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Doppelbock",
+				ABV:         Range{7, 10},
+				IBU:         Range{16, 26},
+				SRM:         Range{6, 25},
+				Glassware:   "Goblet",
+				ServingTemp: Range{7, 10},
+			},
+		},
+	},
+	{
+		Name: "Wheat Beer",
+		Styles: []Style{
+			{
+				Name:        "Weissbier",
+				Aliases:     []string{"Hefeweizen", "Weizen", "Weiss"},
+				ABV:         Range{4.3, 5.6},
+				IBU:         Range{8, 15},
+				SRM:         Range{2, 6},
+				Glassware:   "Weizen",
+				ServingTemp: Range{4, 7},
+				Substyles: []Style{
+					{
+						Name:        "Dunkles Weissbier",
+						Aliases:     []string{"Dunkelweizen"},
+						ABV:         Range{4.3, 5.6},
+						IBU:         Range{10, 18},
+						SRM:         Range{14, 23},
+						Glassware:   "Weizen",
+						ServingTemp: Range{4, 7},
+					},
+				},
+			},
+			{
+				Name:        "Witbier",
+				Aliases:     []string{"Wit", "Belgian White"},
+				ABV:         Range{4.5, 5.5},
+				IBU:         Range{8, 20},
+				SRM:         Range{2, 4},
+				Glassware:   "Tumbler",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "American Wheat Beer",
+				Aliases:     []string{"American Wheat"},
+				ABV:         Range{4, 5.5},
+				IBU:         Range{15, 30},
+				SRM:         Range{3, 6},
+				Glassware:   "Pint",
+				ServingTemp: Range{4, 7},
+			},
+		},
+	},
+	{
+		Name: "Pale Ale",
+		Styles: []Style{
+			{
+				Name:        "Blonde Ale",
+				Aliases:     []string{"American Blonde Ale"},
+				ABV:         Range{3.8, 5.5},
+				IBU:         Range{15, 28},
+				SRM:         Range{3, 6},
+				Glassware:   "Pint",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "American Pale Ale",
+				Aliases:     []string{"APA", "Pale Ale"},
+				ABV:         Range{4.5, 6.2},
+				IBU:         Range{30, 50},
+				SRM:         Range{5, 10},
+				Glassware:   "Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Best Bitter",
+				Aliases:     []string{"Bitter"},
+				ABV:         Range{3.8, 4.6},
+				IBU:         Range{25, 40},
+				SRM:         Range{8, 16},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{10, 13},
+			},
+			{
+				Name:        "Strong Bitter",
+				Aliases:     []string{"ESB", "Extra Special Bitter"},
+				ABV:         Range{4.6, 6.2},
+				IBU:         Range{30, 50},
+				SRM:         Range{8, 18},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{10, 13},
+			},
+		},
+	},
+	{
+		Name: "India Pale Ale",
+		Styles: []Style{
+			{
+				Name:        "American IPA",
+				Aliases:     []string{"IPA", "India Pale Ale"},
+				ABV:         Range{5.5, 7.5},
+				IBU:         Range{40, 70},
+				SRM:         Range{6, 14},
+				Glassware:   "IPA",
+				ServingTemp: Range{7, 10},
+				Substyles: []Style{
+					{
+						Name:        "West Coast IPA",
+						ABV:         Range{6, 7.5},
+						IBU:         Range{50, 75},
+						SRM:         Range{4, 8},
+						Glassware:   "IPA",
+						ServingTemp: Range{7, 10},
+					},
+					{
+						Name:        "Hazy IPA",
+						Aliases:     []string{"NEIPA", "New England IPA"},
+						ABV:         Range{6, 9},
+						IBU:         Range{25, 60},
+						SRM:         Range{3, 7},
+						Glassware:   "IPA",
+						ServingTemp: Range{7, 10},
+					},
+					{
+						Name:        "Black IPA",
+						ABV:         Range{5.5, 9},
+						IBU:         Range{50, 90},
+						SRM:         Range{25, 40},
+						Glassware:   "IPA",
+						ServingTemp: Range{7, 10},
+					},
+				},
+			},
+			{
+				Name:        "English IPA",
+				ABV:         Range{5, 7.5},
+				IBU:         Range{40, 60},
+				SRM:         Range{6, 14},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{10, 13},
+			},
+			{
+				Name:        "Session IPA",
+				ABV:         Range{3, 5},
+				IBU:         Range{40, 55},
+				SRM:         Range{3, 12},
+				Glassware:   "IPA",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "Double IPA",
+				Aliases:     []string{"DIPA", "Imperial IPA"},
+				ABV:         Range{7.5, 10},
+				IBU:         Range{60, 100},
+				SRM:         Range{6, 14},
+				Glassware:   "IPA",
+				ServingTemp: Range{7, 10},
+			},
+		},
+	},
+	{
+		Name: "Amber and Brown Ale",
+		Styles: []Style{
+			{
+				Name:        "Irish Red Ale",
+				Aliases:     []string{"Red Ale"},
+				ABV:         Range{3.8, 5},
+				IBU:         Range{18, 28},
+				SRM:         Range{9, 14},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "American Amber Ale",
+				Aliases:     []string{"Amber Ale"},
+				ABV:         Range{4.5, 6.2},
+				IBU:         Range{25, 40},
+				SRM:         Range{10, 17},
+				Glassware:   "Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "American Brown Ale",
+				Aliases:     []string{"Brown Ale"},
+				ABV:         Range{4.3, 6.2},
+				IBU:         Range{20, 30},
+				SRM:         Range{18, 35},
+				Glassware:   "Pint",
+				ServingTemp: Range{7, 10},
+			},
+		},
+	},
+	{
+		Name: "Stout and Porter",
+		Styles: []Style{
+			{
+				Name:        "American Porter",
+				Aliases:     []string{"Porter"},
+				ABV:         Range{4.8, 6.5},
+				IBU:         Range{25, 50},
+				SRM:         Range{22, 40},
+				Glassware:   "Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Baltic Porter",
+				ABV:         Range{6.5, 9.5},
+				IBU:         Range{20, 40},
+				SRM:         Range{17, 30},
+				Glassware:   "Snifter",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Irish Stout",
+				Aliases:     []string{"Stout", "Dry Stout"},
+				ABV:         Range{4, 4.5},
+				IBU:         Range{25, 45},
+				SRM:         Range{25, 40},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Sweet Stout",
+				Aliases:     []string{"Milk Stout"},
+				ABV:         Range{4, 6},
+				IBU:         Range{20, 40},
+				SRM:         Range{30, 40},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Oatmeal Stout",
+				ABV:         Range{4.2, 5.9},
+				IBU:         Range{25, 40},
+				SRM:         Range{22, 40},
+				Glassware:   "Nonic Pint",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Russian Imperial Stout",
+				Aliases:     []string{"Imperial Stout", "RIS"},
+				ABV:         Range{8, 12},
+				IBU:         Range{50, 90},
+				SRM:         Range{30, 40},
+				Glassware:   "Snifter",
+				ServingTemp: Range{10, 13},
+			},
+		},
+	},
+	{
+		Name: "Belgian Ale",
+		Styles: []Style{
+			{
+				Name:        "Saison",
+				Aliases:     []string{"Farmhouse Ale"},
+				ABV:         Range{3.5, 9.5},
+				IBU:         Range{20, 35},
+				SRM:         Range{5, 22},
+				Glassware:   "Tulip",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Belgian Dubbel",
+				Aliases:     []string{"Dubbel"},
+				ABV:         Range{6, 7.6},
+				IBU:         Range{15, 25},
+				SRM:         Range{10, 17},
+				Glassware:   "Chalice",
+				ServingTemp: Range{10, 13},
+			},
+			{
+				Name:        "Belgian Tripel",
+				Aliases:     []string{"Tripel"},
+				ABV:         Range{7.5, 9.5},
+				IBU:         Range{20, 40},
+				SRM:         Range{4.5, 7},
+				Glassware:   "Chalice",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Belgian Golden Strong Ale",
+				Aliases:     []string{"Golden Strong Ale"},
+				ABV:         Range{7.5, 10.5},
+				IBU:         Range{22, 35},
+				SRM:         Range{3, 6},
+				Glassware:   "Tulip",
+				ServingTemp: Range{7, 10},
+			},
+			{
+				Name:        "Belgian Dark Strong Ale",
+				Aliases:     []string{"Quadrupel", "Quad"},
+				ABV:         Range{8, 12},
+				IBU:         Range{20, 35},
+				SRM:         Range{12, 22},
+				Glassware:   "Chalice",
+				ServingTemp: Range{10, 13},
+			},
+		},
+	},
+	{
+		Name: "Sour Ale",
+		Styles: []Style{
+			{
+				Name:        "Berliner Weisse",
+				ABV:         Range{2.8, 3.8},
+				IBU:         Range{3, 8},
+				SRM:         Range{2, 3},
+				Glassware:   "Goblet",
+				ServingTemp: Range{4, 7},
+				Substyles: []Style{
+					{
+						Name:        "Catharina Sour",
+						ABV:         Range{4, 5.5},
+						IBU:         Range{2, 8},
+						SRM:         Range{2, 7},
+						Glassware:   "Tulip",
+						ServingTemp: Range{4, 7},
+					},
+				},
+			},
+			{
+				Name:        "Gose",
+				ABV:         Range{4.2, 4.8},
+				IBU:         Range{5, 12},
+				SRM:         Range{3, 4},
+				Glassware:   "Stange",
+				ServingTemp: Range{4, 7},
+			},
+			{
+				Name:        "Flanders Red Ale",
+				ABV:         Range{4.6, 6.5},
+				IBU:         Range{10, 25},
+				SRM:         Range{10, 16},
+				Glassware:   "Tulip",
+				ServingTemp: Range{10, 13},
+			},
+			{
+				Name:        "Lambic",
+				ABV:         Range{5, 6.5},
+				IBU:         Range{0, 10},
+				SRM:         Range{3, 6},
+				Glassware:   "Tulip",
+				ServingTemp: Range{10, 13},
+				Substyles: []Style{
+					{
+						Name:        "Gueuze",
+						Aliases:     []string{"Geuze"},
+						ABV:         Range{5, 8},
+						IBU:         Range{0, 10},
+						SRM:         Range{5, 6},
+						Glassware:   "Tulip",
+						ServingTemp: Range{10, 13},
+					},
+					{
+						Name:        "Fruit Lambic",
+						Aliases:     []string{"Kriek", "Framboise"},
+						ABV:         Range{5, 7},
+						IBU:         Range{0, 10},
+						SRM:         Range{3, 7},
+						Glassware:   "Tulip",
+						ServingTemp: Range{10, 13},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name: "Strong Ale",
+		Styles: []Style{
+			{
+				Name:        "Wee Heavy",
+				Aliases:     []string{"Scotch Ale"},
+				ABV:         Range{6.5, 10},
+				IBU:         Range{17, 35},
+				SRM:         Range{14, 25},
+				Glassware:   "Thistle",
+				ServingTemp: Range{10, 13},
+			},
+			{
+				Name:        "English Barleywine",
+				ABV:         Range{8, 12},
+				IBU:         Range{35, 70},
+				SRM:         Range{8, 22},
+				Glassware:   "Snifter",
+				ServingTemp: Range{13, 16},
+			},
+			{
+				Name:        "American Barleywine",
+				Aliases:     []string{"Barleywine", "Barley Wine"},
+				ABV:         Range{8, 12},
+				IBU:         Range{50, 100},
+				SRM:         Range{10, 19},
+				Glassware:   "Snifter",
+				ServingTemp: Range{13, 16},
+			},
+		},
+	},
+}
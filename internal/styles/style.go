@@ -0,0 +1,89 @@
+// Package styles defines the beer style domain model and the catalog of the
+// styles a beer can have.
+package styles
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnknown is used when a style is not in the catalog.
+var ErrUnknown = errors.New("unknown style")
+
+// Range defines the inclusive bounds of a style guideline.
+type Range struct {
+	Min float32 `json:"min"`
+	Max float32 `json:"max"`
+}
+
+// Contains reports whether the value is within the range.
+func (r Range) Contains(v float32) bool {
+	return v >= r.Min && v <= r.Max
+}
+
+// Style defines the guidelines of a beer style, along with its substyles.
+// The serving temperature is in degrees Celsius.
+type Style struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	ABV         Range    `json:"abv"`
+	IBU         Range    `json:"ibu"`
+	SRM         Range    `json:"srm"`
+	Glassware   string   `json:"glassware"`
+	ServingTemp Range    `json:"serving_temp"`
+	Substyles   []Style  `json:"substyles,omitempty"`
+}
+
+// Family defines a group of related styles.
+type Family struct {
+	Name   string  `json:"name"`
+	Styles []Style `json:"styles"`
+}
+
+// Catalog is the hierarchy of the known styles.
+type Catalog []Family
+
+// Lookup returns the style or substyle known by the given name or alias,
+// regardless of the case and spacing.
+func (c Catalog) Lookup(name string) (Style, error) {
+	name = canonical(name)
+
+	var find func(list []Style) (Style, bool)
+	find = func(list []Style) (Style, bool) {
+		for _, s := range list {
+			if s.matches(name) {
+				return s, true
+			}
+			if sub, ok := find(s.Substyles); ok {
+				return sub, true
+			}
+		}
+		return Style{}, false
+	}
+
+	for _, f := range c {
+		if s, ok := find(f.Styles); ok {
+			return s, nil
+		}
+	}
+
+	return Style{}, ErrUnknown
+}
+
+// matches reports whether the style is known by the canonical name.
+func (s Style) matches(name string) bool {
+	if canonical(s.Name) == name {
+		return true
+	}
+	for _, alias := range s.Aliases {
+		if canonical(alias) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// canonical returns the name in lower case and with the spaces collapsed.
+func canonical(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,89 @@
+package styles_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/phbpx/gobeer/internal/styles"
+)
+
+func TestLookup(t *testing.T) {
+	t.Log("Given the need to look up the styles of the catalog")
+	{
+		t.Log("\tWhen looking up a style by its name")
+		{
+			s, err := styles.DefaultCatalog.Lookup("Saison")
+			if err != nil || s.Name != "Saison" {
+				t.Fatalf("\t\t[ERROR] Should find the style. Got %q, %v", s.Name, err)
+			}
+			t.Log("\t\t[OK] Should find the style.")
+		}
+
+		t.Log("\tWhen looking up a style by an alias in another case and spacing")
+		{
+			s, err := styles.DefaultCatalog.Lookup("  new   england ipa ")
+			if err != nil || s.Name != "Hazy IPA" {
+				t.Fatalf("\t\t[ERROR] Should find the canonical style. Got %q, %v", s.Name, err)
+			}
+			t.Log("\t\t[OK] Should find the canonical style.")
+		}
+
+		t.Log("\tWhen looking up a substyle")
+		{
+			s, err := styles.DefaultCatalog.Lookup("Catharina Sour")
+			if err != nil || s.Name != "Catharina Sour" || !s.ABV.Contains(4.5) {
+				t.Fatalf("\t\t[ERROR] Should find the substyle with its own guidelines. Got %+v, %v", s, err)
+			}
+			t.Log("\t\t[OK] Should find the substyle with its own guidelines.")
+		}
+
+		t.Log("\tWhen looking up an unknown style")
+		{
+			if _, err := styles.DefaultCatalog.Lookup("Pumpkin Spice Latte"); !errors.Is(err, styles.ErrUnknown) {
+				t.Fatalf("\t\t[ERROR] Should return ErrUnknown. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should return ErrUnknown.")
+		}
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	t.Log("Given the default catalog")
+	{
+		t.Log("\tWhen checking its styles")
+		{
+			seen := make(map[string]string)
+
+			var check func(family string, list []styles.Style)
+			check = func(family string, list []styles.Style) {
+				for _, s := range list {
+					for _, name := range append([]string{s.Name}, s.Aliases...) {
+						key := strings.ToLower(name)
+						if other, ok := seen[key]; ok {
+							t.Fatalf("\t\t[ERROR] Should know %q by a single style. Got %q and %q", name, other, s.Name)
+						}
+						seen[key] = s.Name
+					}
+
+					for _, r := range []styles.Range{s.ABV, s.IBU, s.SRM, s.ServingTemp} {
+						if r.Min < 0 || r.Min > r.Max {
+							t.Fatalf("\t\t[ERROR] Should have valid ranges. Got %+v on %s/%s", r, family, s.Name)
+						}
+					}
+
+					if s.Glassware == "" {
+						t.Fatalf("\t\t[ERROR] Should have a glassware on %s/%s", family, s.Name)
+					}
+
+					check(family, s.Substyles)
+				}
+			}
+
+			for _, f := range styles.DefaultCatalog {
+				check(f.Name, f.Styles)
+			}
+			t.Log("\t\t[OK] Should have unique names and aliases and valid guidelines.")
+		}
+	}
+}
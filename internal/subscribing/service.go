@@ -0,0 +1,194 @@
+// Package subscribing provides the use cases for managing the webhook
+// subscriptions and their deliveries.
+package subscribing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// MaxDeliveries is the number of deliveries returned in the delivery log.
+const MaxDeliveries = 100
+
+// NewSubscription defines the input parameters for subscribing a webhook.
+type NewSubscription struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=beer_added review_created"`
+	Secret string   `json:"secret" binding:"required,min=16"`
+}
+
+// Repository defines the interface for the subscribing service to interact
+// with the storage.
+type Repository interface {
+	// CreateSubscription stores a new subscription.
+	CreateSubscription(ctx context.Context, s webhooks.Subscription) error
+	// GetSubscription returns the subscription with the given ID.
+	GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error)
+	// ListSubscriptions returns every subscription, from the oldest to the
+	// most recent.
+	ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error)
+	// DeleteSubscription deletes a subscription and its deliveries.
+	DeleteSubscription(ctx context.Context, id string) error
+	// ListDeliveries returns the most recent deliveries of a subscription.
+	ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhooks.Delivery, error)
+	// GetDelivery returns the delivery with the given ID.
+	GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error)
+	// CreateDelivery queues a new delivery.
+	CreateDelivery(ctx context.Context, d webhooks.Delivery) error
+}
+
+// Service provides webhook subscription operations.
+type Service struct {
+	r Repository
+}
+
+// NewService creates a subscribing service with the necessary dependencies.
+func NewService(r Repository) *Service {
+	return &Service{r}
+}
+
+// Subscribe creates a new subscription. The events of the given types are
+// delivered to the URL from now on.
+func (s *Service) Subscribe(ctx context.Context, ns NewSubscription) (webhooks.Subscription, error) {
+	u, err := url.Parse(ns.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return webhooks.Subscription{}, webhooks.ErrInvalidURL
+	}
+
+	sub := webhooks.Subscription{
+		ID:        uuid.NewString(),
+		URL:       u.String(),
+		Secret:    ns.Secret,
+		CreatedAt: time.Now(),
+	}
+
+	// Keep the event types in their canonical order, without duplicates.
+	for _, e := range webhooks.EventTypes {
+		for _, want := range ns.Events {
+			if e == want {
+				sub.Events = append(sub.Events, e)
+				break
+			}
+		}
+	}
+
+	if err := s.r.CreateSubscription(ctx, sub); err != nil {
+		return webhooks.Subscription{}, fmt.Errorf("create subscription: %w", err)
+	}
+
+	return redact(sub), nil
+}
+
+// ListSubscriptions returns every subscription.
+func (s *Service) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	list, err := s.r.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list subscriptions: %w", err)
+	}
+
+	for i := range list {
+		list[i] = redact(list[i])
+	}
+
+	return list, nil
+}
+
+// GetSubscription returns a subscription.
+func (s *Service) GetSubscription(ctx context.Context, id string) (webhooks.Subscription, error) {
+	sub, err := s.subscription(ctx, id)
+	if err != nil {
+		return webhooks.Subscription{}, err
+	}
+
+	return redact(*sub), nil
+}
+
+// Unsubscribe deletes a subscription, along with its pending deliveries.
+func (s *Service) Unsubscribe(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return webhooks.ErrInvalidID
+	}
+
+	if err := s.r.DeleteSubscription(ctx, id); err != nil {
+		return fmt.Errorf("delete subscription[id=%s]: %w", id, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, from the most
+// recent delivery to the oldest.
+func (s *Service) ListDeliveries(ctx context.Context, id string) ([]webhooks.Delivery, error) {
+	if _, err := s.subscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	list, err := s.r.ListDeliveries(ctx, id, MaxDeliveries)
+	if err != nil {
+		return nil, fmt.Errorf("list subscription[id=%s] deliveries: %w", id, err)
+	}
+
+	return list, nil
+}
+
+// Redeliver queues the event of a past delivery to be delivered again, as a
+// new delivery.
+func (s *Service) Redeliver(ctx context.Context, id, deliveryID string) (webhooks.Delivery, error) {
+	if _, err := s.subscription(ctx, id); err != nil {
+		return webhooks.Delivery{}, err
+	}
+
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		return webhooks.Delivery{}, webhooks.ErrDeliveryNotFound
+	}
+
+	prev, err := s.r.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return webhooks.Delivery{}, fmt.Errorf("get delivery[id=%s]: %w", deliveryID, err)
+	}
+
+	if prev.SubscriptionID != id {
+		return webhooks.Delivery{}, fmt.Errorf("get delivery[id=%s]: %w", deliveryID, webhooks.ErrDeliveryNotFound)
+	}
+
+	now := time.Now()
+	d := webhooks.Delivery{
+		ID:             uuid.NewString(),
+		SubscriptionID: id,
+		Event:          prev.Event,
+		Status:         webhooks.StatusPending,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+	}
+
+	if err := s.r.CreateDelivery(ctx, d); err != nil {
+		return webhooks.Delivery{}, fmt.Errorf("create delivery: %w", err)
+	}
+
+	return d, nil
+}
+
+// subscription returns the subscription with the given ID.
+func (s *Service) subscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, webhooks.ErrInvalidID
+	}
+
+	sub, err := s.r.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription[id=%s]: %w", id, err)
+	}
+
+	return sub, nil
+}
+
+// redact removes the secret from the subscription.
+func redact(sub webhooks.Subscription) webhooks.Subscription {
+	sub.Secret = ""
+	return sub
+}
@@ -0,0 +1,167 @@
+package subscribing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/subscribing"
+	"github.com/phbpx/gobeer/internal/webhooks"
+)
+
+// mockRepository is a mock implementation of the Repository interface.
+type mockRepository struct {
+	subscriptions []webhooks.Subscription
+	deliveries    []webhooks.Delivery
+}
+
+// CreateSubscription stores the subscription.
+func (m *mockRepository) CreateSubscription(ctx context.Context, s webhooks.Subscription) error {
+	m.subscriptions = append(m.subscriptions, s)
+	return nil
+}
+
+// GetSubscription returns the subscription with the given ID.
+func (m *mockRepository) GetSubscription(ctx context.Context, id string) (*webhooks.Subscription, error) {
+	for _, s := range m.subscriptions {
+		if s.ID == id {
+			return &s, nil
+		}
+	}
+	return nil, webhooks.ErrNotFound
+}
+
+// ListSubscriptions returns every subscription.
+func (m *mockRepository) ListSubscriptions(ctx context.Context) ([]webhooks.Subscription, error) {
+	return append([]webhooks.Subscription(nil), m.subscriptions...), nil
+}
+
+// DeleteSubscription deletes the subscription.
+func (m *mockRepository) DeleteSubscription(ctx context.Context, id string) error {
+	for i := range m.subscriptions {
+		if m.subscriptions[i].ID == id {
+			m.subscriptions = append(m.subscriptions[:i], m.subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return webhooks.ErrNotFound
+}
+
+// ListDeliveries returns the deliveries of the subscription.
+func (m *mockRepository) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]webhooks.Delivery, error) {
+	var list []webhooks.Delivery
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID && len(list) < limit {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+// GetDelivery returns the delivery with the given ID.
+func (m *mockRepository) GetDelivery(ctx context.Context, id string) (*webhooks.Delivery, error) {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, webhooks.ErrDeliveryNotFound
+}
+
+// CreateDelivery stores the delivery.
+func (m *mockRepository) CreateDelivery(ctx context.Context, d webhooks.Delivery) error {
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &mockRepository{}
+	s := subscribing.NewService(repo)
+
+	t.Log("Given the need to subscribe a webhook")
+	{
+		t.Log("\tWhen subscribing a valid URL")
+		{
+			sub, err := s.Subscribe(ctx, subscribing.NewSubscription{
+				URL:    "https://example.com/hooks",
+				Events: []string{webhooks.EventReviewCreated, webhooks.EventBeerAdded, webhooks.EventReviewCreated},
+				Secret: "0123456789abcdef",
+			})
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to subscribe without error: %v", err)
+			}
+			if sub.Secret != "" {
+				t.Fatalf("\t\t[ERROR] Should not return the secret. Got %q", sub.Secret)
+			}
+			if len(sub.Events) != 2 || sub.Events[0] != webhooks.EventBeerAdded || sub.Events[1] != webhooks.EventReviewCreated {
+				t.Fatalf("\t\t[ERROR] Should keep the events in their canonical order. Got %v", sub.Events)
+			}
+			if len(repo.subscriptions) != 1 || repo.subscriptions[0].Secret != "0123456789abcdef" {
+				t.Fatalf("\t\t[ERROR] Should store the secret. Got %+v", repo.subscriptions)
+			}
+			t.Log("\t\t[OK] Should be able to subscribe without error.")
+		}
+
+		t.Log("\tWhen subscribing a URL which can't be delivered to")
+		{
+			_, err := s.Subscribe(ctx, subscribing.NewSubscription{
+				URL:    "ftp://example.com/hooks",
+				Events: []string{webhooks.EventBeerAdded},
+				Secret: "0123456789abcdef",
+			})
+			if !errors.Is(err, webhooks.ErrInvalidURL) {
+				t.Fatalf("\t\t[ERROR] Should return ErrInvalidURL. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should return ErrInvalidURL.")
+		}
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	ctx := context.Background()
+
+	sub := webhooks.Subscription{ID: uuid.NewString(), URL: "https://example.com/hooks", Events: webhooks.EventTypes}
+	other := webhooks.Subscription{ID: uuid.NewString(), URL: "https://example.org/hooks", Events: webhooks.EventTypes}
+
+	e := webhooks.Event{ID: uuid.NewString(), Type: webhooks.EventBeerAdded, OccurredAt: time.Now()}
+	prev := webhooks.NewDelivery(sub.ID, e)
+	prev.Status = webhooks.StatusFailed
+	prev.Attempts = 10
+
+	repo := &mockRepository{
+		subscriptions: []webhooks.Subscription{sub, other},
+		deliveries:    []webhooks.Delivery{prev},
+	}
+	s := subscribing.NewService(repo)
+
+	t.Log("Given the need to redeliver an event")
+	{
+		t.Log("\tWhen redelivering a failed delivery")
+		{
+			d, err := s.Redeliver(ctx, sub.ID, prev.ID)
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should be able to redeliver without error: %v", err)
+			}
+			if d.ID == prev.ID || d.Status != webhooks.StatusPending || d.Attempts != 0 || d.Event.ID != e.ID {
+				t.Fatalf("\t\t[ERROR] Should queue a new delivery of the event. Got %+v", d)
+			}
+			if len(repo.deliveries) != 2 {
+				t.Fatalf("\t\t[ERROR] Should store the new delivery. Got %d", len(repo.deliveries))
+			}
+			t.Log("\t\t[OK] Should be able to redeliver without error.")
+		}
+
+		t.Log("\tWhen redelivering a delivery of another subscription")
+		{
+			_, err := s.Redeliver(ctx, other.ID, prev.ID)
+			if !errors.Is(err, webhooks.ErrDeliveryNotFound) {
+				t.Fatalf("\t\t[ERROR] Should return ErrDeliveryNotFound. Got %v", err)
+			}
+			t.Log("\t\t[OK] Should return ErrDeliveryNotFound.")
+		}
+	}
+}
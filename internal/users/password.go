@@ -0,0 +1,68 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Set of parameters of the password hashes. The iterations make every guess
+// slow, and the random salt makes the hashes of the same password differ.
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 600_000
+	saltSize       = 16
+	keySize        = 32
+)
+
+// HashPassword returns the salted PBKDF2-HMAC-SHA256 hash of the password,
+// encoded along with its parameters as scheme$iterations$salt$key.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generating salt: %w", err)
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, hashIterations, keySize, sha256.New)
+
+	return strings.Join([]string{
+		hashScheme,
+		strconv.Itoa(hashIterations),
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	}, "$"), nil
+}
+
+// CheckPassword reports whether the password matches the hash. The hashes
+// are checked with the parameters they were created with, so they remain
+// valid when the parameters change.
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	got := pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New)
+	return subtle.ConstantTimeCompare(got, key) == 1
+}
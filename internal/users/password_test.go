@@ -0,0 +1,89 @@
+package users_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/phbpx/gobeer/internal/users"
+)
+
+// hashOf encodes a known key as a password hash, with the given parameters.
+func hashOf(t *testing.T, salt string, iterations int, key string) string {
+	k, err := hex.DecodeString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Join([]string{
+		"pbkdf2-sha256",
+		strconv.Itoa(iterations),
+		base64.RawStdEncoding.EncodeToString([]byte(salt)),
+		base64.RawStdEncoding.EncodeToString(k),
+	}, "$")
+}
+
+func TestCheckPassword(t *testing.T) {
+	// The PBKDF2-HMAC-SHA256 test vectors of RFC 7914, section 11, and the
+	// ones commonly used along with the SHA-1 vectors of RFC 6070.
+	vectors := []struct {
+		password   string
+		salt       string
+		iterations int
+		key        string
+	}{
+		{"password", "salt", 1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+
+	t.Log("Given the need to check the passwords against their hashes.")
+	{
+		t.Log("\tWhen checking the known answers of PBKDF2-HMAC-SHA256.")
+		{
+			for _, v := range vectors {
+				hash := hashOf(t, v.salt, v.iterations, v.key)
+
+				if !users.CheckPassword(hash, v.password) {
+					t.Fatalf("\t\t[ERROR] Should derive the known key of %q with %d iterations.", v.password, v.iterations)
+				}
+				if users.CheckPassword(hash, v.password+"!") {
+					t.Fatalf("\t\t[ERROR] Should not match another password with %d iterations.", v.iterations)
+				}
+			}
+			t.Log("\t\t[OK] Should derive the known keys.")
+		}
+
+		t.Log("\tWhen checking a new hash.")
+		{
+			hash, err := users.HashPassword("correct horse battery staple")
+			if err != nil {
+				t.Fatalf("\t\t[ERROR] Should hash the password: %v", err)
+			}
+
+			other, err := users.HashPassword("correct horse battery staple")
+			if err != nil || other == hash {
+				t.Fatalf("\t\t[ERROR] Should salt every hash. Got %q: %v", other, err)
+			}
+
+			if !users.CheckPassword(hash, "correct horse battery staple") || users.CheckPassword(hash, "wrong") {
+				t.Fatalf("\t\t[ERROR] Should only match the hashed password. Got %q", hash)
+			}
+			t.Log("\t\t[OK] Should only match the hashed password.")
+		}
+
+		t.Log("\tWhen checking a malformed hash.")
+		{
+			for _, hash := range []string{"", "bcrypt$1$c2FsdA$AAAA", "pbkdf2-sha256$0$c2FsdA$AAAA", "pbkdf2-sha256$1$c2FsdA$"} {
+				if users.CheckPassword(hash, "password") {
+					t.Fatalf("\t\t[ERROR] Should not match the malformed hash %q.", hash)
+				}
+			}
+			t.Log("\t\t[OK] Should not match a malformed hash.")
+		}
+	}
+}
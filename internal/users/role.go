@@ -0,0 +1,31 @@
+package users
+
+import "errors"
+
+// ErrInvalidRole is used when a role is not one of the known roles.
+var ErrInvalidRole = errors.New("invalid role")
+
+// Role defines what a user is allowed to do, as granted by the policy.
+type Role string
+
+// Set of roles of the users. New users are members.
+const (
+	RoleAdmin     Role = "admin"
+	RoleModerator Role = "moderator"
+	RoleBrewer    Role = "brewer"
+	RoleMember    Role = "member"
+)
+
+// Roles is the list of the known roles.
+var Roles = []Role{RoleAdmin, RoleModerator, RoleBrewer, RoleMember}
+
+// ParseRole returns the role with the given name, or ErrInvalidRole when
+// there is no such role.
+func ParseRole(name string) (Role, error) {
+	for _, r := range Roles {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	return "", ErrInvalidRole
+}
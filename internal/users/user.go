@@ -0,0 +1,82 @@
+// Package users defines the user domain model.
+package users
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidID is returned when an invalid ID is provided.
+	ErrInvalidID = errors.New("invalid user ID")
+
+	// ErrNotFound is used when a user is not found.
+	ErrNotFound = errors.New("user not found")
+
+	// ErrInvalidHandle is used when a handle has characters other than
+	// lower case letters, digits and underscores.
+	ErrInvalidHandle = errors.New("invalid handle")
+
+	// ErrHandleTaken is used when a handle belongs to another user.
+	ErrHandleTaken = errors.New("handle already taken")
+
+	// ErrEmailTaken is used when an email belongs to another user.
+	ErrEmailTaken = errors.New("email already registered")
+
+	// ErrWrongPassword is used when the current password of a user doesn't
+	// match the one given to change it.
+	ErrWrongPassword = errors.New("wrong current password")
+)
+
+// User defines the properties of a user. The email and the password hash are
+// only kept by the storage, they're never returned to other users, and the
+// password hash is never encoded at all.
+type User struct {
+	ID           string    `json:"id"`
+	Handle       string    `json:"handle"`
+	Email        string    `json:"email,omitempty"`
+	DisplayName  string    `json:"display_name"`
+	Bio          string    `json:"bio"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// handlePattern defines the characters of a handle.
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// NormalizeHandle returns the handle in its canonical, lower case, form. It
+// returns ErrInvalidHandle when the handle has other characters than letters,
+// digits and underscores.
+func NormalizeHandle(handle string) (string, error) {
+	h := strings.ToLower(strings.TrimSpace(handle))
+	if !handlePattern.MatchString(h) {
+		return "", ErrInvalidHandle
+	}
+	return h, nil
+}
+
+// NormalizeEmail returns the email in its canonical, lower case, form, so
+// an email is registered once whatever its case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Legacy returns the placeholder user of an author of reviews written before
+// the users were registered, created at the time of the first review. It has
+// no password, so nobody can log in as it, and the same user is derived from
+// the ID by every storage.
+func Legacy(id string, createdAt time.Time) User {
+	return User{
+		ID:          id,
+		Handle:      "legacy_" + strings.ReplaceAll(id, "-", ""),
+		Email:       id + "@legacy.invalid",
+		DisplayName: "Legacy user",
+		Role:        RoleMember,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
@@ -0,0 +1,127 @@
+// Package webhooks defines the webhook domain model.
+package webhooks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/phbpx/gobeer/internal/beers"
+	"github.com/phbpx/gobeer/internal/events"
+	"github.com/phbpx/gobeer/internal/reviews"
+)
+
+var (
+	// ErrInvalidID is returned when an invalid ID is provided.
+	ErrInvalidID = errors.New("invalid webhook ID")
+
+	// ErrNotFound is used when a webhook subscription is not found.
+	ErrNotFound = errors.New("webhook not found")
+
+	// ErrDeliveryNotFound is used when a webhook delivery is not found.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// ErrInvalidURL is used when a webhook URL can't be delivered to.
+	ErrInvalidURL = errors.New("invalid webhook URL")
+)
+
+// Set of event types a webhook can subscribe to.
+const (
+	EventBeerAdded     = "beer_added"
+	EventReviewCreated = "review_created"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []string{EventBeerAdded, EventReviewCreated}
+
+// Set of delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// deliveryNamespace is the namespace of the delivery IDs derived from their
+// event and subscription.
+var deliveryNamespace = uuid.MustParse("0b6c5a4e-8f0e-4f4b-9a43-3f1c1d7c2a90")
+
+// Subscription defines a URL receiving the events of the given types. The
+// secret signs the deliveries, it's never returned once stored.
+type Subscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Accepts checks if the subscription receives the events of the given type.
+func (s Subscription) Accepts(eventType string) bool {
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event defines what happened, as sent to the webhooks. It's built when the
+// change is stored, so it describes the data at that moment.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Beer       *beers.Beer     `json:"beer,omitempty"`
+	Review     *reviews.Review `json:"review,omitempty"`
+}
+
+// NewBeerAdded returns the event sent to the webhooks when a beer is added.
+func NewBeerAdded(e events.BeerAdded) Event {
+	return Event{
+		ID:         e.ID,
+		Type:       EventBeerAdded,
+		OccurredAt: e.OccurredAt,
+		Beer:       &e.Beer,
+	}
+}
+
+// NewReviewCreated returns the event sent to the webhooks when a beer is
+// reviewed.
+func NewReviewCreated(e events.ReviewAdded) Event {
+	return Event{
+		ID:         e.ID,
+		Type:       EventReviewCreated,
+		OccurredAt: e.OccurredAt,
+		Beer:       &e.Beer,
+		Review:     &e.Review,
+	}
+}
+
+// Delivery defines the delivery of an event to a subscription, along with
+// the result of its last attempt.
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	Event          Event      `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	ResponseStatus int        `json:"response_status"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+// NewDelivery returns the pending delivery of the event to the subscription.
+// Its ID is derived from both, so the same event is never queued twice for a
+// subscription.
+func NewDelivery(subscriptionID string, e Event) Delivery {
+	return Delivery{
+		ID:             uuid.NewSHA1(deliveryNamespace, []byte(subscriptionID+"/"+e.ID)).String(),
+		SubscriptionID: subscriptionID,
+		Event:          e,
+		Status:         StatusPending,
+		NextAttemptAt:  e.OccurredAt,
+		CreatedAt:      e.OccurredAt,
+	}
+}